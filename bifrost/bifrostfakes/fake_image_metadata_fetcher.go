@@ -5,30 +5,30 @@ import (
 	"sync"
 
 	"code.cloudfoundry.org/eirini/bifrost"
+	"code.cloudfoundry.org/eirini/stager/docker"
 	"github.com/containers/image/types"
-	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 type FakeImageMetadataFetcher struct {
-	Stub        func(string, types.SystemContext) (*v1.ImageConfig, error)
+	Stub        func(string, types.SystemContext) (*docker.ImageMetadata, error)
 	mutex       sync.RWMutex
 	argsForCall []struct {
 		arg1 string
 		arg2 types.SystemContext
 	}
 	returns struct {
-		result1 *v1.ImageConfig
+		result1 *docker.ImageMetadata
 		result2 error
 	}
 	returnsOnCall map[int]struct {
-		result1 *v1.ImageConfig
+		result1 *docker.ImageMetadata
 		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeImageMetadataFetcher) Spy(arg1 string, arg2 types.SystemContext) (*v1.ImageConfig, error) {
+func (fake *FakeImageMetadataFetcher) Spy(arg1 string, arg2 types.SystemContext) (*docker.ImageMetadata, error) {
 	fake.mutex.Lock()
 	ret, specificReturn := fake.returnsOnCall[len(fake.argsForCall)]
 	fake.argsForCall = append(fake.argsForCall, struct {
@@ -54,7 +54,7 @@ func (fake *FakeImageMetadataFetcher) CallCount() int {
 	return len(fake.argsForCall)
 }
 
-func (fake *FakeImageMetadataFetcher) Calls(stub func(string, types.SystemContext) (*v1.ImageConfig, error)) {
+func (fake *FakeImageMetadataFetcher) Calls(stub func(string, types.SystemContext) (*docker.ImageMetadata, error)) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = stub
@@ -66,28 +66,28 @@ func (fake *FakeImageMetadataFetcher) ArgsForCall(i int) (string, types.SystemCo
 	return fake.argsForCall[i].arg1, fake.argsForCall[i].arg2
 }
 
-func (fake *FakeImageMetadataFetcher) Returns(result1 *v1.ImageConfig, result2 error) {
+func (fake *FakeImageMetadataFetcher) Returns(result1 *docker.ImageMetadata, result2 error) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = nil
 	fake.returns = struct {
-		result1 *v1.ImageConfig
+		result1 *docker.ImageMetadata
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeImageMetadataFetcher) ReturnsOnCall(i int, result1 *v1.ImageConfig, result2 error) {
+func (fake *FakeImageMetadataFetcher) ReturnsOnCall(i int, result1 *docker.ImageMetadata, result2 error) {
 	fake.mutex.Lock()
 	defer fake.mutex.Unlock()
 	fake.Stub = nil
 	if fake.returnsOnCall == nil {
 		fake.returnsOnCall = make(map[int]struct {
-			result1 *v1.ImageConfig
+			result1 *docker.ImageMetadata
 			result2 error
 		})
 	}
 	fake.returnsOnCall[i] = struct {
-		result1 *v1.ImageConfig
+		result1 *docker.ImageMetadata
 		result2 error
 	}{result1, result2}
 }
@@ -61,28 +61,32 @@ type FakeLRPClient struct {
 		result1 []*opi.LRP
 		result2 error
 	}
-	StopStub        func(opi.LRPIdentifier) error
+	StopStub        func(opi.LRPIdentifier) (*opi.LRP, error)
 	stopMutex       sync.RWMutex
 	stopArgsForCall []struct {
 		arg1 opi.LRPIdentifier
 	}
 	stopReturns struct {
-		result1 error
+		result1 *opi.LRP
+		result2 error
 	}
 	stopReturnsOnCall map[int]struct {
-		result1 error
+		result1 *opi.LRP
+		result2 error
 	}
-	StopInstanceStub        func(opi.LRPIdentifier, uint) error
+	StopInstanceStub        func(opi.LRPIdentifier, uint) (*opi.LRP, error)
 	stopInstanceMutex       sync.RWMutex
 	stopInstanceArgsForCall []struct {
 		arg1 opi.LRPIdentifier
 		arg2 uint
 	}
 	stopInstanceReturns struct {
-		result1 error
+		result1 *opi.LRP
+		result2 error
 	}
 	stopInstanceReturnsOnCall map[int]struct {
-		result1 error
+		result1 *opi.LRP
+		result2 error
 	}
 	UpdateStub        func(*opi.LRP) error
 	updateMutex       sync.RWMutex
@@ -346,7 +350,7 @@ func (fake *FakeLRPClient) ListReturnsOnCall(i int, result1 []*opi.LRP, result2
 	}{result1, result2}
 }
 
-func (fake *FakeLRPClient) Stop(arg1 opi.LRPIdentifier) error {
+func (fake *FakeLRPClient) Stop(arg1 opi.LRPIdentifier) (*opi.LRP, error) {
 	fake.stopMutex.Lock()
 	ret, specificReturn := fake.stopReturnsOnCall[len(fake.stopArgsForCall)]
 	fake.stopArgsForCall = append(fake.stopArgsForCall, struct {
@@ -360,9 +364,9 @@ func (fake *FakeLRPClient) Stop(arg1 opi.LRPIdentifier) error {
 		return stub(arg1)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeLRPClient) StopCallCount() int {
@@ -371,7 +375,7 @@ func (fake *FakeLRPClient) StopCallCount() int {
 	return len(fake.stopArgsForCall)
 }
 
-func (fake *FakeLRPClient) StopCalls(stub func(opi.LRPIdentifier) error) {
+func (fake *FakeLRPClient) StopCalls(stub func(opi.LRPIdentifier) (*opi.LRP, error)) {
 	fake.stopMutex.Lock()
 	defer fake.stopMutex.Unlock()
 	fake.StopStub = stub
@@ -384,30 +388,33 @@ func (fake *FakeLRPClient) StopArgsForCall(i int) opi.LRPIdentifier {
 	return argsForCall.arg1
 }
 
-func (fake *FakeLRPClient) StopReturns(result1 error) {
+func (fake *FakeLRPClient) StopReturns(result1 *opi.LRP, result2 error) {
 	fake.stopMutex.Lock()
 	defer fake.stopMutex.Unlock()
 	fake.StopStub = nil
 	fake.stopReturns = struct {
-		result1 error
-	}{result1}
+		result1 *opi.LRP
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeLRPClient) StopReturnsOnCall(i int, result1 error) {
+func (fake *FakeLRPClient) StopReturnsOnCall(i int, result1 *opi.LRP, result2 error) {
 	fake.stopMutex.Lock()
 	defer fake.stopMutex.Unlock()
 	fake.StopStub = nil
 	if fake.stopReturnsOnCall == nil {
 		fake.stopReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 *opi.LRP
+			result2 error
 		})
 	}
 	fake.stopReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+		result1 *opi.LRP
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeLRPClient) StopInstance(arg1 opi.LRPIdentifier, arg2 uint) error {
+func (fake *FakeLRPClient) StopInstance(arg1 opi.LRPIdentifier, arg2 uint) (*opi.LRP, error) {
 	fake.stopInstanceMutex.Lock()
 	ret, specificReturn := fake.stopInstanceReturnsOnCall[len(fake.stopInstanceArgsForCall)]
 	fake.stopInstanceArgsForCall = append(fake.stopInstanceArgsForCall, struct {
@@ -422,9 +429,9 @@ func (fake *FakeLRPClient) StopInstance(arg1 opi.LRPIdentifier, arg2 uint) error
 		return stub(arg1, arg2)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeLRPClient) StopInstanceCallCount() int {
@@ -433,7 +440,7 @@ func (fake *FakeLRPClient) StopInstanceCallCount() int {
 	return len(fake.stopInstanceArgsForCall)
 }
 
-func (fake *FakeLRPClient) StopInstanceCalls(stub func(opi.LRPIdentifier, uint) error) {
+func (fake *FakeLRPClient) StopInstanceCalls(stub func(opi.LRPIdentifier, uint) (*opi.LRP, error)) {
 	fake.stopInstanceMutex.Lock()
 	defer fake.stopInstanceMutex.Unlock()
 	fake.StopInstanceStub = stub
@@ -446,27 +453,30 @@ func (fake *FakeLRPClient) StopInstanceArgsForCall(i int) (opi.LRPIdentifier, ui
 	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeLRPClient) StopInstanceReturns(result1 error) {
+func (fake *FakeLRPClient) StopInstanceReturns(result1 *opi.LRP, result2 error) {
 	fake.stopInstanceMutex.Lock()
 	defer fake.stopInstanceMutex.Unlock()
 	fake.StopInstanceStub = nil
 	fake.stopInstanceReturns = struct {
-		result1 error
-	}{result1}
+		result1 *opi.LRP
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeLRPClient) StopInstanceReturnsOnCall(i int, result1 error) {
+func (fake *FakeLRPClient) StopInstanceReturnsOnCall(i int, result1 *opi.LRP, result2 error) {
 	fake.stopInstanceMutex.Lock()
 	defer fake.stopInstanceMutex.Unlock()
 	fake.StopInstanceStub = nil
 	if fake.stopInstanceReturnsOnCall == nil {
 		fake.stopInstanceReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 *opi.LRP
+			result2 error
 		})
 	}
 	fake.stopInstanceReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+		result1 *opi.LRP
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeLRPClient) Update(arg1 *opi.LRP) error {
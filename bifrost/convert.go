@@ -4,16 +4,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/models/cf"
 	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/eirini/stager/docker"
 	"code.cloudfoundry.org/lager"
-	"github.com/containers/image/types"
 	"github.com/pkg/errors"
 )
 
-const DockerHubHost = "index.docker.io/v1/"
+const (
+	DockerHubHost = "index.docker.io/v1/"
+
+	CFRouterKey       = "cf-router"
+	TCPRouterKey      = "tcp-router"
+	InternalRouterKey = "internal-router"
+)
 
 var dockerRX = regexp.MustCompile(`([a-zA-Z0-9.-]+)(:([0-9]+))?/(\S+/\S+)`)
 
@@ -22,7 +30,9 @@ type lifecycleOptions struct {
 	env             map[string]string
 	image           string
 	privateRegistry *opi.PrivateRegistry
-	runsAsRoot      bool
+	runAsUser       *int64
+	runAsGroup      *int64
+	architecture    string
 }
 
 type OPIConverter struct {
@@ -30,14 +40,18 @@ type OPIConverter struct {
 	imageMetadataFetcher ImageMetadataFetcher
 	imageRefParser       ImageRefParser
 	allowRunImageAsRoot  bool
+	imagePolicy          ImagePolicy
+	imagePlatform        eirini.ImagePlatformConfig
 }
 
-func NewOPIConverter(logger lager.Logger, imageMetadataFetcher ImageMetadataFetcher, imageRefParser ImageRefParser, allowRunImageAsRoot bool) *OPIConverter {
+func NewOPIConverter(logger lager.Logger, imageMetadataFetcher ImageMetadataFetcher, imageRefParser ImageRefParser, allowRunImageAsRoot bool, imagePolicy ImagePolicy, imagePlatform eirini.ImagePlatformConfig) *OPIConverter {
 	return &OPIConverter{
 		logger:               logger,
 		imageMetadataFetcher: imageMetadataFetcher,
 		imageRefParser:       imageRefParser,
 		allowRunImageAsRoot:  allowRunImageAsRoot,
+		imagePolicy:          imagePolicy,
+		imagePlatform:        imagePlatform,
 	}
 }
 
@@ -81,10 +95,22 @@ func (c *OPIConverter) ConvertLRP(request cf.DesireLRPRequest) (opi.LRP, error)
 		return opi.LRP{}, err
 	}
 
+	tcpRoutes, err := getTCPRoutes(request.Routes)
+	if err != nil {
+		return opi.LRP{}, err
+	}
+
+	internalRoutes, err := getInternalRoutes(request.Routes)
+	if err != nil {
+		return opi.LRP{}, err
+	}
+
 	return opi.LRP{
 		AppName:                request.AppName,
 		AppGUID:                request.AppGUID,
 		AppURIs:                routes,
+		TCPRoutes:              tcpRoutes,
+		InternalRoutes:         internalRoutes,
 		LastUpdated:            request.LastUpdated,
 		OrgName:                request.OrganizationName,
 		OrgGUID:                request.OrganizationGUID,
@@ -105,7 +131,9 @@ func (c *OPIConverter) ConvertLRP(request cf.DesireLRPRequest) (opi.LRP, error)
 		LRP:                    request.LRP,
 		UserDefinedAnnotations: request.UserDefinedAnnotations,
 		PrivateRegistry:        lrpLifecycleOptions.privateRegistry,
-		RunsAsRoot:             lrpLifecycleOptions.runsAsRoot,
+		RunAsUser:              lrpLifecycleOptions.runAsUser,
+		RunAsGroup:             lrpLifecycleOptions.runAsGroup,
+		Architecture:           lrpLifecycleOptions.architecture,
 	}, nil
 }
 
@@ -136,52 +164,84 @@ func (c *OPIConverter) ConvertTask(taskGUID string, request cf.TaskRequest) (opi
 	}
 
 	lifecycle := request.Lifecycle.DockerLifecycle
-	task.Command = lifecycle.Command
-	task.Image = lifecycle.Image
 
-	if lifecycle.RegistryUsername != "" || lifecycle.RegistryPassword != "" {
-		task.PrivateRegistry = &opi.PrivateRegistry{
-			Server:   parseRegistryHost(lifecycle.Image),
-			Username: lifecycle.RegistryUsername,
-			Password: lifecycle.RegistryPassword,
+	image, err := c.imagePolicy.Apply(lifecycle.Image, lifecycle.RegistryUsername, lifecycle.RegistryPassword)
+	if err != nil {
+		return opi.Task{}, err
+	}
+
+	if c.imagePlatform.SelectNodesByArchitecture {
+		imgMetadata, err := c.getImageMetadata(image)
+		if err != nil {
+			return opi.Task{}, errors.Wrap(err, "failed to get the image metadata")
 		}
+
+		task.Architecture = singleArchitecture(imgMetadata.Architectures)
 	}
 
+	task.Command = lifecycle.Command
+	task.Image = image.Image
+	task.PrivateRegistry = image.PrivateRegistry()
+
 	task.Env = mergeEnvs(request.Environment, env)
 
 	return task, nil
 }
 
-func (c *OPIConverter) isAllowedToRunAsRoot(lifecycle *cf.DockerLifecycle) (bool, error) {
-	if !c.allowRunImageAsRoot {
-		return false, nil
+func (c *OPIConverter) getImageMetadata(image PolicyImage) (*docker.ImageMetadata, error) {
+	dockerRef, err := c.imageRefParser.Parse(image.Image)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse image ref")
 	}
 
-	user, err := c.getImageUser(lifecycle)
+	imgMetadata, err := c.imageMetadataFetcher.Fetch(dockerRef, systemContext(image, c.imagePlatform))
 	if err != nil {
-		return false, errors.Wrap(err, "failed to get the user of the image")
+		return nil, errors.Wrap(err, "failed to fetch image metadata")
 	}
 
-	return user == "" || user == "root" || user == "0", nil
+	return imgMetadata, nil
 }
 
-func (c *OPIConverter) getImageUser(lifecycle *cf.DockerLifecycle) (string, error) {
-	dockerRef, err := c.imageRefParser.Parse(lifecycle.Image)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to parse image ref")
+// singleArchitecture returns the architecture of images which are available
+// for only one, for them to be scheduled on nodes of that architecture. Images
+// available for several architectures run anywhere
+func singleArchitecture(architectures []string) string {
+	if len(architectures) != 1 {
+		return ""
 	}
 
-	imgMetadata, err := c.imageMetadataFetcher.Fetch(dockerRef, types.SystemContext{
-		DockerAuthConfig: &types.DockerAuthConfig{
-			Username: lifecycle.RegistryUsername,
-			Password: lifecycle.RegistryPassword,
-		},
-	})
-	if err != nil {
-		return "", errors.Wrap(err, "failed to fetch image metadata")
+	return architectures[0]
+}
+
+// parseImageUser parses the USER of an image, which is either a user or a
+// user:group pair, each of them a name or a numeric id. An image without a
+// USER runs as root
+func parseImageUser(user string) (*int64, *int64) {
+	if user == "" {
+		return parseImageUserID("root"), nil
 	}
 
-	return imgMetadata.User, nil
+	parts := strings.SplitN(user, ":", 2)
+	uid := parseImageUserID(parts[0])
+
+	if len(parts) == 1 {
+		return uid, nil
+	}
+
+	return uid, parseImageUserID(parts[1])
+}
+
+func parseImageUserID(name string) *int64 {
+	if name == "root" {
+		name = "0"
+	}
+
+	id, err := strconv.ParseInt(name, 10, 64)
+	if err != nil || id < 0 {
+		return nil
+	}
+
+	return &id
 }
 
 func getRequestedRoutes(request cf.DesireLRPRequest) ([]opi.Route, error) {
@@ -190,11 +250,11 @@ func getRequestedRoutes(request cf.DesireLRPRequest) ([]opi.Route, error) {
 		return []opi.Route{}, nil
 	}
 
-	if _, ok := jsonRoutes["cf-router"]; !ok {
+	if _, ok := jsonRoutes[CFRouterKey]; !ok {
 		return []opi.Route{}, nil
 	}
 
-	cfRouterRoutes := jsonRoutes["cf-router"]
+	cfRouterRoutes := jsonRoutes[CFRouterKey]
 
 	var routes []opi.Route
 
@@ -206,6 +266,38 @@ func getRequestedRoutes(request cf.DesireLRPRequest) ([]opi.Route, error) {
 	return routes, nil
 }
 
+func getTCPRoutes(jsonRoutes map[string]json.RawMessage) ([]opi.TCPRoute, error) {
+	tcpRouterRoutes, ok := jsonRoutes[TCPRouterKey]
+	if !ok {
+		return []opi.TCPRoute{}, nil
+	}
+
+	var routes []opi.TCPRoute
+
+	err := json.Unmarshal(tcpRouterRoutes, &routes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal tcp routes")
+	}
+
+	return routes, nil
+}
+
+func getInternalRoutes(jsonRoutes map[string]json.RawMessage) ([]opi.InternalRoute, error) {
+	internalRouterRoutes, ok := jsonRoutes[InternalRouterKey]
+	if !ok {
+		return []opi.InternalRoute{}, nil
+	}
+
+	var routes []opi.InternalRoute
+
+	err := json.Unmarshal(internalRouterRoutes, &routes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal internal routes")
+	}
+
+	return routes, nil
+}
+
 func mergeMaps(maps ...map[string]string) map[string]string {
 	result := make(map[string]string)
 
@@ -248,26 +340,35 @@ func (c *OPIConverter) getLifecycleOptions(request cf.DesireLRPRequest) (*lifecy
 		return nil, fmt.Errorf("missing lifecycle data")
 	}
 
-	var err error
-
 	lifecycle := request.Lifecycle.DockerLifecycle
-	options.image = lifecycle.Image
+
+	image, err := c.imagePolicy.Apply(lifecycle.Image, lifecycle.RegistryUsername, lifecycle.RegistryPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	options.image = image.Image
 	options.command = lifecycle.Command
-	options.runsAsRoot, err = c.isAllowedToRunAsRoot(lifecycle)
+	options.privateRegistry = image.PrivateRegistry()
 
+	// The image metadata is only fetched when it changes the pod spec: the
+	// container runtime applies the USER of the image anyway, so it only
+	// needs to be known when the image may run as root
+	if !c.allowRunImageAsRoot && !c.imagePlatform.SelectNodesByArchitecture {
+		return options, nil
+	}
+
+	imgMetadata, err := c.getImageMetadata(image)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to verify if docker image needs root user")
+		return nil, errors.Wrap(err, "failed to get the image metadata")
 	}
 
-	registryUsername := lifecycle.RegistryUsername
-	registryPassword := lifecycle.RegistryPassword
+	if c.allowRunImageAsRoot {
+		options.runAsUser, options.runAsGroup = parseImageUser(imgMetadata.Config.User)
+	}
 
-	if registryUsername != "" || registryPassword != "" {
-		options.privateRegistry = &opi.PrivateRegistry{
-			Server:   parseRegistryHost(options.image),
-			Username: registryUsername,
-			Password: registryPassword,
-		}
+	if c.imagePlatform.SelectNodesByArchitecture {
+		options.architecture = singleArchitecture(imgMetadata.Architectures)
 	}
 
 	return options, nil
@@ -9,9 +9,11 @@ import (
 	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
 	"code.cloudfoundry.org/eirini/models/cf"
 	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/eirini/stager/docker"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -23,13 +25,18 @@ var _ = Describe("OPI Converter", func() {
 		imgMetadataFetcher  *bifrostfakes.FakeImageMetadataFetcher
 		imgRefParser        *bifrostfakes.FakeImageRefParser
 		allowRunImageAsRoot bool
+		imagePolicy         bifrost.ImagePolicy
+		imagePlatform       eirini.ImagePlatformConfig
 	)
 
 	BeforeEach(func() {
 		logger = lagertest.NewTestLogger("converter-test")
 		imgMetadataFetcher = new(bifrostfakes.FakeImageMetadataFetcher)
 		imgRefParser = new(bifrostfakes.FakeImageRefParser)
+		imgMetadataFetcher.Returns(&docker.ImageMetadata{}, nil)
 		allowRunImageAsRoot = false
+		imagePolicy = bifrost.ImagePolicy{}
+		imagePlatform = eirini.ImagePlatformConfig{}
 	})
 
 	JustBeforeEach(func() {
@@ -38,6 +45,8 @@ var _ = Describe("OPI Converter", func() {
 			imgMetadataFetcher.Spy,
 			imgRefParser.Spy,
 			allowRunImageAsRoot,
+			imagePolicy,
+			imagePlatform,
 		)
 	})
 
@@ -86,7 +95,9 @@ var _ = Describe("OPI Converter", func() {
 				HealthCheckTimeoutMs:    400,
 				Ports:                   []int32{8000, 8888},
 				Routes: map[string]json.RawMessage{
-					"cf-router": rawJSON,
+					"cf-router":       rawJSON,
+					"tcp-router":      json.RawMessage(`[{"router_group_guid":"default-tcp","external_port":1234,"container_port":8888}]`),
+					"internal-router": json.RawMessage(`[{"hostname":"backend.apps.internal"}]`),
 				},
 				VolumeMounts: []cf.VolumeMount{
 					{
@@ -190,6 +201,36 @@ var _ = Describe("OPI Converter", func() {
 			))
 		})
 
+		It("sets the tcp routes", func() {
+			Expect(lrp.TCPRoutes).To(ConsistOf(
+				opi.TCPRoute{RouterGroupGUID: "default-tcp", ExternalPort: 1234, ContainerPort: 8888},
+			))
+		})
+
+		When("the tcp routes are invalid", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Routes["tcp-router"] = json.RawMessage(`{"router_group_guid": 1}`)
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to unmarshal tcp routes")))
+			})
+		})
+
+		It("sets the internal routes", func() {
+			Expect(lrp.InternalRoutes).To(ConsistOf(opi.InternalRoute{Hostname: "backend.apps.internal"}))
+		})
+
+		When("the internal routes are invalid", func() {
+			BeforeEach(func() {
+				desireLRPRequest.Routes["internal-router"] = json.RawMessage(`{"hostname": 1}`)
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to unmarshal internal routes")))
+			})
+		})
+
 		It("should set the ports", func() {
 			Expect(lrp.Ports).To(Equal([]int32{8000, 8888}))
 		})
@@ -275,8 +316,111 @@ var _ = Describe("OPI Converter", func() {
 				Expect(lrp.PrivateRegistry).To(BeNil())
 			})
 
-			It("assumes that the pod should run as root", func() {
-				Expect(lrp.RunsAsRoot).To(BeFalse())
+			It("does not let the image run as root", func() {
+				Expect(lrp.RunAsUser).To(BeNil())
+				Expect(lrp.RunAsGroup).To(BeNil())
+			})
+
+			It("does not pin the app to an architecture", func() {
+				Expect(lrp.Architecture).To(BeEmpty())
+			})
+
+			It("does not fetch the image metadata", func() {
+				Expect(imgMetadataFetcher.CallCount()).To(BeZero())
+			})
+
+			Context("when selecting nodes by architecture", func() {
+				BeforeEach(func() {
+					imagePlatform.SelectNodesByArchitecture = true
+				})
+
+				It("fetches the image metadata for the configured platform", func() {
+					Expect(imgMetadataFetcher.CallCount()).To(Equal(1))
+					_, sysCtx := imgMetadataFetcher.ArgsForCall(0)
+					Expect(sysCtx.OSChoice).To(BeEmpty())
+					Expect(sysCtx.ArchitectureChoice).To(BeEmpty())
+				})
+
+				It("leaves the user to the image", func() {
+					Expect(lrp.RunAsUser).To(BeNil())
+					Expect(lrp.RunAsGroup).To(BeNil())
+				})
+
+				Context("when the image platform is configured", func() {
+					BeforeEach(func() {
+						imagePlatform.OS = "linux"
+						imagePlatform.Architecture = "arm64"
+					})
+
+					It("fetches the image metadata for that platform", func() {
+						_, sysCtx := imgMetadataFetcher.ArgsForCall(0)
+						Expect(sysCtx.OSChoice).To(Equal("linux"))
+						Expect(sysCtx.ArchitectureChoice).To(Equal("arm64"))
+					})
+				})
+
+				Context("when the image is available for a single architecture", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{Architectures: []string{"arm64"}}, nil)
+					})
+
+					It("pins the app to that architecture", func() {
+						Expect(lrp.Architecture).To(Equal("arm64"))
+					})
+				})
+
+				Context("when the image is available for several architectures", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{Architectures: []string{"amd64", "arm64"}}, nil)
+					})
+
+					It("lets the app run on any architecture", func() {
+						Expect(lrp.Architecture).To(BeEmpty())
+					})
+				})
+
+				Context("when fetching the image metadata fails", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(nil, errors.New("boom"))
+					})
+
+					It("fails", func() {
+						Expect(err).To(MatchError(ContainSubstring("failed to get the image metadata")))
+					})
+				})
+			})
+
+			Context("when the image policy mirrors the registry of the image", func() {
+				BeforeEach(func() {
+					desireLRPRequest.Lifecycle.DockerLifecycle.Image = "eirini/dorini"
+					imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+						Mirrors: []eirini.ImageMirrorConfig{
+							{Registry: "docker.io", Mirror: "mirror.internal", Username: "mirror-user", Password: "mirror-password"},
+						},
+					})
+				})
+
+				It("should pull the image from the mirror", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(lrp.Image).To(Equal("mirror.internal/eirini/dorini"))
+					Expect(lrp.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+						Server:   "mirror.internal",
+						Username: "mirror-user",
+						Password: "mirror-password",
+					}))
+				})
+			})
+
+			Context("when the image policy denies the image", func() {
+				BeforeEach(func() {
+					imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+						DeniedRegistries: []string{"docker.io"},
+					})
+				})
+
+				It("should reject the request", func() {
+					Expect(errors.Is(err, eirini.ErrImageNotAllowed)).To(BeTrue())
+				})
 			})
 
 			Context("when the image lives in a private registry", func() {
@@ -323,7 +467,7 @@ var _ = Describe("OPI Converter", func() {
 			Context("when running docker images with root user is allowed", func() {
 				BeforeEach(func() {
 					allowRunImageAsRoot = true
-					imgMetadataFetcher.Returns(&v1.ImageConfig{}, nil)
+					imgMetadataFetcher.Returns(&docker.ImageMetadata{}, nil)
 					imgRefParser.Returns("//some-docker-image-ref", nil)
 				})
 
@@ -343,49 +487,89 @@ var _ = Describe("OPI Converter", func() {
 
 				Context("and the image user is root", func() {
 					BeforeEach(func() {
-						imgMetadataFetcher.Returns(&v1.ImageConfig{
-							User: "root",
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{
+							Config: v1.ImageConfig{
+								User: "root",
+							},
 						}, nil)
 					})
 
 					It("should be allowed to run as root", func() {
-						Expect(lrp.RunsAsRoot).To(BeTrue())
+						Expect(lrp.RunAsUser).To(PointTo(Equal(int64(0))))
 					})
 				})
 
 				Context("and the image user is empty", func() {
 					BeforeEach(func() {
-						imgMetadataFetcher.Returns(&v1.ImageConfig{
-							User: "",
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{
+							Config: v1.ImageConfig{
+								User: "",
+							},
 						}, nil)
 					})
 
 					It("should be allowed to run as root", func() {
-						Expect(lrp.RunsAsRoot).To(BeTrue())
+						Expect(lrp.RunAsUser).To(PointTo(Equal(int64(0))))
 					})
 				})
 
 				Context("and the image user is UID 0", func() {
 					BeforeEach(func() {
-						imgMetadataFetcher.Returns(&v1.ImageConfig{
-							User: "0",
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{
+							Config: v1.ImageConfig{
+								User: "0",
+							},
 						}, nil)
 					})
 
 					It("should be allowed to run as root", func() {
-						Expect(lrp.RunsAsRoot).To(BeTrue())
+						Expect(lrp.RunAsUser).To(PointTo(Equal(int64(0))))
+					})
+				})
+
+				Context("and the image user is numeric", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{Config: v1.ImageConfig{User: "1000"}}, nil)
+					})
+
+					It("runs the image as that user", func() {
+						Expect(lrp.RunAsUser).To(PointTo(Equal(int64(1000))))
+						Expect(lrp.RunAsGroup).To(BeNil())
+					})
+				})
+
+				Context("and the image user is a user:group pair", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{Config: v1.ImageConfig{User: "1000:2000"}}, nil)
+					})
+
+					It("runs the image as that user and group", func() {
+						Expect(lrp.RunAsUser).To(PointTo(Equal(int64(1000))))
+						Expect(lrp.RunAsGroup).To(PointTo(Equal(int64(2000))))
+					})
+				})
+
+				Context("and the image is available for a single architecture", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{Architectures: []string{"arm64"}}, nil)
+					})
+
+					It("does not pin the app to that architecture", func() {
+						Expect(lrp.Architecture).To(BeEmpty())
 					})
 				})
 
 				Context("and the image user is not root", func() {
 					BeforeEach(func() {
-						imgMetadataFetcher.Returns(&v1.ImageConfig{
-							User: "vcap",
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{
+							Config: v1.ImageConfig{
+								User: "vcap",
+							},
 						}, nil)
 					})
 
 					It("should not be allowed to run as root", func() {
-						Expect(lrp.RunsAsRoot).To(BeFalse())
+						Expect(lrp.RunAsUser).To(BeNil())
 					})
 				})
 
@@ -493,6 +677,72 @@ var _ = Describe("OPI Converter", func() {
 					Expect(task.PrivateRegistry.Server).To(Equal("private-registry"))
 				})
 			})
+
+			When("the image policy mirrors the registry of the image", func() {
+				BeforeEach(func() {
+					taskRequest.Lifecycle.DockerLifecycle.RegistryUsername = "bob"
+					taskRequest.Lifecycle.DockerLifecycle.RegistryPassword = "12345"
+					imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+						Mirrors: []eirini.ImageMirrorConfig{{Registry: "docker.io", Mirror: "mirror.internal/hub"}},
+					})
+				})
+
+				It("pulls the image from the mirror with the credentials of the request", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(task.Image).To(Equal("mirror.internal/hub/some/image"))
+					Expect(task.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+						Server:   "mirror.internal",
+						Username: "bob",
+						Password: "12345",
+					}))
+				})
+			})
+
+			When("the image policy does not allow the image", func() {
+				BeforeEach(func() {
+					imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+						AllowedRegistries: []string{"registry.internal"},
+					})
+				})
+
+				It("fails with an image policy error", func() {
+					Expect(errors.Is(err, eirini.ErrImageNotAllowed)).To(BeTrue())
+				})
+			})
+
+			It("does not fetch the image metadata", func() {
+				Expect(imgMetadataFetcher.CallCount()).To(BeZero())
+			})
+
+			When("selecting nodes by architecture", func() {
+				BeforeEach(func() {
+					imagePlatform.SelectNodesByArchitecture = true
+				})
+
+				It("fetches the image metadata", func() {
+					Expect(imgMetadataFetcher.CallCount()).To(Equal(1))
+				})
+
+				When("the image is available for a single architecture", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(&docker.ImageMetadata{Architectures: []string{"s390x"}}, nil)
+					})
+
+					It("pins the task to that architecture", func() {
+						Expect(task.Architecture).To(Equal("s390x"))
+					})
+				})
+
+				When("fetching the image metadata fails", func() {
+					BeforeEach(func() {
+						imgMetadataFetcher.Returns(nil, errors.New("boom"))
+					})
+
+					It("fails", func() {
+						Expect(err).To(MatchError(ContainSubstring("failed to get the image metadata")))
+					})
+				})
+			})
 		})
 
 		When("the task does not have any docker lifecycle information", func() {
@@ -4,10 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/logs"
 	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/stager/docker"
 	"code.cloudfoundry.org/lager"
 	"github.com/containers/image/types"
+	"github.com/docker/distribution/reference"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
@@ -20,9 +26,11 @@ type StagingCompleter interface {
 	CompleteStaging(cf.StagingCompletedRequest) error
 }
 
-type ImageMetadataFetcher func(string, types.SystemContext) (*v1.ImageConfig, error)
+// ImageMetadataFetcher returns the config, the digest and the architectures
+// of an image, for the platform of the system context
+type ImageMetadataFetcher func(string, types.SystemContext) (*docker.ImageMetadata, error)
 
-func (f ImageMetadataFetcher) Fetch(dockerRef string, sysCtx types.SystemContext) (*v1.ImageConfig, error) {
+func (f ImageMetadataFetcher) Fetch(dockerRef string, sysCtx types.SystemContext) (*docker.ImageMetadata, error) {
 	return f(dockerRef, sysCtx)
 }
 
@@ -37,6 +45,9 @@ type DockerStaging struct {
 	ImageMetadataFetcher ImageMetadataFetcher
 	ImageRefParser       ImageRefParser
 	StagingCompleter     StagingCompleter
+	LogEmitter           logs.Emitter
+	ImagePolicy          ImagePolicy
+	ImagePlatform        eirini.ImagePlatformConfig
 }
 
 type StagingResult struct {
@@ -59,46 +70,84 @@ type port struct {
 	Protocol string `json:"Protocol"`
 }
 
+// executionMetadata is what the Diego docker lifecycle reports to the Cloud
+// Controller, which derives the start command of the app from it
 type executionMetadata struct {
-	Cmd   []string `json:"cmd"`
-	Ports []port   `json:"ports"`
+	Cmd        []string `json:"cmd"`
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Workdir    string   `json:"workdir,omitempty"`
+	Ports      []port   `json:"ports"`
+	User       string   `json:"user,omitempty"`
 }
 
+// TransferStaging reports staging failures to the Cloud Controller through
+// the completion callback, except for images rejected by the image policy,
+// which fail the staging request itself
 func (s DockerStaging) TransferStaging(ctx context.Context, stagingGUID string, request cf.StagingRequest) error {
 	logger := s.Logger.Session("transfer-staging", lager.Data{"staging-guid": stagingGUID})
 
+	lifecycle := request.Lifecycle.DockerLifecycle
+
+	image, err := s.ImagePolicy.Apply(lifecycle.Image, lifecycle.RegistryUsername, lifecycle.RegistryPassword)
+	if err != nil {
+		logger.Error("image-not-allowed", err)
+
+		return err
+	}
+
 	taskCallbackResponse := cf.StagingCompletedRequest{
 		TaskGUID:   stagingGUID,
 		Annotation: fmt.Sprintf(`{"completion_callback": "%s"}`, request.CompletionCallback),
 	}
 
-	imageConfig, err := s.getImageConfig(request.Lifecycle.DockerLifecycle)
+	s.stagingLog(request.AppGUID, "Staging...")
+
+	imageMetadata, err := s.getImageMetadata(request.AppGUID, image)
 	if err != nil {
 		logger.Error("failed-to-get-image-config", err)
 
-		return s.respondWithFailure(taskCallbackResponse, errors.Wrap(err, "failed to get image config"))
+		return s.respondWithFailure(request.AppGUID, taskCallbackResponse, errors.Wrap(err, "failed to get image config"))
 	}
 
+	imageConfig := &imageMetadata.Config
+
 	ports, err := parseExposedPorts(imageConfig)
 	if err != nil {
 		logger.Error("failed-to-parse-exposed-ports", err)
 
-		return s.respondWithFailure(taskCallbackResponse, errors.Wrap(err, "failed to parse exposed ports"))
+		return s.respondWithFailure(request.AppGUID, taskCallbackResponse, errors.Wrap(err, "failed to parse exposed ports"))
+	}
+
+	s.stagingLog(request.AppGUID, describePorts(ports))
+
+	pinnedImage, err := pinImage(lifecycle.Image, imageMetadata.Digest)
+	if err != nil {
+		logger.Error("failed-to-pin-image", err)
+
+		return s.respondWithFailure(request.AppGUID, taskCallbackResponse, errors.Wrap(err, "failed to pin image to its digest"))
+	}
+
+	if pinnedImage != lifecycle.Image {
+		s.stagingLog(request.AppGUID, fmt.Sprintf("Pinned image to %s", pinnedImage))
 	}
 
-	stagingResult, err := buildStagingResult(request.Lifecycle.DockerLifecycle.Image, ports)
+	stagingResult, err := buildStagingResult(pinnedImage, imageConfig, ports)
 	if err != nil {
 		logger.Error("failed-to-build-staging-result", err)
 
-		return s.respondWithFailure(taskCallbackResponse, errors.Wrap(err, "failed to build staging result"))
+		return s.respondWithFailure(request.AppGUID, taskCallbackResponse, errors.Wrap(err, "failed to build staging result"))
 	}
 
 	taskCallbackResponse.Result = stagingResult
 
+	s.stagingLog(request.AppGUID, "Staging complete")
+
 	return s.CompleteStaging(taskCallbackResponse)
 }
 
-func (s DockerStaging) respondWithFailure(taskCompletedRequest cf.StagingCompletedRequest, err error) error {
+func (s DockerStaging) respondWithFailure(appGUID string, taskCompletedRequest cf.StagingCompletedRequest, err error) error {
+	s.stagingLog(appGUID, fmt.Sprintf("Staging failed: %s", err))
+
 	taskCompletedRequest.Failed = true
 	taskCompletedRequest.FailureReason = err.Error()
 
@@ -109,25 +158,104 @@ func (s DockerStaging) CompleteStaging(taskCompletedRequest cf.StagingCompletedR
 	return s.StagingCompleter.CompleteStaging(taskCompletedRequest)
 }
 
-func (s DockerStaging) getImageConfig(lifecycle *cf.StagingDockerLifecycle) (*v1.ImageConfig, error) {
-	dockerRef, err := s.ImageRefParser.Parse(lifecycle.Image)
+// getImageMetadata fetches the image as the image policy rewrote it, while
+// the staging result keeps the requested repository, so that the policy in
+// place when the app is desired applies
+func (s DockerStaging) getImageMetadata(appGUID string, image PolicyImage) (*docker.ImageMetadata, error) {
+	dockerRef, err := s.ImageRefParser.Parse(image.Image)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse image ref")
 	}
 
-	imgMetadata, err := s.ImageMetadataFetcher.Fetch(dockerRef, types.SystemContext{
-		DockerAuthConfig: &types.DockerAuthConfig{
-			Username: lifecycle.RegistryUsername,
-			Password: lifecycle.RegistryPassword,
-		},
-	})
+	s.stagingLog(appGUID, fmt.Sprintf("Fetching image metadata for %s from registry %s", image.Image, registryOf(dockerRef)))
+
+	imgMetadata, err := s.ImageMetadataFetcher.Fetch(dockerRef, systemContext(image, s.ImagePlatform))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to fetch image metadata")
 	}
 
+	if len(imgMetadata.Architectures) != 0 {
+		s.stagingLog(appGUID, fmt.Sprintf("Image is available for architectures: %s", strings.Join(imgMetadata.Architectures, ", ")))
+	}
+
 	return imgMetadata, nil
 }
 
+// systemContext is what images are fetched with: the credentials they are
+// pulled with and the platform manifest lists are resolved for
+func systemContext(image PolicyImage, platform eirini.ImagePlatformConfig) types.SystemContext {
+	return types.SystemContext{
+		DockerAuthConfig: &types.DockerAuthConfig{
+			Username: image.Username,
+			Password: image.Password,
+		},
+		OSChoice:           platform.OS,
+		ArchitectureChoice: platform.Architecture,
+	}
+}
+
+// pinImage replaces the tag of the image with the digest it resolved to at
+// staging time, so that every instance of the app version runs the same image
+// however the tag moves. Images which are pinned already are left alone
+func pinImage(image, imageDigest string) (string, error) {
+	if imageDigest == "" {
+		return image, nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse image ref")
+	}
+
+	if _, ok := named.(reference.Canonical); ok {
+		return image, nil
+	}
+
+	pinned, err := reference.ParseNormalizedNamed(reference.TrimNamed(named).String() + "@" + imageDigest)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to add digest to image ref")
+	}
+
+	return reference.FamiliarString(pinned), nil
+}
+
+// stagingLog adds a line to the staging logs that cf push shows while the app
+// is being staged
+func (s DockerStaging) stagingLog(appGUID, message string) {
+	s.LogEmitter.Emit(logs.Line{
+		SourceID:   appGUID,
+		SourceType: logs.SourceTypeStaging,
+		InstanceID: "0",
+		Message:    message,
+	})
+}
+
+// registryOf returns the registry host of a docker reference of the form
+// //registry/repository:tag
+func registryOf(dockerRef string) string {
+	ref := strings.TrimPrefix(dockerRef, "//")
+	if i := strings.Index(ref, "/"); i >= 0 {
+		return ref[:i]
+	}
+
+	return ref
+}
+
+func describePorts(ports []port) string {
+	if len(ports) == 0 {
+		return "No exposed ports found in image"
+	}
+
+	descriptions := make([]string, 0, len(ports))
+	for _, p := range ports {
+		descriptions = append(descriptions, fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+	}
+
+	sort.Strings(descriptions)
+
+	return fmt.Sprintf("Found exposed ports: %s", strings.Join(descriptions, ", "))
+}
+
 func parseExposedPorts(imageConfig *v1.ImageConfig) ([]port, error) {
 	var (
 		portNum  uint
@@ -151,10 +279,18 @@ func parseExposedPorts(imageConfig *v1.ImageConfig) ([]port, error) {
 	return ports, nil
 }
 
-func buildStagingResult(image string, ports []port) (string, error) {
+func buildStagingResult(image string, imageConfig *v1.ImageConfig, ports []port) (string, error) {
+	cmd := imageConfig.Cmd
+	if cmd == nil {
+		cmd = []string{}
+	}
+
 	executionMetadataJSON, err := json.Marshal(executionMetadata{
-		Cmd:   []string{},
-		Ports: ports,
+		Cmd:        cmd,
+		Entrypoint: imageConfig.Entrypoint,
+		Workdir:    imageConfig.WorkingDir,
+		Ports:      ports,
+		User:       imageConfig.User,
 	})
 	if err != nil {
 		return "", errors.Wrap(err, "failed to parse execution metadata")
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
+	"code.cloudfoundry.org/eirini/logs"
+	"code.cloudfoundry.org/eirini/logs/logsfakes"
 	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/stager/docker"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -20,19 +24,38 @@ var _ = Describe("DockerStager", func() {
 		fetcher          *bifrostfakes.FakeImageMetadataFetcher
 		parser           *bifrostfakes.FakeImageRefParser
 		stagingCompleter *bifrostfakes.FakeStagingCompleter
+		logEmitter       *logsfakes.FakeEmitter
 	)
 
+	stagingLogs := func() []string {
+		messages := []string{}
+		for i := 0; i < logEmitter.EmitCallCount(); i++ {
+			line := logEmitter.EmitArgsForCall(i)
+			Expect(line.SourceID).To(Equal("app-guid"))
+			Expect(line.SourceType).To(Equal(logs.SourceTypeStaging))
+			messages = append(messages, line.Message)
+		}
+
+		return messages
+	}
+
 	Context("Stage a docker image", func() {
 		var (
 			stagingErr     error
 			stagingRequest cf.StagingRequest
+			imagePolicy    bifrost.ImagePolicy
+			imagePlatform  eirini.ImagePlatformConfig
 		)
 
 		BeforeEach(func() {
 			fetcher = new(bifrostfakes.FakeImageMetadataFetcher)
 			parser = new(bifrostfakes.FakeImageRefParser)
 			stagingCompleter = new(bifrostfakes.FakeStagingCompleter)
+			logEmitter = new(logsfakes.FakeEmitter)
+			imagePolicy = bifrost.ImagePolicy{}
+			imagePlatform = eirini.ImagePlatformConfig{}
 			stagingRequest = cf.StagingRequest{
+				AppGUID:            "app-guid",
 				CompletionCallback: "the-completion-callback/call/me",
 				Lifecycle: cf.StagingLifecycle{
 					DockerLifecycle: &cf.StagingDockerLifecycle{
@@ -41,13 +64,16 @@ var _ = Describe("DockerStager", func() {
 				},
 			}
 
-			fetcher.Returns(&v1.ImageConfig{
-				ExposedPorts: map[string]struct{}{
-					"8888/tcp": {},
+			fetcher.Returns(&docker.ImageMetadata{
+				Config: v1.ImageConfig{
+					ExposedPorts: map[string]struct{}{
+						"8888/tcp": {},
+					},
 				},
+				Digest: "sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881",
 			}, nil)
 
-			parser.Returns("//some-valid-docker-ref", nil)
+			parser.Returns("//docker.io/eirini/some-app:some-tag", nil)
 		})
 
 		JustBeforeEach(func() {
@@ -56,6 +82,9 @@ var _ = Describe("DockerStager", func() {
 				ImageMetadataFetcher: fetcher.Spy,
 				ImageRefParser:       parser.Spy,
 				StagingCompleter:     stagingCompleter,
+				LogEmitter:           logEmitter,
+				ImagePolicy:          imagePolicy,
+				ImagePlatform:        imagePlatform,
 			}
 
 			stagingErr = stager.TransferStaging(context.Background(), "stg-guid", stagingRequest)
@@ -74,7 +103,7 @@ var _ = Describe("DockerStager", func() {
 		It("should use the parsed docker image ref", func() {
 			Expect(fetcher.CallCount()).To(Equal(1))
 			ref, _ := fetcher.ArgsForCall(0)
-			Expect(ref).To(Equal("//some-valid-docker-ref"))
+			Expect(ref).To(Equal("//docker.io/eirini/some-app:some-tag"))
 		})
 
 		It("should complete staging with correct parameters", func() {
@@ -89,11 +118,101 @@ var _ = Describe("DockerStager", func() {
 			Expect(json.Unmarshal([]byte(taskCompletedRequest.Result), &payload)).To(Succeed())
 
 			Expect(payload.LifecycleType).To(Equal("docker"))
-			Expect(payload.LifecycleMetadata.DockerImage).To(Equal("eirini/some-app:some-tag"))
+			Expect(payload.LifecycleMetadata.DockerImage).To(Equal("eirini/some-app@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"))
 			Expect(payload.ProcessTypes.Web).To(BeEmpty())
 			Expect(payload.ExecutionMetadata).To(Equal(`{"cmd":[],"ports":[{"Port":8888,"Protocol":"tcp"}]}`))
 		})
 
+		It("should tell the app how staging is going", func() {
+			Expect(stagingLogs()).To(Equal([]string{
+				"Staging...",
+				"Fetching image metadata for eirini/some-app:some-tag from registry docker.io",
+				"Found exposed ports: 8888/tcp",
+				"Pinned image to eirini/some-app@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881",
+				"Staging complete",
+			}))
+		})
+
+		Context("when the image is pinned to a digest already", func() {
+			BeforeEach(func() {
+				stagingRequest.Lifecycle.DockerLifecycle.Image = "eirini/some-app:some-tag@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"
+			})
+
+			It("should return the image as it is", func() {
+				taskCompletedRequest := stagingCompleter.CompleteStagingArgsForCall(0)
+
+				var payload bifrost.StagingResult
+				Expect(json.Unmarshal([]byte(taskCompletedRequest.Result), &payload)).To(Succeed())
+				Expect(payload.LifecycleMetadata.DockerImage).To(Equal("eirini/some-app:some-tag@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"))
+			})
+		})
+
+		Context("when the registry does not return a digest", func() {
+			BeforeEach(func() {
+				fetcher.Returns(&docker.ImageMetadata{}, nil)
+			})
+
+			It("should return the image as requested", func() {
+				taskCompletedRequest := stagingCompleter.CompleteStagingArgsForCall(0)
+
+				var payload bifrost.StagingResult
+				Expect(json.Unmarshal([]byte(taskCompletedRequest.Result), &payload)).To(Succeed())
+				Expect(payload.LifecycleMetadata.DockerImage).To(Equal("eirini/some-app:some-tag"))
+			})
+		})
+
+		Context("when the digest is invalid", func() {
+			BeforeEach(func() {
+				fetcher.Returns(&docker.ImageMetadata{Digest: "not-a-digest"}, nil)
+			})
+
+			It("should fail the staging", func() {
+				Expect(stagingErr).ToNot(HaveOccurred())
+
+				taskCallbackResponse := stagingCompleter.CompleteStagingArgsForCall(0)
+				Expect(taskCallbackResponse.Failed).To(BeTrue())
+				Expect(taskCallbackResponse.FailureReason).To(ContainSubstring("failed to pin image to its digest"))
+			})
+		})
+
+		Context("when the image has an entrypoint, a command, a working dir and a user", func() {
+			BeforeEach(func() {
+				fetcher.Returns(&docker.ImageMetadata{
+					Config: v1.ImageConfig{
+						Entrypoint:   []string{"/docker-entrypoint.sh"},
+						Cmd:          []string{"nginx", "-g", "daemon off;"},
+						WorkingDir:   "/srv",
+						User:         "1000:1000",
+						ExposedPorts: map[string]struct{}{"8080/tcp": {}},
+					},
+				}, nil)
+			})
+
+			It("should return them in the execution metadata", func() {
+				taskCompletedRequest := stagingCompleter.CompleteStagingArgsForCall(0)
+
+				var payload bifrost.StagingResult
+				Expect(json.Unmarshal([]byte(taskCompletedRequest.Result), &payload)).To(Succeed())
+				Expect(payload.ExecutionMetadata).To(MatchJSON(`{
+					"cmd": ["nginx", "-g", "daemon off;"],
+					"entrypoint": ["/docker-entrypoint.sh"],
+					"workdir": "/srv",
+					"ports": [{"Port": 8080, "Protocol": "tcp"}],
+					"user": "1000:1000"
+				}`))
+			})
+		})
+
+		Context("when the image does not expose any ports", func() {
+			BeforeEach(func() {
+				fetcher.Returns(&docker.ImageMetadata{}, nil)
+			})
+
+			It("should say so in the staging logs", func() {
+				Expect(stagingLogs()).To(ContainElement("No exposed ports found in image"))
+			})
+		})
+
 		Context("when the image is from a private registry", func() {
 			BeforeEach(func() {
 				stagingRequest.Lifecycle.DockerLifecycle.Image = "private-registry.io/user/repo"
@@ -113,6 +232,40 @@ var _ = Describe("DockerStager", func() {
 			})
 		})
 
+		Context("when the image platform is configured", func() {
+			BeforeEach(func() {
+				imagePlatform = eirini.ImagePlatformConfig{OS: "linux", Architecture: "arm64"}
+			})
+
+			It("should fetch the image metadata for that platform", func() {
+				_, ctx := fetcher.ArgsForCall(0)
+				Expect(ctx.OSChoice).To(Equal("linux"))
+				Expect(ctx.ArchitectureChoice).To(Equal("arm64"))
+			})
+		})
+
+		Context("when the image is available for several architectures", func() {
+			BeforeEach(func() {
+				fetcher.Returns(&docker.ImageMetadata{Architectures: []string{"amd64", "arm64"}}, nil)
+			})
+
+			It("should list them in the staging logs", func() {
+				Expect(stagingLogs()).To(ContainElement("Image is available for architectures: amd64, arm64"))
+			})
+		})
+
+		Context("when the image is not available for the platform", func() {
+			BeforeEach(func() {
+				fetcher.Returns(nil, errors.New("image is not available for linux/amd64, available platforms: linux/arm64, linux/s390x"))
+			})
+
+			It("should report the available platforms as the failure reason", func() {
+				taskCallbackResponse := stagingCompleter.CompleteStagingArgsForCall(0)
+				Expect(taskCallbackResponse.Failed).To(BeTrue())
+				Expect(taskCallbackResponse.FailureReason).To(ContainSubstring("available platforms: linux/arm64, linux/s390x"))
+			})
+		})
+
 		Context("when the staging completion callback fails", func() {
 			BeforeEach(func() {
 				stagingCompleter.CompleteStagingReturns(errors.New("callback failed"))
@@ -123,6 +276,48 @@ var _ = Describe("DockerStager", func() {
 			})
 		})
 
+		Context("when the image policy mirrors the registry of the image", func() {
+			BeforeEach(func() {
+				imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+					Mirrors: []eirini.ImageMirrorConfig{
+						{Registry: "docker.io", Mirror: "mirror.internal", Username: "mirror-user", Password: "mirror-password"},
+					},
+				})
+			})
+
+			It("should fetch the image metadata from the mirror", func() {
+				Expect(parser.ArgsForCall(0)).To(Equal("mirror.internal/eirini/some-app:some-tag"))
+				_, ctx := fetcher.ArgsForCall(0)
+				Expect(ctx.DockerAuthConfig.Username).To(Equal("mirror-user"))
+				Expect(ctx.DockerAuthConfig.Password).To(Equal("mirror-password"))
+			})
+
+			It("should keep the requested repository in the staging result", func() {
+				taskCompletedRequest := stagingCompleter.CompleteStagingArgsForCall(0)
+
+				var payload bifrost.StagingResult
+				Expect(json.Unmarshal([]byte(taskCompletedRequest.Result), &payload)).To(Succeed())
+				Expect(payload.LifecycleMetadata.DockerImage).To(Equal("eirini/some-app@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"))
+			})
+		})
+
+		Context("when the image policy denies the image", func() {
+			BeforeEach(func() {
+				imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+					AllowedRepositories: []string{"docker.io/library/*"},
+				})
+			})
+
+			It("should fail the staging request with an image policy error", func() {
+				Expect(errors.Is(stagingErr, eirini.ErrImageNotAllowed)).To(BeTrue())
+			})
+
+			It("should not fetch the image metadata nor complete the staging", func() {
+				Expect(fetcher.CallCount()).To(BeZero())
+				Expect(stagingCompleter.CompleteStagingCallCount()).To(BeZero())
+			})
+		})
+
 		Context("when the image is invalid", func() {
 			BeforeEach(func() {
 				parser.Returns("", errors.New("failed to create an image ref because of reasons"))
@@ -162,6 +357,12 @@ var _ = Describe("DockerStager", func() {
 				Expect(taskCallbackResponse.FailureReason).To(ContainSubstring("failed to fetch image metadata"))
 			})
 
+			It("should tell the app why staging failed", func() {
+				messages := stagingLogs()
+				Expect(messages).ToNot(ContainElement("Staging complete"))
+				Expect(messages[len(messages)-1]).To(HavePrefix("Staging failed: failed to get image config: failed to fetch image metadata"))
+			})
+
 			Context("when the staging completion callback fails", func() {
 				BeforeEach(func() {
 					stagingCompleter.CompleteStagingReturns(errors.New("callback failed"))
@@ -175,9 +376,11 @@ var _ = Describe("DockerStager", func() {
 
 		Context("when exposed ports are wrongly formatted in the image metadata", func() {
 			BeforeEach(func() {
-				fetcher.Returns(&v1.ImageConfig{
-					ExposedPorts: map[string]struct{}{
-						"invalid-port-spec": {},
+				fetcher.Returns(&docker.ImageMetadata{
+					Config: v1.ImageConfig{
+						ExposedPorts: map[string]struct{}{
+							"invalid-port-spec": {},
+						},
 					},
 				}, nil)
 			})
@@ -0,0 +1,152 @@
+package bifrost
+
+import (
+	"path"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/opi"
+	"github.com/docker/distribution/reference"
+	"github.com/pkg/errors"
+)
+
+const dockerHubDomain = "docker.io"
+
+// ImagePolicy decides which images may be run and where they are pulled
+// from. The zero value lets every image through unchanged
+type ImagePolicy struct {
+	config eirini.ImagePolicyConfig
+}
+
+// PolicyImage is an image the policy let through, along with the credentials
+// it is pulled with
+type PolicyImage struct {
+	Image    string
+	Username string
+	Password string
+}
+
+func NewImagePolicy(config eirini.ImagePolicyConfig) ImagePolicy {
+	return ImagePolicy{config: config}
+}
+
+// Apply checks the image against the policy and rewrites it to the mirror of
+// its registry, if there is one. The username and password are the
+// credentials the request brought for the image. The error wraps
+// eirini.ErrImageNotAllowed when the image is rejected
+func (p ImagePolicy) Apply(image, username, password string) (PolicyImage, error) {
+	policyImage := PolicyImage{Image: image, Username: username, Password: password}
+
+	if p.isEmpty() {
+		return policyImage, nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return PolicyImage{}, errors.Wrapf(eirini.ErrImageNotAllowed, "invalid image reference %q", image)
+	}
+
+	registry := reference.Domain(named)
+	repository := named.Name()
+
+	if err := p.check(registry, repository); err != nil {
+		return PolicyImage{}, err
+	}
+
+	mirror, ok := p.mirrorOf(registry)
+	if !ok {
+		return policyImage, nil
+	}
+
+	policyImage.Image = strings.TrimSuffix(mirror.Mirror, "/") + strings.TrimPrefix(named.String(), registry)
+
+	if mirror.Username != "" || mirror.Password != "" {
+		policyImage.Username = mirror.Username
+		policyImage.Password = mirror.Password
+	}
+
+	return policyImage, nil
+}
+
+// PrivateRegistry returns the registry credentials of the image, or nil when
+// it is pulled anonymously
+func (i PolicyImage) PrivateRegistry() *opi.PrivateRegistry {
+	if i.Username == "" && i.Password == "" {
+		return nil
+	}
+
+	return &opi.PrivateRegistry{
+		Server:   parseRegistryHost(i.Image),
+		Username: i.Username,
+		Password: i.Password,
+	}
+}
+
+func (p ImagePolicy) isEmpty() bool {
+	return len(p.config.AllowedRegistries) == 0 &&
+		len(p.config.DeniedRegistries) == 0 &&
+		len(p.config.AllowedRepositories) == 0 &&
+		len(p.config.DeniedRepositories) == 0 &&
+		len(p.config.Mirrors) == 0
+}
+
+func (p ImagePolicy) check(registry, repository string) error {
+	if matchesRegistry(p.config.DeniedRegistries, registry) {
+		return errors.Wrapf(eirini.ErrImageNotAllowed, "registry %s is denied", registry)
+	}
+
+	if matchesRepository(p.config.DeniedRepositories, repository) {
+		return errors.Wrapf(eirini.ErrImageNotAllowed, "repository %s is denied", repository)
+	}
+
+	if len(p.config.AllowedRegistries) == 0 && len(p.config.AllowedRepositories) == 0 {
+		return nil
+	}
+
+	if matchesRegistry(p.config.AllowedRegistries, registry) || matchesRepository(p.config.AllowedRepositories, repository) {
+		return nil
+	}
+
+	return errors.Wrapf(eirini.ErrImageNotAllowed, "repository %s is not in an allowed registry or repository", repository)
+}
+
+func (p ImagePolicy) mirrorOf(registry string) (eirini.ImageMirrorConfig, bool) {
+	for _, mirror := range p.config.Mirrors {
+		if normalizeRegistry(mirror.Registry) == registry {
+			return mirror, true
+		}
+	}
+
+	return eirini.ImageMirrorConfig{}, false
+}
+
+func matchesRegistry(registries []string, registry string) bool {
+	for _, r := range registries {
+		if normalizeRegistry(r) == registry {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesRepository(patterns []string, repository string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, repository); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeRegistry maps the aliases of Docker Hub to the domain image
+// references are normalized to
+func normalizeRegistry(registry string) string {
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io":
+		return dockerHubDomain
+	default:
+		return registry
+	}
+}
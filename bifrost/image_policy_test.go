@@ -0,0 +1,158 @@
+package bifrost_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
+	"code.cloudfoundry.org/eirini/opi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ImagePolicy", func() {
+	var (
+		config eirini.ImagePolicyConfig
+		image  string
+		result bifrost.PolicyImage
+		err    error
+	)
+
+	BeforeEach(func() {
+		config = eirini.ImagePolicyConfig{}
+		image = "eirini/dorini:latest"
+	})
+
+	JustBeforeEach(func() {
+		result, err = bifrost.NewImagePolicy(config).Apply(image, "user", "pass")
+	})
+
+	It("lets any image through unchanged", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(bifrost.PolicyImage{Image: "eirini/dorini:latest", Username: "user", Password: "pass"}))
+	})
+
+	When("the registry is denied", func() {
+		BeforeEach(func() {
+			config.DeniedRegistries = []string{"index.docker.io"}
+		})
+
+		It("rejects the image", func() {
+			Expect(errors.Is(err, eirini.ErrImageNotAllowed)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("registry docker.io is denied")))
+		})
+	})
+
+	When("the repository is denied", func() {
+		BeforeEach(func() {
+			config.AllowedRegistries = []string{"docker.io"}
+			config.DeniedRepositories = []string{"docker.io/eirini/*"}
+		})
+
+		It("rejects the image, even though its registry is allowed", func() {
+			Expect(errors.Is(err, eirini.ErrImageNotAllowed)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("repository docker.io/eirini/dorini is denied")))
+		})
+	})
+
+	When("only some registries are allowed", func() {
+		BeforeEach(func() {
+			config.AllowedRegistries = []string{"registry.internal"}
+		})
+
+		It("rejects images from other registries", func() {
+			Expect(errors.Is(err, eirini.ErrImageNotAllowed)).To(BeTrue())
+		})
+
+		When("the image is from an allowed registry", func() {
+			BeforeEach(func() {
+				image = "registry.internal/team/app@sha256:4bc6e2c4c7c7a3d5e1f5bd9cfa1b5b8e2b8b0c1b5b8e2b8b0c1b5b8e2b8b0c1b"
+			})
+
+			It("lets it through", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Image).To(Equal(image))
+			})
+		})
+
+		When("the repository is allowed explicitly", func() {
+			BeforeEach(func() {
+				config.AllowedRepositories = []string{"docker.io/eirini/*"}
+			})
+
+			It("lets it through", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+
+	When("the image reference is invalid", func() {
+		BeforeEach(func() {
+			config.DeniedRegistries = []string{"evil.io"}
+			image = "Not A Valid Image"
+		})
+
+		It("rejects it", func() {
+			Expect(errors.Is(err, eirini.ErrImageNotAllowed)).To(BeTrue())
+		})
+	})
+
+	When("the registry is mirrored", func() {
+		BeforeEach(func() {
+			config.Mirrors = []eirini.ImageMirrorConfig{
+				{Registry: "docker.io", Mirror: "mirror.internal/hub/"},
+			}
+		})
+
+		It("rewrites the image to the mirror", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Image).To(Equal("mirror.internal/hub/eirini/dorini:latest"))
+		})
+
+		It("pulls with the credentials of the request", func() {
+			Expect(result.Username).To(Equal("user"))
+			Expect(result.Password).To(Equal("pass"))
+		})
+
+		When("the image is an official image", func() {
+			BeforeEach(func() {
+				image = "busybox"
+			})
+
+			It("rewrites it with the library namespace", func() {
+				Expect(result.Image).To(Equal("mirror.internal/hub/library/busybox"))
+			})
+		})
+
+		When("the mirror has credentials", func() {
+			BeforeEach(func() {
+				config.Mirrors[0].Username = "mirror-user"
+				config.Mirrors[0].Password = "mirror-pass"
+			})
+
+			It("pulls with the credentials of the mirror", func() {
+				Expect(result.PrivateRegistry()).To(Equal(&opi.PrivateRegistry{
+					Server:   "mirror.internal",
+					Username: "mirror-user",
+					Password: "mirror-pass",
+				}))
+			})
+		})
+
+		When("the image is from another registry", func() {
+			BeforeEach(func() {
+				image = "quay.io/team/app"
+			})
+
+			It("leaves it alone", func() {
+				Expect(result.Image).To(Equal("quay.io/team/app"))
+			})
+		})
+	})
+
+	Describe("PrivateRegistry", func() {
+		It("is nil for anonymous pulls", func() {
+			Expect(bifrost.PolicyImage{Image: "eirini/dorini"}.PrivateRegistry()).To(BeNil())
+		})
+	})
+})
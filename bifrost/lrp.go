@@ -3,8 +3,11 @@ package bifrost
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"code.cloudfoundry.org/eirini/k8s/shared"
+	"code.cloudfoundry.org/eirini/logs"
 	"code.cloudfoundry.org/eirini/models/cf"
 	"code.cloudfoundry.org/eirini/opi"
 	"github.com/pkg/errors"
@@ -24,8 +27,8 @@ type LRPClient interface {
 	Get(identifier opi.LRPIdentifier) (*opi.LRP, error)
 	GetInstances(identifier opi.LRPIdentifier) ([]*opi.Instance, error)
 	Update(lrp *opi.LRP) error
-	Stop(identifier opi.LRPIdentifier) error
-	StopInstance(identifier opi.LRPIdentifier, index uint) error
+	Stop(identifier opi.LRPIdentifier) (*opi.LRP, error)
+	StopInstance(identifier opi.LRPIdentifier, index uint) (*opi.LRP, error)
 }
 
 type LRPNamespacer interface {
@@ -33,9 +36,11 @@ type LRPNamespacer interface {
 }
 
 type LRP struct {
-	Converter  LRPConverter
-	LRPClient  LRPClient
-	Namespacer LRPNamespacer
+	Converter   LRPConverter
+	LRPClient   LRPClient
+	Namespacer  LRPNamespacer
+	LogEmitter  logs.Emitter
+	ImagePolicy ImagePolicy
 }
 
 func (l *LRP) Transfer(ctx context.Context, request cf.DesireLRPRequest) error {
@@ -46,7 +51,13 @@ func (l *LRP) Transfer(ctx context.Context, request cf.DesireLRPRequest) error {
 
 	namespace := l.Namespacer.GetNamespace(request.Namespace)
 
-	return errors.Wrap(l.LRPClient.Desire(namespace, &desiredLRP), "failed to desire")
+	if err := l.LRPClient.Desire(namespace, &desiredLRP); err != nil {
+		return errors.Wrap(err, "failed to desire")
+	}
+
+	l.cellLog(&desiredLRP, "", fmt.Sprintf("Creating %d instance(s) of process %s", desiredLRP.TargetInstances, desiredLRP.ProcessType))
+
+	return nil
 }
 
 func (l *LRP) List(ctx context.Context) ([]cf.DesiredLRPSchedulingInfo, error) {
@@ -84,6 +95,7 @@ func (l *LRP) Update(ctx context.Context, request cf.UpdateDesiredLRPRequest) er
 		return errors.Wrap(err, "failed to get app")
 	}
 
+	previousInstances := lrp.TargetInstances
 	lrp.TargetInstances = request.Update.Instances
 	lrp.LastUpdated = request.Update.Annotation
 
@@ -92,9 +104,37 @@ func (l *LRP) Update(ctx context.Context, request cf.UpdateDesiredLRPRequest) er
 		return err
 	}
 
-	lrp.Image = request.Update.Image
+	lrp.TCPRoutes, err = getTCPRoutes(request.Update.Routes)
+	if err != nil {
+		return err
+	}
+
+	lrp.InternalRoutes, err = getInternalRoutes(request.Update.Routes)
+	if err != nil {
+		return err
+	}
+
+	lrp.Image = ""
 
-	return errors.Wrap(l.LRPClient.Update(lrp), "failed to update")
+	if request.Update.Image != "" {
+		image, err := l.ImagePolicy.Apply(request.Update.Image, "", "")
+		if err != nil {
+			return err
+		}
+
+		lrp.Image = image.Image
+		lrp.PrivateRegistry = image.PrivateRegistry()
+	}
+
+	if err := l.LRPClient.Update(lrp); err != nil {
+		return errors.Wrap(err, "failed to update")
+	}
+
+	if previousInstances != lrp.TargetInstances {
+		l.cellLog(lrp, "", fmt.Sprintf("Scaling process %s from %d to %d instance(s)", lrp.ProcessType, previousInstances, lrp.TargetInstances))
+	}
+
+	return nil
 }
 
 func (l *LRP) GetApp(ctx context.Context, identifier opi.LRPIdentifier) (cf.DesiredLRP, error) {
@@ -116,25 +156,77 @@ func (l *LRP) GetApp(ctx context.Context, identifier opi.LRPIdentifier) (cf.Desi
 			return cf.DesiredLRP{}, errors.Wrap(err, "failed to marshal app uris")
 		}
 
-		lrpRoutes := map[string]json.RawMessage{"cf-router": data}
-		desiredLRP.Routes = lrpRoutes
+		desiredLRP.Routes = map[string]json.RawMessage{CFRouterKey: data}
+	}
+
+	if len(lrp.TCPRoutes) > 0 {
+		data, err := json.Marshal(lrp.TCPRoutes)
+		if err != nil {
+			return cf.DesiredLRP{}, errors.Wrap(err, "failed to marshal tcp routes")
+		}
+
+		if desiredLRP.Routes == nil {
+			desiredLRP.Routes = map[string]json.RawMessage{}
+		}
+
+		desiredLRP.Routes[TCPRouterKey] = data
+	}
+
+	if len(lrp.InternalRoutes) > 0 {
+		data, err := json.Marshal(lrp.InternalRoutes)
+		if err != nil {
+			return cf.DesiredLRP{}, errors.Wrap(err, "failed to marshal internal routes")
+		}
+
+		if desiredLRP.Routes == nil {
+			desiredLRP.Routes = map[string]json.RawMessage{}
+		}
+
+		desiredLRP.Routes[InternalRouterKey] = data
 	}
 
 	return desiredLRP, nil
 }
 
+// Stop only logs to the app when it can still find it, as the log lines need
+// the app GUID, which the identifier does not contain
 func (l *LRP) Stop(ctx context.Context, identifier opi.LRPIdentifier) error {
-	return errors.Wrap(l.LRPClient.Stop(identifier), "failed to stop app")
+	lrp, err := l.LRPClient.Stop(identifier)
+	if err != nil {
+		return errors.Wrap(err, "failed to stop app")
+	}
+
+	if lrp != nil {
+		l.cellLog(lrp, "", fmt.Sprintf("Stopping all instances of process %s", lrp.ProcessType))
+	}
+
+	return nil
 }
 
 func (l *LRP) StopInstance(ctx context.Context, identifier opi.LRPIdentifier, index uint) error {
-	if err := l.LRPClient.StopInstance(identifier, index); err != nil {
+	lrp, err := l.LRPClient.StopInstance(identifier, index)
+	if err != nil {
 		return errors.Wrap(err, "failed to stop instance")
 	}
 
+	if lrp != nil {
+		l.cellLog(lrp, strconv.FormatUint(uint64(index), 10), fmt.Sprintf("Stopping instance %d of process %s", index, lrp.ProcessType))
+	}
+
 	return nil
 }
 
+// cellLog adds a line to the app logs, like Diego cells do when they
+// start and stop containers
+func (l *LRP) cellLog(lrp *opi.LRP, instance, message string) {
+	l.LogEmitter.Emit(logs.Line{
+		SourceID:   lrp.AppGUID,
+		SourceType: logs.SourceTypeCell,
+		InstanceID: instance,
+		Message:    message,
+	})
+}
+
 func (l *LRP) GetInstances(ctx context.Context, identifier opi.LRPIdentifier) ([]*cf.Instance, error) {
 	opiInstances, err := l.LRPClient.GetInstances(identifier)
 	if err != nil {
@@ -155,7 +247,7 @@ func (l *LRP) GetInstances(ctx context.Context, identifier opi.LRPIdentifier) ([
 }
 
 func getURIs(update cf.DesiredLRPUpdate) ([]opi.Route, error) {
-	cfRouterRoutes, hasRoutes := update.Routes["cf-router"]
+	cfRouterRoutes, hasRoutes := update.Routes[CFRouterKey]
 	if !hasRoutes {
 		return []opi.Route{}, nil
 	}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/bifrost/bifrostfakes"
+	"code.cloudfoundry.org/eirini/logs"
+	"code.cloudfoundry.org/eirini/logs/logsfakes"
 	"code.cloudfoundry.org/eirini/models/cf"
 	"code.cloudfoundry.org/eirini/opi"
 	. "github.com/onsi/ginkgo"
@@ -21,6 +24,8 @@ var _ = Describe("Bifrost LRP", func() {
 		lrpConverter  *bifrostfakes.FakeLRPConverter
 		lrpClient     *bifrostfakes.FakeLRPClient
 		lrpNamespacer *bifrostfakes.FakeLRPNamespacer
+		logEmitter    *logsfakes.FakeEmitter
+		imagePolicy   bifrost.ImagePolicy
 	)
 
 	BeforeEach(func() {
@@ -28,6 +33,8 @@ var _ = Describe("Bifrost LRP", func() {
 		lrpClient = new(bifrostfakes.FakeLRPClient)
 		lrpNamespacer = new(bifrostfakes.FakeLRPNamespacer)
 		lrpNamespacer.GetNamespaceReturns("my-namespace")
+		logEmitter = new(logsfakes.FakeEmitter)
+		imagePolicy = bifrost.ImagePolicy{}
 
 		request = cf.DesireLRPRequest{
 			GUID:      "my-guid",
@@ -37,9 +44,11 @@ var _ = Describe("Bifrost LRP", func() {
 
 	JustBeforeEach(func() {
 		lrpBifrost = &bifrost.LRP{
-			Converter:  lrpConverter,
-			LRPClient:  lrpClient,
-			Namespacer: lrpNamespacer,
+			Converter:   lrpConverter,
+			LRPClient:   lrpClient,
+			Namespacer:  lrpNamespacer,
+			LogEmitter:  logEmitter,
+			ImagePolicy: imagePolicy,
 		}
 	})
 
@@ -52,11 +61,23 @@ var _ = Describe("Bifrost LRP", func() {
 
 			BeforeEach(func() {
 				lrp = opi.LRP{
-					Image: "docker.png",
+					Image:           "docker.png",
+					AppGUID:         "app-guid",
+					ProcessType:     "web",
+					TargetInstances: 3,
 				}
 				lrpConverter.ConvertLRPReturns(lrp, nil)
 			})
 
+			It("should tell the app its instances are being created", func() {
+				Expect(logEmitter.EmitCallCount()).To(Equal(1))
+				Expect(logEmitter.EmitArgsForCall(0)).To(Equal(logs.Line{
+					SourceID:   "app-guid",
+					SourceType: logs.SourceTypeCell,
+					Message:    "Creating 3 instance(s) of process web",
+				}))
+			})
+
 			It("should not return an error", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -110,6 +131,11 @@ var _ = Describe("Bifrost LRP", func() {
 				It("shoud return an error", func() {
 					Expect(lrpBifrost.Transfer(context.Background(), request)).To(MatchError(ContainSubstring("failed to desire")))
 				})
+
+				It("should not log to the app", func() {
+					Expect(lrpBifrost.Transfer(context.Background(), request)).ToNot(Succeed())
+					Expect(logEmitter.EmitCallCount()).To(BeZero())
+				})
 			})
 		})
 	})
@@ -200,13 +226,17 @@ var _ = Describe("Bifrost LRP", func() {
 					Instances:  5,
 					Annotation: "21421321.3",
 					Routes: map[string]json.RawMessage{
-						"cf-router": json.RawMessage(routesJSON),
+						"cf-router":       json.RawMessage(routesJSON),
+						"tcp-router":      json.RawMessage(`[{"router_group_guid":"default-tcp","external_port":1234,"container_port":8080}]`),
+						"internal-router": json.RawMessage(`[{"hostname":"my.apps.internal"}]`),
 					},
 					Image: "the/image",
 				},
 			}
 
 			lrpClient.GetReturns(&opi.LRP{
+				AppGUID:         "app-guid",
+				ProcessType:     "web",
 				TargetInstances: 2,
 				LastUpdated:     "whenever",
 				AppURIs: []opi.Route{
@@ -242,9 +272,82 @@ var _ = Describe("Bifrost LRP", func() {
 				{Hostname: "my.route", Port: 8080},
 				{Hostname: "my.other.route", Port: 7777},
 			}))
+			Expect(lrp.TCPRoutes).To(Equal([]opi.TCPRoute{
+				{RouterGroupGUID: "default-tcp", ExternalPort: 1234, ContainerPort: 8080},
+			}))
+			Expect(lrp.InternalRoutes).To(Equal([]opi.InternalRoute{{Hostname: "my.apps.internal"}}))
 			Expect(lrp.Image).To(Equal("the/image"))
 		})
 
+		It("should tell the app it is being scaled", func() {
+			Expect(logEmitter.EmitCallCount()).To(Equal(1))
+			Expect(logEmitter.EmitArgsForCall(0)).To(Equal(logs.Line{
+				SourceID:   "app-guid",
+				SourceType: logs.SourceTypeCell,
+				Message:    "Scaling process web from 2 to 5 instance(s)",
+			}))
+		})
+
+		Context("when the image policy mirrors the registry of the image", func() {
+			BeforeEach(func() {
+				imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+					Mirrors: []eirini.ImageMirrorConfig{
+						{Registry: "docker.io", Mirror: "mirror.internal", Username: "mirror-user", Password: "mirror-password"},
+					},
+				})
+			})
+
+			It("should pull the image from the mirror", func() {
+				lrp := lrpClient.UpdateArgsForCall(0)
+				Expect(lrp.Image).To(Equal("mirror.internal/the/image"))
+				Expect(lrp.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+					Server:   "mirror.internal",
+					Username: "mirror-user",
+					Password: "mirror-password",
+				}))
+			})
+		})
+
+		Context("when the image policy denies the image", func() {
+			BeforeEach(func() {
+				imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+					DeniedRepositories: []string{"docker.io/the/*"},
+				})
+			})
+
+			It("should return an image policy error", func() {
+				Expect(errors.Is(err, eirini.ErrImageNotAllowed)).To(BeTrue())
+			})
+
+			It("should not submit anything to be updated", func() {
+				Expect(lrpClient.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		Context("when the image does not change", func() {
+			BeforeEach(func() {
+				updateRequest.Update.Image = ""
+				imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+					DeniedRegistries: []string{"docker.io"},
+				})
+			})
+
+			It("should not check the image policy", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lrpClient.UpdateArgsForCall(0).Image).To(BeEmpty())
+			})
+		})
+
+		Context("when the number of instances does not change", func() {
+			BeforeEach(func() {
+				updateRequest.Update.Instances = 2
+			})
+
+			It("should not log to the app", func() {
+				Expect(logEmitter.EmitCallCount()).To(BeZero())
+			})
+		})
+
 		Context("when the update fails", func() {
 			BeforeEach(func() {
 				lrpClient.UpdateReturns(errors.New("your app is bad"))
@@ -253,6 +356,10 @@ var _ = Describe("Bifrost LRP", func() {
 			It("should propagate the error", func() {
 				Expect(err).To(MatchError(ContainSubstring("failed to update")))
 			})
+
+			It("should not log to the app", func() {
+				Expect(logEmitter.EmitCallCount()).To(BeZero())
+			})
 		})
 
 		Context("When there are no routes provided", func() {
@@ -304,7 +411,11 @@ var _ = Describe("Bifrost LRP", func() {
 						{Hostname: "route1.io", Port: 6666},
 						{Hostname: "route2.io", Port: 9999},
 					},
-					Image: "the/image",
+					TCPRoutes: []opi.TCPRoute{
+						{RouterGroupGUID: "default-tcp", ExternalPort: 1234, ContainerPort: 6666},
+					},
+					InternalRoutes: []opi.InternalRoute{{Hostname: "my.apps.internal"}},
+					Image:          "the/image",
 				}
 
 				lrpClient.GetReturns(lrp, nil)
@@ -324,6 +435,8 @@ var _ = Describe("Bifrost LRP", func() {
 				Expect(desiredLRP.Instances).To(Equal(int32(5)))
 				Expect(desiredLRP.Annotation).To(Equal("1234.5"))
 				Expect(desiredLRP.Routes).To(HaveKeyWithValue("cf-router", json.RawMessage(`[{"hostname":"route1.io","port":6666},{"hostname":"route2.io","port":9999}]`)))
+				Expect(desiredLRP.Routes).To(HaveKeyWithValue("tcp-router", json.RawMessage(`[{"router_group_guid":"default-tcp","external_port":1234,"container_port":6666}]`)))
+				Expect(desiredLRP.Routes).To(HaveKeyWithValue("internal-router", json.RawMessage(`[{"hostname":"my.apps.internal"}]`)))
 				Expect(desiredLRP.Image).To(Equal("the/image"))
 			})
 		})
@@ -341,6 +454,10 @@ var _ = Describe("Bifrost LRP", func() {
 	})
 
 	Describe("Stop an app", func() {
+		BeforeEach(func() {
+			lrpClient.StopReturns(&opi.LRP{AppGUID: "app-guid", ProcessType: "web"}, nil)
+		})
+
 		JustBeforeEach(func() {
 			err = lrpBifrost.Stop(context.Background(), opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})
 		})
@@ -355,9 +472,33 @@ var _ = Describe("Bifrost LRP", func() {
 			Expect(identifier.Version).To(Equal("version_1234"))
 		})
 
+		It("should tell the app it is being stopped", func() {
+			Expect(logEmitter.EmitCallCount()).To(Equal(1))
+			Expect(logEmitter.EmitArgsForCall(0)).To(Equal(logs.Line{
+				SourceID:   "app-guid",
+				SourceType: logs.SourceTypeCell,
+				Message:    "Stopping all instances of process web",
+			}))
+		})
+
+		It("should not get the app to tell it", func() {
+			Expect(lrpClient.GetCallCount()).To(BeZero())
+		})
+
+		Context("when there was nothing to stop", func() {
+			BeforeEach(func() {
+				lrpClient.StopReturns(nil, nil)
+			})
+
+			It("should not tell anyone", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(logEmitter.EmitCallCount()).To(BeZero())
+			})
+		})
+
 		Context("when LRPClient's stop fails", func() {
 			BeforeEach(func() {
-				lrpClient.StopReturns(errors.New("failed-to-stop"))
+				lrpClient.StopReturns(nil, errors.New("failed-to-stop"))
 			})
 
 			It("returns an error", func() {
@@ -367,6 +508,10 @@ var _ = Describe("Bifrost LRP", func() {
 	})
 
 	Describe("Stop an app instance", func() {
+		BeforeEach(func() {
+			lrpClient.StopInstanceReturns(&opi.LRP{AppGUID: "app-guid", ProcessType: "web"}, nil)
+		})
+
 		JustBeforeEach(func() {
 			err = lrpBifrost.StopInstance(context.Background(), opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 1)
 		})
@@ -382,9 +527,23 @@ var _ = Describe("Bifrost LRP", func() {
 			Expect(index).To(Equal(uint(1)))
 		})
 
+		It("should tell the app the instance is being stopped", func() {
+			Expect(logEmitter.EmitCallCount()).To(Equal(1))
+			Expect(logEmitter.EmitArgsForCall(0)).To(Equal(logs.Line{
+				SourceID:   "app-guid",
+				SourceType: logs.SourceTypeCell,
+				InstanceID: "1",
+				Message:    "Stopping instance 1 of process web",
+			}))
+		})
+
 		Context("when LRPClient's stop instance fails", func() {
 			BeforeEach(func() {
-				lrpClient.StopInstanceReturns(errors.New("failed-to-stop"))
+				lrpClient.StopInstanceReturns(nil, errors.New("failed-to-stop"))
+			})
+
+			It("should not tell the app", func() {
+				Expect(logEmitter.EmitCallCount()).To(BeZero())
 			})
 
 			It("returns a meaningful error", func() {
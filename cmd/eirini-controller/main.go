@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
 	cmdcommons "code.cloudfoundry.org/eirini/cmd"
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/k8s/client"
@@ -61,6 +62,7 @@ func main() {
 
 	logger := lager.NewLogger("eirini-controller")
 	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+	cmdcommons.RegisterOTLPSink(eiriniCfg.Properties.OTLP, "eirini-controller", logger)
 
 	managerOptions := manager.Options{
 		// do not serve prometheus metrics; disabled because port clashes during integration tests
@@ -133,6 +135,7 @@ func createLRPReconciler(
 		eiriniCfg.Properties.UnsafeAllowAutomountServiceAccountToken,
 		k8s.CreateLivenessProbe,
 		k8s.CreateReadinessProbe,
+		eiriniCfg.Properties.RouteIntegrity,
 	)
 	lrpClient := k8s.NewLRPClient(
 		logger.Session("stateful-set-desirer"),
@@ -141,6 +144,7 @@ func createLRPReconciler(
 		client.NewPod(clientset, eiriniCfg.WorkloadsNamespace),
 		client.NewPodDisruptionBudget(clientset),
 		client.NewEvent(clientset),
+		client.NewService(clientset),
 		lrpToStatefulSetConverter,
 		stset.NewStatefulSetToLRPConverter(),
 	)
@@ -150,7 +154,8 @@ func createLRPReconciler(
 		controllerClient,
 		lrpClient,
 		client.NewStatefulSet(clientset, eiriniCfg.WorkloadsNamespace),
-		scheme)
+		scheme,
+		bifrost.NewImagePolicy(eiriniCfg.Properties.ImagePolicy))
 }
 
 func createTaskReconciler(
@@ -172,7 +177,7 @@ func createTaskReconciler(
 		client.NewSecret(clientset),
 	)
 
-	return reconciler.NewTask(logger, controllerClient, &taskDesirer, scheme)
+	return reconciler.NewTask(logger, controllerClient, &taskDesirer, scheme, bifrost.NewImagePolicy(eiriniCfg.Properties.ImagePolicy))
 }
 
 func createPodCrashReconciler(
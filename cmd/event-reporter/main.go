@@ -61,6 +61,7 @@ func main() {
 
 	crashLogger := lager.NewLogger("instance-crash-informer")
 	crashLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+	cmdcommons.RegisterOTLPSink(cfg.OTLP, "event-reporter", crashReporterLogger, crashLogger)
 
 	controllerClient, err := runtimeclient.New(kubeConfig, runtimeclient.Options{Scheme: kscheme.Scheme})
 	cmdcommons.ExitfIfError(err, "Failed to create k8s runtime client")
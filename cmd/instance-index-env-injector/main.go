@@ -1,13 +1,20 @@
 package main
 
 import (
+	"crypto/x509"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"code.cloudfoundry.org/eirini"
 	cmdcommons "code.cloudfoundry.org/eirini/cmd"
+	"code.cloudfoundry.org/eirini/credentials"
+	"code.cloudfoundry.org/eirini/k8s/client"
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"code.cloudfoundry.org/eirini/k8s/reconciler"
 	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/route/integrity"
 	eirinix "code.cloudfoundry.org/eirinix"
 	"code.cloudfoundry.org/lager"
 	"github.com/jessevdk/go-flags"
@@ -15,6 +22,14 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	defaultCertValidity = 24 * time.Hour
+
+	// credHubClientCertValidity is how long the certificates the webhook
+	// authenticates against CredHub with on behalf of an instance are valid
+	credHubClientCertValidity = 5 * time.Minute
+)
+
 type options struct {
 	ConfigFile   string `short:"c" long:"config" description:"Config for running event-reporter"`
 	RegisterOnly bool   `short:"r" long:"register-only" description:"Register mutating webhook and exit"`
@@ -32,7 +47,9 @@ func main() {
 	log := lager.NewLogger("instance-index-env-injector")
 	log.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
 
-	filterEiriniApps := true
+	// the pods of tasks only reach the webhook when their credentials are
+	// interpolated, every extension checks the source type of the pods
+	filterEiriniApps := cfg.CredentialInterpolation.Backend == ""
 
 	register := true
 	if opts.ExecuteOnly {
@@ -52,12 +69,35 @@ func main() {
 	}
 
 	manager := eirinix.NewManager(managerOptions)
-	err = manager.AddExtension(webhook.NewInstanceIndexEnvInjector(log))
-	cmdcommons.ExitfIfError(err, "failed to add the instance index env injector extension")
+
+	for _, mutator := range envMutators(cfg) {
+		extension, extensionErr := createExtension(log, mutator, cfg.InjectIntoSidecars)
+		cmdcommons.ExitfIfError(extensionErr, "failed to create env mutator")
+
+		err = manager.AddExtension(extension)
+		cmdcommons.ExitfIfError(err, "failed to add the "+mutator+" env mutator extension")
+	}
+
+	if cfg.InstanceIdentity.Enabled {
+		addInstanceIdentity(log, manager, cfg)
+	}
+
+	if cfg.RouteIntegrity.Enabled {
+		addRouteIntegrity(log, manager, cfg)
+	}
+
+	if cfg.CredentialInterpolation.Backend != "" {
+		resolvers, resolversErr := createCredentialResolvers(cfg)
+		cmdcommons.ExitfIfError(resolversErr, "failed to create credential resolvers")
+
+		secrets := client.NewSecret(cmdcommons.CreateKubeClient(cfg.ConfigPath))
+		err = manager.AddExtension(webhook.NewCredentialsInjector(log.Session("credential-interpolation"), resolvers, secrets, cfg.InjectIntoSidecars))
+		cmdcommons.ExitfIfError(err, "failed to add the credential interpolation extension")
+	}
 
 	if opts.RegisterOnly {
 		err = manager.RegisterExtensions()
-		cmdcommons.ExitfIfError(err, "failed to register the instance index env injector extension")
+		cmdcommons.ExitfIfError(err, "failed to register the env mutator extensions")
 
 		return
 	}
@@ -65,6 +105,128 @@ func main() {
 	log.Fatal("instance-index-env-injector-errored", manager.Start())
 }
 
+func addInstanceIdentity(log lager.Logger, manager eirinix.Manager, cfg *eirini.InstanceIndexEnvInjectorConfig) {
+	identityCfg := cfg.InstanceIdentity
+	validity, renewBefore := certRotation(identityCfg.CertValidityInMinutes, identityCfg.RenewBeforeInMinutes)
+
+	issuer, err := createIdentityIssuer(identityCfg, validity)
+	cmdcommons.ExitfIfError(err, "failed to create instance identity issuer")
+
+	secrets := client.NewSecret(cmdcommons.CreateKubeClient(cfg.ConfigPath))
+
+	err = manager.AddExtension(webhook.NewInstanceIdentityInjector(log.Session("instance-identity"), issuer, secrets, cfg.InjectIntoSidecars))
+	cmdcommons.ExitfIfError(err, "failed to add the instance identity extension")
+
+	manager.AddReconciler(reconciler.NewInstanceIdentity(log.Session("instance-identity-rotation"), issuer, renewBefore))
+}
+
+func addRouteIntegrity(log lager.Logger, manager eirinix.Manager, cfg *eirini.InstanceIndexEnvInjectorConfig) {
+	caCert, err := ioutil.ReadFile(filepath.Clean(cfg.RouteIntegrity.CACertPath))
+	cmdcommons.ExitfIfError(err, "failed to read route integrity CA certificate")
+
+	caKey, err := ioutil.ReadFile(filepath.Clean(cfg.RouteIntegrity.CAKeyPath))
+	cmdcommons.ExitfIfError(err, "failed to read route integrity CA key")
+
+	validity, renewBefore := certRotation(cfg.RouteIntegrity.CertValidityInMinutes, cfg.RouteIntegrity.RenewBeforeInMinutes)
+
+	issuer, err := integrity.NewIssuer(caCert, caKey, validity)
+	cmdcommons.ExitfIfError(err, "failed to create route integrity issuer")
+
+	secrets := client.NewSecret(cmdcommons.CreateKubeClient(cfg.ConfigPath))
+
+	err = manager.AddExtension(webhook.NewRouteIntegrityCertInjector(log.Session("route-integrity"), issuer, secrets))
+	cmdcommons.ExitfIfError(err, "failed to add the route integrity extension")
+
+	manager.AddReconciler(reconciler.NewRouteIntegrityCerts(log.Session("route-integrity-rotation"), issuer, renewBefore))
+}
+
+// certRotation returns how long the certificates the webhook issues are valid
+// and how long before they expire they are renewed, applying the defaults
+func certRotation(validityInMinutes, renewBeforeInMinutes int) (time.Duration, time.Duration) {
+	validity := time.Duration(validityInMinutes) * time.Minute
+	if validity == 0 {
+		validity = defaultCertValidity
+	}
+
+	renewBefore := time.Duration(renewBeforeInMinutes) * time.Minute
+	if renewBefore == 0 {
+		renewBefore = validity / 4
+	}
+
+	if renewBefore >= validity {
+		cmdcommons.Exitf("renew_before_in_minutes must be less than cert_validity_in_minutes")
+	}
+
+	return validity, renewBefore
+}
+
+func createCredentialResolvers(cfg *eirini.InstanceIndexEnvInjectorConfig) (credentials.InstanceResolvers, error) {
+	interpolationCfg := cfg.CredentialInterpolation
+
+	switch interpolationCfg.Backend {
+	case eirini.CredentialBackendCredHub:
+		issuer, err := createIdentityIssuer(cfg.InstanceIdentity, credHubClientCertValidity)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create instance identity issuer for credhub")
+		}
+
+		caCert, err := ioutil.ReadFile(filepath.Clean(interpolationCfg.CredHubCAPath))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read credhub CA certificate")
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("credhub CA certificate is not a PEM certificate")
+		}
+
+		return credentials.NewCredHubInstanceResolvers(interpolationCfg.CredHubURL, rootCAs, issuer), nil
+	case eirini.CredentialBackendKubernetes:
+		secrets := client.NewSecret(cmdcommons.CreateKubeClient(cfg.ConfigPath))
+
+		return credentials.NewSecretInstanceResolvers(secrets, interpolationCfg.SecretsNamespace), nil
+	default:
+		return nil, errors.Errorf("unknown credential backend %q", interpolationCfg.Backend)
+	}
+}
+
+func createIdentityIssuer(identityCfg eirini.InstanceIdentityConfig, validity time.Duration) (*identity.Issuer, error) {
+	caCert, err := ioutil.ReadFile(filepath.Clean(identityCfg.CACertPath))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read instance identity CA certificate")
+	}
+
+	caKey, err := ioutil.ReadFile(filepath.Clean(identityCfg.CAKeyPath))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read instance identity CA key")
+	}
+
+	return identity.NewIssuer(caCert, caKey, validity)
+}
+
+func envMutators(cfg *eirini.InstanceIndexEnvInjectorConfig) []string {
+	if len(cfg.EnvMutators) == 0 {
+		return []string{eirini.EnvMutatorInstanceIndex}
+	}
+
+	return cfg.EnvMutators
+}
+
+func createExtension(log lager.Logger, mutator string, injectIntoSidecars bool) (eirinix.Extension, error) {
+	logger := log.Session(mutator)
+
+	switch mutator {
+	case eirini.EnvMutatorInstanceIndex:
+		return webhook.NewInstanceIndexEnvInjector(logger, injectIntoSidecars), nil
+	case eirini.EnvMutatorVCAPApplication:
+		return webhook.NewVCAPApplicationEnvInjector(logger, injectIntoSidecars), nil
+	case eirini.EnvMutatorInstanceAddress:
+		return webhook.NewInstanceAddressEnvInjector(logger, injectIntoSidecars), nil
+	default:
+		return nil, errors.Errorf("unknown env mutator %q", mutator)
+	}
+}
+
 func readConfigFile(path string) (*eirini.InstanceIndexEnvInjectorConfig, error) {
 	fileBytes, err := ioutil.ReadFile(filepath.Clean(path))
 	if err != nil {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	cmdcommons "code.cloudfoundry.org/eirini/cmd"
+	logsinformer "code.cloudfoundry.org/eirini/k8s/informers/logs"
+	"code.cloudfoundry.org/eirini/logs"
+	"code.cloudfoundry.org/lager"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+type options struct {
+	ConfigFile string `short:"c" long:"config" description:"Config for running log-forwarder"`
+}
+
+func main() {
+	var opts options
+	_, err := flags.ParseArgs(&opts, os.Args)
+	cmdcommons.ExitfIfError(err, "Failed to parse args")
+
+	cfg, err := readLogForwarderConfigFromFile(opts.ConfigFile)
+	cmdcommons.ExitfIfError(err, "Failed to read config file")
+
+	clientset := cmdcommons.CreateKubeClient(cfg.ConfigPath)
+
+	logger := lager.NewLogger("log-forwarder")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	loggregatorClient := cmdcommons.CreateLoggregatorClient(
+		cfg.LoggregatorAddress,
+		cfg.LoggregatorCAPath,
+		cfg.LoggregatorCertPath,
+		cfg.LoggregatorKeyPath,
+	)
+
+	defer func() {
+		err = loggregatorClient.CloseSend()
+		cmdcommons.ExitfIfError(err, "Failed to close send stream to the loggregator ingress server")
+	}()
+
+	rateLimit := eirini.AppLogRateLimitPerSecond
+	if cfg.AppLogRateLimitPerSecond > 0 {
+		rateLimit = cfg.AppLogRateLimitPerSecond
+	}
+
+	emitter := logs.NewRateLimitedEmitter(logs.NewLoggregatorEmitter(loggregatorClient), rateLimit, cfg.AppLogRateLimitBurst)
+
+	forwarder := logsinformer.NewForwarder(
+		logsinformer.NewKubeLogStreamer(clientset),
+		emitter,
+		eirini.LogStreamRetryIntervalInSecs*time.Second,
+		logger.Session("forwarder"),
+	)
+
+	informer := logsinformer.NewInformer(clientset, cfg.WorkloadsNamespace, forwarder)
+	informer.Start(make(chan struct{}))
+}
+
+func readLogForwarderConfigFromFile(path string) (*eirini.LogForwarderConfig, error) {
+	fileBytes, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read file")
+	}
+
+	var conf eirini.LogForwarderConfig
+	err = yaml.Unmarshal(fileBytes, &conf)
+
+	return &conf, errors.Wrap(err, "failed to unmarshal yaml")
+}
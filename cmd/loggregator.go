@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/go-loggregator"
+)
+
+// CreateLoggregatorClient falls back to the default certificate paths and
+// exits when the certificates are missing or invalid
+func CreateLoggregatorClient(address, caPath, certPath, keyPath string) *loggregator.IngressClient {
+	tlsConfig, err := loggregator.NewIngressTLSConfig(
+		GetExistingFile(caPath, eirini.LoggregatorCAPath, "Loggregator CA"),
+		GetExistingFile(certPath, eirini.LoggregatorCertPath, "Loggregator Cert"),
+		GetExistingFile(keyPath, eirini.LoggregatorKeyPath, "Loggregator Key"),
+	)
+	ExitfIfError(err, "Failed to create loggregator tls config")
+
+	loggregatorClient, err := loggregator.NewIngressClient(
+		tlsConfig,
+		loggregator.WithAddr(address),
+		loggregator.WithLogger(log.New(os.Stdout, "loggregator-ingress-client", log.LstdFlags)),
+	)
+	ExitfIfError(err, "Failed to create Loggregator ingress client")
+
+	return loggregatorClient
+}
@@ -1,8 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"io/ioutil"
-	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -12,17 +13,26 @@ import (
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/k8s/client"
 	"code.cloudfoundry.org/eirini/k8s/kubelet"
+	"code.cloudfoundry.org/eirini/k8s/stset"
 	"code.cloudfoundry.org/eirini/metrics"
+	"code.cloudfoundry.org/eirini/otlp"
 	"code.cloudfoundry.org/eirini/util"
-	"code.cloudfoundry.org/go-loggregator"
 	"code.cloudfoundry.org/lager"
 	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
+// informerResync makes sure missed watch events are eventually corrected
+const informerResync = 10 * time.Minute
+
 type options struct {
 	ConfigFile string `short:"c" long:"config" description:"Config for running metrics-collector"`
 }
@@ -38,49 +48,147 @@ func main() {
 	clientset := cmdcommons.CreateKubeClient(cfg.ConfigPath)
 	metricsClient := cmdcommons.CreateMetricsClient(cfg.ConfigPath)
 
-	tlsConfig, err := loggregator.NewIngressTLSConfig(
-		cmdcommons.GetExistingFile(cfg.LoggregatorCAPath, eirini.LoggregatorCAPath, "Loggregator CA"),
-		cmdcommons.GetExistingFile(cfg.LoggregatorCertPath, eirini.LoggregatorCertPath, "Loggregator Cert"),
-		cmdcommons.GetExistingFile(cfg.LoggregatorKeyPath, eirini.LoggregatorKeyPath, "Loggregator Key"),
-	)
-	cmdcommons.ExitfIfError(err, "Failed to create loggregator tls config")
+	metricsLogger := lager.NewLogger("metrics")
+	metricsLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+	otlpExporter := cmdcommons.RegisterOTLPSink(cfg.OTLP, "metrics-collector", metricsLogger)
+
+	tickerInterval := eirini.AppMetricsEmissionIntervalInSecs
+	if cfg.AppMetricsEmissionIntervalInSecs > 0 {
+		tickerInterval = cfg.AppMetricsEmissionIntervalInSecs
+	}
+
+	emitters := []metrics.Emitter{}
+
+	if !cfg.LoggregatorDisabled {
+		loggregatorClient := cmdcommons.CreateLoggregatorClient(
+			cfg.LoggregatorAddress,
+			cfg.LoggregatorCAPath,
+			cfg.LoggregatorCertPath,
+			cfg.LoggregatorKeyPath,
+		)
+
+		defer func() {
+			err = loggregatorClient.CloseSend()
+			cmdcommons.ExitfIfError(err, "Failed to close send stream to the loggregator ingress server")
+		}()
+
+		emitters = append(emitters, metrics.NewLoggregatorEmitter(loggregatorClient))
+	}
 
-	loggregatorClient, err := loggregator.NewIngressClient(
-		tlsConfig,
-		loggregator.WithAddr(cfg.LoggregatorAddress),
-		loggregator.WithLogger(log.New(os.Stdout, "loggregator-ingress-client", log.LstdFlags)),
+	var prometheusEmitter *metrics.PrometheusEmitter
+	if cfg.PrometheusEnabled {
+		prometheusEmitter = metrics.NewPrometheusEmitter(prometheusMaxAge(tickerInterval))
+		emitters = append(emitters, prometheusEmitter)
+	}
+
+	if otlpExporter != nil {
+		resource := otlp.Resource{ServiceName: "metrics-collector"}
+		otlpEmitter := metrics.NewOTLPEmitter(otlpExporter, resource, cmdcommons.CreateOTLPErrorLogger("otlp-emitter"))
+		emitters = append(emitters, otlpEmitter)
+
+		go otlpEmitter.Run(time.Duration(tickerInterval)*time.Second, make(chan struct{}))
+	}
+
+	if len(emitters) == 0 {
+		cmdcommons.Exitf("No metrics emitters configured: enable prometheus, otlp or loggregator")
+	}
+
+	podLister, nodeLister := startInformers(clientset, cfg, make(chan struct{}))
+
+	diskClient := kubelet.NewDiskMetricsClient(
+		nodeLister,
+		kubelet.NewClient(clientset.CoreV1().RESTClient()),
+		cfg.KubeletScrapeConcurrency,
+		time.Duration(cfg.KubeletScrapeTimeoutInSecs)*time.Second,
+		metricsLogger.Session("metrics-collector").Session("disk-metrics-client"),
 	)
-	cmdcommons.ExitfIfError(err, "Failed to create Loggregator ingress client")
 
-	defer func() {
-		err = loggregatorClient.CloseSend()
-		cmdcommons.ExitfIfError(err, "Failed to close send stream to the loggregator ingress server")
-	}()
+	if prometheusEmitter != nil {
+		go servePrometheusMetrics(cfg, metricsLogger.Session("prometheus-exporter"), prometheusEmitter, diskClient)
+	}
 
 	launchMetricsEmitter(
-		clientset,
+		client.NewPodLister(podLister, cfg.WorkloadsNamespace),
 		metricsClient,
-		loggregatorClient,
+		diskClient,
+		metrics.NewMultiEmitter(emitters...),
+		tickerInterval,
 		cfg,
+		metricsLogger,
 	)
 }
 
+// startInformers starts the pod and node informers the collector reads from,
+// so that each tick does not have to list all pods and nodes from the API
+// server
+func startInformers(clientset kubernetes.Interface, cfg *eirini.MetricsCollectorConfig, stop <-chan struct{}) (corelisters.PodLister, corelisters.NodeLister) {
+	podFactory := informers.NewSharedInformerFactoryWithOptions(clientset,
+		informerResync,
+		informers.WithNamespace(cfg.WorkloadsNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = fmt.Sprintf("%s in (%s,%s)", stset.LabelSourceType, "APP", "TASK")
+		}),
+	)
+	nodeFactory := informers.NewSharedInformerFactory(clientset, informerResync)
+
+	podLister := podFactory.Core().V1().Pods().Lister()
+	nodeLister := nodeFactory.Core().V1().Nodes().Lister()
+
+	podFactory.Start(stop)
+	nodeFactory.Start(stop)
+
+	for informer, synced := range podFactory.WaitForCacheSync(stop) {
+		if !synced {
+			cmdcommons.Exitf("Failed to sync informer cache for %s", informer)
+		}
+	}
+
+	for informer, synced := range nodeFactory.WaitForCacheSync(stop) {
+		if !synced {
+			cmdcommons.Exitf("Failed to sync informer cache for %s", informer)
+		}
+	}
+
+	return podLister, nodeLister
+}
+
+// An instance that has missed a few emission rounds is considered gone, e.g.
+// because the app has been stopped or scaled down.
+func prometheusMaxAge(tickerInterval int) time.Duration {
+	return 3 * time.Duration(tickerInterval) * time.Second //nolint:gomnd
+}
+
+func servePrometheusMetrics(cfg *eirini.MetricsCollectorConfig, logger lager.Logger, collectors ...prometheus.Collector) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors...)
+
+	port := eirini.PrometheusExporterPort
+	if cfg.PrometheusPort > 0 {
+		port = cfg.PrometheusPort
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+
+	logger.Info("serving", lager.Data{"port": port})
+	logger.Fatal("prometheus-exporter-crashed", server.ListenAndServe())
+}
+
 func launchMetricsEmitter(
-	clientset kubernetes.Interface,
+	podsGetter k8s.PodsGetter,
 	metricsClient metricsclientset.Interface,
-	loggregatorClient metrics.LoggregatorClient,
+	diskClient *kubelet.DiskMetricsClient,
+	emitter *metrics.MultiEmitter,
+	tickerInterval int,
 	cfg *eirini.MetricsCollectorConfig,
+	metricsLogger lager.Logger,
 ) {
-	podClient := client.NewPod(clientset, cfg.WorkloadsNamespace)
-
 	podMetricsClient := metricsClient.MetricsV1beta1().PodMetricses(cfg.WorkloadsNamespace)
-	metricsLogger := lager.NewLogger("metrics")
-	metricsLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
-
-	tickerInterval := eirini.AppMetricsEmissionIntervalInSecs
-	if cfg.AppMetricsEmissionIntervalInSecs > 0 {
-		tickerInterval = cfg.AppMetricsEmissionIntervalInSecs
-	}
 
 	collectorScheduler := &util.TickerTaskScheduler{
 		Ticker: time.NewTicker(time.Duration(tickerInterval) * time.Second),
@@ -88,17 +196,13 @@ func launchMetricsEmitter(
 	}
 
 	metricsCollectorLogger := metricsLogger.Session("metrics-collector", lager.Data{})
-	diskClientLogger := metricsCollectorLogger.Session("disk-metrics-client", lager.Data{})
-	kubeletClient := kubelet.NewClient(clientset.CoreV1().RESTClient())
-	diskClient := kubelet.NewDiskMetricsClient(clientset.CoreV1().Nodes(),
-		kubeletClient,
-		diskClientLogger)
-	collector := k8s.NewMetricsCollector(podMetricsClient, podClient, diskClient, metricsCollectorLogger)
-
-	emitter := metrics.NewLoggregatorEmitter(loggregatorClient)
+	collector := k8s.NewMetricsCollector(podMetricsClient, podsGetter, diskClient, metricsCollectorLogger)
 
 	collectorScheduler.Schedule(func() error {
-		return k8s.ForwardMetricsToEmitter(collector, emitter)
+		err := k8s.ForwardMetricsToEmitter(collector, emitter)
+		k8s.ForwardNodeScrapesToEmitter(diskClient, emitter)
+
+		return err
 	})
 }
 
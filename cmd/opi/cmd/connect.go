@@ -15,6 +15,7 @@ import (
 	"code.cloudfoundry.org/eirini/k8s/client"
 	"code.cloudfoundry.org/eirini/k8s/jobs"
 	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/logs"
 	"code.cloudfoundry.org/eirini/stager"
 	"code.cloudfoundry.org/eirini/stager/docker"
 	"code.cloudfoundry.org/eirini/util"
@@ -39,12 +40,16 @@ func connect(cmd *cobra.Command, args []string) {
 	cfg := setConfigFromFile(path)
 	clientset := cmdcommons.CreateKubeClient(cfg.Properties.ConfigPath)
 
-	dockerStagingBifrost := initDockerStagingBifrost(cfg)
+	logEmitter := initLogEmitter(cfg)
+	dockerStagingBifrost := initDockerStagingBifrost(cfg, logEmitter)
 	taskBifrost := initTaskBifrost(cfg, clientset)
-	bifrost := initLRPBifrost(clientset, cfg)
+	bifrost := initLRPBifrost(clientset, cfg, logEmitter)
+
+	go migrateOriginalRequests(clientset, cfg)
 
 	handlerLogger := lager.NewLogger("handler")
 	handlerLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+	cmdcommons.RegisterOTLPSink(cfg.Properties.OTLP, "opi", handlerLogger)
 	handler := handler.New(bifrost, dockerStagingBifrost, taskBifrost, handlerLogger)
 	handlerLogger.Info("opi-connected")
 
@@ -137,7 +142,22 @@ func initTaskClient(cfg *eirini.Config, clientset kubernetes.Interface) *k8s.Tas
 	)
 }
 
-func initDockerStagingBifrost(cfg *eirini.Config) *bifrost.DockerStaging {
+func initLogEmitter(cfg *eirini.Config) logs.Emitter {
+	if cfg.Properties.LoggregatorAddress == "" {
+		return logs.DiscardEmitter{}
+	}
+
+	loggregatorClient := cmdcommons.CreateLoggregatorClient(
+		cfg.Properties.LoggregatorAddress,
+		cfg.Properties.LoggregatorCAPath,
+		cfg.Properties.LoggregatorCertPath,
+		cfg.Properties.LoggregatorKeyPath,
+	)
+
+	return logs.NewLoggregatorEmitter(loggregatorClient)
+}
+
+func initDockerStagingBifrost(cfg *eirini.Config, logEmitter logs.Emitter) *bifrost.DockerStaging {
 	logger := lager.NewLogger("docker-staging-bifrost")
 	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
 	stagingCompleter := initStagingCompleter(cfg, logger)
@@ -147,6 +167,9 @@ func initDockerStagingBifrost(cfg *eirini.Config) *bifrost.DockerStaging {
 		ImageMetadataFetcher: docker.Fetch,
 		ImageRefParser:       docker.Parse,
 		StagingCompleter:     stagingCompleter,
+		LogEmitter:           logEmitter,
+		ImagePolicy:          bifrost.NewImagePolicy(cfg.Properties.ImagePolicy),
+		ImagePlatform:        cfg.Properties.ImagePlatform,
 	}
 }
 
@@ -175,7 +198,7 @@ func setConfigFromFile(path string) *eirini.Config {
 	return &conf
 }
 
-func initLRPBifrost(clientset kubernetes.Interface, cfg *eirini.Config) *bifrost.LRP {
+func initLRPBifrost(clientset kubernetes.Interface, cfg *eirini.Config, logEmitter logs.Emitter) *bifrost.LRP {
 	desireLogger := lager.NewLogger("desirer")
 	desireLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
 
@@ -185,6 +208,7 @@ func initLRPBifrost(clientset kubernetes.Interface, cfg *eirini.Config) *bifrost
 		cfg.Properties.UnsafeAllowAutomountServiceAccountToken,
 		k8s.CreateLivenessProbe,
 		k8s.CreateReadinessProbe,
+		cfg.Properties.RouteIntegrity,
 	)
 	lrpClient := k8s.NewLRPClient(
 		desireLogger,
@@ -193,6 +217,7 @@ func initLRPBifrost(clientset kubernetes.Interface, cfg *eirini.Config) *bifrost
 		client.NewPod(clientset, cfg.WorkloadsNamespace),
 		client.NewPodDisruptionBudget(clientset),
 		client.NewEvent(clientset),
+		client.NewService(clientset),
 		lrpToStatefulSetConverter,
 		stset.NewStatefulSetToLRPConverter(),
 	)
@@ -201,9 +226,25 @@ func initLRPBifrost(clientset kubernetes.Interface, cfg *eirini.Config) *bifrost
 	namespacer := bifrost.NewNamespacer(cfg.Properties.DefaultWorkloadsNamespace)
 
 	return &bifrost.LRP{
-		Converter:  converter,
-		LRPClient:  lrpClient,
-		Namespacer: namespacer,
+		Converter:   converter,
+		LRPClient:   lrpClient,
+		Namespacer:  namespacer,
+		LogEmitter:  logEmitter,
+		ImagePolicy: bifrost.NewImagePolicy(cfg.Properties.ImagePolicy),
+	}
+}
+
+// migrateOriginalRequests moves the desire requests still kept in StatefulSet
+// annotations into secrets, without restarting any instances
+func migrateOriginalRequests(clientset kubernetes.Interface, cfg *eirini.Config) {
+	migrateLogger := lager.NewLogger("migrator")
+	migrateLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	statefulSets := client.NewStatefulSet(clientset, cfg.WorkloadsNamespace)
+	migrator := stset.NewOriginalRequestMigrator(migrateLogger, statefulSets, statefulSets, client.NewSecret(clientset))
+
+	if err := migrator.Migrate(); err != nil {
+		migrateLogger.Error("failed-to-migrate-original-requests", err)
 	}
 }
 
@@ -216,5 +257,7 @@ func initConverter(cfg *eirini.Config) *bifrost.OPIConverter {
 		docker.Fetch,
 		docker.Parse,
 		cfg.Properties.AllowRunImageAsRoot,
+		bifrost.NewImagePolicy(cfg.Properties.ImagePolicy),
+		cfg.Properties.ImagePlatform,
 	)
 }
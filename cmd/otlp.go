@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/otlp"
+	"code.cloudfoundry.org/lager"
+)
+
+// CreateOTLPExporter returns nil when no OTLP endpoint is configured
+func CreateOTLPExporter(cfg eirini.OTLPConfig) otlp.Exporter {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+
+	exporter, err := otlp.NewExporter(cfg)
+	ExitfIfError(err, "Failed to create OTLP exporter")
+
+	return exporter
+}
+
+// RegisterOTLPSink makes the given loggers export their logs and log event
+// counters to the configured OTLP collector, and returns the exporter for the
+// component to export anything else through the same connection. It does
+// nothing and returns nil when no OTLP endpoint is configured.
+func RegisterOTLPSink(cfg eirini.OTLPConfig, serviceName string, loggers ...lager.Logger) otlp.Exporter {
+	exporter := CreateOTLPExporter(cfg)
+	if exporter == nil {
+		return nil
+	}
+
+	sink := otlp.NewLogSink(exporter, otlp.Resource{ServiceName: serviceName}, lager.INFO)
+	for _, logger := range loggers {
+		logger.RegisterSink(sink)
+	}
+
+	interval := eirini.OTLPExportIntervalInSecs
+	if cfg.ExportIntervalInSecs > 0 {
+		interval = cfg.ExportIntervalInSecs
+	}
+
+	go sink.Run(time.Duration(interval)*time.Second, make(chan struct{}), CreateOTLPErrorLogger("otlp-exporter"))
+
+	return exporter
+}
+
+// CreateOTLPErrorLogger returns a logger which only writes to stdout, for
+// OTLP export errors to be logged without being exported through the failing
+// exporter again.
+func CreateOTLPErrorLogger(name string) lager.Logger {
+	logger := lager.NewLogger(name)
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	return logger
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	cmdcommons "code.cloudfoundry.org/eirini/cmd"
+	"code.cloudfoundry.org/eirini/k8s/client"
+	"code.cloudfoundry.org/eirini/k8s/networkpolicy"
+	"code.cloudfoundry.org/eirini/util"
+	"code.cloudfoundry.org/lager"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultSyncPeriod uint = 30
+
+	// policyServerTimeout bounds a request to the policy server, so that a
+	// hanging policy server cannot stall the syncs for good
+	policyServerTimeout = 10 * time.Second
+)
+
+type options struct {
+	ConfigFile string `short:"c" long:"config" description:"Config for running policy-sync" required:"true"`
+}
+
+func main() {
+	var opts options
+	_, err := flags.ParseArgs(&opts, os.Args)
+	cmdcommons.ExitfIfError(err, "Failed to parse args")
+
+	cfg, err := readConfigFile(opts.ConfigFile)
+	cmdcommons.ExitfIfError(err, "Failed to read config file")
+
+	if cfg.PolicyServerAddress == "" {
+		cmdcommons.Exitf("policy_server_address is required")
+	}
+
+	if cfg.WorkloadsNamespace == "" {
+		cmdcommons.Exitf("WorkloadsNamespace is required")
+	}
+
+	if cfg.SyncPeriodInSeconds == 0 {
+		cfg.SyncPeriodInSeconds = defaultSyncPeriod
+	}
+
+	logger := lager.NewLogger("policy-sync")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	httpClient, err := createHTTPClient(cfg)
+	cmdcommons.ExitfIfError(err, "Failed to create policy server http client")
+
+	clientset := cmdcommons.CreateKubeClient(cfg.ConfigPath)
+
+	metrics := networkpolicy.NewMetrics()
+
+	if cfg.PrometheusPort > 0 {
+		metricsRegistry := prometheus.NewRegistry()
+		metricsRegistry.MustRegister(metrics)
+
+		go cmdcommons.ServePrometheusMetrics(cfg.PrometheusPort, metricsRegistry, logger.Session("prometheus-exporter"))
+	}
+
+	syncer := networkpolicy.NewSyncer(
+		networkpolicy.NewPolicyServerClient(cfg.PolicyServerAddress, httpClient),
+		client.NewNetworkPolicy(clientset),
+		cfg.WorkloadsNamespace,
+		cfg.DefaultDeny,
+		metrics,
+		logger,
+	)
+
+	if err := syncer.Sync(); err != nil {
+		logger.Error("initial-sync-failed", err)
+	}
+
+	scheduler := &util.TickerTaskScheduler{
+		Ticker: time.NewTicker(time.Duration(cfg.SyncPeriodInSeconds) * time.Second),
+		Logger: logger.Session("scheduler"),
+	}
+	scheduler.Schedule(syncer.Sync)
+}
+
+func readConfigFile(path string) (eirini.PolicySyncConfig, error) {
+	fileBytes, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return eirini.PolicySyncConfig{}, errors.Wrap(err, "failed to read file")
+	}
+
+	var conf eirini.PolicySyncConfig
+	err = yaml.Unmarshal(fileBytes, &conf)
+
+	return conf, errors.Wrap(err, "failed to unmarshal yaml")
+}
+
+func createHTTPClient(cfg eirini.PolicySyncConfig) (*http.Client, error) {
+	if cfg.PolicyServerTLSDisabled {
+		return &http.Client{Timeout: policyServerTimeout}, nil
+	}
+
+	httpClient, err := util.CreateTLSHTTPClient(
+		[]util.CertPaths{
+			{
+				Crt: cfg.PolicyServerCertPath,
+				Key: cfg.PolicyServerKeyPath,
+				Ca:  cfg.PolicyServerCAPath,
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient.Timeout = policyServerTimeout
+
+	return httpClient, nil
+}
@@ -11,9 +11,13 @@ import (
 	"code.cloudfoundry.org/eirini/util"
 	"code.cloudfoundry.org/lager"
 	"github.com/jessevdk/go-flags"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-const tickerPeriod uint = 30
+const (
+	tickerPeriod      uint = 30
+	fullRefreshPeriod uint = 60
+)
 
 type options struct {
 	ConfigFile string `short:"c" long:"config" description:"Config for running route-collector"`
@@ -31,21 +35,40 @@ func main() {
 		cfg.EmitPeriodInSeconds = tickerPeriod
 	}
 
+	if cfg.FullRefreshPeriodInSeconds == 0 {
+		cfg.FullRefreshPeriodInSeconds = fullRefreshPeriod
+	}
+
 	logger := lager.NewLogger("route-collector")
 	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
-
-	routeEmitter, err := route.NewEmitterFromConfig(cfg.NatsIP, cfg.NatsPort, cfg.NatsPassword, logger)
-	cmdcommons.ExitfIfError(err, "Failed to create route emitter")
+	logger.Info("deprecated", lager.Data{"replacement": "route-emitter"})
 
 	clientset := cmdcommons.CreateKubeClient(cfg.ConfigPath)
+	metricsRegistry := prometheus.NewRegistry()
+	routeEmitter := cmdcommons.CreateRouteEmitter(cfg, clientset, metricsRegistry, logger)
+
+	tcpRouteEmitter, err := route.NewTCPEmitterFromConfig(cfg.RoutingAPI, logger)
+	cmdcommons.ExitfIfError(err, "Failed to create TCP route emitter")
+
 	podClient := client.NewPod(clientset, cfg.WorkloadsNamespace)
 	statefulSetClient := client.NewStatefulSet(clientset, cfg.WorkloadsNamespace)
 
 	collector := k8s.NewRouteCollector(podClient, statefulSetClient, logger)
+	metrics := route.NewMetrics()
+	metricsRegistry.MustRegister(metrics)
+
+	if cfg.PrometheusPort > 0 {
+		go cmdcommons.ServePrometheusMetrics(cfg.PrometheusPort, metricsRegistry, logger.Session("prometheus-exporter"))
+	}
 
 	scheduler := route.CollectorScheduler{
-		Collector: collector,
-		Emitter:   routeEmitter,
+		Collector:        collector,
+		Emitter:          routeEmitter,
+		RouteTable:       route.NewRouteTable(),
+		FullRefreshEvery: route.FullRefreshTicks(cfg.FullRefreshPeriodInSeconds, cfg.EmitPeriodInSeconds),
+		Metrics:          metrics,
+		TCPCollector:     collector,
+		TCPEmitter:       tcpRouteEmitter,
 		Scheduler: &util.TickerTaskScheduler{
 			Ticker: time.NewTicker(time.Duration(cfg.EmitPeriodInSeconds) * time.Second),
 			Logger: logger.Session("scheduler"),
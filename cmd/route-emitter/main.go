@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	cmdcommons "code.cloudfoundry.org/eirini/cmd"
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/client"
+	k8sroute "code.cloudfoundry.org/eirini/k8s/informers/route"
+	"code.cloudfoundry.org/eirini/k8s/informers/route/event"
+	"code.cloudfoundry.org/eirini/route"
+	"code.cloudfoundry.org/eirini/util"
+	"code.cloudfoundry.org/lager"
+	"github.com/jessevdk/go-flags"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	kscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	tickerPeriod         uint = 30
+	fullRefreshPeriod    uint = 60
+	informerResyncPeriod uint = 300
+)
+
+type options struct {
+	ConfigFile string `short:"c" long:"config" description:"Config for running route-emitter" required:"true"`
+}
+
+func main() {
+	var opts options
+	_, err := flags.ParseArgs(&opts, os.Args)
+	cmdcommons.ExitfIfError(err, "Failed to parse args")
+
+	cfg, err := route.ReadConfig(opts.ConfigFile)
+	cmdcommons.ExitfIfError(err, "Failed to read config file")
+
+	if cfg.EmitPeriodInSeconds == 0 {
+		cfg.EmitPeriodInSeconds = tickerPeriod
+	}
+
+	if cfg.FullRefreshPeriodInSeconds == 0 {
+		cfg.FullRefreshPeriodInSeconds = fullRefreshPeriod
+	}
+
+	if cfg.InformerResyncPeriodInSeconds == 0 {
+		cfg.InformerResyncPeriodInSeconds = informerResyncPeriod
+	}
+
+	logger := lager.NewLogger("route-emitter")
+	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+
+	clientset := cmdcommons.CreateKubeClient(cfg.ConfigPath)
+
+	kubeConfig, err := clientcmd.BuildConfigFromFlags("", cfg.ConfigPath)
+	cmdcommons.ExitfIfError(err, "Failed to build kubeconfig")
+
+	managerOptions := manager.Options{
+		// do not serve controller-runtime metrics; route metrics are served on prometheus_port
+		MetricsBindAddress: "0",
+		Namespace:          cfg.WorkloadsNamespace,
+		Scheme:             kscheme.Scheme,
+		Logger:             util.NewLagerLogr(logger),
+		LeaderElection:     true,
+		LeaderElectionID:   "route-emitter-leader",
+	}
+
+	if cfg.LeaderElectionID != "" {
+		managerOptions.LeaderElectionID = cfg.LeaderElectionID
+	}
+
+	if cfg.LeaderElectionNamespace != "" {
+		managerOptions.LeaderElectionNamespace = cfg.LeaderElectionNamespace
+	}
+
+	if cfg.HealthProbePort > 0 {
+		managerOptions.HealthProbeBindAddress = fmt.Sprintf(":%d", cfg.HealthProbePort)
+	}
+
+	mgr, err := manager.New(kubeConfig, managerOptions)
+	cmdcommons.ExitfIfError(err, "Failed to create k8s controller runtime manager")
+
+	err = mgr.AddHealthzCheck("ping", healthz.Ping)
+	cmdcommons.ExitfIfError(err, "Failed to add healthz check")
+
+	err = mgr.AddReadyzCheck("ping", healthz.Ping)
+	cmdcommons.ExitfIfError(err, "Failed to add readyz check")
+
+	err = mgr.Add(&routeEmitterRunnable{
+		cfg:       cfg,
+		clientset: clientset,
+		logger:    logger,
+	})
+	cmdcommons.ExitfIfError(err, "Failed to add route emitter to manager")
+
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	cmdcommons.ExitfIfError(err, "Failed to start manager")
+}
+
+// routeEmitterRunnable runs the route informers and the route collector on
+// top of a single informer cache. It only starts once this replica becomes
+// the leader, so that routes are never published twice
+type routeEmitterRunnable struct {
+	cfg       *eirini.RouteEmitterConfig
+	clientset kubernetes.Interface
+	logger    lager.Logger
+}
+
+func (r *routeEmitterRunnable) NeedLeaderElection() bool {
+	return true
+}
+
+func (r *routeEmitterRunnable) Start(stop <-chan struct{}) error {
+	metricsRegistry := prometheus.NewRegistry()
+	routeEmitter := cmdcommons.CreateRouteEmitter(r.cfg, r.clientset, metricsRegistry, r.logger)
+
+	tcpRouteEmitter, err := route.NewTCPEmitterFromConfig(r.cfg.RoutingAPI, r.logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create TCP route emitter")
+	}
+
+	metrics := route.NewMetrics()
+	metricsRegistry.MustRegister(metrics)
+
+	if r.cfg.PrometheusPort > 0 {
+		go cmdcommons.ServePrometheusMetrics(r.cfg.PrometheusPort, metricsRegistry, r.logger.Session("prometheus-exporter"))
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(r.clientset,
+		time.Duration(r.cfg.InformerResyncPeriodInSeconds)*time.Second,
+		informers.WithNamespace(r.cfg.WorkloadsNamespace))
+
+	podInformer := factory.Core().V1().Pods()
+	statefulSetInformer := factory.Apps().V1().StatefulSets()
+	statefulSetLister := client.NewStatefulSetLister(statefulSetInformer.Lister(), r.cfg.WorkloadsNamespace)
+	podLister := podInformer.Lister().Pods(r.cfg.WorkloadsNamespace)
+
+	k8sroute.AddPodUpdateHandler(podInformer.Informer(), event.PodUpdateHandler{
+		StatefulSetGetter: statefulSetLister,
+		Logger:            r.logger.Session("pod-update-handler"),
+		RouteEmitter:      routeEmitter,
+		TCPRouteEmitter:   tcpRouteEmitter,
+	})
+
+	k8sroute.AddStatefulSetHandlers(statefulSetInformer.Informer(),
+		event.URIAnnotationUpdateHandler{
+			PodLister:       podLister,
+			Logger:          r.logger.Session("update-handler"),
+			RouteEmitter:    routeEmitter,
+			TCPRouteEmitter: tcpRouteEmitter,
+		},
+		event.StatefulSetDeleteHandler{
+			PodLister:       podLister,
+			Logger:          r.logger.Session("uri-delete-informer"),
+			RouteEmitter:    routeEmitter,
+			TCPRouteEmitter: tcpRouteEmitter,
+		},
+	)
+
+	factory.Start(stop)
+
+	for informer, synced := range factory.WaitForCacheSync(stop) {
+		if !synced {
+			return fmt.Errorf("failed to sync informer cache for %s", informer)
+		}
+	}
+
+	collector := k8s.NewRouteCollector(
+		client.NewPodLister(podInformer.Lister(), r.cfg.WorkloadsNamespace),
+		statefulSetLister,
+		r.logger,
+	)
+	ticker := time.NewTicker(time.Duration(r.cfg.EmitPeriodInSeconds) * time.Second)
+
+	scheduler := route.CollectorScheduler{
+		Collector:        collector,
+		Emitter:          routeEmitter,
+		RouteTable:       route.NewRouteTable(),
+		FullRefreshEvery: route.FullRefreshTicks(r.cfg.FullRefreshPeriodInSeconds, r.cfg.EmitPeriodInSeconds),
+		Metrics:          metrics,
+		TCPCollector:     collector,
+		TCPEmitter:       tcpRouteEmitter,
+		Scheduler: &util.TickerTaskScheduler{
+			Ticker: ticker,
+			Logger: r.logger.Session("scheduler"),
+		},
+	}
+	go scheduler.Start()
+
+	<-stop
+	ticker.Stop()
+
+	return nil
+}
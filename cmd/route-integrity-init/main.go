@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	cmdcommons "code.cloudfoundry.org/eirini/cmd"
+	"code.cloudfoundry.org/eirini/route/integrity"
+)
+
+// the proxy sidecar does not necessarily run as the same user as this
+// container, and the file is only ever visible to the two of them
+const fileMode = 0o644
+
+// route-integrity-init runs as an init container of every LRP pod when route
+// integrity is enabled. It writes the config of the proxy sidecar, which
+// serves the instance certificate the instance-index-env-injector issued for
+// the pod, and reloads it whenever it is renewed
+func main() {
+	var tlsPorts map[int32]int32
+	err := json.Unmarshal([]byte(os.Getenv(integrity.EnvTLSPorts)), &tlsPorts)
+	cmdcommons.ExitfIfError(err, "Failed to parse "+integrity.EnvTLSPorts)
+
+	sdsConfigPath := filepath.Join(integrity.ConfigMountPath, integrity.SDSConfFileName)
+
+	envoyConfig, err := integrity.EnvoyConfig(tlsPorts, sdsConfigPath)
+	cmdcommons.ExitfIfError(err, "Failed to generate proxy config")
+
+	sdsConfig, err := integrity.SDSConfig(integrity.InstanceCertMountPath)
+	cmdcommons.ExitfIfError(err, "Failed to generate proxy sds config")
+
+	err = ioutil.WriteFile(filepath.Join(integrity.ConfigMountPath, integrity.EnvoyConfFileName), envoyConfig, fileMode) //#nosec G306
+	cmdcommons.ExitfIfError(err, "Failed to write "+integrity.EnvoyConfFileName)
+
+	err = ioutil.WriteFile(sdsConfigPath, sdsConfig, fileMode) //#nosec G306
+	cmdcommons.ExitfIfError(err, "Failed to write "+integrity.SDSConfFileName)
+}
@@ -10,6 +10,7 @@ import (
 	"code.cloudfoundry.org/eirini/route"
 	"code.cloudfoundry.org/lager"
 	"github.com/jessevdk/go-flags"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type options struct {
@@ -26,16 +27,24 @@ func main() {
 
 	logger := lager.NewLogger("route-pod-informer")
 	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
-
-	routeEmitter, err := route.NewEmitterFromConfig(cfg.NatsIP, cfg.NatsPort, cfg.NatsPassword, logger)
-	cmdcommons.ExitfIfError(err, "Failed to create Route Emitter")
+	logger.Info("deprecated", lager.Data{"replacement": "route-emitter"})
 
 	clientset := cmdcommons.CreateKubeClient(cfg.ConfigPath)
+	metricsRegistry := prometheus.NewRegistry()
+	routeEmitter := cmdcommons.CreateRouteEmitter(cfg, clientset, metricsRegistry, logger)
+
+	if cfg.PrometheusPort > 0 {
+		go cmdcommons.ServePrometheusMetrics(cfg.PrometheusPort, metricsRegistry, logger.Session("prometheus-exporter"))
+	}
+
+	tcpRouteEmitter, err := route.NewTCPEmitterFromConfig(cfg.RoutingAPI, logger)
+	cmdcommons.ExitfIfError(err, "Failed to create TCP route emitter")
 
 	podUpdateHandler := event.PodUpdateHandler{
 		StatefulSetGetter: client.NewStatefulSet(clientset, cfg.WorkloadsNamespace),
 		Logger:            logger.Session("pod-update-handler"),
 		RouteEmitter:      routeEmitter,
+		TCPRouteEmitter:   tcpRouteEmitter,
 	}
 
 	instanceInformer := k8sroute.NewInstanceChangeInformer(
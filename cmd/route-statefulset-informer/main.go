@@ -9,6 +9,7 @@ import (
 	"code.cloudfoundry.org/eirini/route"
 	"code.cloudfoundry.org/lager"
 	"github.com/jessevdk/go-flags"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type options struct {
@@ -25,22 +26,31 @@ func main() {
 
 	logger := lager.NewLogger("route-statefulset-informer")
 	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
-
-	routeEmitter, err := route.NewEmitterFromConfig(cfg.NatsIP, cfg.NatsPort, cfg.NatsPassword, logger)
-	cmdcommons.ExitfIfError(err, "Failed to create Route Emitter")
+	logger.Info("deprecated", lager.Data{"replacement": "route-emitter"})
 
 	clientset := cmdcommons.CreateKubeClient(cfg.ConfigPath)
+	metricsRegistry := prometheus.NewRegistry()
+	routeEmitter := cmdcommons.CreateRouteEmitter(cfg, clientset, metricsRegistry, logger)
+
+	if cfg.PrometheusPort > 0 {
+		go cmdcommons.ServePrometheusMetrics(cfg.PrometheusPort, metricsRegistry, logger.Session("prometheus-exporter"))
+	}
+
+	tcpRouteEmitter, err := route.NewTCPEmitterFromConfig(cfg.RoutingAPI, logger)
+	cmdcommons.ExitfIfError(err, "Failed to create TCP route emitter")
 
 	deleteHandler := event.StatefulSetDeleteHandler{
-		Pods:         clientset.CoreV1().Pods(""),
-		Logger:       logger.Session("uri-delete-informer"),
-		RouteEmitter: routeEmitter,
+		Pods:            clientset.CoreV1().Pods(""),
+		Logger:          logger.Session("uri-delete-informer"),
+		RouteEmitter:    routeEmitter,
+		TCPRouteEmitter: tcpRouteEmitter,
 	}
 
 	updateHandler := event.URIAnnotationUpdateHandler{
-		Pods:         clientset.CoreV1().Pods(""),
-		Logger:       logger.Session("update-handler"),
-		RouteEmitter: routeEmitter,
+		Pods:            clientset.CoreV1().Pods(""),
+		Logger:          logger.Session("update-handler"),
+		RouteEmitter:    routeEmitter,
+		TCPRouteEmitter: tcpRouteEmitter,
 	}
 
 	uriInformer := k8sroute.NewURIChangeInformer(
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s/client"
+	"code.cloudfoundry.org/eirini/k8s/ingress"
+	"code.cloudfoundry.org/eirini/route"
+	"code.cloudfoundry.org/lager"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func CreateDynamicClient(kubeConfigPath string) dynamic.Interface {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+	ExitfIfError(err, "Failed to get kubeconfig")
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	ExitfIfError(err, "Failed to create k8s dynamic client")
+
+	return dynamicClient
+}
+
+// CreateRouteEmitter creates the route emitter for the backend selected in the
+// config, publishing to NATS unless told otherwise
+func CreateRouteEmitter(
+	cfg *eirini.RouteEmitterConfig,
+	clientset kubernetes.Interface,
+	metricsRegisterer prometheus.Registerer,
+	logger lager.Logger,
+) route.Emitter {
+	var backend ingress.Backend
+
+	switch cfg.Backend {
+	case "", eirini.RouteBackendNATS:
+		routeEmitter, err := route.NewEmitterFromConfig(cfg, metricsRegisterer, logger)
+		ExitfIfError(err, "Failed to create route emitter")
+
+		return routeEmitter
+	case eirini.RouteBackendIngress:
+		backend = ingress.NewIngressBackend(client.NewIngress(clientset), cfg.Ingress.ClassName)
+	case eirini.RouteBackendGatewayAPI:
+		if cfg.GatewayAPI.GatewayName == "" {
+			Exitf("gateway_api.gateway_name is required by the %s route backend", eirini.RouteBackendGatewayAPI)
+		}
+
+		backend = ingress.NewGatewayBackend(
+			client.NewHTTPRoute(CreateDynamicClient(cfg.ConfigPath)),
+			cfg.GatewayAPI.GatewayName,
+			cfg.GatewayAPI.GatewayNamespace,
+		)
+	default:
+		Exitf("Unsupported route backend %q", cfg.Backend)
+	}
+
+	return ingress.NewEmitter(
+		client.NewStatefulSet(clientset, cfg.WorkloadsNamespace),
+		client.NewService(clientset),
+		backend,
+		logger.Session("ingress-emitter"),
+	)
+}
+
+// ServePrometheusMetrics serves the metrics in the registry on /metrics
+// until the process exits
+func ServePrometheusMetrics(port int, registry *prometheus.Registry, logger lager.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+
+	logger.Info("serving", lager.Data{"port": port})
+	logger.Fatal("prometheus-exporter-crashed", server.ListenAndServe())
+}
@@ -51,6 +51,7 @@ func main() {
 
 	taskLogger := lager.NewLogger("task-informer")
 	taskLogger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
+	cmdcommons.RegisterOTLPSink(cfg.OTLP, "task-reporter", taskLogger)
 
 	reporter := k8stask.StateReporter{
 		Client: httpClient,
@@ -0,0 +1,117 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package credentialsfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/eirini/credentials"
+	"code.cloudfoundry.org/eirini/k8s/identity"
+)
+
+type FakeInstanceResolvers struct {
+	ForInstanceStub        func(identity.Identity) (credentials.Resolver, error)
+	forInstanceMutex       sync.RWMutex
+	forInstanceArgsForCall []struct {
+		arg1 identity.Identity
+	}
+	forInstanceReturns struct {
+		result1 credentials.Resolver
+		result2 error
+	}
+	forInstanceReturnsOnCall map[int]struct {
+		result1 credentials.Resolver
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeInstanceResolvers) ForInstance(arg1 identity.Identity) (credentials.Resolver, error) {
+	fake.forInstanceMutex.Lock()
+	ret, specificReturn := fake.forInstanceReturnsOnCall[len(fake.forInstanceArgsForCall)]
+	fake.forInstanceArgsForCall = append(fake.forInstanceArgsForCall, struct {
+		arg1 identity.Identity
+	}{arg1})
+	stub := fake.ForInstanceStub
+	fakeReturns := fake.forInstanceReturns
+	fake.recordInvocation("ForInstance", []interface{}{arg1})
+	fake.forInstanceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeInstanceResolvers) ForInstanceCallCount() int {
+	fake.forInstanceMutex.RLock()
+	defer fake.forInstanceMutex.RUnlock()
+	return len(fake.forInstanceArgsForCall)
+}
+
+func (fake *FakeInstanceResolvers) ForInstanceCalls(stub func(identity.Identity) (credentials.Resolver, error)) {
+	fake.forInstanceMutex.Lock()
+	defer fake.forInstanceMutex.Unlock()
+	fake.ForInstanceStub = stub
+}
+
+func (fake *FakeInstanceResolvers) ForInstanceArgsForCall(i int) identity.Identity {
+	fake.forInstanceMutex.RLock()
+	defer fake.forInstanceMutex.RUnlock()
+	argsForCall := fake.forInstanceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeInstanceResolvers) ForInstanceReturns(result1 credentials.Resolver, result2 error) {
+	fake.forInstanceMutex.Lock()
+	defer fake.forInstanceMutex.Unlock()
+	fake.ForInstanceStub = nil
+	fake.forInstanceReturns = struct {
+		result1 credentials.Resolver
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInstanceResolvers) ForInstanceReturnsOnCall(i int, result1 credentials.Resolver, result2 error) {
+	fake.forInstanceMutex.Lock()
+	defer fake.forInstanceMutex.Unlock()
+	fake.ForInstanceStub = nil
+	if fake.forInstanceReturnsOnCall == nil {
+		fake.forInstanceReturnsOnCall = make(map[int]struct {
+			result1 credentials.Resolver
+			result2 error
+		})
+	}
+	fake.forInstanceReturnsOnCall[i] = struct {
+		result1 credentials.Resolver
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeInstanceResolvers) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.forInstanceMutex.RLock()
+	defer fake.forInstanceMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeInstanceResolvers) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ credentials.InstanceResolvers = new(FakeInstanceResolvers)
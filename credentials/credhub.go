@@ -0,0 +1,122 @@
+package credentials
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"github.com/pkg/errors"
+)
+
+const (
+	credHubDataPath = "/api/v1/data"
+	credHubTimeout  = 10 * time.Second
+)
+
+//counterfeiter:generate . ClientCertIssuer
+
+// ClientCertIssuer issues the identity certificate of an app instance
+type ClientCertIssuer interface {
+	Issue(id identity.Identity) ([]byte, []byte, error)
+}
+
+type credHubDataResponse struct {
+	Data []struct {
+		Value interface{} `json:"value"`
+	} `json:"data"`
+}
+
+// CredHubInstanceResolvers authenticate against CredHub as the app instance
+// whose credentials are resolved, with an instance identity certificate, as
+// Diego cells do. CredHub then only hands out the credentials of the service
+// instances bound to the app
+type CredHubInstanceResolvers struct {
+	address string
+	rootCAs *x509.CertPool
+	issuer  ClientCertIssuer
+}
+
+func NewCredHubInstanceResolvers(address string, rootCAs *x509.CertPool, issuer ClientCertIssuer) *CredHubInstanceResolvers {
+	return &CredHubInstanceResolvers{
+		address: address,
+		rootCAs: rootCAs,
+		issuer:  issuer,
+	}
+}
+
+func (r *CredHubInstanceResolvers) ForInstance(id identity.Identity) (Resolver, error) {
+	if id.AppGUID == "" {
+		return nil, errors.New("instance has no app guid")
+	}
+
+	certPEM, keyPEM, err := r.issuer.Issue(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to issue instance identity certificate")
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load instance identity certificate")
+	}
+
+	// the client is dropped once the pod is admitted, so its connections must
+	// not be kept alive
+	httpClient := &http.Client{
+		Timeout: credHubTimeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig: &tls.Config{
+				RootCAs:      r.rootCAs,
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			},
+		},
+	}
+
+	return NewCredHubResolver(r.address, httpClient), nil
+}
+
+// CredHubResolver reads the current value of a credential from the CredHub
+// API. The http client is expected to authenticate against CredHub with a
+// client certificate
+type CredHubResolver struct {
+	address    string
+	httpClient *http.Client
+}
+
+func NewCredHubResolver(address string, httpClient *http.Client) *CredHubResolver {
+	return &CredHubResolver{
+		address:    address,
+		httpClient: httpClient,
+	}
+}
+
+func (r *CredHubResolver) Resolve(ref string) (interface{}, error) {
+	query := url.Values{"name": {ref}, "current": {"true"}}
+
+	resp, err := r.httpClient.Get(r.address + credHubDataPath + "?" + query.Encode())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credential")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting credential failed with status code %d", resp.StatusCode)
+	}
+
+	var data credHubDataResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, errors.Wrap(err, "failed to decode credential")
+	}
+
+	if len(data.Data) == 0 {
+		return nil, errors.New("credential has no value")
+	}
+
+	return data.Data[0].Value, nil
+}
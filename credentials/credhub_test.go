@@ -0,0 +1,182 @@
+package credentials_test
+
+import (
+	"crypto/x509"
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/eirini/credentials"
+	"code.cloudfoundry.org/eirini/credentials/credentialsfakes"
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"code.cloudfoundry.org/tlsconfig/certtest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("CredHubResolver", func() {
+	var (
+		server   *ghttp.Server
+		resolver *credentials.CredHubResolver
+		resolved interface{}
+		err      error
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		resolver = credentials.NewCredHubResolver(server.URL(), http.DefaultClient)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		resolved, err = resolver.Resolve("/c/p-mysql/db/credentials")
+	})
+
+	When("CredHub has the credential", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/api/v1/data", "current=true&name=%2Fc%2Fp-mysql%2Fdb%2Fcredentials"),
+				ghttp.RespondWith(http.StatusOK, `{
+					"data": [{
+						"type": "json",
+						"name": "/c/p-mysql/db/credentials",
+						"value": {"password": "secret"}
+					}]
+				}`),
+			))
+		})
+
+		It("returns its current value", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resolved).To(Equal(map[string]interface{}{"password": "secret"}))
+		})
+	})
+
+	When("CredHub responds with an error status", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusNotFound, ""))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("getting credential failed with status code 404"))
+		})
+	})
+
+	When("the credential has no value", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"data": []}`))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("credential has no value"))
+		})
+	})
+
+	When("CredHub responds with invalid JSON", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{`))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to decode credential")))
+		})
+	})
+})
+
+var _ = Describe("CredHubInstanceResolvers", func() {
+	var (
+		server    *ghttp.Server
+		issuer    *credentialsfakes.FakeClientCertIssuer
+		resolvers *credentials.CredHubInstanceResolvers
+		id        identity.Identity
+		resolver  credentials.Resolver
+		err       error
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		authority, caErr := certtest.BuildCA("instance-identity-ca")
+		Expect(caErr).NotTo(HaveOccurred())
+		cert, certErr := authority.BuildSignedCertificate("some-app-0")
+		Expect(certErr).NotTo(HaveOccurred())
+		certPEM, keyPEM, pemErr := cert.CertificatePEMAndPrivateKey()
+		Expect(pemErr).NotTo(HaveOccurred())
+
+		issuer = new(credentialsfakes.FakeClientCertIssuer)
+		issuer.IssueReturns(certPEM, keyPEM, nil)
+		resolvers = credentials.NewCredHubInstanceResolvers(server.URL(), x509.NewCertPool(), issuer)
+		id = identity.Identity{InstanceID: "some-app-0", AppGUID: "app-guid"}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		resolver, err = resolvers.ForInstance(id)
+	})
+
+	It("authenticates as the instance", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issuer.IssueCallCount()).To(Equal(1))
+		Expect(issuer.IssueArgsForCall(0)).To(Equal(id))
+	})
+
+	It("resolves against CredHub", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("GET", "/api/v1/data", "current=true&name=%2Fc%2Fp-mysql%2Fdb%2Fcredentials"),
+			ghttp.RespondWith(http.StatusOK, `{"data": [{"value": {"password": "secret"}}]}`),
+		))
+
+		resolved, resolveErr := resolver.Resolve("/c/p-mysql/db/credentials")
+		Expect(resolveErr).NotTo(HaveOccurred())
+		Expect(resolved).To(Equal(map[string]interface{}{"password": "secret"}))
+	})
+
+	It("does not keep the connection to CredHub alive", func() {
+		server.AppendHandlers(ghttp.CombineHandlers(
+			func(_ http.ResponseWriter, r *http.Request) {
+				Expect(r.Close).To(BeTrue())
+			},
+			ghttp.RespondWith(http.StatusOK, `{"data": [{"value": "secret"}]}`),
+		))
+
+		_, resolveErr := resolver.Resolve("/c/p-mysql/db/credentials")
+		Expect(resolveErr).NotTo(HaveOccurred())
+	})
+
+	When("the instance has no app guid", func() {
+		BeforeEach(func() {
+			id.AppGUID = ""
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("instance has no app guid"))
+			Expect(issuer.IssueCallCount()).To(BeZero())
+		})
+	})
+
+	When("issuing the certificate fails", func() {
+		BeforeEach(func() {
+			issuer.IssueReturns(nil, nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("failed to issue instance identity certificate: boom"))
+		})
+	})
+
+	When("the certificate is invalid", func() {
+		BeforeEach(func() {
+			issuer.IssueReturns([]byte("cert"), []byte("key"), nil)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to load instance identity certificate")))
+		})
+	})
+})
@@ -0,0 +1,77 @@
+package credentials
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"github.com/pkg/errors"
+)
+
+const refKey = "credhub-ref"
+
+//counterfeiter:generate . Resolver
+//counterfeiter:generate . InstanceResolvers
+
+// Resolver looks up the credentials a reference points to
+type Resolver interface {
+	Resolve(ref string) (interface{}, error)
+}
+
+// InstanceResolvers give the resolver for an app instance, which only
+// resolves the credentials of the service instances bound to its app
+type InstanceResolvers interface {
+	ForInstance(id identity.Identity) (Resolver, error)
+}
+
+// Interpolate replaces the credentials of every service instance in
+// VCAP_SERVICES that only holds a credential reference, i.e.
+// {"credhub-ref": "/c/some-broker/some-instance/credentials"}, with the
+// credentials the resolver finds for it. It tells whether there was
+// anything to resolve
+func Interpolate(vcapServices string, resolver Resolver) (string, bool, error) {
+	services := map[string][]map[string]interface{}{}
+	if err := json.Unmarshal([]byte(vcapServices), &services); err != nil {
+		return "", false, errors.Wrap(err, "failed to parse VCAP_SERVICES")
+	}
+
+	interpolated := false
+
+	for _, instances := range services {
+		for _, instance := range instances {
+			ref, ok := credentialRef(instance["credentials"])
+			if !ok {
+				continue
+			}
+
+			credentials, err := resolver.Resolve(ref)
+			if err != nil {
+				return "", false, errors.Wrapf(err, "failed to resolve %s", ref)
+			}
+
+			instance["credentials"] = credentials
+			interpolated = true
+		}
+	}
+
+	if !interpolated {
+		return vcapServices, false, nil
+	}
+
+	result, err := json.Marshal(services)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to marshal VCAP_SERVICES")
+	}
+
+	return string(result), true, nil
+}
+
+func credentialRef(credentials interface{}) (string, bool) {
+	fields, ok := credentials.(map[string]interface{})
+	if !ok || len(fields) != 1 {
+		return "", false
+	}
+
+	ref, ok := fields[refKey].(string)
+
+	return ref, ok && ref != ""
+}
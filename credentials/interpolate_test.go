@@ -0,0 +1,89 @@
+package credentials_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini/credentials"
+	"code.cloudfoundry.org/eirini/credentials/credentialsfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Interpolate", func() {
+	var (
+		resolver     *credentialsfakes.FakeResolver
+		vcapServices string
+		result       string
+		interpolated bool
+		err          error
+	)
+
+	BeforeEach(func() {
+		resolver = new(credentialsfakes.FakeResolver)
+		resolver.ResolveReturns(map[string]interface{}{"password": "secret"}, nil)
+
+		vcapServices = `{
+			"p-mysql": [
+				{"name": "db", "credentials": {"credhub-ref": "/c/p-mysql/db/credentials"}},
+				{"name": "plain-db", "credentials": {"password": "plain"}}
+			],
+			"user-provided": [
+				{"name": "ups", "credentials": {"uri": "https://example.com"}}
+			]
+		}`
+	})
+
+	JustBeforeEach(func() {
+		result, interpolated, err = credentials.Interpolate(vcapServices, resolver)
+	})
+
+	It("replaces credential references with the resolved credentials", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(interpolated).To(BeTrue())
+		Expect(resolver.ResolveCallCount()).To(Equal(1))
+		Expect(resolver.ResolveArgsForCall(0)).To(Equal("/c/p-mysql/db/credentials"))
+
+		Expect(result).To(MatchJSON(`{
+			"p-mysql": [
+				{"name": "db", "credentials": {"password": "secret"}},
+				{"name": "plain-db", "credentials": {"password": "plain"}}
+			],
+			"user-provided": [
+				{"name": "ups", "credentials": {"uri": "https://example.com"}}
+			]
+		}`))
+	})
+
+	When("there are no credential references", func() {
+		BeforeEach(func() {
+			vcapServices = `{"user-provided": [{"name": "ups", "credentials": {"uri": "https://example.com"}}]}`
+		})
+
+		It("returns VCAP_SERVICES as is", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interpolated).To(BeFalse())
+			Expect(result).To(Equal(vcapServices))
+			Expect(resolver.ResolveCallCount()).To(BeZero())
+		})
+	})
+
+	When("a reference cannot be resolved", func() {
+		BeforeEach(func() {
+			resolver.ResolveReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to resolve /c/p-mysql/db/credentials: boom")))
+		})
+	})
+
+	When("VCAP_SERVICES is not valid JSON", func() {
+		BeforeEach(func() {
+			vcapServices = "{"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to parse VCAP_SERVICES")))
+		})
+	})
+})
@@ -0,0 +1,5 @@
+// Package credentials resolves the credential references Cloud Controller
+// puts in VCAP_SERVICES instead of the service credentials themselves
+package credentials
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
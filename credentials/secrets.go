@@ -0,0 +1,112 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// SecretValueKey is the key of the credentials JSON in the secret a
+	// reference resolves to
+	SecretValueKey = "value"
+
+	// AnnotationAppGUIDs lists the GUIDs of the apps which may read the
+	// credentials of a secret, separated by commas
+	AnnotationAppGUIDs = "cloudfoundry.org/app_guids"
+)
+
+//counterfeiter:generate . SecretGetter
+
+type SecretGetter interface {
+	Get(namespace, name string) (*corev1.Secret, error)
+}
+
+// SecretInstanceResolvers give every app instance a SecretResolver for its
+// app
+type SecretInstanceResolvers struct {
+	secrets   SecretGetter
+	namespace string
+}
+
+func NewSecretInstanceResolvers(secrets SecretGetter, namespace string) *SecretInstanceResolvers {
+	return &SecretInstanceResolvers{
+		secrets:   secrets,
+		namespace: namespace,
+	}
+}
+
+func (r *SecretInstanceResolvers) ForInstance(id identity.Identity) (Resolver, error) {
+	if id.AppGUID == "" {
+		return nil, errors.New("instance has no app guid")
+	}
+
+	return NewSecretResolver(r.secrets, r.namespace, id.AppGUID), nil
+}
+
+// SecretResolver is a local stand-in for CredHub, resolving references to
+// Kubernetes secrets. The reference /c/broker/instance/credentials resolves
+// to the secret named c.broker.instance.credentials. As CredHub does with
+// its permissions, the secret has to name the app in its app GUIDs
+// annotation for the app to read it
+type SecretResolver struct {
+	secrets   SecretGetter
+	namespace string
+	appGUID   string
+}
+
+func NewSecretResolver(secrets SecretGetter, namespace, appGUID string) *SecretResolver {
+	return &SecretResolver{
+		secrets:   secrets,
+		namespace: namespace,
+		appGUID:   appGUID,
+	}
+}
+
+func (r *SecretResolver) Resolve(ref string) (interface{}, error) {
+	name := SecretName(ref)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, fmt.Errorf("reference does not map to a valid secret name: %s", strings.Join(errs, ", "))
+	}
+
+	secret, err := r.secrets.Get(r.namespace, name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	if !r.readableBy(secret) {
+		return nil, fmt.Errorf("secret %s is not bound to app %s", name, r.appGUID)
+	}
+
+	value, ok := secret.Data[SecretValueKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no %s key", name, SecretValueKey)
+	}
+
+	var credentials interface{}
+	if err := json.Unmarshal(value, &credentials); err != nil {
+		return nil, errors.Wrap(err, "failed to parse credentials")
+	}
+
+	return credentials, nil
+}
+
+func (r *SecretResolver) readableBy(secret *corev1.Secret) bool {
+	for _, guid := range strings.Split(secret.Annotations[AnnotationAppGUIDs], ",") {
+		if strings.TrimSpace(guid) == r.appGUID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SecretName is the name of the secret a reference resolves to
+func SecretName(ref string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.Trim(ref, "/"), "/", "."))
+}
@@ -0,0 +1,152 @@
+package credentials_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini/credentials"
+	"code.cloudfoundry.org/eirini/credentials/credentialsfakes"
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("SecretResolver", func() {
+	var (
+		secrets  *credentialsfakes.FakeSecretGetter
+		resolver *credentials.SecretResolver
+		ref      string
+		resolved interface{}
+		err      error
+	)
+
+	BeforeEach(func() {
+		secrets = new(credentialsfakes.FakeSecretGetter)
+		secrets.GetReturns(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{credentials.AnnotationAppGUIDs: "other-app-guid, app-guid"},
+			},
+			Data: map[string][]byte{"value": []byte(`{"password": "secret"}`)},
+		}, nil)
+		resolver = credentials.NewSecretResolver(secrets, "creds-ns", "app-guid")
+		ref = "/c/p-mysql/DB/credentials"
+	})
+
+	JustBeforeEach(func() {
+		resolved, err = resolver.Resolve(ref)
+	})
+
+	It("resolves the reference to the secret named after it", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved).To(Equal(map[string]interface{}{"password": "secret"}))
+
+		Expect(secrets.GetCallCount()).To(Equal(1))
+		namespace, name := secrets.GetArgsForCall(0)
+		Expect(namespace).To(Equal("creds-ns"))
+		Expect(name).To(Equal("c.p-mysql.db.credentials"))
+	})
+
+	When("the reference does not map to a valid secret name", func() {
+		BeforeEach(func() {
+			ref = "/c/p_mysql/db"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("reference does not map to a valid secret name")))
+			Expect(secrets.GetCallCount()).To(BeZero())
+		})
+	})
+
+	When("getting the secret fails", func() {
+		BeforeEach(func() {
+			secrets.GetReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to get secret: boom")))
+		})
+	})
+
+	When("the secret is not bound to the app", func() {
+		BeforeEach(func() {
+			secrets.GetReturns(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{credentials.AnnotationAppGUIDs: "other-app-guid"},
+				},
+				Data: map[string][]byte{"value": []byte(`{"password": "secret"}`)},
+			}, nil)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("secret c.p-mysql.db.credentials is not bound to app app-guid"))
+			Expect(resolved).To(BeNil())
+		})
+	})
+
+	When("the secret does not name any apps", func() {
+		BeforeEach(func() {
+			secrets.GetReturns(&corev1.Secret{
+				Data: map[string][]byte{"value": []byte(`{"password": "secret"}`)},
+			}, nil)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("is not bound to app app-guid")))
+		})
+	})
+
+	When("the secret has no value", func() {
+		BeforeEach(func() {
+			secrets.GetReturns(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{credentials.AnnotationAppGUIDs: "app-guid"},
+				},
+			}, nil)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("secret c.p-mysql.db.credentials has no value key"))
+		})
+	})
+
+	When("the value is not JSON", func() {
+		BeforeEach(func() {
+			secrets.GetReturns(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{credentials.AnnotationAppGUIDs: "app-guid"},
+				},
+				Data: map[string][]byte{"value": []byte("nope")},
+			}, nil)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to parse credentials")))
+		})
+	})
+})
+
+var _ = Describe("SecretInstanceResolvers", func() {
+	var (
+		secrets   *credentialsfakes.FakeSecretGetter
+		resolvers *credentials.SecretInstanceResolvers
+	)
+
+	BeforeEach(func() {
+		secrets = new(credentialsfakes.FakeSecretGetter)
+		resolvers = credentials.NewSecretInstanceResolvers(secrets, "creds-ns")
+	})
+
+	It("gives the instance a resolver for its app", func() {
+		resolver, err := resolvers.ForInstance(identity.Identity{InstanceID: "some-app-0", AppGUID: "app-guid"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolver).To(Equal(credentials.NewSecretResolver(secrets, "creds-ns", "app-guid")))
+	})
+
+	When("the instance has no app guid", func() {
+		It("returns an error", func() {
+			_, err := resolvers.ForInstance(identity.Identity{InstanceID: "some-app-0"})
+			Expect(err).To(MatchError("instance has no app guid"))
+		})
+	})
+})
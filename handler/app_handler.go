@@ -49,6 +49,13 @@ func (a *App) Desire(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 
 	if err := a.lrpBifrost.Transfer(r.Context(), request); err != nil {
 		loggerSession.Error("bifrost-failed", err)
+
+		if errors.Is(err, eirini.ErrImageNotAllowed) {
+			writeErrorResponse(loggerSession, w, http.StatusForbidden, err)
+
+			return
+		}
+
 		w.WriteHeader(http.StatusBadRequest)
 
 		return
@@ -172,7 +179,13 @@ func (a *App) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 
 	if err := a.lrpBifrost.Update(r.Context(), request); err != nil {
 		loggerSession.Error("bifrost-failed", err)
-		writeUpdateErrorResponse(w, err, http.StatusInternalServerError, loggerSession)
+
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, eirini.ErrImageNotAllowed) {
+			statusCode = http.StatusForbidden
+		}
+
+		writeUpdateErrorResponse(w, err, statusCode, loggerSession)
 	}
 }
 
@@ -170,6 +170,22 @@ var _ = Describe("AppHandler", func() {
 			It("should provide a helpful log message", findLog("app-handler-test.desire-app.bifrost-failed", "myguid"))
 		})
 
+		Context("When the image policy rejects the image", func() {
+			BeforeEach(func() {
+				lrpBifrost.TransferReturns(fmt.Errorf("registry docker.io is denied: %w", eirini.ErrImageNotAllowed))
+			})
+
+			It("should return Forbidden status", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			})
+
+			It("should explain why in the response body", func() {
+				var responseError cf.Error
+				Expect(json.NewDecoder(response.Body).Decode(&responseError)).To(Succeed())
+				Expect(responseError.Message).To(ContainSubstring("registry docker.io is denied"))
+			})
+		})
+
 		Context("when the body is empty", func() {
 			BeforeEach(func() {
 				body = ""
@@ -531,6 +547,16 @@ var _ = Describe("AppHandler", func() {
 				verifyResponseObject()
 			})
 		})
+
+		Context("when the image policy rejects the image", func() {
+			BeforeEach(func() {
+				lrpBifrost.UpdateReturns(fmt.Errorf("registry docker.io is denied: %w", eirini.ErrImageNotAllowed))
+			})
+
+			It("should return a 403 HTTP status code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
 	})
 
 	Context("Stop an app", func() {
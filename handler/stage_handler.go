@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/models/cf"
 	"code.cloudfoundry.org/lager"
 	"github.com/julienschmidt/httprouter"
@@ -49,7 +50,13 @@ func (s *Stage) Run(resp http.ResponseWriter, req *http.Request, ps httprouter.P
 	if err := s.stage(stagingGUID, stagingRequest); err != nil {
 		reason := fmt.Sprintf("failed to stage task with guid %q", stagingGUID)
 		logger.Error("staging-failed", errors.Wrap(err, reason))
-		writeErrorResponse(logger, resp, http.StatusInternalServerError, errors.Wrap(err, reason))
+
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, eirini.ErrImageNotAllowed) {
+			statusCode = http.StatusForbidden
+		}
+
+		writeErrorResponse(logger, resp, statusCode, errors.Wrap(err, reason))
 
 		return
 	}
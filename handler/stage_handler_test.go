@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 
+	"code.cloudfoundry.org/eirini"
 	. "code.cloudfoundry.org/eirini/handler"
 	"code.cloudfoundry.org/eirini/handler/handlerfakes"
 	"code.cloudfoundry.org/eirini/models/cf"
@@ -146,6 +148,16 @@ var _ = Describe("StageHandler", func() {
 			})
 		})
 
+		Context("and the image policy rejects the image", func() {
+			BeforeEach(func() {
+				dockerStagingClient.TransferStagingReturns(fmt.Errorf("registry docker.io is denied: %w", eirini.ErrImageNotAllowed))
+			})
+
+			It("should return a 403 Forbidden", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+
 		Context("and the body is invalid", func() {
 			BeforeEach(func() {
 				body = "{ this json is invalid"
@@ -117,6 +117,16 @@ var _ = Describe("TaskHandler", func() {
 			})
 		})
 
+		When("the image policy rejects the image", func() {
+			BeforeEach(func() {
+				taskBifrost.TransferTaskReturns(errors.Wrap(eirini.ErrImageNotAllowed, "registry docker.io is denied"))
+			})
+
+			It("should return 403 Forbidden code", func() {
+				Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			})
+		})
+
 		Context("when the request body cannot be unmarshalled", func() {
 			BeforeEach(func() {
 				body = "random stuff"
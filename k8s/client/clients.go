@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"code.cloudfoundry.org/eirini/k8s/jobs"
@@ -12,10 +13,17 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 )
 
 type Pod struct {
@@ -75,6 +83,97 @@ func (c *Pod) SetAnnotation(pod *corev1.Pod, key, value string) (*corev1.Pod, er
 	)
 }
 
+// PodLister serves pods from an informer cache instead of listing them from
+// the API server on every call
+type PodLister struct {
+	lister             corelisters.PodLister
+	workloadsNamespace string
+}
+
+func NewPodLister(lister corelisters.PodLister, workloadsNamespace string) *PodLister {
+	return &PodLister{
+		lister:             lister,
+		workloadsNamespace: workloadsNamespace,
+	}
+}
+
+func (c *PodLister) GetAll() ([]corev1.Pod, error) {
+	selector, err := labels.Parse(fmt.Sprintf(
+		"%s in (%s,%s)",
+		stset.LabelSourceType, "APP", "TASK",
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse label selector")
+	}
+
+	var pods []*corev1.Pod
+	if c.workloadsNamespace == "" {
+		pods, err = c.lister.List(selector)
+	} else {
+		pods, err = c.lister.Pods(c.workloadsNamespace).List(selector)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods from cache")
+	}
+
+	result := make([]corev1.Pod, 0, len(pods))
+	for _, p := range pods {
+		result = append(result, *p)
+	}
+
+	return result, nil
+}
+
+// StatefulSetLister serves statefulsets from an informer cache instead of
+// getting them from the API server on every call
+type StatefulSetLister struct {
+	lister             appslisters.StatefulSetLister
+	workloadsNamespace string
+}
+
+func NewStatefulSetLister(lister appslisters.StatefulSetLister, workloadsNamespace string) *StatefulSetLister {
+	return &StatefulSetLister{
+		lister:             lister,
+		workloadsNamespace: workloadsNamespace,
+	}
+}
+
+func (c *StatefulSetLister) Get(namespace, name string) (*appsv1.StatefulSet, error) {
+	statefulSet, err := c.lister.StatefulSets(namespace).Get(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get statefulset from cache")
+	}
+
+	return statefulSet.DeepCopy(), nil
+}
+
+func (c *StatefulSetLister) GetBySourceType(sourceType string) ([]appsv1.StatefulSet, error) {
+	selector := labels.SelectorFromSet(labels.Set{stset.LabelSourceType: sourceType})
+
+	var (
+		statefulSets []*appsv1.StatefulSet
+		err          error
+	)
+
+	if c.workloadsNamespace == "" {
+		statefulSets, err = c.lister.List(selector)
+	} else {
+		statefulSets, err = c.lister.StatefulSets(c.workloadsNamespace).List(selector)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list statefulsets from cache")
+	}
+
+	result := make([]appsv1.StatefulSet, 0, len(statefulSets))
+	for _, s := range statefulSets {
+		result = append(result, *s)
+	}
+
+	return result, nil
+}
+
 type PodDisruptionBudget struct {
 	clientSet kubernetes.Interface
 }
@@ -122,6 +221,17 @@ func (c *StatefulSet) GetBySourceType(sourceType string) ([]appsv1.StatefulSet,
 	return statefulSetList.Items, nil
 }
 
+func (c *StatefulSet) GetByGUID(guid string) ([]appsv1.StatefulSet, error) {
+	statefulSetList, err := c.clientSet.AppsV1().StatefulSets(c.workloadsNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", stset.LabelGUID, guid),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list statefulsets by guid")
+	}
+
+	return statefulSetList.Items, nil
+}
+
 func (c *StatefulSet) GetByLRPIdentifier(id opi.LRPIdentifier) ([]appsv1.StatefulSet, error) {
 	statefulSetList, err := c.clientSet.AppsV1().StatefulSets(c.workloadsNamespace).List(context.Background(), metav1.ListOptions{
 		LabelSelector: fmt.Sprintf(
@@ -295,3 +405,164 @@ func (c *Event) Create(namespace string, event *corev1.Event) (*corev1.Event, er
 func (c *Event) Update(namespace string, event *corev1.Event) (*corev1.Event, error) {
 	return c.clientSet.CoreV1().Events(namespace).Update(context.Background(), event, metav1.UpdateOptions{})
 }
+
+const (
+	// RouteFieldManager owns the fields of the objects the route emitter applies
+	RouteFieldManager = "eirini-route-emitter"
+	// PolicyFieldManager owns the fields of the network policies policy-sync applies
+	PolicyFieldManager = "eirini-policy-sync"
+)
+
+// HTTPRouteResource is the Gateway API resource routes are published as
+var HTTPRouteResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+func applyOptions() metav1.PatchOptions {
+	return applyOptionsFor(RouteFieldManager)
+}
+
+func applyOptionsFor(fieldManager string) metav1.PatchOptions {
+	force := true
+
+	return metav1.PatchOptions{FieldManager: fieldManager, Force: &force}
+}
+
+type Service struct {
+	clientSet kubernetes.Interface
+}
+
+func NewService(clientSet kubernetes.Interface) *Service {
+	return &Service{clientSet: clientSet}
+}
+
+func (c *Service) Apply(service *corev1.Service) error {
+	data, err := json.Marshal(service)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal service")
+	}
+
+	_, err = c.clientSet.CoreV1().Services(service.Namespace).Patch(
+		context.Background(), service.Name, types.ApplyPatchType, data, applyOptions(),
+	)
+
+	return errors.Wrap(err, "failed to apply service")
+}
+
+func (c *Service) Get(namespace, name string) (*corev1.Service, error) {
+	return c.clientSet.CoreV1().Services(namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+func (c *Service) List(namespace, labelSelector string) ([]corev1.Service, error) {
+	list, err := c.clientSet.CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list services")
+	}
+
+	return list.Items, nil
+}
+
+func (c *Service) Delete(namespace, name string) error {
+	return c.clientSet.CoreV1().Services(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+type Ingress struct {
+	clientSet kubernetes.Interface
+}
+
+func NewIngress(clientSet kubernetes.Interface) *Ingress {
+	return &Ingress{clientSet: clientSet}
+}
+
+func (c *Ingress) Apply(ingress *networkingv1.Ingress) error {
+	data, err := json.Marshal(ingress)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal ingress")
+	}
+
+	_, err = c.clientSet.NetworkingV1().Ingresses(ingress.Namespace).Patch(
+		context.Background(), ingress.Name, types.ApplyPatchType, data, applyOptions(),
+	)
+
+	return errors.Wrap(err, "failed to apply ingress")
+}
+
+func (c *Ingress) Delete(namespace, name string) error {
+	return c.clientSet.NetworkingV1().Ingresses(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+type HTTPRoute struct {
+	dynamicClient dynamic.Interface
+}
+
+func NewHTTPRoute(dynamicClient dynamic.Interface) *HTTPRoute {
+	return &HTTPRoute{dynamicClient: dynamicClient}
+}
+
+func (c *HTTPRoute) Apply(httpRoute *unstructured.Unstructured) error {
+	data, err := httpRoute.MarshalJSON()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal httproute")
+	}
+
+	_, err = c.dynamicClient.Resource(HTTPRouteResource).Namespace(httpRoute.GetNamespace()).Patch(
+		context.Background(), httpRoute.GetName(), types.ApplyPatchType, data, applyOptions(),
+	)
+
+	return errors.Wrap(err, "failed to apply httproute")
+}
+
+func (c *HTTPRoute) List(namespace, labelSelector string) ([]unstructured.Unstructured, error) {
+	list, err := c.dynamicClient.Resource(HTTPRouteResource).Namespace(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list httproutes")
+	}
+
+	return list.Items, nil
+}
+
+func (c *HTTPRoute) Delete(namespace, name string) error {
+	return c.dynamicClient.Resource(HTTPRouteResource).Namespace(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+type NetworkPolicy struct {
+	clientSet kubernetes.Interface
+}
+
+func NewNetworkPolicy(clientSet kubernetes.Interface) *NetworkPolicy {
+	return &NetworkPolicy{clientSet: clientSet}
+}
+
+func (c *NetworkPolicy) Apply(networkPolicy *networkingv1.NetworkPolicy) error {
+	data, err := json.Marshal(networkPolicy)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal network policy")
+	}
+
+	_, err = c.clientSet.NetworkingV1().NetworkPolicies(networkPolicy.Namespace).Patch(
+		context.Background(), networkPolicy.Name, types.ApplyPatchType, data, applyOptionsFor(PolicyFieldManager),
+	)
+
+	return errors.Wrap(err, "failed to apply network policy")
+}
+
+func (c *NetworkPolicy) List(namespace, labelSelector string) ([]networkingv1.NetworkPolicy, error) {
+	list, err := c.clientSet.NetworkingV1().NetworkPolicies(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list network policies")
+	}
+
+	return list.Items, nil
+}
+
+func (c *NetworkPolicy) Delete(namespace, name string) error {
+	return c.clientSet.NetworkingV1().NetworkPolicies(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
@@ -0,0 +1,174 @@
+// Package identity issues the instance identity credentials CF apps find in
+// CF_INSTANCE_CERT and CF_INSTANCE_KEY
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	VolumeName   = "cf-instance-identity"
+	MountPath    = "/etc/cf-instance-credentials"
+	CertFileName = "instance.crt"
+	KeyFileName  = "instance.key"
+
+	// LabelPodName marks the secrets holding instance identity credentials
+	// with the name of the pod they belong to
+	LabelPodName = "cloudfoundry.org/instance_identity_pod"
+
+	serialNumberBits = 128
+)
+
+// Identity is what an instance certificate vouches for. The organization,
+// space and app GUIDs go into the OU of the subject, as Diego does it
+type Identity struct {
+	InstanceID string
+	IP         string
+	AppGUID    string
+	SpaceGUID  string
+	OrgGUID    string
+}
+
+func FromPod(pod *corev1.Pod) Identity {
+	return Identity{
+		InstanceID: pod.Name,
+		IP:         pod.Status.PodIP,
+		AppGUID:    pod.Annotations[stset.AnnotationAppID],
+		SpaceGUID:  pod.Annotations[stset.AnnotationSpaceGUID],
+		OrgGUID:    pod.Annotations[stset.AnnotationOrgGUID],
+	}
+}
+
+// SecretName is the name of the secret holding the credentials of a pod
+func SecretName(podName string) string {
+	return podName + "-instance-identity"
+}
+
+type Issuer struct {
+	caCert   *x509.Certificate
+	caKey    interface{}
+	validity time.Duration
+}
+
+func NewIssuer(caCertPEM, caKeyPEM []byte, validity time.Duration) (*Issuer, error) {
+	ca, err := tls.X509KeyPair(caCertPEM, caKeyPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load CA key pair")
+	}
+
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse CA certificate")
+	}
+
+	return &Issuer{
+		caCert:   caCert,
+		caKey:    ca.PrivateKey,
+		validity: validity,
+	}, nil
+}
+
+// Issue returns a PEM encoded certificate, chained to the CA, and key for the
+// given identity, valid for both client and server authentication
+func (i *Issuer) Issue(id Identity) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberBits))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: id.InstanceID,
+			OrganizationalUnit: []string{
+				"organization:" + id.OrgGUID,
+				"space:" + id.SpaceGUID,
+				"app:" + id.AppGUID,
+			},
+		},
+		DNSNames:    []string{id.InstanceID},
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    now.Add(i.validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+
+	if ip := net.ParseIP(id.IP); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, i.caCert, key.Public(), i.caKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create certificate")
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal key")
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: i.caCert.Raw})...)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
+
+// NeedsRenewal tells whether the certificate expires within renewBefore or
+// does not vouch for the identity anymore, e.g. because the pod got its IP
+// after the certificate was issued
+func NeedsRenewal(certPEM []byte, id Identity, renewBefore time.Duration) (bool, time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return true, time.Time{}, errors.New("failed to decode certificate PEM")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true, time.Time{}, errors.Wrap(err, "failed to parse certificate")
+	}
+
+	renewAt := cert.NotAfter.Add(-renewBefore)
+	if !time.Now().Before(renewAt) {
+		return true, renewAt, nil
+	}
+
+	if cert.Subject.CommonName != id.InstanceID {
+		return true, renewAt, nil
+	}
+
+	if ip := net.ParseIP(id.IP); ip != nil && !containsIP(cert.IPAddresses, ip) {
+		return true, renewAt, nil
+	}
+
+	return false, renewAt, nil
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, i := range ips {
+		if i.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
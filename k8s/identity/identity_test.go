@@ -0,0 +1,165 @@
+package identity_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("FromPod", func() {
+	It("takes the identity from the pod name, IP and annotations", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dora-space-0",
+				Annotations: map[string]string{
+					"cloudfoundry.org/application_id": "app-guid",
+					"cloudfoundry.org/space_guid":     "space-guid",
+					"cloudfoundry.org/org_guid":       "org-guid",
+				},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+		}
+
+		Expect(identity.FromPod(pod)).To(Equal(identity.Identity{
+			InstanceID: "dora-space-0",
+			IP:         "10.0.0.1",
+			AppGUID:    "app-guid",
+			SpaceGUID:  "space-guid",
+			OrgGUID:    "org-guid",
+		}))
+	})
+})
+
+var _ = Describe("Issuer", func() {
+	var (
+		caCert    *x509.Certificate
+		caCertPEM []byte
+		caKeyPEM  []byte
+		issuer    *identity.Issuer
+		id        identity.Identity
+	)
+
+	BeforeEach(func() {
+		caCert, caCertPEM, caKeyPEM = generateCA()
+
+		var err error
+		issuer, err = identity.NewIssuer(caCertPEM, caKeyPEM, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		id = identity.Identity{
+			InstanceID: "dora-space-0",
+			IP:         "10.0.0.1",
+			AppGUID:    "app-guid",
+			SpaceGUID:  "space-guid",
+			OrgGUID:    "org-guid",
+		}
+	})
+
+	parseCert := func(certPEM []byte) *x509.Certificate {
+		block, _ := pem.Decode(certPEM)
+		Expect(block).NotTo(BeNil())
+		cert, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+
+		return cert
+	}
+
+	It("issues a certificate for the instance signed by the CA", func() {
+		certPEM, keyPEM, err := issuer.Issue(id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyPEM).NotTo(BeEmpty())
+
+		cert := parseCert(certPEM)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(caCert)
+		_, err = cert.Verify(x509.VerifyOptions{
+			DNSName:   "dora-space-0",
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cert.NotAfter).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+	})
+
+	It("puts the app, space and org GUIDs in the OU and the pod IP in the SAN", func() {
+		certPEM, _, err := issuer.Issue(id)
+		Expect(err).NotTo(HaveOccurred())
+
+		cert := parseCert(certPEM)
+		Expect(cert.Subject.CommonName).To(Equal("dora-space-0"))
+		Expect(cert.Subject.OrganizationalUnit).To(ConsistOf("organization:org-guid", "space:space-guid", "app:app-guid"))
+		Expect(cert.IPAddresses).To(HaveLen(1))
+		Expect(cert.IPAddresses[0].Equal(net.ParseIP("10.0.0.1"))).To(BeTrue())
+	})
+
+	It("appends the CA certificate to the chain", func() {
+		certPEM, _, err := issuer.Issue(id)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, rest := pem.Decode(certPEM)
+		Expect(parseCert(rest).Equal(caCert)).To(BeTrue())
+	})
+
+	When("the IP is not known yet", func() {
+		BeforeEach(func() {
+			id.IP = ""
+		})
+
+		It("issues a certificate without an IP SAN", func() {
+			certPEM, _, err := issuer.Issue(id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parseCert(certPEM).IPAddresses).To(BeEmpty())
+		})
+	})
+
+	When("the CA key pair is invalid", func() {
+		It("returns an error", func() {
+			_, err := identity.NewIssuer(caCertPEM, []byte("nope"), time.Hour)
+			Expect(err).To(MatchError(ContainSubstring("failed to load CA key pair")))
+		})
+	})
+
+	Describe("NeedsRenewal", func() {
+		var certPEM []byte
+
+		BeforeEach(func() {
+			var err error
+			certPEM, _, err = issuer.Issue(id)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("does not renew a fresh certificate for the same identity", func() {
+			renew, renewAt, err := identity.NeedsRenewal(certPEM, id, 10*time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(renew).To(BeFalse())
+			Expect(renewAt).To(BeTemporally("~", time.Now().Add(50*time.Minute), time.Minute))
+		})
+
+		It("renews a certificate that expires within the renewal period", func() {
+			renew, _, err := identity.NeedsRenewal(certPEM, id, 2*time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(renew).To(BeTrue())
+		})
+
+		It("renews a certificate missing the pod IP", func() {
+			id.IP = "10.0.0.2"
+			renew, _, err := identity.NeedsRenewal(certPEM, id, 10*time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(renew).To(BeTrue())
+		})
+
+		It("renews a certificate which cannot be decoded", func() {
+			renew, _, err := identity.NeedsRenewal([]byte("nope"), id, 10*time.Minute)
+			Expect(err).To(MatchError(ContainSubstring("failed to decode certificate PEM")))
+			Expect(renew).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,266 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/logs"
+	"code.cloudfoundry.org/eirini/util"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const defaultProcessType = "web"
+
+//counterfeiter:generate . LogStreamer
+
+type LogStreamer interface {
+	Stream(ctx context.Context, pod *corev1.Pod, container string, since time.Time) (io.ReadCloser, error)
+}
+
+// Forwarder follows the logs of the app container of every Eirini pod it is
+// told about and emits them line by line. When a stream breaks, e.g. because
+// the container restarted, it is reopened from the last line seen.
+type Forwarder struct {
+	streamer      LogStreamer
+	emitter       logs.Emitter
+	retryInterval time.Duration
+	logger        lager.Logger
+	startedAt     time.Time
+	lock          sync.Mutex
+	tailers       map[types.UID]*tailer
+	wg            sync.WaitGroup
+}
+
+type source struct {
+	id         string
+	sourceType string
+	instance   string
+	container  string
+}
+
+type tailer struct {
+	cancel    context.CancelFunc
+	lock      sync.Mutex
+	completed bool
+}
+
+func NewForwarder(streamer LogStreamer, emitter logs.Emitter, retryInterval time.Duration, logger lager.Logger) *Forwarder {
+	return &Forwarder{
+		streamer:      streamer,
+		emitter:       emitter,
+		retryInterval: retryInterval,
+		logger:        logger,
+		startedAt:     time.Now(),
+		tailers:       map[types.UID]*tailer{},
+	}
+}
+
+// Update starts following the logs of the pod once it has started. Pods that
+// were created before the forwarder started are only followed from that
+// moment on, so that restarting the forwarder does not replay their logs.
+func (f *Forwarder) Update(pod *corev1.Pod) {
+	src, ok := sourceOf(pod)
+	if !ok || !hasStarted(pod) {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	t, ok := f.tailers[pod.UID]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		t = &tailer{cancel: cancel}
+		f.tailers[pod.UID] = t
+
+		var since time.Time
+		if pod.CreationTimestamp.Time.Before(f.startedAt) {
+			since = f.startedAt
+		}
+
+		f.wg.Add(1)
+
+		go func(pod *corev1.Pod) {
+			defer f.wg.Done()
+			f.tail(ctx, t, pod, src, since)
+		}(pod.DeepCopy())
+	}
+
+	if isCompleted(pod) {
+		t.complete()
+	}
+}
+
+func (f *Forwarder) Delete(pod *corev1.Pod) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if t, ok := f.tailers[pod.UID]; ok {
+		t.cancel()
+		delete(f.tailers, pod.UID)
+	}
+}
+
+// Stop stops following all pods and waits for the streams to be closed
+func (f *Forwarder) Stop() {
+	f.lock.Lock()
+	for uid, t := range f.tailers {
+		t.cancel()
+		delete(f.tailers, uid)
+	}
+	f.lock.Unlock()
+
+	f.wg.Wait()
+}
+
+func (f *Forwarder) tail(ctx context.Context, t *tailer, pod *corev1.Pod, src source, since time.Time) {
+	logger := f.logger.Session("tail", lager.Data{"pod-name": pod.Name, "container": src.container})
+
+	for {
+		var err error
+
+		since, err = f.stream(ctx, pod, src, since)
+		if err != nil && ctx.Err() == nil {
+			logger.Debug("stream-interrupted", lager.Data{"error": err.Error()})
+		}
+
+		if ctx.Err() != nil || t.isCompleted() {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(f.retryInterval):
+		}
+	}
+}
+
+// stream emits the lines newer than since and returns the timestamp of the
+// last line it has emitted
+func (f *Forwarder) stream(ctx context.Context, pod *corev1.Pod, src source, since time.Time) (time.Time, error) {
+	body, err := f.streamer.Stream(ctx, pod, src.container, since)
+	if err != nil {
+		return since, errors.Wrap(err, "failed to stream logs")
+	}
+	defer body.Close()
+
+	reader := bufio.NewReader(body)
+
+	for {
+		text, err := reader.ReadString('\n')
+
+		if text != "" {
+			timestamp, message := parseLine(text)
+
+			if timestamp.IsZero() || timestamp.After(since) {
+				f.emitter.Emit(logs.Line{
+					SourceID:   src.id,
+					SourceType: src.sourceType,
+					InstanceID: src.instance,
+					Message:    message,
+				})
+			}
+
+			if timestamp.After(since) {
+				since = timestamp
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return since, nil
+		}
+
+		if err != nil {
+			return since, errors.Wrap(err, "failed to read logs")
+		}
+	}
+}
+
+// parseLine splits the RFC3339 timestamp the Kubernetes log API prefixes each
+// line with from the line itself
+func parseLine(text string) (time.Time, string) {
+	text = strings.TrimRight(text, "\r\n")
+
+	separator := strings.Index(text, " ")
+	if separator < 0 {
+		return time.Time{}, text
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, text[:separator])
+	if err != nil {
+		return time.Time{}, text
+	}
+
+	return timestamp, text[separator+1:]
+}
+
+// sourceOf tags LRP instances as APP/PROC/<PROCESS TYPE> with their instance
+// index and tasks as APP/TASK/<task name> with instance 0, as Diego does.
+func sourceOf(pod *corev1.Pod) (source, bool) {
+	switch pod.Labels[stset.LabelSourceType] {
+	case stset.AppSourceType:
+		index, err := util.ParseAppIndex(pod.Name)
+		if err != nil {
+			return source{}, false
+		}
+
+		processType := pod.Labels[stset.LabelProcessType]
+		if processType == "" {
+			processType = defaultProcessType
+		}
+
+		return source{
+			id:         pod.Labels[stset.LabelAppGUID],
+			sourceType: logs.SourceTypeProc + "/" + strings.ToUpper(processType),
+			instance:   strconv.Itoa(index),
+			container:  stset.OPIContainerName,
+		}, true
+	case jobs.TaskSourceType:
+		sourceType := logs.SourceTypeTask
+		if name := pod.Labels[jobs.LabelName]; name != "" {
+			sourceType += "/" + name
+		}
+
+		return source{
+			id:         pod.Labels[jobs.LabelAppGUID],
+			sourceType: sourceType,
+			instance:   "0",
+			container:  pod.Annotations[jobs.AnnotationOpiTaskContainerName],
+		}, true
+	default:
+		return source{}, false
+	}
+}
+
+func hasStarted(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodRunning || isCompleted(pod)
+}
+
+func isCompleted(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+func (t *tailer) complete() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.completed = true
+}
+
+func (t *tailer) isCompleted() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.completed
+}
@@ -0,0 +1,242 @@
+package logs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	. "code.cloudfoundry.org/eirini/k8s/informers/logs"
+	"code.cloudfoundry.org/eirini/k8s/informers/logs/logsfakes"
+	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/logs"
+	eirinilogsfakes "code.cloudfoundry.org/eirini/logs/logsfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Forwarder", func() {
+	var (
+		streamer  *logsfakes.FakeLogStreamer
+		emitter   *eirinilogsfakes.FakeEmitter
+		forwarder *Forwarder
+		pod       *corev1.Pod
+	)
+
+	logBody := func(lines ...string) io.ReadCloser {
+		return ioutil.NopCloser(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	}
+
+	// blockingBody keeps the stream open until the forwarder cancels it
+	blockingBody := func(ctx context.Context, lines ...string) io.ReadCloser {
+		reader, writer := io.Pipe()
+
+		go func() {
+			for _, line := range lines {
+				_, _ = writer.Write([]byte(line + "\n"))
+			}
+			<-ctx.Done()
+			writer.Close()
+		}()
+
+		return reader
+	}
+
+	emittedLines := func() []logs.Line {
+		lines := []logs.Line{}
+		for i := 0; i < emitter.EmitCallCount(); i++ {
+			lines = append(lines, emitter.EmitArgsForCall(i))
+		}
+
+		return lines
+	}
+
+	BeforeEach(func() {
+		streamer = new(logsfakes.FakeLogStreamer)
+		emitter = new(eirinilogsfakes.FakeEmitter)
+		forwarder = NewForwarder(streamer, emitter, 10*time.Millisecond, lagertest.NewTestLogger("forwarder"))
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "dora-space-3",
+				Namespace:         "workloads",
+				UID:               types.UID("dora-uid"),
+				CreationTimestamp: metav1.NewTime(time.Now().Add(time.Minute)),
+				Labels: map[string]string{
+					stset.LabelSourceType: stset.AppSourceType,
+					stset.LabelAppGUID:    "app-guid",
+				},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+
+		streamer.StreamStub = func(ctx context.Context, _ *corev1.Pod, _ string, _ time.Time) (io.ReadCloser, error) {
+			return blockingBody(ctx,
+				"2021-02-03T10:00:00.000000001Z hello",
+				"2021-02-03T10:00:00.000000002Z world",
+			), nil
+		}
+	})
+
+	AfterEach(func() {
+		forwarder.Stop()
+	})
+
+	It("should emit the lines of the app container tagged with the app instance", func() {
+		forwarder.Update(pod)
+
+		Eventually(emittedLines).Should(Equal([]logs.Line{
+			{SourceID: "app-guid", SourceType: "APP/PROC/WEB", InstanceID: "3", Message: "hello"},
+			{SourceID: "app-guid", SourceType: "APP/PROC/WEB", InstanceID: "3", Message: "world"},
+		}))
+
+		_, streamedPod, container, since := streamer.StreamArgsForCall(0)
+		Expect(streamedPod.Name).To(Equal("dora-space-3"))
+		Expect(container).To(Equal(stset.OPIContainerName))
+		Expect(since).To(BeZero())
+	})
+
+	It("should tag the lines with the process type", func() {
+		pod.Labels[stset.LabelProcessType] = "worker"
+		forwarder.Update(pod)
+
+		Eventually(emittedLines).Should(HaveLen(2))
+		Expect(emittedLines()[0].SourceType).To(Equal("APP/PROC/WORKER"))
+	})
+
+	It("should follow each pod only once", func() {
+		forwarder.Update(pod)
+		forwarder.Update(pod)
+
+		Eventually(streamer.StreamCallCount).Should(Equal(1))
+		Consistently(streamer.StreamCallCount, "100ms").Should(Equal(1))
+	})
+
+	It("should stop following a pod once it is deleted", func() {
+		var streamCtx context.Context
+
+		streamer.StreamStub = func(ctx context.Context, _ *corev1.Pod, _ string, _ time.Time) (io.ReadCloser, error) {
+			streamCtx = ctx
+
+			return blockingBody(ctx), nil
+		}
+
+		forwarder.Update(pod)
+		Eventually(streamer.StreamCallCount).Should(Equal(1))
+
+		forwarder.Delete(pod)
+		Eventually(streamCtx.Done()).Should(BeClosed())
+	})
+
+	When("the pod has not started yet", func() {
+		BeforeEach(func() {
+			pod.Status.Phase = corev1.PodPending
+		})
+
+		It("should not follow it", func() {
+			forwarder.Update(pod)
+			Consistently(streamer.StreamCallCount, "100ms").Should(BeZero())
+		})
+	})
+
+	When("the pod is not an LRP or task pod", func() {
+		BeforeEach(func() {
+			delete(pod.Labels, stset.LabelSourceType)
+		})
+
+		It("should not follow it", func() {
+			forwarder.Update(pod)
+			Consistently(streamer.StreamCallCount, "100ms").Should(BeZero())
+		})
+	})
+
+	When("the pod was created before the forwarder started", func() {
+		BeforeEach(func() {
+			pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+		})
+
+		It("should only follow the lines written since the forwarder started", func() {
+			forwarder.Update(pod)
+
+			Eventually(streamer.StreamCallCount).Should(Equal(1))
+			_, _, _, since := streamer.StreamArgsForCall(0)
+			Expect(since).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+	})
+
+	When("the stream breaks", func() {
+		BeforeEach(func() {
+			streamer.StreamReturns(nil, errors.New("container is gone"))
+			streamer.StreamReturnsOnCall(0, logBody(
+				"2021-02-03T10:00:00.000000001Z hello",
+				"2021-02-03T10:00:00.000000002Z world",
+			), nil)
+			streamer.StreamReturnsOnCall(1, nil, errors.New("container is restarting"))
+			streamer.StreamReturnsOnCall(2, logBody(
+				"2021-02-03T10:00:00.000000002Z world",
+				"2021-02-03T10:00:01Z again",
+			), nil)
+		})
+
+		It("should reopen it from the last line it has seen", func() {
+			forwarder.Update(pod)
+
+			Eventually(emittedLines).Should(HaveLen(3))
+			Expect(emittedLines()[2].Message).To(Equal("again"))
+
+			_, _, _, since := streamer.StreamArgsForCall(2)
+			Expect(since).To(Equal(time.Date(2021, 2, 3, 10, 0, 0, 2, time.UTC)))
+		})
+	})
+
+	When("the pod has completed", func() {
+		BeforeEach(func() {
+			pod.Status.Phase = corev1.PodSucceeded
+			streamer.StreamStub = nil
+			streamer.StreamReturns(logBody("2021-02-03T10:00:00Z done"), nil)
+		})
+
+		It("should stop following it once the stream ends", func() {
+			forwarder.Update(pod)
+
+			Eventually(emittedLines).Should(HaveLen(1))
+			Consistently(streamer.StreamCallCount, "100ms").Should(Equal(1))
+		})
+	})
+
+	When("the pod is a task pod", func() {
+		BeforeEach(func() {
+			pod.Name = "dora-space-task-abc12"
+			pod.Labels = map[string]string{
+				jobs.LabelSourceType: jobs.TaskSourceType,
+				jobs.LabelAppGUID:    "app-guid",
+				jobs.LabelName:       "migrate",
+			}
+			pod.Annotations = map[string]string{
+				jobs.AnnotationOpiTaskContainerName: "opi-task",
+			}
+		})
+
+		It("should tag the lines as task output", func() {
+			forwarder.Update(pod)
+
+			Eventually(emittedLines).Should(HaveLen(2))
+			Expect(emittedLines()[0]).To(Equal(logs.Line{
+				SourceID:   "app-guid",
+				SourceType: "APP/TASK/migrate",
+				InstanceID: "0",
+				Message:    "hello",
+			}))
+
+			_, _, container, _ := streamer.StreamArgsForCall(0)
+			Expect(container).To(Equal("opi-task"))
+		})
+	})
+})
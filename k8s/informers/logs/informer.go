@@ -0,0 +1,102 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const NoResync = 0
+
+//counterfeiter:generate . PodEventHandler
+
+type PodEventHandler interface {
+	Update(pod *corev1.Pod)
+	Delete(pod *corev1.Pod)
+	Stop()
+}
+
+// Informer tells the handler about every LRP and task pod in the workloads
+// namespace
+type Informer struct {
+	client    kubernetes.Interface
+	namespace string
+	handler   PodEventHandler
+}
+
+func NewInformer(client kubernetes.Interface, namespace string, handler PodEventHandler) *Informer {
+	return &Informer{
+		client:    client,
+		namespace: namespace,
+		handler:   handler,
+	}
+}
+
+func (i *Informer) Start(stop <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(i.client,
+		NoResync,
+		informers.WithNamespace(i.namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = fmt.Sprintf("%s in (%s,%s)", stset.LabelSourceType, stset.AppSourceType, jobs.TaskSourceType)
+		}),
+	)
+
+	podInformer := factory.Core().V1().Pods().Informer()
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			i.handler.Update(obj.(*corev1.Pod))
+		},
+		UpdateFunc: func(_, updatedObj interface{}) {
+			i.handler.Update(updatedObj.(*corev1.Pod))
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			if pod, ok := obj.(*corev1.Pod); ok {
+				i.handler.Delete(pod)
+			}
+		},
+	})
+
+	podInformer.Run(stop)
+	i.handler.Stop()
+}
+
+type KubeLogStreamer struct {
+	client kubernetes.Interface
+}
+
+func NewKubeLogStreamer(client kubernetes.Interface) *KubeLogStreamer {
+	return &KubeLogStreamer{
+		client: client,
+	}
+}
+
+func (s *KubeLogStreamer) Stream(ctx context.Context, pod *corev1.Pod, container string, since time.Time) (io.ReadCloser, error) {
+	options := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     true,
+		Timestamps: true,
+	}
+
+	if !since.IsZero() {
+		sinceTime := metav1.NewTime(since)
+		options.SinceTime = &sinceTime
+	}
+
+	body, err := s.client.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Stream(ctx)
+
+	return body, errors.Wrap(err, "failed to get pod logs")
+}
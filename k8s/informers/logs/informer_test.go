@@ -0,0 +1,67 @@
+package logs_test
+
+import (
+	. "code.cloudfoundry.org/eirini/k8s/informers/logs"
+	"code.cloudfoundry.org/eirini/k8s/informers/logs/logsfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	testcore "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Informer", func() {
+	var (
+		handler    *logsfakes.FakePodEventHandler
+		podWatcher *watch.FakeWatcher
+		stopChan   chan struct{}
+		stopped    chan struct{}
+	)
+
+	BeforeEach(func() {
+		handler = new(logsfakes.FakePodEventHandler)
+		client := fake.NewSimpleClientset()
+		podWatcher = watch.NewFake()
+		client.PrependWatchReactor("pods", testcore.DefaultWatchReactor(podWatcher, nil))
+
+		stopChan = make(chan struct{})
+		stopped = make(chan struct{})
+
+		informer := NewInformer(client, "workloads", handler)
+		go func() {
+			informer.Start(stopChan)
+			close(stopped)
+		}()
+	})
+
+	AfterEach(func() {
+		close(stopChan)
+		Eventually(stopped).Should(BeClosed())
+	})
+
+	It("should tell the handler about added and updated pods", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-0"}}
+		podWatcher.Add(pod)
+		podWatcher.Modify(pod)
+
+		Eventually(handler.UpdateCallCount).Should(Equal(2))
+		Expect(handler.UpdateArgsForCall(1).Name).To(Equal("app-0"))
+	})
+
+	It("should tell the handler about deleted pods", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-0"}}
+		podWatcher.Add(pod)
+		podWatcher.Delete(pod)
+
+		Eventually(handler.DeleteCallCount).Should(Equal(1))
+		Expect(handler.DeleteArgsForCall(0).Name).To(Equal("app-0"))
+	})
+
+	It("should stop the handler when stopped", func() {
+		close(stopChan)
+		Eventually(handler.StopCallCount).Should(Equal(1))
+		stopChan = make(chan struct{})
+	})
+})
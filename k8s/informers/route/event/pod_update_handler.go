@@ -8,6 +8,7 @@ import (
 	"code.cloudfoundry.org/eirini/models/cf"
 	eiriniroute "code.cloudfoundry.org/eirini/route"
 	"code.cloudfoundry.org/lager"
+	set "github.com/deckarep/golang-set"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -23,12 +24,13 @@ type PodUpdateHandler struct {
 	StatefulSetGetter StatefulSetGetter
 	Logger            lager.Logger
 	RouteEmitter      eiriniroute.Emitter
+	TCPRouteEmitter   eiriniroute.TCPEmitter
 }
 
 func (h PodUpdateHandler) Handle(oldPod, updatedPod *corev1.Pod) {
 	loggerSession := h.Logger.Session("pod-update", lager.Data{"pod-name": updatedPod.Name, "guid": updatedPod.Annotations[stset.AnnotationProcessGUID]})
 
-	userDefinedRoutes, err := h.getUserDefinedRoutes(updatedPod)
+	userDefinedRoutes, tcpRoutes, err := h.getUserDefinedRoutes(updatedPod)
 	if err != nil {
 		loggerSession.Debug("failed-to-get-user-defined-routes", lager.Data{"error": err.Error()})
 
@@ -38,10 +40,13 @@ func (h PodUpdateHandler) Handle(oldPod, updatedPod *corev1.Pod) {
 	if markedForDeletion(*updatedPod) || (!isReady(updatedPod.Status.Conditions) && isReady(oldPod.Status.Conditions)) {
 		loggerSession.Debug("pod-not-ready", lager.Data{"statuses": updatedPod.Status.Conditions, "deletion-timestamp": updatedPod.DeletionTimestamp})
 		h.unregisterPodRoutes(oldPod, userDefinedRoutes)
+		h.emitTCPRoutes(loggerSession, *oldPod, groupTCPRoutesByPort(tcpRoutesSet(tcpRoutes), set.NewSet()))
 
 		return
 	}
 
+	h.emitTCPRoutes(loggerSession, *updatedPod, groupTCPRoutesByPort(set.NewSet(), tcpRoutesSet(tcpRoutes)))
+
 	for _, r := range userDefinedRoutes {
 		routes, err := route.NewRouteMessage(
 			updatedPod,
@@ -77,13 +82,26 @@ func (h PodUpdateHandler) unregisterPodRoutes(pod *corev1.Pod, userDefinedRoutes
 	}
 }
 
-func (h PodUpdateHandler) getUserDefinedRoutes(pod *corev1.Pod) ([]cf.Route, error) {
+func (h PodUpdateHandler) emitTCPRoutes(loggerSession lager.Logger, pod corev1.Pod, grouped tcpPortGroup) {
+	for _, message := range createTCPRouteMessages(loggerSession, pod, grouped) {
+		h.TCPRouteEmitter.Emit(*message)
+	}
+}
+
+func (h PodUpdateHandler) getUserDefinedRoutes(pod *corev1.Pod) ([]cf.Route, []cf.TCPRoute, error) {
 	owner, err := h.getOwner(pod)
 	if err != nil {
-		return []cf.Route{}, errors.Wrap(err, "failed to get owner")
+		return []cf.Route{}, []cf.TCPRoute{}, errors.Wrap(err, "failed to get owner")
 	}
 
-	return decodeRoutes(owner.Annotations[stset.AnnotationRegisteredRoutes])
+	routes, err := decodeRoutes(owner.Annotations[stset.AnnotationRegisteredRoutes])
+	if err != nil {
+		return []cf.Route{}, []cf.TCPRoute{}, err
+	}
+
+	tcpRoutes, err := decodeTCPRoutes(owner.Annotations[stset.AnnotationRegisteredTCPRoutes])
+
+	return routes, tcpRoutes, err
 }
 
 func (h PodUpdateHandler) getOwner(pod *corev1.Pod) (*appsv1.StatefulSet, error) {
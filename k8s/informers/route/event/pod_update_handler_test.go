@@ -150,6 +150,29 @@ var _ = Describe("UpdateEventHandler", func() {
 		})
 	})
 
+	Context("When the pod has a route integrity proxy", func() {
+		BeforeEach(func() {
+			updatedPod.Annotations = map[string]string{
+				stset.AnnotationTLSPorts: `{"8080":61001,"6565":61002}`,
+			}
+		})
+
+		It("should register the TLS ports with the instance as the expected certificate SAN", func() {
+			handler.Handle(pod, updatedPod)
+
+			Expect(routeEmitter.EmitCallCount()).To(Equal(2))
+			first := routeEmitter.EmitArgsForCall(0)
+			Expect(first.Port).To(Equal(uint32(8080)))
+			Expect(first.TLSPort).To(Equal(uint32(61001)))
+			Expect(first.ServerCertDomainSAN).To(Equal("mr-stateful-0"))
+
+			second := routeEmitter.EmitArgsForCall(1)
+			Expect(second.Port).To(Equal(uint32(6565)))
+			Expect(second.TLSPort).To(Equal(uint32(61002)))
+			Expect(second.ServerCertDomainSAN).To(Equal("mr-stateful-0"))
+		})
+	})
+
 	Context("When there is no owner for a pod", func() {
 		It("should not send routes for the pod", func() {
 			updatedPod.OwnerReferences = []metav1.OwnerReference{}
@@ -13,12 +13,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 )
 
+// StatefulSetDeleteHandler unregisters the routes of all pods of a deleted
+// StatefulSet. The pods are listed from the informer cache of PodLister when
+// it is set, and from the API through Pods otherwise
 type StatefulSetDeleteHandler struct {
-	Pods         typedv1.PodInterface
-	Logger       lager.Logger
-	RouteEmitter eiriniroute.Emitter
+	Pods            typedv1.PodInterface
+	PodLister       corelisters.PodNamespaceLister
+	Logger          lager.Logger
+	RouteEmitter    eiriniroute.Emitter
+	TCPRouteEmitter eiriniroute.TCPEmitter
 }
 
 func (h StatefulSetDeleteHandler) Handle(deletedStatefulSet *appsv1.StatefulSet) {
@@ -41,10 +47,39 @@ func (h StatefulSetDeleteHandler) Handle(deletedStatefulSet *appsv1.StatefulSet)
 	for _, route := range routes {
 		h.RouteEmitter.Emit(*route)
 	}
+
+	h.unregisterTCPRoutes(loggerSession, deletedStatefulSet)
+}
+
+func (h StatefulSetDeleteHandler) unregisterTCPRoutes(loggerSession lager.Logger, statefulset *appsv1.StatefulSet) {
+	tcpRouteSet, err := decodeTCPRoutesAsSet(statefulset)
+	if err != nil {
+		loggerSession.Error("failed-to-decode-deleted-tcp-routes", err)
+
+		return
+	}
+
+	if tcpRouteSet.Cardinality() == 0 {
+		return
+	}
+
+	pods, err := getChildrenPods(h.Pods, h.PodLister, statefulset)
+	if err != nil {
+		loggerSession.Error("failed-to-get-child-pods", err)
+
+		return
+	}
+
+	grouped := groupTCPRoutesByPort(tcpRouteSet, set.NewSet())
+	for _, pod := range pods {
+		for _, message := range createTCPRouteMessages(loggerSession, pod, grouped) {
+			h.TCPRouteEmitter.Emit(*message)
+		}
+	}
 }
 
 func (h StatefulSetDeleteHandler) createRoutesOnDelete(loggerSession lager.Logger, statefulset *appsv1.StatefulSet, grouped portGroup) []*eiriniroute.Message {
-	pods, err := getChildrenPods(h.Pods, statefulset)
+	pods, err := getChildrenPods(h.Pods, h.PodLister, statefulset)
 	if err != nil {
 		loggerSession.Error("failed-to-get-child-pods", err)
 
@@ -59,8 +94,23 @@ func (h StatefulSetDeleteHandler) createRoutesOnDelete(loggerSession lager.Logge
 	return resultRoutes
 }
 
-func getChildrenPods(podClient typedv1.PodInterface, st *appsv1.StatefulSet) ([]corev1.Pod, error) {
+func getChildrenPods(podClient typedv1.PodInterface, podLister corelisters.PodNamespaceLister, st *appsv1.StatefulSet) ([]corev1.Pod, error) {
 	set := labels.Set(st.Spec.Selector.MatchLabels)
+
+	if podLister != nil {
+		pods, err := podLister.List(set.AsSelector())
+		if err != nil {
+			return []corev1.Pod{}, errors.Wrap(err, "failed to list pods from cache")
+		}
+
+		result := make([]corev1.Pod, 0, len(pods))
+		for _, p := range pods {
+			result = append(result, *p)
+		}
+
+		return result, nil
+	}
+
 	opts := metav1.ListOptions{LabelSelector: set.AsSelector().String()}
 
 	podlist, err := podClient.List(context.Background(), opts)
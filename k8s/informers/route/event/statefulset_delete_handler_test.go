@@ -15,6 +15,8 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 var _ = Describe("StatefulsetDeleteHandler", func() {
@@ -153,6 +155,36 @@ var _ = Describe("StatefulsetDeleteHandler", func() {
 			})
 		})
 
+		Context("and the pods are listed from a cache", func() {
+			BeforeEach(func() {
+				indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+				for _, pod := range []corev1.Pod{
+					createPod("mr-stateful-0", "10.20.30.40"),
+					createPod("mr-stateful-1", "50.60.70.80"),
+					createPod("mr-stateless-0", "90.100.110.120"),
+				} {
+					pod := pod
+					pod.Namespace = "workloads"
+					if pod.Name != "mr-stateless-0" {
+						pod.Labels["name"] = "the-app-name"
+					}
+					Expect(indexer.Add(&pod)).To(Succeed())
+				}
+
+				handler = event.StatefulSetDeleteHandler{
+					PodLister:    corelisters.NewPodLister(indexer).Pods("workloads"),
+					Logger:       logger,
+					RouteEmitter: routeEmitter,
+				}
+			})
+
+			It("should unregister all routes for the pods of the statefulset", func() {
+				handler.Handle(deletedStatefulSet)
+				assertUnregisteredRoutesForAllPods()
+				Expect(podClient.ListCallCount()).To(BeZero())
+			})
+		})
+
 		Context("and decoding routes fails", func() {
 			BeforeEach(func() {
 				handler.Handle(createStatefulSetWithRoutes(`[`))
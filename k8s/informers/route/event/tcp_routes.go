@@ -0,0 +1,98 @@
+package event
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/eirini/k8s/informers/route"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/models/cf"
+	eiriniroute "code.cloudfoundry.org/eirini/route"
+	"code.cloudfoundry.org/lager"
+	set "github.com/deckarep/golang-set"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type tcpPortGroup map[uint32]eiriniroute.TCPRoutes
+
+// decodeTCPRoutes treats a missing annotation as no routes, as statefulsets
+// created before TCP routes were supported do not have it
+func decodeTCPRoutes(s string) ([]cf.TCPRoute, error) {
+	routes := []cf.TCPRoute{}
+	if s == "" {
+		return routes, nil
+	}
+
+	err := json.Unmarshal([]byte(s), &routes)
+
+	return routes, errors.Wrap(err, "failed to unmarshal tcp routes")
+}
+
+func decodeTCPRoutesAsSet(statefulset *appsv1.StatefulSet) (set.Set, error) {
+	routes := set.NewSet()
+
+	tcpRoutes, err := decodeTCPRoutes(statefulset.Annotations[stset.AnnotationRegisteredTCPRoutes])
+	if err != nil {
+		return set.NewSet(), err
+	}
+
+	for _, r := range tcpRoutes {
+		routes.Add(r)
+	}
+
+	return routes, nil
+}
+
+func groupTCPRoutesByPort(remove, add set.Set) tcpPortGroup {
+	group := make(tcpPortGroup)
+
+	for _, toAdd := range add.ToSlice() {
+		current := toAdd.(cf.TCPRoute)
+		routes := group[current.ContainerPort]
+		routes.RegisteredRoutes = append(routes.RegisteredRoutes, toRouteTCPRoute(current))
+		group[current.ContainerPort] = routes
+	}
+
+	for _, toRemove := range remove.ToSlice() {
+		current := toRemove.(cf.TCPRoute)
+		routes := group[current.ContainerPort]
+		routes.UnregisteredRoutes = append(routes.UnregisteredRoutes, toRouteTCPRoute(current))
+		group[current.ContainerPort] = routes
+	}
+
+	return group
+}
+
+func toRouteTCPRoute(r cf.TCPRoute) eiriniroute.TCPRoute {
+	return eiriniroute.TCPRoute{
+		RouterGroupGUID: r.RouterGroupGUID,
+		ExternalPort:    r.ExternalPort,
+	}
+}
+
+func tcpRoutesSet(routes []cf.TCPRoute) set.Set {
+	result := set.NewSet()
+	for _, r := range routes {
+		result.Add(r)
+	}
+
+	return result
+}
+
+func createTCPRouteMessages(loggerSession lager.Logger, pod corev1.Pod, grouped tcpPortGroup) []*eiriniroute.TCPMessage {
+	resultRoutes := []*eiriniroute.TCPMessage{}
+
+	for port, routes := range grouped {
+		podRoute, err := route.NewTCPRouteMessage(&pod, port, routes)
+		if err != nil {
+			loggerSession.Debug("failed-to-construct-a-tcp-route-message", lager.Data{"error": err.Error()})
+
+			continue
+		}
+
+		resultRoutes = append(resultRoutes, podRoute)
+	}
+
+	return resultRoutes
+}
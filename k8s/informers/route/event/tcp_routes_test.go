@@ -0,0 +1,183 @@
+package event_test
+
+import (
+	"code.cloudfoundry.org/eirini/k8s/informers/route/event"
+	"code.cloudfoundry.org/eirini/k8s/informers/route/event/eventfakes"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	eiriniroute "code.cloudfoundry.org/eirini/route"
+	eiriniroutefakes "code.cloudfoundry.org/eirini/route/routefakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("TCP routes", func() {
+	var (
+		podClient          *eventfakes.FakePodInterface
+		statefulSetGetter  *eventfakes.FakeStatefulSetGetter
+		routeEmitter       *eiriniroutefakes.FakeEmitter
+		tcpRouteEmitter    *eiriniroutefakes.FakeTCPEmitter
+		logger             *lagertest.TestLogger
+		statefulSet        *appsv1.StatefulSet
+		allTCPEmitArgs     func() []eiriniroute.TCPMessage
+		withTCPRoutes      func(string) *appsv1.StatefulSet
+		registeredTCPRoute eiriniroute.TCPMessage
+	)
+
+	BeforeEach(func() {
+		podClient = new(eventfakes.FakePodInterface)
+		statefulSetGetter = new(eventfakes.FakeStatefulSetGetter)
+		routeEmitter = new(eiriniroutefakes.FakeEmitter)
+		tcpRouteEmitter = new(eiriniroutefakes.FakeTCPEmitter)
+		logger = lagertest.NewTestLogger("tcp-routes-test")
+
+		withTCPRoutes = func(tcpRoutes string) *appsv1.StatefulSet {
+			st := createStatefulSetWithRoutes(`[]`)
+			st.Annotations[stset.AnnotationRegisteredTCPRoutes] = tcpRoutes
+
+			return st
+		}
+		allTCPEmitArgs = func() []eiriniroute.TCPMessage {
+			args := []eiriniroute.TCPMessage{}
+			for i := 0; i < tcpRouteEmitter.EmitCallCount(); i++ {
+				args = append(args, tcpRouteEmitter.EmitArgsForCall(i))
+			}
+
+			return args
+		}
+
+		statefulSet = withTCPRoutes(`[{"router_group_guid": "default-tcp", "external_port": 1234, "container_port": 8080}]`)
+		registeredTCPRoute = eiriniroute.TCPMessage{
+			TCPRoutes: eiriniroute.TCPRoutes{
+				RegisteredRoutes: []eiriniroute.TCPRoute{{RouterGroupGUID: "default-tcp", ExternalPort: 1234}},
+			},
+			Name:       "mr-stateful-0-guid",
+			InstanceID: "mr-stateful-0",
+			Address:    "10.20.30.40",
+			Port:       8080,
+		}
+	})
+
+	Describe("PodUpdateHandler", func() {
+		var (
+			handler    event.PodUpdateHandler
+			pod        corev1.Pod
+			updatedPod corev1.Pod
+		)
+
+		BeforeEach(func() {
+			statefulSetGetter.GetReturns(statefulSet, nil)
+			pod = createPod("mr-stateful-0", "10.20.30.40")
+			updatedPod = createPod("mr-stateful-0", "10.20.30.40")
+
+			handler = event.PodUpdateHandler{
+				StatefulSetGetter: statefulSetGetter,
+				Logger:            logger,
+				RouteEmitter:      routeEmitter,
+				TCPRouteEmitter:   tcpRouteEmitter,
+			}
+		})
+
+		It("registers the tcp routes of a ready pod", func() {
+			handler.Handle(&pod, &updatedPod)
+
+			Expect(allTCPEmitArgs()).To(ConsistOf(registeredTCPRoute))
+		})
+
+		When("the pod stops being ready", func() {
+			BeforeEach(func() {
+				updatedPod.Status.Conditions[0].Status = corev1.ConditionFalse
+			})
+
+			It("unregisters the tcp routes", func() {
+				handler.Handle(&pod, &updatedPod)
+
+				Expect(allTCPEmitArgs()).To(ConsistOf(eiriniroute.TCPMessage{
+					TCPRoutes: eiriniroute.TCPRoutes{
+						UnregisteredRoutes: registeredTCPRoute.RegisteredRoutes,
+					},
+					Name:       "mr-stateful-0-guid",
+					InstanceID: "mr-stateful-0",
+					Address:    "10.20.30.40",
+					Port:       8080,
+				}))
+			})
+		})
+	})
+
+	Describe("StatefulSetDeleteHandler", func() {
+		It("unregisters the tcp routes of all pods", func() {
+			podClient.ListReturns(&corev1.PodList{Items: []corev1.Pod{
+				createPod("mr-stateful-0", "10.20.30.40"),
+				createPod("mr-stateful-1", "50.60.70.80"),
+			}}, nil)
+
+			handler := event.StatefulSetDeleteHandler{
+				Pods:            podClient,
+				Logger:          logger,
+				RouteEmitter:    routeEmitter,
+				TCPRouteEmitter: tcpRouteEmitter,
+			}
+			handler.Handle(statefulSet)
+
+			unregistered := registeredTCPRoute.RegisteredRoutes
+			Expect(allTCPEmitArgs()).To(ConsistOf(
+				eiriniroute.TCPMessage{
+					TCPRoutes:  eiriniroute.TCPRoutes{UnregisteredRoutes: unregistered},
+					Name:       "mr-stateful-0-guid",
+					InstanceID: "mr-stateful-0",
+					Address:    "10.20.30.40",
+					Port:       8080,
+				},
+				eiriniroute.TCPMessage{
+					TCPRoutes:  eiriniroute.TCPRoutes{UnregisteredRoutes: unregistered},
+					Name:       "mr-stateful-1-guid",
+					InstanceID: "mr-stateful-1",
+					Address:    "50.60.70.80",
+					Port:       8080,
+				},
+			))
+		})
+	})
+
+	Describe("URIAnnotationUpdateHandler", func() {
+		var handler event.URIAnnotationUpdateHandler
+
+		BeforeEach(func() {
+			podClient.ListReturns(&corev1.PodList{Items: []corev1.Pod{
+				createPod("mr-stateful-0", "10.20.30.40"),
+			}}, nil)
+
+			handler = event.URIAnnotationUpdateHandler{
+				Pods:            podClient,
+				Logger:          logger,
+				RouteEmitter:    routeEmitter,
+				TCPRouteEmitter: tcpRouteEmitter,
+			}
+		})
+
+		It("registers current tcp routes and unregisters removed ones", func() {
+			updatedStatefulSet := withTCPRoutes(`[{"router_group_guid": "default-tcp", "external_port": 4321, "container_port": 8080}]`)
+			handler.Handle(statefulSet, updatedStatefulSet)
+
+			Expect(allTCPEmitArgs()).To(ConsistOf(eiriniroute.TCPMessage{
+				TCPRoutes: eiriniroute.TCPRoutes{
+					RegisteredRoutes:   []eiriniroute.TCPRoute{{RouterGroupGUID: "default-tcp", ExternalPort: 4321}},
+					UnregisteredRoutes: []eiriniroute.TCPRoute{{RouterGroupGUID: "default-tcp", ExternalPort: 1234}},
+				},
+				Name:       "mr-stateful-0-guid",
+				InstanceID: "mr-stateful-0",
+				Address:    "10.20.30.40",
+				Port:       8080,
+			}))
+		})
+
+		It("does not emit tcp routes when they did not change", func() {
+			handler.Handle(statefulSet, withTCPRoutes(statefulSet.Annotations[stset.AnnotationRegisteredTCPRoutes]))
+
+			Expect(tcpRouteEmitter.EmitCallCount()).To(BeZero())
+		})
+	})
+})
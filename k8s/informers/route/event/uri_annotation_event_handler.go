@@ -12,16 +12,23 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
 )
 
 //counterfeiter:generate -o eventfakes/fake_pod_interface.go k8s.io/client-go/kubernetes/typed/core/v1.PodInterface
 
 type portGroup map[int32]eiriniroute.Routes
 
+// URIAnnotationUpdateHandler registers and unregisters the routes of all pods
+// of a StatefulSet whose routes changed. The pods are listed from the
+// informer cache of PodLister when it is set, and from the API through Pods
+// otherwise
 type URIAnnotationUpdateHandler struct {
-	Pods         typedv1.PodInterface
-	Logger       lager.Logger
-	RouteEmitter eiriniroute.Emitter
+	Pods            typedv1.PodInterface
+	PodLister       corelisters.PodNamespaceLister
+	Logger          lager.Logger
+	RouteEmitter    eiriniroute.Emitter
+	TCPRouteEmitter eiriniroute.TCPEmitter
 }
 
 func (h URIAnnotationUpdateHandler) Handle(oldStatefulSet, updatedStatefulSet *appsv1.StatefulSet) {
@@ -58,10 +65,49 @@ func (h URIAnnotationUpdateHandler) onUpdate(oldStatefulSet, updatedStatefulSet
 	for _, route := range routes {
 		h.RouteEmitter.Emit(*route)
 	}
+
+	h.onTCPRoutesUpdate(loggerSession, oldStatefulSet, updatedStatefulSet)
+}
+
+func (h URIAnnotationUpdateHandler) onTCPRoutesUpdate(loggerSession lager.Logger, oldStatefulSet, updatedStatefulSet *appsv1.StatefulSet) {
+	updatedSet, err := decodeTCPRoutesAsSet(updatedStatefulSet)
+	if err != nil {
+		loggerSession.Error("failed-to-decode-updated-tcp-routes", err)
+
+		return
+	}
+
+	oldSet, err := decodeTCPRoutesAsSet(oldStatefulSet)
+	if err != nil {
+		loggerSession.Error("failed-to-decode-old-tcp-routes", err)
+	}
+
+	if updatedSet.Equal(oldSet) {
+		return
+	}
+
+	grouped := groupTCPRoutesByPort(oldSet.Difference(updatedSet), updatedSet)
+
+	pods, err := getChildrenPods(h.Pods, h.PodLister, updatedStatefulSet)
+	if err != nil {
+		loggerSession.Error("failed-to-get-child-pods", err)
+
+		return
+	}
+
+	for _, pod := range pods {
+		if markedForDeletion(pod) {
+			continue
+		}
+
+		for _, message := range createTCPRouteMessages(loggerSession, pod, grouped) {
+			h.TCPRouteEmitter.Emit(*message)
+		}
+	}
 }
 
 func (h URIAnnotationUpdateHandler) createRoutesOnUpdate(loggerSession lager.Logger, statefulset *appsv1.StatefulSet, grouped portGroup) []*eiriniroute.Message {
-	pods, err := getChildrenPods(h.Pods, statefulset)
+	pods, err := getChildrenPods(h.Pods, h.PodLister, statefulset)
 	if err != nil {
 		loggerSession.Error("failed-to-get-child-pods", err)
 
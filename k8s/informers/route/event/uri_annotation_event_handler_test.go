@@ -517,10 +517,11 @@ var _ = Describe("StatefulsetAnnotationEventHandler", func() {
 						"RegisteredRoutes":   ConsistOf("mr-stateful.cf.domain", "mr-boombastic.cf.domain"),
 						"UnregisteredRoutes": BeEmpty(),
 					}),
-					"InstanceID": Equal("mr-stateful-0"),
-					"Address":    Equal("10.20.30.40"),
-					"Port":       BeNumerically("==", 8080),
-					"TLSPort":    BeNumerically("==", 0),
+					"InstanceID":          Equal("mr-stateful-0"),
+					"Address":             Equal("10.20.30.40"),
+					"Port":                BeNumerically("==", 8080),
+					"TLSPort":             BeNumerically("==", 0),
+					"ServerCertDomainSAN": BeEmpty(),
 				}),
 				MatchAllFields(Fields{
 					"Name": Equal("mr-stateful-1-guid"),
@@ -528,10 +529,11 @@ var _ = Describe("StatefulsetAnnotationEventHandler", func() {
 						"RegisteredRoutes":   ConsistOf("mr-stateful.cf.domain", "mr-boombastic.cf.domain"),
 						"UnregisteredRoutes": BeEmpty(),
 					}),
-					"InstanceID": Equal("mr-stateful-1"),
-					"Address":    Equal("50.60.70.80"),
-					"Port":       BeNumerically("==", 8080),
-					"TLSPort":    BeNumerically("==", 0),
+					"InstanceID":          Equal("mr-stateful-1"),
+					"Address":             Equal("50.60.70.80"),
+					"Port":                BeNumerically("==", 8080),
+					"TLSPort":             BeNumerically("==", 0),
+					"ServerCertDomainSAN": BeEmpty(),
 				}),
 			))
 		})
@@ -0,0 +1,48 @@
+package route
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// AddPodUpdateHandler wires the handler to the update events of a pod
+// informer. Informers created with a resync period also deliver the periodic
+// resyncs as updates, so the handler re-registers all routes of ready pods
+func AddPodUpdateHandler(informer cache.SharedInformer, handler PodUpdateEventHandler) {
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, updatedObj interface{}) {
+			oldPod, oldOK := oldObj.(*v1.Pod)
+			updatedPod, updatedOK := updatedObj.(*v1.Pod)
+
+			if oldOK && updatedOK {
+				handler.Handle(oldPod, updatedPod)
+			}
+		},
+	})
+}
+
+// AddStatefulSetHandlers wires the handlers to the update and delete events
+// of a statefulset informer. Deletions missed while the watch was down arrive
+// as tombstones carrying the last known state of the statefulset
+func AddStatefulSetHandlers(informer cache.SharedInformer, updateHandler StatefulSetUpdateEventHandler, deleteHandler StatefulSetDeleteEventHandler) {
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, updatedObj interface{}) {
+			oldStatefulSet, oldOK := oldObj.(*appsv1.StatefulSet)
+			updatedStatefulSet, updatedOK := updatedObj.(*appsv1.StatefulSet)
+
+			if oldOK && updatedOK {
+				updateHandler.Handle(oldStatefulSet, updatedStatefulSet)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			if statefulSet, ok := obj.(*appsv1.StatefulSet); ok {
+				deleteHandler.Handle(statefulSet)
+			}
+		},
+	})
+}
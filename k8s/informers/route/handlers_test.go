@@ -0,0 +1,96 @@
+package route_test
+
+import (
+	. "code.cloudfoundry.org/eirini/k8s/informers/route"
+	"code.cloudfoundry.org/eirini/k8s/informers/route/routefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+type handlerCapturingInformer struct {
+	cache.SharedInformer
+	handler cache.ResourceEventHandler
+}
+
+func (i *handlerCapturingInformer) AddEventHandler(handler cache.ResourceEventHandler) {
+	i.handler = handler
+}
+
+var _ = Describe("Informer handlers", func() {
+	var informer *handlerCapturingInformer
+
+	BeforeEach(func() {
+		informer = &handlerCapturingInformer{}
+	})
+
+	Describe("AddPodUpdateHandler", func() {
+		var updateHandler *routefakes.FakePodUpdateEventHandler
+
+		BeforeEach(func() {
+			updateHandler = new(routefakes.FakePodUpdateEventHandler)
+			AddPodUpdateHandler(informer, updateHandler)
+		})
+
+		It("passes pod updates to the handler", func() {
+			oldPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", ResourceVersion: "1"}}
+			updatedPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", ResourceVersion: "2"}}
+			informer.handler.OnUpdate(oldPod, updatedPod)
+
+			Expect(updateHandler.HandleCallCount()).To(Equal(1))
+			actualOld, actualUpdated := updateHandler.HandleArgsForCall(0)
+			Expect(actualOld).To(Equal(oldPod))
+			Expect(actualUpdated).To(Equal(updatedPod))
+		})
+
+		It("ignores objects which are not pods", func() {
+			informer.handler.OnUpdate(&appsv1.StatefulSet{}, &appsv1.StatefulSet{})
+
+			Expect(updateHandler.HandleCallCount()).To(BeZero())
+		})
+	})
+
+	Describe("AddStatefulSetHandlers", func() {
+		var (
+			updateHandler *routefakes.FakeStatefulSetUpdateEventHandler
+			deleteHandler *routefakes.FakeStatefulSetDeleteEventHandler
+			statefulSet   *appsv1.StatefulSet
+		)
+
+		BeforeEach(func() {
+			updateHandler = new(routefakes.FakeStatefulSetUpdateEventHandler)
+			deleteHandler = new(routefakes.FakeStatefulSetDeleteEventHandler)
+			statefulSet = &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "mr-stateful"}}
+			AddStatefulSetHandlers(informer, updateHandler, deleteHandler)
+		})
+
+		It("passes statefulset updates to the update handler", func() {
+			informer.handler.OnUpdate(statefulSet, statefulSet)
+
+			Expect(updateHandler.HandleCallCount()).To(Equal(1))
+		})
+
+		It("passes deleted statefulsets to the delete handler", func() {
+			informer.handler.OnDelete(statefulSet)
+
+			Expect(deleteHandler.HandleCallCount()).To(Equal(1))
+			Expect(deleteHandler.HandleArgsForCall(0)).To(Equal(statefulSet))
+		})
+
+		It("unwraps statefulsets from deletion tombstones", func() {
+			informer.handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "ns/mr-stateful", Obj: statefulSet})
+
+			Expect(deleteHandler.HandleCallCount()).To(Equal(1))
+			Expect(deleteHandler.HandleArgsForCall(0)).To(Equal(statefulSet))
+		})
+
+		It("ignores tombstones which do not carry a statefulset", func() {
+			informer.handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "ns/pod", Obj: &corev1.Pod{}})
+
+			Expect(deleteHandler.HandleCallCount()).To(BeZero())
+		})
+	})
+})
@@ -5,7 +5,6 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
 )
 
 const NoResync = 0
@@ -38,13 +37,7 @@ func (c *InstanceChangeInformer) Start() {
 		informers.WithNamespace(c.Namespace))
 
 	podInformer := factory.Core().V1().Pods().Informer()
-	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: func(oldObj, updatedObj interface{}) {
-			oldPod := oldObj.(*v1.Pod)
-			updatedPod := updatedObj.(*v1.Pod)
-			c.UpdateHandler.Handle(oldPod, updatedPod)
-		},
-	})
+	AddPodUpdateHandler(podInformer, c.UpdateHandler)
 
 	podInformer.Run(c.Cancel)
 }
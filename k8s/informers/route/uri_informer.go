@@ -9,7 +9,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/cache"
 )
 
 //counterfeiter:generate . StatefulSetUpdateEventHandler
@@ -47,17 +46,7 @@ func (i *URIChangeInformer) Start() {
 		informers.WithNamespace(i.Namespace))
 
 	informer := factory.Apps().V1().StatefulSets().Informer()
-	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		UpdateFunc: func(oldObj, updatedObj interface{}) {
-			oldStatefulSet := oldObj.(*appsv1.StatefulSet)
-			updatedStatefulSet := updatedObj.(*appsv1.StatefulSet)
-			i.UpdateHandler.Handle(oldStatefulSet, updatedStatefulSet)
-		},
-		DeleteFunc: func(obj interface{}) {
-			statefulSet := obj.(*appsv1.StatefulSet)
-			i.DeleteHandler.Handle(statefulSet)
-		},
-	})
+	AddStatefulSetHandlers(informer, i.UpdateHandler, i.DeleteHandler)
 
 	informer.Run(i.Cancel)
 }
@@ -75,7 +64,36 @@ func NewRouteMessage(pod *corev1.Pod, port uint32, routes eiriniroute.Routes) (*
 		InstanceID: pod.Name,
 		Address:    pod.Status.PodIP,
 		Port:       port,
-		TLSPort:    0,
+		TLSPort:    stset.TLSPort(pod.Annotations, port),
+	}
+	if message.TLSPort != 0 {
+		message.ServerCertDomainSAN = pod.Name
+	}
+
+	if isReady(pod.Status.Conditions) {
+		message.RegisteredRoutes = routes.RegisteredRoutes
+	}
+
+	if len(message.RegisteredRoutes) == 0 && len(message.UnregisteredRoutes) == 0 {
+		return nil, errors.New("no-routes-provided")
+	}
+
+	return message, nil
+}
+
+func NewTCPRouteMessage(pod *corev1.Pod, port uint32, routes eiriniroute.TCPRoutes) (*eiriniroute.TCPMessage, error) {
+	if len(pod.Status.PodIP) == 0 {
+		return nil, errors.New("missing ip address")
+	}
+
+	message := &eiriniroute.TCPMessage{
+		TCPRoutes: eiriniroute.TCPRoutes{
+			UnregisteredRoutes: routes.UnregisteredRoutes,
+		},
+		Name:       pod.Labels[stset.LabelGUID],
+		InstanceID: pod.Name,
+		Address:    pod.Status.PodIP,
+		Port:       port,
 	}
 	if isReady(pod.Status.Conditions) {
 		message.RegisteredRoutes = routes.RegisteredRoutes
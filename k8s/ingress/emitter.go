@@ -0,0 +1,189 @@
+package ingress
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/route"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+//counterfeiter:generate . StatefulSetGetter
+//counterfeiter:generate . ServiceClient
+//counterfeiter:generate . Backend
+
+type StatefulSetGetter interface {
+	GetByGUID(guid string) ([]appsv1.StatefulSet, error)
+}
+
+type ServiceClient interface {
+	Apply(service *corev1.Service) error
+	Delete(namespace, name string) error
+}
+
+// Backend publishes the routes of an app, already exposed by a Service with
+// the name of its StatefulSet, to whatever routes traffic into the cluster
+type Backend interface {
+	Apply(statefulSet *appsv1.StatefulSet, routes []cf.Route) error
+}
+
+// Emitter is a route.Emitter which exposes apps through Kubernetes objects
+// instead of gorouter. Messages only trigger a sync of the app they refer to:
+// the hostnames are always taken from the AnnotationRegisteredRoutes of the
+// StatefulSet, while instance availability is left to the Service endpoints
+type Emitter struct {
+	statefulSets StatefulSetGetter
+	services     ServiceClient
+	backend      Backend
+	logger       lager.Logger
+}
+
+func NewEmitter(statefulSets StatefulSetGetter, services ServiceClient, backend Backend, logger lager.Logger) Emitter {
+	return Emitter{
+		statefulSets: statefulSets,
+		services:     services,
+		backend:      backend,
+		logger:       logger,
+	}
+}
+
+func (e Emitter) Emit(message route.Message) {
+	logger := e.logger.Session("emit", lager.Data{"guid": message.Name, "instance-id": message.InstanceID})
+
+	statefulSet, err := e.getOwnerStatefulSet(message)
+	if err != nil {
+		logger.Debug("failed-to-get-statefulset", lager.Data{"error": err.Error()})
+
+		return
+	}
+
+	if err := e.sync(statefulSet); err != nil {
+		logger.Error("failed-to-sync-routes", err, lager.Data{"statefulset-name": statefulSet.Name})
+	}
+}
+
+func (e Emitter) getOwnerStatefulSet(message route.Message) (*appsv1.StatefulSet, error) {
+	statefulSets, err := e.statefulSets.GetByGUID(message.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range statefulSets {
+		if strings.HasPrefix(message.InstanceID, statefulSets[i].Name+"-") {
+			return &statefulSets[i], nil
+		}
+	}
+
+	return nil, errors.New("statefulset not found")
+}
+
+func (e Emitter) sync(statefulSet *appsv1.StatefulSet) error {
+	routes, err := decodeRoutes(statefulSet)
+	if err != nil {
+		return err
+	}
+
+	if len(routes) == 0 {
+		if err := e.services.Delete(statefulSet.Namespace, statefulSet.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to delete service")
+		}
+	} else if err := e.services.Apply(toService(statefulSet, routes)); err != nil {
+		return err
+	}
+
+	return errors.Wrap(e.backend.Apply(statefulSet, routes), "failed to apply routes")
+}
+
+func decodeRoutes(statefulSet *appsv1.StatefulSet) ([]cf.Route, error) {
+	routes := []cf.Route{}
+
+	annotation, ok := statefulSet.Annotations[stset.AnnotationRegisteredRoutes]
+	if !ok || annotation == "" {
+		return routes, nil
+	}
+
+	if err := json.Unmarshal([]byte(annotation), &routes); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal routes")
+	}
+
+	return routes, nil
+}
+
+func toService(statefulSet *appsv1.StatefulSet, routes []cf.Route) *corev1.Service {
+	ports := []corev1.ServicePort{}
+	for _, port := range routePorts(routes) {
+		ports = append(ports, corev1.ServicePort{
+			Name:       portName(port),
+			Port:       port,
+			TargetPort: intstr.FromInt(int(port)),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+
+	return &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: objectMeta(statefulSet, statefulSet.Name),
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: statefulSet.Spec.Selector.MatchLabels,
+			Ports:    ports,
+		},
+	}
+}
+
+// objectMeta names, labels and owns the objects exposing a StatefulSet, so
+// that they are garbage collected together with it
+func objectMeta(statefulSet *appsv1.StatefulSet, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: statefulSet.Namespace,
+		Labels: map[string]string{
+			stset.LabelGUID:    statefulSet.Labels[stset.LabelGUID],
+			stset.LabelVersion: statefulSet.Labels[stset.LabelVersion],
+		},
+		OwnerReferences: []metav1.OwnerReference{
+			*metav1.NewControllerRef(statefulSet, appsv1.SchemeGroupVersion.WithKind("StatefulSet")),
+		},
+	}
+}
+
+func routePorts(routes []cf.Route) []int32 {
+	seen := map[int32]bool{}
+	ports := []int32{}
+
+	for _, r := range routes {
+		if !seen[r.Port] {
+			seen[r.Port] = true
+			ports = append(ports, r.Port)
+		}
+	}
+
+	sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+
+	return ports
+}
+
+func portName(port int32) string {
+	return fmt.Sprintf("port-%d", port)
+}
+
+// splitHostname separates the host from the context path of a CF route
+// such as "example.com/api"
+func splitHostname(hostname string) (string, string) {
+	idx := strings.Index(hostname, "/")
+	if idx == -1 {
+		return hostname, "/"
+	}
+
+	return hostname[:idx], hostname[idx:]
+}
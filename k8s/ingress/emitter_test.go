@@ -0,0 +1,170 @@
+package ingress_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s/ingress"
+	"code.cloudfoundry.org/eirini/k8s/ingress/ingressfakes"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/route"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Emitter", func() {
+	var (
+		statefulSetGetter *ingressfakes.FakeStatefulSetGetter
+		serviceClient     *ingressfakes.FakeServiceClient
+		backend           *ingressfakes.FakeBackend
+		logger            *lagertest.TestLogger
+		statefulSet       *appsv1.StatefulSet
+		message           route.Message
+	)
+
+	BeforeEach(func() {
+		statefulSetGetter = new(ingressfakes.FakeStatefulSetGetter)
+		serviceClient = new(ingressfakes.FakeServiceClient)
+		backend = new(ingressfakes.FakeBackend)
+		logger = lagertest.NewTestLogger("ingress-emitter")
+
+		statefulSet = createStatefulSet(`[
+			{"hostname": "dora.example.com", "port": 8080},
+			{"hostname": "dora-admin.example.com", "port": 9090},
+			{"hostname": "dora.example.org", "port": 8080}
+		]`)
+		otherVersion := createStatefulSet("[]")
+		otherVersion.Name = "dora-space-5678"
+		statefulSetGetter.GetByGUIDReturns([]appsv1.StatefulSet{*otherVersion, *statefulSet}, nil)
+
+		message = route.Message{
+			Name:       "dora-guid",
+			InstanceID: "dora-space-1234-0",
+			Address:    "10.0.0.1",
+			Port:       8080,
+			Routes: route.Routes{
+				RegisteredRoutes: []string{"dora.example.com"},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		ingress.NewEmitter(statefulSetGetter, serviceClient, backend, logger).Emit(message)
+	})
+
+	It("looks up the statefulset of the instance", func() {
+		Expect(statefulSetGetter.GetByGUIDCallCount()).To(Equal(1))
+		Expect(statefulSetGetter.GetByGUIDArgsForCall(0)).To(Equal("dora-guid"))
+	})
+
+	It("applies a service exposing the route ports of the app", func() {
+		Expect(serviceClient.ApplyCallCount()).To(Equal(1))
+		service := serviceClient.ApplyArgsForCall(0)
+
+		Expect(service.Name).To(Equal("dora-space-1234"))
+		Expect(service.Namespace).To(Equal("workloads"))
+		Expect(service.Labels).To(HaveKeyWithValue(stset.LabelGUID, "dora-guid"))
+		Expect(service.Spec.Selector).To(Equal(statefulSet.Spec.Selector.MatchLabels))
+		Expect(service.Spec.Ports).To(ConsistOf(
+			corev1.ServicePort{Name: "port-8080", Port: 8080, TargetPort: intstr.FromInt(8080), Protocol: corev1.ProtocolTCP},
+			corev1.ServicePort{Name: "port-9090", Port: 9090, TargetPort: intstr.FromInt(9090), Protocol: corev1.ProtocolTCP},
+		))
+	})
+
+	It("makes the service owned by the statefulset", func() {
+		service := serviceClient.ApplyArgsForCall(0)
+
+		Expect(service.OwnerReferences).To(HaveLen(1))
+		Expect(service.OwnerReferences[0].Kind).To(Equal("StatefulSet"))
+		Expect(service.OwnerReferences[0].Name).To(Equal("dora-space-1234"))
+		Expect(service.OwnerReferences[0].UID).To(BeEquivalentTo("sts-uid"))
+	})
+
+	It("applies all routes of the statefulset through the backend", func() {
+		Expect(backend.ApplyCallCount()).To(Equal(1))
+		actualStatefulSet, routes := backend.ApplyArgsForCall(0)
+
+		Expect(actualStatefulSet.Name).To(Equal("dora-space-1234"))
+		Expect(routes).To(Equal([]cf.Route{
+			{Hostname: "dora.example.com", Port: 8080},
+			{Hostname: "dora-admin.example.com", Port: 9090},
+			{Hostname: "dora.example.org", Port: 8080},
+		}))
+	})
+
+	When("the message unregisters routes", func() {
+		BeforeEach(func() {
+			message.Routes = route.Routes{UnregisteredRoutes: []string{"dora.example.com"}}
+		})
+
+		It("still applies the routes of the statefulset", func() {
+			Expect(backend.ApplyCallCount()).To(Equal(1))
+			_, routes := backend.ApplyArgsForCall(0)
+			Expect(routes).To(HaveLen(3))
+		})
+	})
+
+	When("the statefulset has no routes", func() {
+		BeforeEach(func() {
+			statefulSet.Annotations[stset.AnnotationRegisteredRoutes] = "[]"
+			statefulSetGetter.GetByGUIDReturns([]appsv1.StatefulSet{*statefulSet}, nil)
+			serviceClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "services"}, "dora-space-1234"))
+		})
+
+		It("deletes the service", func() {
+			Expect(serviceClient.ApplyCallCount()).To(BeZero())
+			Expect(serviceClient.DeleteCallCount()).To(Equal(1))
+			namespace, name := serviceClient.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("workloads"))
+			Expect(name).To(Equal("dora-space-1234"))
+		})
+
+		It("applies no routes through the backend", func() {
+			Expect(backend.ApplyCallCount()).To(Equal(1))
+			_, routes := backend.ApplyArgsForCall(0)
+			Expect(routes).To(BeEmpty())
+		})
+	})
+
+	When("the statefulset of the instance no longer exists", func() {
+		BeforeEach(func() {
+			statefulSetGetter.GetByGUIDReturns([]appsv1.StatefulSet{}, nil)
+		})
+
+		It("does nothing", func() {
+			Expect(serviceClient.Invocations()).To(BeEmpty())
+			Expect(backend.ApplyCallCount()).To(BeZero())
+			Expect(logger.LogMessages()).To(ConsistOf("ingress-emitter.emit.failed-to-get-statefulset"))
+		})
+	})
+
+	When("the routes annotation is invalid", func() {
+		BeforeEach(func() {
+			statefulSet.Annotations[stset.AnnotationRegisteredRoutes] = "["
+			statefulSetGetter.GetByGUIDReturns([]appsv1.StatefulSet{*statefulSet}, nil)
+		})
+
+		It("logs the error", func() {
+			Expect(backend.ApplyCallCount()).To(BeZero())
+			Expect(logger.LogMessages()).To(ConsistOf("ingress-emitter.emit.failed-to-sync-routes"))
+		})
+	})
+
+	When("applying the routes fails", func() {
+		BeforeEach(func() {
+			backend.ApplyReturns(errors.New("boom"))
+		})
+
+		It("logs the error", func() {
+			Expect(logger.Logs()).To(HaveLen(1))
+			Expect(logger.Logs()[0].Message).To(Equal("ingress-emitter.emit.failed-to-sync-routes"))
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue("error", "failed to apply routes: boom"))
+		})
+	})
+})
@@ -0,0 +1,161 @@
+package ingress
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/util"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+//counterfeiter:generate . HTTPRouteClient
+
+type HTTPRouteClient interface {
+	Apply(httpRoute *unstructured.Unstructured) error
+	List(namespace, labelSelector string) ([]unstructured.Unstructured, error)
+	Delete(namespace, name string) error
+}
+
+// GatewayBackend publishes the routes of an app as Gateway API HTTPRoutes
+// attached to a shared Gateway. As hostnames apply to a whole HTTPRoute, there
+// is one HTTPRoute per app port and hostname, matching only the paths routed
+// on that hostname
+type GatewayBackend struct {
+	client           HTTPRouteClient
+	gatewayName      string
+	gatewayNamespace string
+}
+
+func NewGatewayBackend(client HTTPRouteClient, gatewayName, gatewayNamespace string) GatewayBackend {
+	return GatewayBackend{
+		client:           client,
+		gatewayName:      gatewayName,
+		gatewayNamespace: gatewayNamespace,
+	}
+}
+
+func (b GatewayBackend) Apply(statefulSet *appsv1.StatefulSet, routes []cf.Route) error {
+	desired := map[string]bool{}
+
+	for _, port := range routePorts(routes) {
+		for _, host := range hostPaths(routes, port) {
+			httpRoute, err := b.toHTTPRoute(statefulSet, port, host)
+			if err != nil {
+				return err
+			}
+
+			if err := b.client.Apply(httpRoute); err != nil {
+				return err
+			}
+
+			desired[httpRoute.GetName()] = true
+		}
+	}
+
+	existing, err := b.client.List(statefulSet.Namespace, fmt.Sprintf(
+		"%s=%s,%s=%s",
+		stset.LabelGUID, statefulSet.Labels[stset.LabelGUID],
+		stset.LabelVersion, statefulSet.Labels[stset.LabelVersion],
+	))
+	if err != nil {
+		return err
+	}
+
+	for _, httpRoute := range existing {
+		if desired[httpRoute.GetName()] {
+			continue
+		}
+
+		if err := b.client.Delete(httpRoute.GetNamespace(), httpRoute.GetName()); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete httproute %s", httpRoute.GetName())
+		}
+	}
+
+	return nil
+}
+
+type hostRoutes struct {
+	host  string
+	paths []string
+}
+
+// hostPaths groups the paths routed to a port by hostname, in the order the
+// hostnames first appear in
+func hostPaths(routes []cf.Route, port int32) []hostRoutes {
+	hosts := []hostRoutes{}
+	index := map[string]int{}
+	seenPaths := map[string]bool{}
+
+	for _, r := range routes {
+		if r.Port != port {
+			continue
+		}
+
+		host, path := splitHostname(r.Hostname)
+
+		i, ok := index[host]
+		if !ok {
+			i = len(hosts)
+			index[host] = i
+			hosts = append(hosts, hostRoutes{host: host})
+		}
+
+		if seenPaths[host+path] {
+			continue
+		}
+
+		seenPaths[host+path] = true
+		hosts[i].paths = append(hosts[i].paths, path)
+	}
+
+	return hosts
+}
+
+func (b GatewayBackend) toHTTPRoute(statefulSet *appsv1.StatefulSet, port int32, host hostRoutes) (*unstructured.Unstructured, error) {
+	matches := []interface{}{}
+	for _, path := range host.paths {
+		matches = append(matches, map[string]interface{}{
+			"path": map[string]interface{}{"type": "PathPrefix", "value": path},
+		})
+	}
+
+	parentRef := map[string]interface{}{"name": b.gatewayName}
+	if b.gatewayNamespace != "" {
+		parentRef["namespace"] = b.gatewayNamespace
+	}
+
+	httpRoute := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"parentRefs": []interface{}{parentRef},
+			"hostnames":  []interface{}{host.host},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"matches": matches,
+					"backendRefs": []interface{}{
+						map[string]interface{}{"name": statefulSet.Name, "port": int64(port)},
+					},
+				},
+			},
+		},
+	}}
+
+	// hostnames can be longer than a name may be, so that they go in hashed
+	hostHash, err := util.Hash(host.host)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash hostname")
+	}
+
+	meta := objectMeta(statefulSet, fmt.Sprintf("%s-%s-%s", statefulSet.Name, portName(port), hostHash))
+	httpRoute.SetAPIVersion("gateway.networking.k8s.io/v1")
+	httpRoute.SetKind("HTTPRoute")
+	httpRoute.SetName(meta.Name)
+	httpRoute.SetNamespace(meta.Namespace)
+	httpRoute.SetLabels(meta.Labels)
+	httpRoute.SetOwnerReferences(meta.OwnerReferences)
+
+	return httpRoute, nil
+}
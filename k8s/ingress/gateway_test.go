@@ -0,0 +1,111 @@
+package ingress_test
+
+import (
+	"code.cloudfoundry.org/eirini/k8s/ingress"
+	"code.cloudfoundry.org/eirini/k8s/ingress/ingressfakes"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/models/cf"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("GatewayBackend", func() {
+	var (
+		httpRouteClient *ingressfakes.FakeHTTPRouteClient
+		backend         ingress.GatewayBackend
+		routes          []cf.Route
+		err             error
+	)
+
+	BeforeEach(func() {
+		httpRouteClient = new(ingressfakes.FakeHTTPRouteClient)
+		backend = ingress.NewGatewayBackend(httpRouteClient, "cf-gateway", "gateways")
+		routes = []cf.Route{
+			{Hostname: "dora.example.com", Port: 8080},
+			{Hostname: "dora.example.com/v2", Port: 8080},
+			{Hostname: "dora.example.org/api", Port: 8080},
+			{Hostname: "dora-admin.example.com", Port: 9090},
+		}
+
+		stale := unstructured.Unstructured{}
+		stale.SetName("dora-space-1234-port-7070-b4ec77dca4")
+		stale.SetNamespace("workloads")
+		current := unstructured.Unstructured{}
+		current.SetName("dora-space-1234-port-8080-b4ec77dca4")
+		current.SetNamespace("workloads")
+		httpRouteClient.ListReturns([]unstructured.Unstructured{stale, current}, nil)
+	})
+
+	JustBeforeEach(func() {
+		err = backend.Apply(createStatefulSet(""), routes)
+	})
+
+	It("applies an httproute per port and hostname", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpRouteClient.ApplyCallCount()).To(Equal(3))
+
+		httpRoute := httpRouteClient.ApplyArgsForCall(0)
+		Expect(httpRoute.GetAPIVersion()).To(Equal("gateway.networking.k8s.io/v1"))
+		Expect(httpRoute.GetKind()).To(Equal("HTTPRoute"))
+		Expect(httpRoute.GetName()).To(Equal("dora-space-1234-port-8080-b4ec77dca4"))
+		Expect(httpRoute.GetNamespace()).To(Equal("workloads"))
+		Expect(httpRoute.GetLabels()).To(HaveKeyWithValue(stset.LabelGUID, "dora-guid"))
+		Expect(httpRoute.GetOwnerReferences()).To(HaveLen(1))
+		Expect(httpRoute.GetOwnerReferences()[0].Name).To(Equal("dora-space-1234"))
+
+		Expect(httpRouteClient.ApplyArgsForCall(1).GetName()).To(Equal("dora-space-1234-port-8080-1e8c20a759"))
+		Expect(httpRouteClient.ApplyArgsForCall(2).GetName()).To(Equal("dora-space-1234-port-9090-7b42bbd467"))
+	})
+
+	It("attaches the httproute to the gateway", func() {
+		parentRefs, _, _ := unstructured.NestedSlice(httpRouteClient.ApplyArgsForCall(0).Object, "spec", "parentRefs")
+		Expect(parentRefs).To(ConsistOf(map[string]interface{}{"name": "cf-gateway", "namespace": "gateways"}))
+	})
+
+	It("routes every hostname of the port with only its own paths to the app service", func() {
+		expectRoute := func(httpRoute *unstructured.Unstructured, hostname string, paths ...string) {
+			hostnames, _, _ := unstructured.NestedStringSlice(httpRoute.Object, "spec", "hostnames")
+			Expect(hostnames).To(Equal([]string{hostname}))
+
+			rules, _, _ := unstructured.NestedSlice(httpRoute.Object, "spec", "rules")
+			Expect(rules).To(HaveLen(1))
+
+			matches := []interface{}{}
+			for _, path := range paths {
+				matches = append(matches, map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": path}})
+			}
+
+			rule := rules[0].(map[string]interface{})
+			Expect(rule["matches"]).To(Equal(matches))
+			Expect(rule["backendRefs"]).To(ConsistOf(map[string]interface{}{"name": "dora-space-1234", "port": int64(8080)}))
+		}
+
+		expectRoute(httpRouteClient.ApplyArgsForCall(0), "dora.example.com", "/", "/v2")
+		expectRoute(httpRouteClient.ApplyArgsForCall(1), "dora.example.org", "/api")
+	})
+
+	It("deletes httproutes of ports and hostnames which are no longer routed", func() {
+		Expect(httpRouteClient.ListCallCount()).To(Equal(1))
+		namespace, selector := httpRouteClient.ListArgsForCall(0)
+		Expect(namespace).To(Equal("workloads"))
+		Expect(selector).To(Equal("cloudfoundry.org/guid=dora-guid,cloudfoundry.org/version=dora-version"))
+
+		Expect(httpRouteClient.DeleteCallCount()).To(Equal(1))
+		namespace, name := httpRouteClient.DeleteArgsForCall(0)
+		Expect(namespace).To(Equal("workloads"))
+		Expect(name).To(Equal("dora-space-1234-port-7070-b4ec77dca4"))
+	})
+
+	When("there are no routes", func() {
+		BeforeEach(func() {
+			routes = []cf.Route{}
+		})
+
+		It("deletes all httproutes of the app", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(httpRouteClient.ApplyCallCount()).To(BeZero())
+			Expect(httpRouteClient.DeleteCallCount()).To(Equal(2))
+		})
+	})
+})
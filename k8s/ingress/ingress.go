@@ -0,0 +1,90 @@
+package ingress
+
+import (
+	"code.cloudfoundry.org/eirini/models/cf"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//counterfeiter:generate . IngressClient
+
+type IngressClient interface {
+	Apply(ingress *networkingv1.Ingress) error
+	Delete(namespace, name string) error
+}
+
+// IngressBackend publishes the routes of an app as a single Ingress with a
+// rule per hostname
+type IngressBackend struct {
+	client    IngressClient
+	className string
+}
+
+func NewIngressBackend(client IngressClient, className string) IngressBackend {
+	return IngressBackend{
+		client:    client,
+		className: className,
+	}
+}
+
+func (b IngressBackend) Apply(statefulSet *appsv1.StatefulSet, routes []cf.Route) error {
+	if len(routes) == 0 {
+		err := b.client.Delete(statefulSet.Namespace, statefulSet.Name)
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+
+		return errors.Wrap(err, "failed to delete ingress")
+	}
+
+	return b.client.Apply(b.toIngress(statefulSet, routes))
+}
+
+func (b IngressBackend) toIngress(statefulSet *appsv1.StatefulSet, routes []cf.Route) *networkingv1.Ingress {
+	pathType := networkingv1.PathTypePrefix
+	rules := []networkingv1.IngressRule{}
+	ruleIndex := map[string]int{}
+
+	for _, r := range routes {
+		host, path := splitHostname(r.Hostname)
+
+		idx, ok := ruleIndex[host]
+		if !ok {
+			idx = len(rules)
+			ruleIndex[host] = idx
+			rules = append(rules, networkingv1.IngressRule{
+				Host: host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{},
+				},
+			})
+		}
+
+		rules[idx].HTTP.Paths = append(rules[idx].HTTP.Paths, networkingv1.HTTPIngressPath{
+			Path:     path,
+			PathType: &pathType,
+			Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: statefulSet.Name,
+					Port: networkingv1.ServiceBackendPort{Number: r.Port},
+				},
+			},
+		})
+	}
+
+	ingress := &networkingv1.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: objectMeta(statefulSet, statefulSet.Name),
+		Spec:       networkingv1.IngressSpec{Rules: rules},
+	}
+
+	if b.className != "" {
+		className := b.className
+		ingress.Spec.IngressClassName = &className
+	}
+
+	return ingress
+}
@@ -0,0 +1,41 @@
+package ingress_test
+
+import (
+	"testing"
+
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIngress(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Ingress Suite")
+}
+
+func createStatefulSet(routes string) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "dora-space-1234",
+			Namespace: "workloads",
+			UID:       "sts-uid",
+			Labels: map[string]string{
+				stset.LabelGUID:    "dora-guid",
+				stset.LabelVersion: "dora-version",
+			},
+			Annotations: map[string]string{
+				stset.AnnotationRegisteredRoutes: routes,
+			},
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					stset.LabelGUID:    "dora-guid",
+					stset.LabelVersion: "dora-version",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,93 @@
+package ingress_test
+
+import (
+	"code.cloudfoundry.org/eirini/k8s/ingress"
+	"code.cloudfoundry.org/eirini/k8s/ingress/ingressfakes"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/models/cf"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("IngressBackend", func() {
+	var (
+		ingressClient *ingressfakes.FakeIngressClient
+		backend       ingress.IngressBackend
+		routes        []cf.Route
+		err           error
+	)
+
+	BeforeEach(func() {
+		ingressClient = new(ingressfakes.FakeIngressClient)
+		backend = ingress.NewIngressBackend(ingressClient, "nginx")
+		routes = []cf.Route{
+			{Hostname: "dora.example.com", Port: 8080},
+			{Hostname: "dora.example.com/admin", Port: 9090},
+			{Hostname: "dora.example.org", Port: 8080},
+		}
+	})
+
+	JustBeforeEach(func() {
+		err = backend.Apply(createStatefulSet(""), routes)
+	})
+
+	It("applies an ingress owned by the statefulset", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ingressClient.ApplyCallCount()).To(Equal(1))
+
+		ing := ingressClient.ApplyArgsForCall(0)
+		Expect(ing.Name).To(Equal("dora-space-1234"))
+		Expect(ing.Namespace).To(Equal("workloads"))
+		Expect(ing.Labels).To(HaveKeyWithValue(stset.LabelVersion, "dora-version"))
+		Expect(ing.OwnerReferences).To(HaveLen(1))
+		Expect(ing.OwnerReferences[0].Name).To(Equal("dora-space-1234"))
+		Expect(*ing.Spec.IngressClassName).To(Equal("nginx"))
+	})
+
+	It("has a rule per hostname pointing at the app service", func() {
+		ing := ingressClient.ApplyArgsForCall(0)
+		Expect(ing.Spec.Rules).To(HaveLen(2))
+
+		Expect(ing.Spec.Rules[0].Host).To(Equal("dora.example.com"))
+		paths := ing.Spec.Rules[0].HTTP.Paths
+		Expect(paths).To(HaveLen(2))
+		Expect(paths[0].Path).To(Equal("/"))
+		Expect(*paths[0].PathType).To(Equal(networkingv1.PathTypePrefix))
+		Expect(paths[0].Backend.Service.Name).To(Equal("dora-space-1234"))
+		Expect(paths[0].Backend.Service.Port.Number).To(Equal(int32(8080)))
+		Expect(paths[1].Path).To(Equal("/admin"))
+		Expect(paths[1].Backend.Service.Port.Number).To(Equal(int32(9090)))
+
+		Expect(ing.Spec.Rules[1].Host).To(Equal("dora.example.org"))
+		Expect(ing.Spec.Rules[1].HTTP.Paths).To(HaveLen(1))
+	})
+
+	When("no ingress class is configured", func() {
+		BeforeEach(func() {
+			backend = ingress.NewIngressBackend(ingressClient, "")
+		})
+
+		It("leaves it to the cluster default", func() {
+			Expect(ingressClient.ApplyArgsForCall(0).Spec.IngressClassName).To(BeNil())
+		})
+	})
+
+	When("there are no routes", func() {
+		BeforeEach(func() {
+			routes = []cf.Route{}
+			ingressClient.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{Resource: "ingresses"}, "dora-space-1234"))
+		})
+
+		It("deletes the ingress", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ingressClient.ApplyCallCount()).To(BeZero())
+			Expect(ingressClient.DeleteCallCount()).To(Equal(1))
+			namespace, name := ingressClient.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("workloads"))
+			Expect(name).To(Equal("dora-space-1234"))
+		})
+	})
+})
@@ -0,0 +1,117 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package ingressfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/eirini/k8s/ingress"
+	v1 "k8s.io/api/apps/v1"
+)
+
+type FakeStatefulSetGetter struct {
+	GetByGUIDStub        func(string) ([]v1.StatefulSet, error)
+	getByGUIDMutex       sync.RWMutex
+	getByGUIDArgsForCall []struct {
+		arg1 string
+	}
+	getByGUIDReturns struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}
+	getByGUIDReturnsOnCall map[int]struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeStatefulSetGetter) GetByGUID(arg1 string) ([]v1.StatefulSet, error) {
+	fake.getByGUIDMutex.Lock()
+	ret, specificReturn := fake.getByGUIDReturnsOnCall[len(fake.getByGUIDArgsForCall)]
+	fake.getByGUIDArgsForCall = append(fake.getByGUIDArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GetByGUIDStub
+	fakeReturns := fake.getByGUIDReturns
+	fake.recordInvocation("GetByGUID", []interface{}{arg1})
+	fake.getByGUIDMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeStatefulSetGetter) GetByGUIDCallCount() int {
+	fake.getByGUIDMutex.RLock()
+	defer fake.getByGUIDMutex.RUnlock()
+	return len(fake.getByGUIDArgsForCall)
+}
+
+func (fake *FakeStatefulSetGetter) GetByGUIDCalls(stub func(string) ([]v1.StatefulSet, error)) {
+	fake.getByGUIDMutex.Lock()
+	defer fake.getByGUIDMutex.Unlock()
+	fake.GetByGUIDStub = stub
+}
+
+func (fake *FakeStatefulSetGetter) GetByGUIDArgsForCall(i int) string {
+	fake.getByGUIDMutex.RLock()
+	defer fake.getByGUIDMutex.RUnlock()
+	argsForCall := fake.getByGUIDArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeStatefulSetGetter) GetByGUIDReturns(result1 []v1.StatefulSet, result2 error) {
+	fake.getByGUIDMutex.Lock()
+	defer fake.getByGUIDMutex.Unlock()
+	fake.GetByGUIDStub = nil
+	fake.getByGUIDReturns = struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStatefulSetGetter) GetByGUIDReturnsOnCall(i int, result1 []v1.StatefulSet, result2 error) {
+	fake.getByGUIDMutex.Lock()
+	defer fake.getByGUIDMutex.Unlock()
+	fake.GetByGUIDStub = nil
+	if fake.getByGUIDReturnsOnCall == nil {
+		fake.getByGUIDReturnsOnCall = make(map[int]struct {
+			result1 []v1.StatefulSet
+			result2 error
+		})
+	}
+	fake.getByGUIDReturnsOnCall[i] = struct {
+		result1 []v1.StatefulSet
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeStatefulSetGetter) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.getByGUIDMutex.RLock()
+	defer fake.getByGUIDMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeStatefulSetGetter) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ ingress.StatefulSetGetter = new(FakeStatefulSetGetter)
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"strings"
 
+	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/lager"
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 //counterfeiter:generate . JobDeleter
@@ -74,6 +76,10 @@ func (d *Deleter) delete(logger lager.Logger, job batchv1.Job) (string, error) {
 		return "", err
 	}
 
+	if err := d.deleteEnvSecrets(logger, job); err != nil {
+		return "", err
+	}
+
 	callbackURL := job.Annotations[AnnotationCompletionCallback]
 
 	if len(job.OwnerReferences) != 0 {
@@ -110,3 +116,18 @@ func (d *Deleter) deleteDockerRegistrySecret(logger lager.Logger, job batchv1.Jo
 
 	return nil
 }
+
+func (d *Deleter) deleteEnvSecrets(logger lager.Logger, job batchv1.Job) error {
+	for _, container := range job.Spec.Template.Spec.Containers {
+		for _, name := range shared.EnvSecretNames(job.Name, container) {
+			err := d.secretDeleter.Delete(job.Namespace, name)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				logger.Error("failed-to-delete-env-secret", err, lager.Data{"name": name, "namespace": job.Namespace})
+
+				return errors.Wrap(err, "failed to delete env secret")
+			}
+		}
+	}
+
+	return nil
+}
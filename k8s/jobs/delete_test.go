@@ -11,7 +11,9 @@ import (
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var _ = Describe("Delete", func() {
@@ -168,6 +170,53 @@ var _ = Describe("Delete", func() {
 		})
 	})
 
+	When("the job takes its environment from a secret", func() {
+		BeforeEach(func() {
+			job.Spec.Template.Spec.Containers = []corev1.Container{
+				{
+					Name: "opi-task",
+					Env: []corev1.EnvVar{
+						{Name: "FOO", ValueFrom: secretValueFrom("my-job-env-0123456789", "FOO")},
+						{Name: "BAR", ValueFrom: secretValueFrom("my-job-env-0123456789", "BAR")},
+						{Name: "BAZ", ValueFrom: secretValueFrom("another-random-secret", "BAZ")},
+					},
+				},
+			}
+			jobGetter.GetByGUIDReturns([]batchv1.Job{job}, nil)
+		})
+
+		It("deletes the env secret", func() {
+			Expect(secretDeleter.DeleteCallCount()).To(Equal(1))
+			actualNamespace, actualSecretName := secretDeleter.DeleteArgsForCall(0)
+			Expect(actualNamespace).To(Equal("my-namespace"))
+			Expect(actualSecretName).To(Equal("my-job-env-0123456789"))
+		})
+
+		When("the env secret is already gone", func() {
+			BeforeEach(func() {
+				secretDeleter.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "my-job-env-0123456789"))
+			})
+
+			It("succeeds", func() {
+				Expect(deleteErr).NotTo(HaveOccurred())
+			})
+		})
+
+		When("deleting the env secret fails", func() {
+			BeforeEach(func() {
+				secretDeleter.DeleteReturns(errors.New("env-secret-delete-failure"))
+			})
+
+			It("returns the error", func() {
+				Expect(deleteErr).To(MatchError(ContainSubstring("env-secret-delete-failure")))
+			})
+
+			It("does not call the deleter", func() {
+				Expect(jobDeleter.DeleteCallCount()).To(BeZero())
+			})
+		})
+	})
+
 	When("getting the jobs by GUID fails", func() {
 		BeforeEach(func() {
 			jobGetter.GetByGUIDReturns(nil, errors.New("failed to list jobs"))
@@ -11,11 +11,12 @@ import (
 	"github.com/pkg/errors"
 	batch "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 //counterfeiter:generate . TaskToJobConverter
 //counterfeiter:generate . JobCreator
-//counterfeiter:generate . SecretCreator
+//counterfeiter:generate . SecretClient
 
 type TaskToJobConverter interface {
 	Convert(*opi.Task) *batch.Job
@@ -25,28 +26,29 @@ type JobCreator interface {
 	Create(namespace string, job *batch.Job) (*batch.Job, error)
 }
 
-type SecretCreator interface {
+type SecretClient interface {
 	Create(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Update(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
 }
 
 type Desirer struct {
 	logger             lager.Logger
 	taskToJobConverter TaskToJobConverter
 	jobCreator         JobCreator
-	secretCreator      SecretCreator
+	secretClient       SecretClient
 }
 
 func NewDesirer(
 	logger lager.Logger,
 	taskToJobConverter TaskToJobConverter,
 	jobCreator JobCreator,
-	secretCreator SecretCreator,
+	secretClient SecretClient,
 ) Desirer {
 	return Desirer{
 		logger:             logger,
 		taskToJobConverter: taskToJobConverter,
 		jobCreator:         jobCreator,
-		secretCreator:      secretCreator,
+		secretClient:       secretClient,
 	}
 }
 
@@ -55,7 +57,12 @@ func (d *Desirer) Desire(namespace string, task *opi.Task, opts ...shared.Option
 
 	job := d.taskToJobConverter.Convert(task)
 
-	if imageInPrivateRegistry(task) {
+	if task.PrivateRegistry != nil && task.PrivateRegistry.SecretName != "" {
+		spec := &job.Spec.Template.Spec
+		spec.ImagePullSecrets = append(spec.ImagePullSecrets, corev1.LocalObjectReference{
+			Name: task.PrivateRegistry.SecretName,
+		})
+	} else if imageInPrivateRegistry(task) {
 		if err := d.addImagePullSecret(namespace, task, job); err != nil {
 			logger.Error("failed-to-add-image-pull-secret", err)
 
@@ -71,16 +78,59 @@ func (d *Desirer) Desire(namespace string, task *opi.Task, opts ...shared.Option
 		return err
 	}
 
-	_, err := d.jobCreator.Create(namespace, job)
+	envSecret, err := d.createEnvSecret(namespace, job.Name, task)
+	if err != nil {
+		logger.Error("failed-to-create-env-secret", err)
+
+		return err
+	}
+
+	createdJob, err := d.jobCreator.Create(namespace, job)
 	if err != nil {
 		logger.Error("failed-to-create-job", err)
 
 		return errors.Wrap(err, "failed to create job")
 	}
 
+	if err := d.ownEnvSecret(namespace, envSecret, createdJob); err != nil {
+		logger.Error("failed-to-own-env-secret", err)
+
+		return err
+	}
+
 	return nil
 }
 
+// createEnvSecret creates the secret the task container takes the
+// environment of the task from
+func (d *Desirer) createEnvSecret(namespace, jobName string, task *opi.Task) (*corev1.Secret, error) {
+	if len(task.Env) == 0 {
+		return nil, nil
+	}
+
+	secret, err := d.secretClient.Create(namespace, shared.NewEnvSecret(shared.EnvSecretName(jobName, task.Env), task.Env))
+
+	return secret, errors.Wrap(err, "failed to create env secret for job")
+}
+
+// ownEnvSecret makes the env secret owned by the job using it, so that it
+// goes away with it
+func (d *Desirer) ownEnvSecret(namespace string, secret *corev1.Secret, job *batch.Job) error {
+	if secret == nil || job == nil {
+		return nil
+	}
+
+	secret.OwnerReferences = append(secret.OwnerReferences, metav1.OwnerReference{
+		APIVersion: batch.SchemeGroupVersion.String(),
+		Kind:       "Job",
+		Name:       job.Name,
+		UID:        job.UID,
+	})
+	_, err := d.secretClient.Update(namespace, secret)
+
+	return errors.Wrap(err, "failed to set env secret owner")
+}
+
 func imageInPrivateRegistry(task *opi.Task) bool {
 	return task.PrivateRegistry != nil && task.PrivateRegistry.Username != "" && task.PrivateRegistry.Password != ""
 }
@@ -120,7 +170,7 @@ func (d *Desirer) createTaskSecret(namespace string, task *opi.Task) (*corev1.Se
 		dockerutils.DockerConfigKey: dockerConfigJSON,
 	}
 
-	return d.secretCreator.Create(namespace, secret)
+	return d.secretClient.Create(namespace, secret)
 }
 
 func dockerImagePullSecretNamePrefix(appName, spaceName, taskGUID string) string {
@@ -7,6 +7,7 @@ import (
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s/jobs"
 	"code.cloudfoundry.org/eirini/k8s/jobs/jobsfakes"
+	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/k8s/shared/sharedfakes"
 	"code.cloudfoundry.org/eirini/opi"
 	"code.cloudfoundry.org/lager/lagertest"
@@ -26,7 +27,7 @@ var _ = Describe("Desire", func() {
 
 	var (
 		jobCreator         *jobsfakes.FakeJobCreator
-		secretCreator      *jobsfakes.FakeSecretCreator
+		secretClient       *jobsfakes.FakeSecretClient
 		taskToJobConverter *jobsfakes.FakeTaskToJobConverter
 		desireOpt          *sharedfakes.FakeOption
 
@@ -38,7 +39,7 @@ var _ = Describe("Desire", func() {
 	)
 
 	BeforeEach(func() {
-		job = &batch.Job{}
+		job = &batch.Job{ObjectMeta: metav1.ObjectMeta{Name: "my-job"}}
 
 		desireOpt = new(sharedfakes.FakeOption)
 		desireOpt.Stub = func(resource interface{}) error {
@@ -50,7 +51,7 @@ var _ = Describe("Desire", func() {
 		}
 
 		jobCreator = new(jobsfakes.FakeJobCreator)
-		secretCreator = new(jobsfakes.FakeSecretCreator)
+		secretClient = new(jobsfakes.FakeSecretClient)
 		taskToJobConverter = new(jobsfakes.FakeTaskToJobConverter)
 		taskToJobConverter.ConvertReturns(job)
 
@@ -80,7 +81,7 @@ var _ = Describe("Desire", func() {
 			lagertest.NewTestLogger("desiretask"),
 			taskToJobConverter,
 			jobCreator,
-			secretCreator,
+			secretClient,
 		)
 	})
 
@@ -133,6 +134,70 @@ var _ = Describe("Desire", func() {
 		})
 	})
 
+	It("stores the task environment in a secret", func() {
+		Expect(secretClient.CreateCallCount()).To(Equal(1))
+		namespace, actualSecret := secretClient.CreateArgsForCall(0)
+		Expect(namespace).To(Equal("app-namespace"))
+		Expect(actualSecret.Name).To(Equal(shared.EnvSecretName("my-job", task.Env)))
+		Expect(actualSecret.StringData).To(Equal(task.Env))
+	})
+
+	When("the env secret is created", func() {
+		BeforeEach(func() {
+			secretClient.CreateStub = func(namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+				return secret.DeepCopy(), nil
+			}
+			jobCreator.CreateStub = func(namespace string, job *batch.Job) (*batch.Job, error) {
+				created := job.DeepCopy()
+				created.UID = "job-uid"
+
+				return created, nil
+			}
+		})
+
+		It("makes the job own it", func() {
+			Expect(secretClient.UpdateCallCount()).To(Equal(1))
+			_, actualSecret := secretClient.UpdateArgsForCall(0)
+			Expect(actualSecret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+				APIVersion: "batch/v1",
+				Kind:       "Job",
+				Name:       "my-job",
+				UID:        "job-uid",
+			}))
+		})
+
+		When("setting the owner fails", func() {
+			BeforeEach(func() {
+				secretClient.UpdateReturns(nil, errors.New("update-failed"))
+			})
+
+			It("returns an error", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("failed to set env secret owner")))
+			})
+		})
+	})
+
+	When("creating the env secret fails", func() {
+		BeforeEach(func() {
+			secretClient.CreateReturns(nil, errors.New("create-secret-err"))
+		})
+
+		It("does not create the job", func() {
+			Expect(desireErr).To(MatchError(ContainSubstring("failed to create env secret")))
+			Expect(jobCreator.CreateCallCount()).To(BeZero())
+		})
+	})
+
+	When("the task has no environment", func() {
+		BeforeEach(func() {
+			task.Env = nil
+		})
+
+		It("does not create an env secret", func() {
+			Expect(secretClient.CreateCallCount()).To(BeZero())
+		})
+	})
+
 	When("the task uses a private registry", func() {
 		BeforeEach(func() {
 			task.PrivateRegistry = &opi.PrivateRegistry{
@@ -140,12 +205,12 @@ var _ = Describe("Desire", func() {
 				Username: "username",
 				Password: "password",
 			}
-			secretCreator.CreateReturns(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "the-generated-secret-name"}}, nil)
+			secretClient.CreateReturns(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "the-generated-secret-name"}}, nil)
 		})
 
 		It("creates a secret with the registry credentials", func() {
-			Expect(secretCreator.CreateCallCount()).To(Equal(1))
-			namespace, actualSecret := secretCreator.CreateArgsForCall(0)
+			Expect(secretClient.CreateCallCount()).To(Equal(2))
+			namespace, actualSecret := secretClient.CreateArgsForCall(0)
 			Expect(namespace).To(Equal("app-namespace"))
 			Expect(actualSecret.GenerateName).To(Equal("my-app-my-space-registry-secret-"))
 			Expect(actualSecret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
@@ -169,12 +234,31 @@ var _ = Describe("Desire", func() {
 
 		When("creating the secret fails", func() {
 			BeforeEach(func() {
-				secretCreator.CreateReturns(nil, errors.New("create-secret-err"))
+				secretClient.CreateReturns(nil, errors.New("create-secret-err"))
 			})
 
 			It("returns an error", func() {
 				Expect(desireErr).To(MatchError(ContainSubstring("create-secret-err")))
 			})
 		})
+
+		When("the task refers to a registry secret", func() {
+			BeforeEach(func() {
+				task.PrivateRegistry.SecretName = "my-registry-secret"
+			})
+
+			It("pulls the image with it", func() {
+				_, job = jobCreator.CreateArgsForCall(0)
+				Expect(job.Spec.Template.Spec.ImagePullSecrets).To(ConsistOf(
+					corev1.LocalObjectReference{Name: "my-registry-secret"},
+				))
+			})
+
+			It("does not generate a registry secret", func() {
+				Expect(secretClient.CreateCallCount()).To(Equal(1))
+				_, actualSecret := secretClient.CreateArgsForCall(0)
+				Expect(actualSecret.Type).NotTo(Equal(corev1.SecretTypeDockerConfigJson))
+			})
+		})
 	})
 })
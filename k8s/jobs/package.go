@@ -24,4 +24,6 @@ const (
 
 	LabelTaskCompleted = "cloudfoundry.org/task_completed"
 	TaskCompletedTrue  = "true"
+
+	TaskSourceType = "TASK"
 )
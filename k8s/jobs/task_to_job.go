@@ -12,7 +12,6 @@ import (
 )
 
 const (
-	taskSourceType       = "TASK"
 	opiTaskContainerName = "opi-task"
 	parallelism          = 1
 	completions          = 1
@@ -39,19 +38,19 @@ func NewTaskToJobConverter(
 func (m *Converter) Convert(task *opi.Task) *batch.Job {
 	job := m.toJob(task)
 	job.Spec.Template.Spec.ServiceAccountName = m.serviceAccountName
-	job.Labels[LabelSourceType] = taskSourceType
+	job.Labels[LabelSourceType] = TaskSourceType
 	job.Labels[LabelName] = task.Name
 	job.Annotations[AnnotationCompletionCallback] = task.CompletionCallback
 	job.Spec.Template.Annotations[AnnotationGUID] = task.GUID
 	job.Spec.Template.Annotations[AnnotationOpiTaskContainerName] = opiTaskContainerName
 	job.Spec.Template.Annotations[AnnotationCompletionCallback] = task.CompletionCallback
 
-	envs := getEnvs(task)
+	envs := getEnvs(job.Name, task)
 	containers := []corev1.Container{
 		{
 			Name:            opiTaskContainerName,
 			Image:           task.Image,
-			ImagePullPolicy: corev1.PullAlways,
+			ImagePullPolicy: shared.ImagePullPolicy(task.Image, task.PrivateRegistry != nil),
 			Env:             envs,
 			Command:         task.Command,
 		},
@@ -79,6 +78,7 @@ func (m *Converter) toJob(task *opi.Task) *batch.Job {
 			Template: corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
 					RestartPolicy: corev1.RestartPolicyNever,
+					NodeSelector:  shared.NodeSelector(task.Architecture),
 					SecurityContext: &corev1.PodSecurityContext{
 						RunAsNonRoot: &runAsNonRoot,
 					},
@@ -122,8 +122,12 @@ func (m *Converter) toJob(task *opi.Task) *batch.Job {
 	return job
 }
 
-func getEnvs(task *opi.Task) []corev1.EnvVar {
-	envs := shared.MapToEnvVar(task.Env)
+func getEnvs(jobName string, task *opi.Task) []corev1.EnvVar {
+	envs := []corev1.EnvVar{}
+	if len(task.Env) > 0 {
+		envs = shared.MapToSecretEnvVar(shared.EnvSecretName(jobName, task.Env), task.Env)
+	}
+
 	fieldEnvs := []corev1.EnvVar{
 		{
 			Name: eirini.EnvPodName,
@@ -5,6 +5,7 @@ import (
 
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/opi"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -39,10 +40,11 @@ var _ = Describe("TaskToJob", func() {
 		Expect(container.Image).To(Equal(image))
 		Expect(container.ImagePullPolicy).To(Equal(corev1.PullAlways))
 
+		envSecretName := shared.EnvSecretName(job.Name, task.Env)
 		Expect(container.Env).To(ContainElements(
-			corev1.EnvVar{Name: eirini.EnvDownloadURL, Value: "example.com/download"},
-			corev1.EnvVar{Name: eirini.EnvDropletUploadURL, Value: "example.com/upload"},
-			corev1.EnvVar{Name: eirini.EnvAppID, Value: "env-app-id"},
+			corev1.EnvVar{Name: eirini.EnvDownloadURL, ValueFrom: secretValueFrom(envSecretName, eirini.EnvDownloadURL)},
+			corev1.EnvVar{Name: eirini.EnvDropletUploadURL, ValueFrom: secretValueFrom(envSecretName, eirini.EnvDropletUploadURL)},
+			corev1.EnvVar{Name: eirini.EnvAppID, ValueFrom: secretValueFrom(envSecretName, eirini.EnvAppID)},
 			corev1.EnvVar{Name: eirini.EnvCFInstanceGUID, ValueFrom: expectedValFrom("metadata.uid")},
 			corev1.EnvVar{Name: eirini.EnvCFInstanceInternalIP, ValueFrom: expectedValFrom("status.podIP")},
 			corev1.EnvVar{Name: eirini.EnvCFInstanceIP, ValueFrom: expectedValFrom("status.hostIP")},
@@ -83,6 +85,36 @@ var _ = Describe("TaskToJob", func() {
 		job = jobs.NewTaskToJobConverter(serviceAccount, registrySecret, allowAutomountServiceAccountToken).Convert(task)
 	})
 
+	When("the image is pinned to a digest", func() {
+		BeforeEach(func() {
+			task.Image = "eirini/dorini@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"
+		})
+
+		It("pulls the image only if it is not present", func() {
+			Expect(job.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		})
+
+		When("the image is in a private registry", func() {
+			BeforeEach(func() {
+				task.PrivateRegistry = &opi.PrivateRegistry{Server: "host", Username: "user", Password: "pass"}
+			})
+
+			It("always pulls the image", func() {
+				Expect(job.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+			})
+		})
+	})
+
+	When("the image is available for a single architecture", func() {
+		BeforeEach(func() {
+			task.Architecture = "arm64"
+		})
+
+		It("schedules the task on a node of that architecture", func() {
+			Expect(job.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/arch": "arm64"}))
+		})
+	})
+
 	It("returns a job for the task with the correct attributes", func() {
 		assertGeneralSpec(job)
 
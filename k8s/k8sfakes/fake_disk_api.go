@@ -5,36 +5,44 @@ import (
 	"sync"
 
 	"code.cloudfoundry.org/eirini/k8s"
+	v1 "k8s.io/api/core/v1"
 )
 
 type FakeDiskAPI struct {
-	GetPodMetricsStub        func() (map[string]float64, error)
+	GetPodMetricsStub        func([]v1.Pod) (map[string]map[string]float64, error)
 	getPodMetricsMutex       sync.RWMutex
 	getPodMetricsArgsForCall []struct {
+		arg1 []v1.Pod
 	}
 	getPodMetricsReturns struct {
-		result1 map[string]float64
+		result1 map[string]map[string]float64
 		result2 error
 	}
 	getPodMetricsReturnsOnCall map[int]struct {
-		result1 map[string]float64
+		result1 map[string]map[string]float64
 		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeDiskAPI) GetPodMetrics() (map[string]float64, error) {
+func (fake *FakeDiskAPI) GetPodMetrics(arg1 []v1.Pod) (map[string]map[string]float64, error) {
+	var arg1Copy []v1.Pod
+	if arg1 != nil {
+		arg1Copy = make([]v1.Pod, len(arg1))
+		copy(arg1Copy, arg1)
+	}
 	fake.getPodMetricsMutex.Lock()
 	ret, specificReturn := fake.getPodMetricsReturnsOnCall[len(fake.getPodMetricsArgsForCall)]
 	fake.getPodMetricsArgsForCall = append(fake.getPodMetricsArgsForCall, struct {
-	}{})
+		arg1 []v1.Pod
+	}{arg1Copy})
 	stub := fake.GetPodMetricsStub
 	fakeReturns := fake.getPodMetricsReturns
-	fake.recordInvocation("GetPodMetrics", []interface{}{})
+	fake.recordInvocation("GetPodMetrics", []interface{}{arg1Copy})
 	fake.getPodMetricsMutex.Unlock()
 	if stub != nil {
-		return stub()
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -48,34 +56,41 @@ func (fake *FakeDiskAPI) GetPodMetricsCallCount() int {
 	return len(fake.getPodMetricsArgsForCall)
 }
 
-func (fake *FakeDiskAPI) GetPodMetricsCalls(stub func() (map[string]float64, error)) {
+func (fake *FakeDiskAPI) GetPodMetricsCalls(stub func([]v1.Pod) (map[string]map[string]float64, error)) {
 	fake.getPodMetricsMutex.Lock()
 	defer fake.getPodMetricsMutex.Unlock()
 	fake.GetPodMetricsStub = stub
 }
 
-func (fake *FakeDiskAPI) GetPodMetricsReturns(result1 map[string]float64, result2 error) {
+func (fake *FakeDiskAPI) GetPodMetricsArgsForCall(i int) []v1.Pod {
+	fake.getPodMetricsMutex.RLock()
+	defer fake.getPodMetricsMutex.RUnlock()
+	argsForCall := fake.getPodMetricsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeDiskAPI) GetPodMetricsReturns(result1 map[string]map[string]float64, result2 error) {
 	fake.getPodMetricsMutex.Lock()
 	defer fake.getPodMetricsMutex.Unlock()
 	fake.GetPodMetricsStub = nil
 	fake.getPodMetricsReturns = struct {
-		result1 map[string]float64
+		result1 map[string]map[string]float64
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeDiskAPI) GetPodMetricsReturnsOnCall(i int, result1 map[string]float64, result2 error) {
+func (fake *FakeDiskAPI) GetPodMetricsReturnsOnCall(i int, result1 map[string]map[string]float64, result2 error) {
 	fake.getPodMetricsMutex.Lock()
 	defer fake.getPodMetricsMutex.Unlock()
 	fake.GetPodMetricsStub = nil
 	if fake.getPodMetricsReturnsOnCall == nil {
 		fake.getPodMetricsReturnsOnCall = make(map[int]struct {
-			result1 map[string]float64
+			result1 map[string]map[string]float64
 			result2 error
 		})
 	}
 	fake.getPodMetricsReturnsOnCall[i] = struct {
-		result1 map[string]float64
+		result1 map[string]map[string]float64
 		result2 error
 	}{result1, result2}
 }
@@ -0,0 +1,103 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package k8sfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/metrics"
+)
+
+type FakeNodeScrapeSource struct {
+	NodeScrapeStatsStub        func() []metrics.NodeScrapeMessage
+	nodeScrapeStatsMutex       sync.RWMutex
+	nodeScrapeStatsArgsForCall []struct {
+	}
+	nodeScrapeStatsReturns struct {
+		result1 []metrics.NodeScrapeMessage
+	}
+	nodeScrapeStatsReturnsOnCall map[int]struct {
+		result1 []metrics.NodeScrapeMessage
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeNodeScrapeSource) NodeScrapeStats() []metrics.NodeScrapeMessage {
+	fake.nodeScrapeStatsMutex.Lock()
+	ret, specificReturn := fake.nodeScrapeStatsReturnsOnCall[len(fake.nodeScrapeStatsArgsForCall)]
+	fake.nodeScrapeStatsArgsForCall = append(fake.nodeScrapeStatsArgsForCall, struct {
+	}{})
+	stub := fake.NodeScrapeStatsStub
+	fakeReturns := fake.nodeScrapeStatsReturns
+	fake.recordInvocation("NodeScrapeStats", []interface{}{})
+	fake.nodeScrapeStatsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeNodeScrapeSource) NodeScrapeStatsCallCount() int {
+	fake.nodeScrapeStatsMutex.RLock()
+	defer fake.nodeScrapeStatsMutex.RUnlock()
+	return len(fake.nodeScrapeStatsArgsForCall)
+}
+
+func (fake *FakeNodeScrapeSource) NodeScrapeStatsCalls(stub func() []metrics.NodeScrapeMessage) {
+	fake.nodeScrapeStatsMutex.Lock()
+	defer fake.nodeScrapeStatsMutex.Unlock()
+	fake.NodeScrapeStatsStub = stub
+}
+
+func (fake *FakeNodeScrapeSource) NodeScrapeStatsReturns(result1 []metrics.NodeScrapeMessage) {
+	fake.nodeScrapeStatsMutex.Lock()
+	defer fake.nodeScrapeStatsMutex.Unlock()
+	fake.NodeScrapeStatsStub = nil
+	fake.nodeScrapeStatsReturns = struct {
+		result1 []metrics.NodeScrapeMessage
+	}{result1}
+}
+
+func (fake *FakeNodeScrapeSource) NodeScrapeStatsReturnsOnCall(i int, result1 []metrics.NodeScrapeMessage) {
+	fake.nodeScrapeStatsMutex.Lock()
+	defer fake.nodeScrapeStatsMutex.Unlock()
+	fake.NodeScrapeStatsStub = nil
+	if fake.nodeScrapeStatsReturnsOnCall == nil {
+		fake.nodeScrapeStatsReturnsOnCall = make(map[int]struct {
+			result1 []metrics.NodeScrapeMessage
+		})
+	}
+	fake.nodeScrapeStatsReturnsOnCall[i] = struct {
+		result1 []metrics.NodeScrapeMessage
+	}{result1}
+}
+
+func (fake *FakeNodeScrapeSource) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.nodeScrapeStatsMutex.RLock()
+	defer fake.nodeScrapeStatsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeNodeScrapeSource) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ k8s.NodeScrapeSource = new(FakeNodeScrapeSource)
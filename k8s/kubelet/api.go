@@ -4,18 +4,18 @@ import (
 	"context"
 
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 //counterfeiter:generate . API
-//counterfeiter:generate . NodeAPI
+//counterfeiter:generate . NodeGetter
 
 type API interface {
-	StatsSummary(nodename string) (StatsSummary, error)
+	StatsSummary(ctx context.Context, nodename string) (StatsSummary, error)
 }
 
-type NodeAPI interface {
-	List(ctx context.Context, opts metav1.ListOptions) (*corev1.NodeList, error)
+// NodeGetter is usually backed by a node informer cache
+type NodeGetter interface {
+	Get(name string) (*corev1.Node, error)
 }
 
 type StatsSummary struct {
@@ -28,6 +28,7 @@ type PodStats struct {
 }
 
 type ContainerStats struct {
+	Name   string   `json:"name"`
 	Rootfs *FsStats `json:"rootfs,omitempty"`
 	Logs   *FsStats `json:"logs,omitempty"`
 }
@@ -2,16 +2,18 @@
 package kubeletfakes
 
 import (
+	"context"
 	"sync"
 
 	"code.cloudfoundry.org/eirini/k8s/kubelet"
 )
 
 type FakeAPI struct {
-	StatsSummaryStub        func(string) (kubelet.StatsSummary, error)
+	StatsSummaryStub        func(context.Context, string) (kubelet.StatsSummary, error)
 	statsSummaryMutex       sync.RWMutex
 	statsSummaryArgsForCall []struct {
-		arg1 string
+		arg1 context.Context
+		arg2 string
 	}
 	statsSummaryReturns struct {
 		result1 kubelet.StatsSummary
@@ -25,18 +27,19 @@ type FakeAPI struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeAPI) StatsSummary(arg1 string) (kubelet.StatsSummary, error) {
+func (fake *FakeAPI) StatsSummary(arg1 context.Context, arg2 string) (kubelet.StatsSummary, error) {
 	fake.statsSummaryMutex.Lock()
 	ret, specificReturn := fake.statsSummaryReturnsOnCall[len(fake.statsSummaryArgsForCall)]
 	fake.statsSummaryArgsForCall = append(fake.statsSummaryArgsForCall, struct {
-		arg1 string
-	}{arg1})
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
 	stub := fake.StatsSummaryStub
 	fakeReturns := fake.statsSummaryReturns
-	fake.recordInvocation("StatsSummary", []interface{}{arg1})
+	fake.recordInvocation("StatsSummary", []interface{}{arg1, arg2})
 	fake.statsSummaryMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -50,17 +53,17 @@ func (fake *FakeAPI) StatsSummaryCallCount() int {
 	return len(fake.statsSummaryArgsForCall)
 }
 
-func (fake *FakeAPI) StatsSummaryCalls(stub func(string) (kubelet.StatsSummary, error)) {
+func (fake *FakeAPI) StatsSummaryCalls(stub func(context.Context, string) (kubelet.StatsSummary, error)) {
 	fake.statsSummaryMutex.Lock()
 	defer fake.statsSummaryMutex.Unlock()
 	fake.StatsSummaryStub = stub
 }
 
-func (fake *FakeAPI) StatsSummaryArgsForCall(i int) string {
+func (fake *FakeAPI) StatsSummaryArgsForCall(i int) (context.Context, string) {
 	fake.statsSummaryMutex.RLock()
 	defer fake.statsSummaryMutex.RUnlock()
 	argsForCall := fake.statsSummaryArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeAPI) StatsSummaryReturns(result1 kubelet.StatsSummary, result2 error) {
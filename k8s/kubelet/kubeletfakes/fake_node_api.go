@@ -1,121 +0,0 @@
-// Code generated by counterfeiter. DO NOT EDIT.
-package kubeletfakes
-
-import (
-	"context"
-	"sync"
-
-	"code.cloudfoundry.org/eirini/k8s/kubelet"
-	v1 "k8s.io/api/core/v1"
-	v1a "k8s.io/apimachinery/pkg/apis/meta/v1"
-)
-
-type FakeNodeAPI struct {
-	ListStub        func(context.Context, v1a.ListOptions) (*v1.NodeList, error)
-	listMutex       sync.RWMutex
-	listArgsForCall []struct {
-		arg1 context.Context
-		arg2 v1a.ListOptions
-	}
-	listReturns struct {
-		result1 *v1.NodeList
-		result2 error
-	}
-	listReturnsOnCall map[int]struct {
-		result1 *v1.NodeList
-		result2 error
-	}
-	invocations      map[string][][]interface{}
-	invocationsMutex sync.RWMutex
-}
-
-func (fake *FakeNodeAPI) List(arg1 context.Context, arg2 v1a.ListOptions) (*v1.NodeList, error) {
-	fake.listMutex.Lock()
-	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
-	fake.listArgsForCall = append(fake.listArgsForCall, struct {
-		arg1 context.Context
-		arg2 v1a.ListOptions
-	}{arg1, arg2})
-	stub := fake.ListStub
-	fakeReturns := fake.listReturns
-	fake.recordInvocation("List", []interface{}{arg1, arg2})
-	fake.listMutex.Unlock()
-	if stub != nil {
-		return stub(arg1, arg2)
-	}
-	if specificReturn {
-		return ret.result1, ret.result2
-	}
-	return fakeReturns.result1, fakeReturns.result2
-}
-
-func (fake *FakeNodeAPI) ListCallCount() int {
-	fake.listMutex.RLock()
-	defer fake.listMutex.RUnlock()
-	return len(fake.listArgsForCall)
-}
-
-func (fake *FakeNodeAPI) ListCalls(stub func(context.Context, v1a.ListOptions) (*v1.NodeList, error)) {
-	fake.listMutex.Lock()
-	defer fake.listMutex.Unlock()
-	fake.ListStub = stub
-}
-
-func (fake *FakeNodeAPI) ListArgsForCall(i int) (context.Context, v1a.ListOptions) {
-	fake.listMutex.RLock()
-	defer fake.listMutex.RUnlock()
-	argsForCall := fake.listArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
-}
-
-func (fake *FakeNodeAPI) ListReturns(result1 *v1.NodeList, result2 error) {
-	fake.listMutex.Lock()
-	defer fake.listMutex.Unlock()
-	fake.ListStub = nil
-	fake.listReturns = struct {
-		result1 *v1.NodeList
-		result2 error
-	}{result1, result2}
-}
-
-func (fake *FakeNodeAPI) ListReturnsOnCall(i int, result1 *v1.NodeList, result2 error) {
-	fake.listMutex.Lock()
-	defer fake.listMutex.Unlock()
-	fake.ListStub = nil
-	if fake.listReturnsOnCall == nil {
-		fake.listReturnsOnCall = make(map[int]struct {
-			result1 *v1.NodeList
-			result2 error
-		})
-	}
-	fake.listReturnsOnCall[i] = struct {
-		result1 *v1.NodeList
-		result2 error
-	}{result1, result2}
-}
-
-func (fake *FakeNodeAPI) Invocations() map[string][][]interface{} {
-	fake.invocationsMutex.RLock()
-	defer fake.invocationsMutex.RUnlock()
-	fake.listMutex.RLock()
-	defer fake.listMutex.RUnlock()
-	copiedInvocations := map[string][][]interface{}{}
-	for key, value := range fake.invocations {
-		copiedInvocations[key] = value
-	}
-	return copiedInvocations
-}
-
-func (fake *FakeNodeAPI) recordInvocation(key string, args []interface{}) {
-	fake.invocationsMutex.Lock()
-	defer fake.invocationsMutex.Unlock()
-	if fake.invocations == nil {
-		fake.invocations = map[string][][]interface{}{}
-	}
-	if fake.invocations[key] == nil {
-		fake.invocations[key] = [][]interface{}{}
-	}
-	fake.invocations[key] = append(fake.invocations[key], args)
-}
-
-var _ kubelet.NodeAPI = new(FakeNodeAPI)
@@ -2,55 +2,233 @@ package kubelet
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"code.cloudfoundry.org/eirini/metrics"
 	"code.cloudfoundry.org/lager"
 	"github.com/pkg/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	DefaultScrapeConcurrency = 10
+	DefaultScrapeTimeout     = 5 * time.Second
 )
 
 type DiskMetricsClient struct {
-	nodeClient    NodeAPI
+	nodeGetter    NodeGetter
 	kubeletClient API
+	concurrency   int
+	timeout       time.Duration
 	logger        lager.Logger
+
+	lock        sync.Mutex
+	firstSeen   map[string]time.Time
+	lastScraped map[string]time.Time
+	failures    map[string]float64
+
+	stalenessDesc *prometheus.Desc
+	failuresDesc  *prometheus.Desc
 }
 
-func NewDiskMetricsClient(nodeClient NodeAPI, kubeletClient API, logger lager.Logger) DiskMetricsClient {
-	return DiskMetricsClient{
-		nodeClient:    nodeClient,
+func NewDiskMetricsClient(nodeGetter NodeGetter, kubeletClient API, concurrency int, timeout time.Duration, logger lager.Logger) *DiskMetricsClient {
+	if concurrency <= 0 {
+		concurrency = DefaultScrapeConcurrency
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultScrapeTimeout
+	}
+
+	return &DiskMetricsClient{
+		nodeGetter:    nodeGetter,
 		kubeletClient: kubeletClient,
+		concurrency:   concurrency,
+		timeout:       timeout,
 		logger:        logger,
+		firstSeen:     map[string]time.Time{},
+		lastScraped:   map[string]time.Time{},
+		failures:      map[string]float64{},
+		stalenessDesc: prometheus.NewDesc(
+			"eirini_kubelet_scrape_staleness_seconds",
+			"Time since the stats summary of the node was last scraped successfully",
+			[]string{"node"},
+			nil,
+		),
+		failuresDesc: prometheus.NewDesc(
+			"eirini_kubelet_scrape_failures_total",
+			"Number of failed attempts to scrape the stats summary of the node",
+			[]string{"node"},
+			nil,
+		),
 	}
 }
 
-func (d DiskMetricsClient) GetPodMetrics() (map[string]float64, error) {
-	metrics := map[string]float64{}
-	pods := []PodStats{}
+// GetPodMetrics returns the disk usage of every container of the given pods,
+// keyed by pod name and then by container name. Only the nodes the pods are
+// scheduled on are scraped, and the scrape stats of any other node are
+// dropped, so that nodes which no longer host eirini pods are not reported as
+// stale.
+func (d *DiskMetricsClient) GetPodMetrics(pods []corev1.Pod) (map[string]map[string]float64, error) {
+	wanted := map[string]bool{}
+	nodes := map[string]bool{}
 
-	nodes, err := d.nodeClient.List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		return metrics, errors.Wrap(err, "failed to list nodes")
+	for _, p := range pods {
+		wanted[p.Name] = true
+
+		if p.Spec.NodeName != "" {
+			nodes[p.Spec.NodeName] = true
+		}
 	}
 
-	for _, n := range nodes.Items {
-		statsSummary, err := d.kubeletClient.StatsSummary(n.Name)
-		if err != nil {
-			d.logger.Error("failed-to-get-stats-summary", err, lager.Data{"node-name": n.Name})
+	metrics := map[string]map[string]float64{}
+	results := make(chan []PodStats)
+	semaphore := make(chan struct{}, d.concurrency)
+
+	var wg sync.WaitGroup
+
+	for nodeName := range nodes {
+		if _, err := d.nodeGetter.Get(nodeName); err != nil {
+			d.logger.Debug("skipping-unknown-node", lager.Data{"node-name": nodeName, "error": err.Error()})
+			delete(nodes, nodeName)
+
+			continue
 		}
 
-		pods = append(pods, statsSummary.Pods...)
+		wg.Add(1)
+
+		go func(nodeName string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results <- d.scrapeNode(nodeName)
+		}(nodeName)
 	}
 
-	for _, p := range pods {
-		if len(p.Containers) != 0 {
-			logsBytes := getUsedBytes(p.Containers[0].Logs)
-			rootfsBytes := getUsedBytes(p.Containers[0].Rootfs)
-			metrics[p.PodRef.Name] = logsBytes + rootfsBytes
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for podStats := range results {
+		for _, p := range podStats {
+			if !wanted[p.PodRef.Name] || len(p.Containers) == 0 {
+				continue
+			}
+
+			containerMetrics := map[string]float64{}
+
+			for _, c := range p.Containers {
+				containerMetrics[c.Name] = getUsedBytes(c.Logs) + getUsedBytes(c.Rootfs)
+			}
+
+			metrics[p.PodRef.Name] = containerMetrics
 		}
 	}
 
+	d.forgetNodesOtherThan(nodes)
+
 	return metrics, nil
 }
 
+func (d *DiskMetricsClient) scrapeNode(nodeName string) []PodStats {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	d.lock.Lock()
+	if _, ok := d.firstSeen[nodeName]; !ok {
+		d.firstSeen[nodeName] = time.Now()
+	}
+	d.lock.Unlock()
+
+	statsSummary, err := d.kubeletClient.StatsSummary(ctx, nodeName)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if err != nil {
+		d.failures[nodeName]++
+
+		d.logger.Error("failed-to-get-stats-summary", errors.Wrap(err, "node unreachable"), lager.Data{
+			"node-name": nodeName,
+			"staleness": d.staleness(nodeName).String(),
+		})
+
+		return nil
+	}
+
+	d.lastScraped[nodeName] = time.Now()
+
+	return statsSummary.Pods
+}
+
+func (d *DiskMetricsClient) forgetNodesOtherThan(nodes map[string]bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for nodeName := range d.firstSeen {
+		if nodes[nodeName] {
+			continue
+		}
+
+		delete(d.firstSeen, nodeName)
+		delete(d.lastScraped, nodeName)
+		delete(d.failures, nodeName)
+	}
+}
+
+// staleness is the time since the node was last scraped successfully, or
+// since it was first seen when it never was. It must be called with the lock
+// held
+func (d *DiskMetricsClient) staleness(nodeName string) time.Duration {
+	if lastScraped, ok := d.lastScraped[nodeName]; ok {
+		return time.Since(lastScraped)
+	}
+
+	return time.Since(d.firstSeen[nodeName])
+}
+
+// NodeScrapeStats returns the staleness and the failed scrapes of every node
+// the client has tried to scrape
+func (d *DiskMetricsClient) NodeScrapeStats() []metrics.NodeScrapeMessage {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	stats := make([]metrics.NodeScrapeMessage, 0, len(d.firstSeen))
+
+	for nodeName := range d.firstSeen {
+		stats = append(stats, metrics.NodeScrapeMessage{
+			Node:      nodeName,
+			Staleness: d.staleness(nodeName).Seconds(),
+			Failures:  d.failures[nodeName],
+		})
+	}
+
+	return stats
+}
+
+func (d *DiskMetricsClient) Describe(ch chan<- *prometheus.Desc) {
+	ch <- d.stalenessDesc
+	ch <- d.failuresDesc
+}
+
+func (d *DiskMetricsClient) Collect(ch chan<- prometheus.Metric) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for nodeName := range d.firstSeen {
+		ch <- prometheus.MustNewConstMetric(d.stalenessDesc, prometheus.GaugeValue, d.staleness(nodeName).Seconds(), nodeName)
+	}
+
+	for nodeName, failures := range d.failures {
+		ch <- prometheus.MustNewConstMetric(d.failuresDesc, prometheus.CounterValue, failures, nodeName)
+	}
+}
+
 func getUsedBytes(stats *FsStats) float64 {
 	if stats == nil || stats.UsedBytes == nil {
 		return 0
@@ -1,23 +1,33 @@
 package kubelet_test
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
 
 	"code.cloudfoundry.org/eirini/k8s/kubelet"
 	"code.cloudfoundry.org/eirini/k8s/kubelet/kubeletfakes"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("Stats", func() {
 	var (
-		diskMetricsClient kubelet.DiskMetricsClient
-		nodeClient        *kubeletfakes.FakeNodeAPI
+		diskMetricsClient *kubelet.DiskMetricsClient
+		nodeGetter        *kubeletfakes.FakeNodeGetter
 		kubeletClient     *kubeletfakes.FakeAPI
 		logger            *lagertest.TestLogger
+		pods              []corev1.Pod
+		summaries         map[string]kubelet.StatsSummary
 	)
 
 	createStatsSummary := func(podName string, namespace string, rootfsBytes, logsBytes uint64) kubelet.StatsSummary {
@@ -30,6 +40,7 @@ var _ = Describe("Stats", func() {
 					},
 					Containers: []kubelet.ContainerStats{
 						{
+							Name: "opi",
 							Rootfs: &kubelet.FsStats{
 								UsedBytes: &rootfsBytes,
 							},
@@ -43,108 +54,290 @@ var _ = Describe("Stats", func() {
 		}
 	}
 
+	createPod := func(podName, nodeName string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: podName},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+		}
+	}
+
 	BeforeEach(func() {
-		nodeClient = new(kubeletfakes.FakeNodeAPI)
+		nodeGetter = new(kubeletfakes.FakeNodeGetter)
 		kubeletClient = new(kubeletfakes.FakeAPI)
 		logger = lagertest.NewTestLogger("statstest")
 
-		diskMetricsClient = kubelet.NewDiskMetricsClient(nodeClient, kubeletClient, logger)
-	})
+		summaries = map[string]kubelet.StatsSummary{
+			"node1": createStatsSummary("pod-1", "ns-1", 300, 700),
+			"node2": createStatsSummary("pod-2", "ns-2", 200, 256),
+		}
+		kubeletClient.StatsSummaryStub = func(_ context.Context, nodeName string) (kubelet.StatsSummary, error) {
+			return summaries[nodeName], nil
+		}
+		nodeGetter.GetStub = func(name string) (*corev1.Node, error) {
+			return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+		}
 
-	It("should return the disk metrics for all pods on all nodes", func() {
-		nodeClient.ListReturns(&corev1.NodeList{
-			Items: []corev1.Node{
-				{ObjectMeta: metav1.ObjectMeta{
-					Name: "node1",
-				}},
-				{ObjectMeta: metav1.ObjectMeta{
-					Name: "node2",
-				}},
-			},
-		}, nil)
-		kubeletClient.StatsSummaryReturnsOnCall(0, createStatsSummary("pod-1", "ns-1", 300, 700), nil)
-		kubeletClient.StatsSummaryReturnsOnCall(1, createStatsSummary("pod-2", "ns-2", 200, 256), nil)
+		pods = []corev1.Pod{createPod("pod-1", "node1"), createPod("pod-2", "node2")}
 
-		metrics, err := diskMetricsClient.GetPodMetrics()
+		diskMetricsClient = kubelet.NewDiskMetricsClient(nodeGetter, kubeletClient, 2, time.Second, logger)
+	})
+
+	It("should return the disk metrics for all pods on their nodes", func() {
+		metrics, err := diskMetricsClient.GetPodMetrics(pods)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(nodeClient.ListCallCount()).To(Equal(1))
 		Expect(kubeletClient.StatsSummaryCallCount()).To(Equal(2))
-		Expect(kubeletClient.StatsSummaryArgsForCall(0)).To(Equal("node1"))
-		Expect(kubeletClient.StatsSummaryArgsForCall(1)).To(Equal("node2"))
-		Expect(metrics).To(HaveKeyWithValue("pod-1", float64(1000)))
-		Expect(metrics).To(HaveKeyWithValue("pod-2", float64(456)))
+
+		_, node1 := kubeletClient.StatsSummaryArgsForCall(0)
+		_, node2 := kubeletClient.StatsSummaryArgsForCall(1)
+		Expect([]string{node1, node2}).To(ConsistOf("node1", "node2"))
+		Expect(metrics).To(HaveKeyWithValue("pod-1", map[string]float64{"opi": 1000}))
+		Expect(metrics).To(HaveKeyWithValue("pod-2", map[string]float64{"opi": 456}))
+	})
+
+	It("should scrape each node only once", func() {
+		pods = []corev1.Pod{createPod("pod-1", "node1"), createPod("pod-3", "node1")}
+
+		_, err := diskMetricsClient.GetPodMetrics(pods)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeletClient.StatsSummaryCallCount()).To(Equal(1))
+	})
+
+	It("should not scrape nodes without eirini pods", func() {
+		pods = []corev1.Pod{createPod("pod-1", "node1"), createPod("pending-pod", "")}
+
+		_, err := diskMetricsClient.GetPodMetrics(pods)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeletClient.StatsSummaryCallCount()).To(Equal(1))
+
+		_, nodeName := kubeletClient.StatsSummaryArgsForCall(0)
+		Expect(nodeName).To(Equal("node1"))
+	})
+
+	It("should only return metrics for the requested pods", func() {
+		summaries["node1"].Pods[0].PodRef.Name = "not-an-eirini-pod"
+
+		metrics, err := diskMetricsClient.GetPodMetrics(pods)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(metrics).To(HaveLen(1))
+		Expect(metrics).To(HaveKey("pod-2"))
 	})
 
-	When("the node client return an error", func() {
-		It("should return an error", func() {
-			nodeClient.ListReturns(&corev1.NodeList{}, errors.New("oopsie"))
-			_, err := diskMetricsClient.GetPodMetrics()
-			Expect(err).To(HaveOccurred())
-			Expect(err).To(MatchError(ContainSubstring("oopsie")))
+	It("should not scrape more nodes than the concurrency limit at a time", func() {
+		var inFlight, maxInFlight int32
+
+		kubeletClient.StatsSummaryStub = func(_ context.Context, nodeName string) (kubelet.StatsSummary, error) {
+			current := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+
+			return kubelet.StatsSummary{}, nil
+		}
+
+		pods = []corev1.Pod{
+			createPod("pod-1", "node1"),
+			createPod("pod-2", "node2"),
+			createPod("pod-3", "node3"),
+			createPod("pod-4", "node4"),
+			createPod("pod-5", "node5"),
+		}
+
+		_, err := diskMetricsClient.GetPodMetrics(pods)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeletClient.StatsSummaryCallCount()).To(Equal(5))
+		Expect(atomic.LoadInt32(&maxInFlight)).To(BeNumerically("<=", 2))
+	})
+
+	It("should scrape each node with a timeout", func() {
+		kubeletClient.StatsSummaryStub = func(ctx context.Context, nodeName string) (kubelet.StatsSummary, error) {
+			<-ctx.Done()
+
+			return kubelet.StatsSummary{}, ctx.Err()
+		}
+		diskMetricsClient = kubelet.NewDiskMetricsClient(nodeGetter, kubeletClient, 2, 10*time.Millisecond, logger)
+
+		metrics, err := diskMetricsClient.GetPodMetrics(pods)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(metrics).To(BeEmpty())
+		Expect(logger.LogMessages()).To(ConsistOf(
+			"statstest.failed-to-get-stats-summary",
+			"statstest.failed-to-get-stats-summary",
+		))
+	})
+
+	When("a node is not in the node cache", func() {
+		BeforeEach(func() {
+			nodeGetter.GetStub = func(name string) (*corev1.Node, error) {
+				if name == "node1" {
+					return nil, errors.New("not found")
+				}
+
+				return &corev1.Node{}, nil
+			}
+		})
+
+		It("should skip that node", func() {
+			metrics, err := diskMetricsClient.GetPodMetrics(pods)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(kubeletClient.StatsSummaryCallCount()).To(Equal(1))
+			Expect(metrics).To(HaveLen(1))
+			Expect(metrics).To(HaveKey("pod-2"))
+		})
+	})
+
+	When("a pod has sidecar containers", func() {
+		It("should return the disk metrics for each container by name", func() {
+			sidecarBytes := uint64(42)
+			summaries["node1"].Pods[0].Containers = append([]kubelet.ContainerStats{{
+				Name:   "sidecar",
+				Rootfs: &kubelet.FsStats{UsedBytes: &sidecarBytes},
+			}}, summaries["node1"].Pods[0].Containers...)
+
+			metrics, err := diskMetricsClient.GetPodMetrics(pods)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(metrics).To(HaveKeyWithValue("pod-1", map[string]float64{
+				"opi":     1000,
+				"sidecar": 42,
+			}))
 		})
 	})
 
 	When("there are no containers in the pod stats", func() {
 		It("the pod should be ignored", func() {
-			nodeClient.ListReturns(&corev1.NodeList{
-				Items: []corev1.Node{
-					{ObjectMeta: metav1.ObjectMeta{
-						Name: "node1",
-					}},
-					{ObjectMeta: metav1.ObjectMeta{
-						Name: "node2",
-					}},
-				},
-			}, nil)
-			stats := createStatsSummary("pod-1", "ns-1", 300, 700)
-			stats.Pods[0].Containers = nil
-			kubeletClient.StatsSummaryReturnsOnCall(0, stats, nil)
-			kubeletClient.StatsSummaryReturnsOnCall(1, createStatsSummary("pod-2", "ns-1", 200, 256), nil)
+			summaries["node1"].Pods[0].Containers = nil
 
-			metrics, _ := diskMetricsClient.GetPodMetrics()
+			metrics, _ := diskMetricsClient.GetPodMetrics(pods)
 			Expect(metrics).To(HaveLen(1))
-			Expect(metrics).To(HaveKeyWithValue("pod-2", float64(456)))
+			Expect(metrics).To(HaveKeyWithValue("pod-2", map[string]float64{"opi": 456}))
 		})
 	})
 
 	When("the kubeletClient returns an error for a node", func() {
-		It("should ignore that node", func() {
-			nodeClient.ListReturns(&corev1.NodeList{
-				Items: []corev1.Node{
-					{ObjectMeta: metav1.ObjectMeta{
-						Name: "node1",
-					}},
-				},
-			}, nil)
-			kubeletClient.StatsSummaryReturnsOnCall(0, kubelet.StatsSummary{}, errors.New("oopsie"))
+		BeforeEach(func() {
+			kubeletClient.StatsSummaryStub = nil
+			kubeletClient.StatsSummaryReturns(kubelet.StatsSummary{}, errors.New("oopsie"))
+			pods = []corev1.Pod{createPod("pod-1", "node1")}
+		})
 
-			metrics, _ := diskMetricsClient.GetPodMetrics()
+		It("should ignore that node", func() {
+			metrics, _ := diskMetricsClient.GetPodMetrics(pods)
 			Expect(metrics).To(BeEmpty())
 			logs := logger.Logs()
 			Expect(logs).To(HaveLen(1))
 			Expect(logs[0].Data).To(HaveKeyWithValue("node-name", "node1"))
-			Expect(logs[0].Data).To(HaveKeyWithValue("error", "oopsie"))
+			Expect(logs[0].Data).To(HaveKeyWithValue("error", ContainSubstring("oopsie")))
+		})
+
+		It("should report the scrape failures and staleness", func() {
+			kubeletClient.StatsSummaryReturnsOnCall(0, summaries["node1"], nil)
+
+			_, err := diskMetricsClient.GetPodMetrics(pods)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = diskMetricsClient.GetPodMetrics(pods)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(logger.Logs()).To(HaveLen(1))
+			Expect(logger.Logs()[0].Data).To(HaveKey("staleness"))
+
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(diskMetricsClient)
+			server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`eirini_kubelet_scrape_failures_total{node="node1"} 1`))
+			Expect(string(body)).To(ContainSubstring(`eirini_kubelet_scrape_staleness_seconds{node="node1"}`))
+		})
+
+		When("the node has never been scraped successfully", func() {
+			It("should report the staleness since the first attempt", func() {
+				_, err := diskMetricsClient.GetPodMetrics(pods)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(logger.Logs()[0].Data).To(HaveKey("staleness"))
+
+				stats := diskMetricsClient.NodeScrapeStats()
+				Expect(stats).To(HaveLen(1))
+				Expect(stats[0].Node).To(Equal("node1"))
+				Expect(stats[0].Failures).To(Equal(float64(1)))
+
+				staleness := stats[0].Staleness
+				Eventually(func() float64 {
+					return diskMetricsClient.NodeScrapeStats()[0].Staleness
+				}).Should(BeNumerically(">", staleness))
+			})
+		})
+	})
+
+	It("should report the nodes it has scraped", func() {
+		_, err := diskMetricsClient.GetPodMetrics(pods)
+		Expect(err).ToNot(HaveOccurred())
+
+		stats := diskMetricsClient.NodeScrapeStats()
+		Expect(stats).To(HaveLen(2))
+		for _, s := range stats {
+			Expect(s.Node).To(BeElementOf("node1", "node2"))
+			Expect(s.Failures).To(BeZero())
+		}
+	})
+
+	When("a node no longer hosts eirini pods", func() {
+		BeforeEach(func() {
+			kubeletClient.StatsSummaryStub = func(_ context.Context, nodeName string) (kubelet.StatsSummary, error) {
+				if nodeName == "node1" {
+					return kubelet.StatsSummary{}, errors.New("oopsie")
+				}
+
+				return summaries[nodeName], nil
+			}
+		})
+
+		It("should stop reporting it", func() {
+			_, err := diskMetricsClient.GetPodMetrics(pods)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskMetricsClient.NodeScrapeStats()).To(HaveLen(2))
+
+			_, err = diskMetricsClient.GetPodMetrics([]corev1.Pod{createPod("pod-2", "node2")})
+			Expect(err).ToNot(HaveOccurred())
+
+			stats := diskMetricsClient.NodeScrapeStats()
+			Expect(stats).To(HaveLen(1))
+			Expect(stats[0].Node).To(Equal("node2"))
+
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(diskMetricsClient)
+			families, err := registry.Gather()
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, family := range families {
+				for _, metric := range family.GetMetric() {
+					Expect(metric.GetLabel()[0].GetValue()).To(Equal("node2"))
+				}
+			}
 		})
 	})
 
 	When("the disk metrics for a pod are missing", func() {
 		It("should report the used bytes as zero", func() {
-			nodeClient.ListReturns(&corev1.NodeList{
-				Items: []corev1.Node{
-					{ObjectMeta: metav1.ObjectMeta{
-						Name: "node1",
-					}},
-				},
-			}, nil)
-
-			stats := createStatsSummary("pod-1", "ns-1", 300, 700)
-			stats.Pods[0].Containers[0].Rootfs = nil
-			stats.Pods[0].Containers[0].Logs.UsedBytes = nil
-			kubeletClient.StatsSummaryReturnsOnCall(0, stats, nil)
+			summaries["node1"].Pods[0].Containers[0].Rootfs = nil
+			summaries["node1"].Pods[0].Containers[0].Logs.UsedBytes = nil
+			pods = []corev1.Pod{createPod("pod-1", "node1")}
 
-			metrics, _ := diskMetricsClient.GetPodMetrics()
+			metrics, _ := diskMetricsClient.GetPodMetrics(pods)
 			Expect(metrics).To(HaveLen(1))
-			Expect(metrics).To(HaveKeyWithValue("pod-1", float64(0)))
+			Expect(metrics).To(HaveKeyWithValue("pod-1", map[string]float64{"opi": 0}))
 		})
 	})
 })
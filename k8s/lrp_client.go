@@ -30,6 +30,7 @@ type StatefulSetClient interface {
 
 type SecretsClient interface {
 	Create(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Update(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
 	Delete(namespace string, name string) error
 }
 
@@ -52,15 +53,16 @@ func NewLRPClient(
 	pods PodClient,
 	pdbs PodDisruptionBudgetClient,
 	events EventsClient,
+	services stset.ServicesClient,
 	lrpToStatefulSetConverter stset.LRPToStatefulSetConverter,
 	statefulSetToLRPConverter stset.StatefulSetToLRPConverter,
 
 ) *LRPClient {
 	return &LRPClient{
-		Desirer: stset.NewDesirer(logger, secrets, statefulSets, lrpToStatefulSetConverter, pdbs),
+		Desirer: stset.NewDesirer(logger, secrets, statefulSets, lrpToStatefulSetConverter, pdbs, services),
 		Lister:  stset.NewLister(logger, statefulSets, statefulSetToLRPConverter),
-		Stopper: stset.NewStopper(logger, statefulSets, statefulSets, pods, pdbs, secrets),
-		Updater: stset.NewUpdater(logger, statefulSets, statefulSets, pdbs, pdbs),
+		Stopper: stset.NewStopper(logger, statefulSets, statefulSets, pods, pdbs, secrets, services),
+		Updater: stset.NewUpdater(logger, statefulSets, statefulSets, pdbs, pdbs, services, secrets),
 		Getter:  stset.NewGetter(logger, statefulSets, pods, events, statefulSetToLRPConverter),
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strconv"
 
+	"code.cloudfoundry.org/eirini/k8s/jobs"
 	"code.cloudfoundry.org/eirini/k8s/stset"
 	"code.cloudfoundry.org/eirini/metrics"
 	"code.cloudfoundry.org/eirini/util"
@@ -19,6 +20,7 @@ import (
 //counterfeiter:generate . MetricsCollector
 //counterfeiter:generate . DiskAPI
 //counterfeiter:generate . Emitter
+//counterfeiter:generate . NodeScrapeSource
 //counterfeiter:generate -o k8sfakes/fake_pod_metrics_interface.go k8s.io/metrics/pkg/client/clientset/versioned/typed/metrics/v1beta1.PodMetricsInterface
 
 type PodsGetter interface {
@@ -30,13 +32,23 @@ type MetricsCollector interface {
 }
 
 type DiskAPI interface {
-	GetPodMetrics() (map[string]float64, error)
+	GetPodMetrics(pods []corev1.Pod) (map[string]map[string]float64, error)
 }
 
 type Emitter interface {
 	Emit(metrics.Message)
 }
 
+type NodeScrapeSource interface {
+	NodeScrapeStats() []metrics.NodeScrapeMessage
+}
+
+func ForwardNodeScrapesToEmitter(source NodeScrapeSource, emitter metrics.NodeScrapeEmitter) {
+	for _, m := range source.NodeScrapeStats() {
+		emitter.EmitNodeScrape(m)
+	}
+}
+
 func ForwardMetricsToEmitter(collector MetricsCollector, emitter Emitter) error {
 	messages, err := collector.Collect()
 	if err != nil {
@@ -55,6 +67,11 @@ type metricsCollector struct {
 	podsGetter    PodsGetter
 	diskClient    DiskAPI
 	logger        lager.Logger
+
+	// taskMemoryPeaks holds the highest memory usage seen so far for each
+	// running task, keyed by task GUID, so that it can be reported once the
+	// task completes and metrics-server no longer has its usage
+	taskMemoryPeaks map[string]float64
 }
 
 func NewMetricsCollector(metricsClient metricsv1beta1.PodMetricsInterface,
@@ -66,6 +83,8 @@ func NewMetricsCollector(metricsClient metricsv1beta1.PodMetricsInterface,
 		podsGetter:    podsGetter,
 		diskClient:    diskClient,
 		logger:        logger,
+
+		taskMemoryPeaks: map[string]float64{},
 	}
 }
 
@@ -81,7 +100,7 @@ func (c *metricsCollector) Collect() ([]metrics.Message, error) {
 func (c *metricsCollector) collectMetrics(pods []corev1.Pod) []metrics.Message {
 	logger := c.logger.Session("collect")
 
-	diskMetrics, err := c.diskClient.GetPodMetrics()
+	diskMetrics, err := c.diskClient.GetPodMetrics(pods)
 	if err != nil {
 		logger.Error("failed-to-get-disk-metrics", err, lager.Data{})
 	}
@@ -93,47 +112,148 @@ func (c *metricsCollector) collectMetrics(pods []corev1.Pod) []metrics.Message {
 		logger.Error("failed-to-get-metrics-from-kubernetes", err, lager.Data{})
 	}
 
+	seenTasks := map[string]bool{}
+
 	for _, pod := range pods {
+		usage := parseMetrics(podMetrics[pod.Name])
+		diskUsage := diskMetrics[pod.Name]
+
+		if pod.Labels[stset.LabelSourceType] == jobs.TaskSourceType {
+			taskGUID := pod.Labels[jobs.LabelGUID]
+			seenTasks[taskGUID] = true
+
+			if message, ok := c.taskMessage(pod, usage, diskUsage); ok {
+				messages = append(messages, message)
+			}
+
+			continue
+		}
+
 		indexID, err := util.ParseAppIndex(pod.Name)
 		if err != nil {
 			continue
 		}
 
-		cpuPercentage, memoryValue := parseMetrics(podMetrics[pod.Name])
+		message := metrics.Message{
+			AppID:     pod.Labels[stset.LabelGUID],
+			IndexID:   strconv.Itoa(indexID),
+			AppGUID:   pod.Labels[stset.LabelAppGUID],
+			OrgGUID:   pod.Labels[stset.LabelOrgGUID],
+			SpaceGUID: pod.Labels[stset.LabelSpaceGUID],
+		}
+		fillUsage(&message, pod, stset.OPIContainerName, usage, diskUsage)
 
-		appContainer := pod.Spec.Containers[0]
-		memoryLimit := appContainer.Resources.Limits.Memory()
-		diskLimit := appContainer.Resources.Limits.StorageEphemeral()
+		messages = append(messages, message)
+	}
 
-		diskUsage := diskMetrics[pod.Name]
+	for taskGUID := range c.taskMemoryPeaks {
+		if !seenTasks[taskGUID] {
+			delete(c.taskMemoryPeaks, taskGUID)
+		}
+	}
+
+	return messages
+}
+
+// taskMessage reports task pods with the app GUID as source and the task GUID
+// as instance. Completed tasks are reported once more with zero usage and the
+// peak memory seen while they were running.
+func (c *metricsCollector) taskMessage(pod corev1.Pod, usage map[string]containerUsage, diskUsage map[string]float64) (metrics.Message, bool) {
+	taskGUID := pod.Labels[jobs.LabelGUID]
+	message := metrics.Message{
+		AppID:     pod.Labels[jobs.LabelAppGUID],
+		IndexID:   taskGUID,
+		TaskGUID:  taskGUID,
+		AppGUID:   pod.Labels[jobs.LabelAppGUID],
+		OrgGUID:   pod.Annotations[jobs.AnnotationOrgGUID],
+		SpaceGUID: pod.Annotations[jobs.AnnotationSpaceGUID],
+	}
+	containerName := pod.Annotations[jobs.AnnotationOpiTaskContainerName]
+
+	if isCompleted(pod) {
+		peak, ok := c.taskMemoryPeaks[taskGUID]
+		if !ok {
+			return metrics.Message{}, false
+		}
+
+		delete(c.taskMemoryPeaks, taskGUID)
+		fillUsage(&message, pod, containerName, nil, nil)
+		message.MemoryPeak = peak
+		message.TaskCompleted = true
+
+		return message, true
+	}
+
+	fillUsage(&message, pod, containerName, usage, diskUsage)
+
+	if message.Memory > c.taskMemoryPeaks[taskGUID] {
+		c.taskMemoryPeaks[taskGUID] = message.Memory
+	}
+
+	message.MemoryPeak = c.taskMemoryPeaks[taskGUID]
+
+	return message, true
+}
+
+// fillUsage sets the usage and quotas of the main container and reports all
+// other containers of the pod as sidecars
+func fillUsage(message *metrics.Message, pod corev1.Pod, mainContainerName string, usage map[string]containerUsage, diskUsage map[string]float64) {
+	var podCPU, podMemory, cpuRequests, memoryRequests float64
+
+	for _, container := range pod.Spec.Containers {
+		containerUsage := usage[container.Name]
+		memoryLimit := container.Resources.Limits.Memory()
+		podCPU += containerUsage.cpu
+		podMemory += containerUsage.memory
+		cpuRequests += toCPUPercentage(container.Resources.Requests.Cpu().MilliValue())
+		memoryRequests += float64(container.Resources.Requests.Memory().Value())
+
+		if container.Name == mainContainerName {
+			diskLimit := container.Resources.Limits.StorageEphemeral()
+			message.CPU = containerUsage.cpu
+			message.Memory = containerUsage.memory
+			message.MemoryQuota = float64(memoryLimit.Value())
+			message.Disk = diskUsage[container.Name]
+			message.DiskQuota = float64(diskLimit.Value())
+
+			continue
+		}
 
-		messages = append(messages, metrics.Message{
-			AppID:       pod.Labels[stset.LabelGUID],
-			IndexID:     strconv.Itoa(indexID),
-			CPU:         cpuPercentage,
-			Memory:      memoryValue,
+		message.Sidecars = append(message.Sidecars, metrics.SidecarMessage{
+			Name:        container.Name,
+			CPU:         containerUsage.cpu,
+			Memory:      containerUsage.memory,
 			MemoryQuota: float64(memoryLimit.Value()),
-			Disk:        diskUsage,
-			DiskQuota:   float64(diskLimit.Value()),
+			Disk:        diskUsage[container.Name],
 		})
 	}
 
-	return messages
+	message.CPUEntitlement = toEntitlement(podCPU, cpuRequests)
+	message.MemoryEntitlement = toEntitlement(podMemory, memoryRequests)
 }
 
-func parseMetrics(metric v1beta1.PodMetrics) (cpu float64, memory float64) {
-	if len(metric.Containers) == 0 {
-		return
-	}
+func isCompleted(pod corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
 
-	container := metric.Containers[0]
-	usage := container.Usage
-	res := usage[corev1.ResourceCPU]
-	cpu = toCPUPercentage(res.MilliValue())
-	res = usage[corev1.ResourceMemory]
-	memory = float64(res.Value())
+type containerUsage struct {
+	cpu    float64
+	memory float64
+}
+
+func parseMetrics(metric v1beta1.PodMetrics) map[string]containerUsage {
+	usages := map[string]containerUsage{}
 
-	return
+	for _, container := range metric.Containers {
+		cpu := container.Usage[corev1.ResourceCPU]
+		memory := container.Usage[corev1.ResourceMemory]
+		usages[container.Name] = containerUsage{
+			cpu:    toCPUPercentage(cpu.MilliValue()),
+			memory: float64(memory.Value()),
+		}
+	}
+
+	return usages
 }
 
 func (c *metricsCollector) getPodMetrics() (map[string]v1beta1.PodMetrics, error) {
@@ -154,3 +274,11 @@ func (c *metricsCollector) getPodMetrics() (map[string]v1beta1.PodMetrics, error
 func toCPUPercentage(cpuMillicores int64) float64 {
 	return float64(cpuMillicores) / 10 //nolint:gomnd
 }
+
+func toEntitlement(usage, requests float64) float64 {
+	if requests == 0 {
+		return 0
+	}
+
+	return usage / requests * 100 //nolint:gomnd
+}
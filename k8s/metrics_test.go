@@ -2,9 +2,11 @@ package k8s_test
 
 import (
 	"code.cloudfoundry.org/eirini/k8s"
+	"code.cloudfoundry.org/eirini/k8s/jobs"
 	"code.cloudfoundry.org/eirini/k8s/k8sfakes"
 	"code.cloudfoundry.org/eirini/k8s/stset"
 	"code.cloudfoundry.org/eirini/metrics"
+	"code.cloudfoundry.org/eirini/metrics/metricsfakes"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -53,36 +55,112 @@ var _ = Describe("Metrics", func() {
 				podList := []v1.Pod{*createPod(podName1), *createPod(podName2)}
 				podsGetter.GetAllReturns(podList, nil)
 
-				diskClient.GetPodMetricsReturns(map[string]float64{
-					podName1: 50,
-					podName2: 88,
+				diskClient.GetPodMetricsReturns(map[string]map[string]float64{
+					podName1: {"opi": 50},
+					podName2: {"opi": 88},
 				}, nil)
 
 				collected, err := collector.Collect()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(collected).To(ConsistOf(
 					metrics.Message{
-						AppID:       podName1,
-						IndexID:     "9000",
-						CPU:         420.5,
-						Memory:      430080,
-						MemoryQuota: 800000,
-						Disk:        50,
-						DiskQuota:   10000000,
+						AppID:             podName1,
+						IndexID:           "9000",
+						AppGUID:           podName1 + "-app",
+						OrgGUID:           podName1 + "-org",
+						SpaceGUID:         podName1 + "-space",
+						CPU:               420.5,
+						CPUEntitlement:    210.25,
+						Memory:            430080,
+						MemoryEntitlement: 50,
+						MemoryQuota:       800000,
+						Disk:              50,
+						DiskQuota:         10000000,
 					},
 					metrics.Message{
-						AppID:       podName2,
-						IndexID:     "8000",
-						CPU:         420.5,
-						Memory:      430080,
-						MemoryQuota: 800000,
-						Disk:        88,
-						DiskQuota:   10000000,
+						AppID:             podName2,
+						IndexID:           "8000",
+						AppGUID:           podName2 + "-app",
+						OrgGUID:           podName2 + "-org",
+						SpaceGUID:         podName2 + "-space",
+						CPU:               420.5,
+						CPUEntitlement:    210.25,
+						Memory:            430080,
+						MemoryEntitlement: 50,
+						MemoryQuota:       800000,
+						Disk:              88,
+						DiskQuota:         10000000,
 					},
 				))
 			})
 		})
 
+		When("the pod has sidecars", func() {
+			BeforeEach(func() {
+				pod := createPod(podName1)
+				sidecar := v1.Container{
+					Name: "sidecar",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{
+							v1.ResourceMemory: *resource.NewScaledQuantity(200, resource.Kilo),
+						},
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    resource.MustParse("2"),
+							v1.ResourceMemory: resource.MustParse("840Ki"),
+						},
+					},
+				}
+				pod.Spec.Containers = append([]v1.Container{sidecar}, pod.Spec.Containers...)
+				podsGetter.GetAllReturns([]v1.Pod{*pod}, nil)
+
+				podMetrics := createMetrics(podName1)
+				podMetrics.Containers = append([]metricsv1beta1api.ContainerMetrics{{
+					Name: "sidecar",
+					Usage: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse("1795m"),
+						v1.ResourceMemory: resource.MustParse("420Ki"),
+					},
+				}}, podMetrics.Containers...)
+				podMetricsClient.ListReturns(&metricsv1beta1api.PodMetricsList{
+					Items: []metricsv1beta1api.PodMetrics{podMetrics},
+				}, nil)
+
+				diskClient.GetPodMetricsReturns(map[string]map[string]float64{
+					podName1: {"opi": 50, "sidecar": 10},
+				}, nil)
+			})
+
+			It("attributes the app metrics to the opi container regardless of its position", func() {
+				collected, err := collector.Collect()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collected).To(HaveLen(1))
+				Expect(collected[0].CPU).To(Equal(420.5))
+				Expect(collected[0].Memory).To(Equal(float64(430080)))
+				Expect(collected[0].MemoryQuota).To(Equal(float64(800000)))
+				Expect(collected[0].Disk).To(Equal(float64(50)))
+				Expect(collected[0].DiskQuota).To(Equal(float64(10000000)))
+			})
+
+			It("reports the sidecar metrics by container name", func() {
+				collected, err := collector.Collect()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collected[0].Sidecars).To(ConsistOf(metrics.SidecarMessage{
+					Name:        "sidecar",
+					CPU:         179.5,
+					Memory:      430080,
+					MemoryQuota: 200000,
+					Disk:        10,
+				}))
+			})
+
+			It("computes the entitlements from the summed pod requests", func() {
+				collected, err := collector.Collect()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collected[0].CPUEntitlement).To(Equal(float64(150)))
+				Expect(collected[0].MemoryEntitlement).To(Equal(float64(50)))
+			})
+		})
+
 		When("there are no pods", func() {
 			It("should return empty list", func() {
 				podsGetter.GetAllReturns([]v1.Pod{}, nil)
@@ -124,13 +202,18 @@ var _ = Describe("Metrics", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(collected).To(ConsistOf(
 					metrics.Message{
-						AppID:       podName1,
-						IndexID:     "9000",
-						CPU:         420.5,
-						Memory:      430080,
-						MemoryQuota: 800000,
-						Disk:        0,
-						DiskQuota:   10000000,
+						AppID:             podName1,
+						IndexID:           "9000",
+						AppGUID:           podName1 + "-app",
+						OrgGUID:           podName1 + "-org",
+						SpaceGUID:         podName1 + "-space",
+						CPU:               420.5,
+						CPUEntitlement:    210.25,
+						Memory:            430080,
+						MemoryEntitlement: 50,
+						MemoryQuota:       800000,
+						Disk:              0,
+						DiskQuota:         10000000,
 					}))
 			})
 		})
@@ -146,8 +229,8 @@ var _ = Describe("Metrics", func() {
 
 		When("there are no container metrics for a pod", func() {
 			It("should return only disk metrics", func() {
-				diskClient.GetPodMetricsReturns(map[string]float64{
-					podName1: 50,
+				diskClient.GetPodMetricsReturns(map[string]map[string]float64{
+					podName1: {"opi": 50},
 				}, nil)
 
 				podsGetter.GetAllReturns([]v1.Pod{*createPod(podName1)}, nil)
@@ -163,6 +246,9 @@ var _ = Describe("Metrics", func() {
 					metrics.Message{
 						AppID:       podName1,
 						IndexID:     "9000",
+						AppGUID:     podName1 + "-app",
+						OrgGUID:     podName1 + "-org",
+						SpaceGUID:   podName1 + "-space",
 						CPU:         0,
 						Memory:      0,
 						MemoryQuota: 800000,
@@ -174,7 +260,7 @@ var _ = Describe("Metrics", func() {
 
 		When("there are no disk metrics", func() {
 			It("should return only CPU/memory metrics", func() {
-				diskClient.GetPodMetricsReturns(map[string]float64{}, nil)
+				diskClient.GetPodMetricsReturns(map[string]map[string]float64{}, nil)
 				podMetrics := &metricsv1beta1api.PodMetricsList{
 					Items: []metricsv1beta1api.PodMetrics{
 						createMetrics(podName1),
@@ -189,13 +275,18 @@ var _ = Describe("Metrics", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(collected).To(ConsistOf(
 					metrics.Message{
-						AppID:       podName1,
-						IndexID:     "9000",
-						CPU:         420.5,
-						Memory:      430080,
-						MemoryQuota: 800000,
-						Disk:        0,
-						DiskQuota:   10000000,
+						AppID:             podName1,
+						IndexID:           "9000",
+						AppGUID:           podName1 + "-app",
+						OrgGUID:           podName1 + "-org",
+						SpaceGUID:         podName1 + "-space",
+						CPU:               420.5,
+						CPUEntitlement:    210.25,
+						Memory:            430080,
+						MemoryEntitlement: 50,
+						MemoryQuota:       800000,
+						Disk:              0,
+						DiskQuota:         10000000,
 					},
 				))
 			})
@@ -212,32 +303,131 @@ var _ = Describe("Metrics", func() {
 				}
 				podMetricsClient.ListReturns(&podMetrics, nil)
 
-				diskClient.GetPodMetricsReturns(map[string]float64{
-					aPodHasNoIndex: 50,
-					podName2:       88,
+				diskClient.GetPodMetricsReturns(map[string]map[string]float64{
+					aPodHasNoIndex: {"opi": 50},
+					podName2:       {"opi": 88},
 				}, nil)
 
 				collected, err := collector.Collect()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(collected).To(ConsistOf(metrics.Message{
-					AppID:       podName2,
-					IndexID:     "8000",
-					CPU:         420.5,
-					Memory:      430080,
-					MemoryQuota: 800000,
-					Disk:        88,
-					DiskQuota:   10000000,
+					AppID:             podName2,
+					IndexID:           "8000",
+					AppGUID:           podName2 + "-app",
+					OrgGUID:           podName2 + "-org",
+					SpaceGUID:         podName2 + "-space",
+					CPU:               420.5,
+					CPUEntitlement:    210.25,
+					Memory:            430080,
+					MemoryEntitlement: 50,
+					MemoryQuota:       800000,
+					Disk:              88,
+					DiskQuota:         10000000,
 				}))
 			})
 		})
 
+		When("there are task pods", func() {
+			const taskPodName = "app-space-task-12345"
+
+			var taskMetrics metricsv1beta1api.PodMetrics
+
+			BeforeEach(func() {
+				taskMetrics = createMetrics(taskPodName)
+				taskMetrics.Containers[0].Name = "opi-task"
+				podMetricsClient.ListReturns(&metricsv1beta1api.PodMetricsList{
+					Items: []metricsv1beta1api.PodMetrics{taskMetrics},
+				}, nil)
+				podsGetter.GetAllReturns([]v1.Pod{*createTaskPod(taskPodName)}, nil)
+				diskClient.GetPodMetricsReturns(map[string]map[string]float64{
+					taskPodName: {"opi-task": 50},
+				}, nil)
+			})
+
+			It("reports them with the app GUID as source and the task GUID as instance", func() {
+				collected, err := collector.Collect()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collected).To(ConsistOf(metrics.Message{
+					AppID:             "app-guid",
+					IndexID:           "task-guid",
+					TaskGUID:          "task-guid",
+					AppGUID:           "app-guid",
+					OrgGUID:           "org-guid",
+					SpaceGUID:         "space-guid",
+					CPU:               420.5,
+					CPUEntitlement:    210.25,
+					Memory:            430080,
+					MemoryEntitlement: 50,
+					MemoryQuota:       800000,
+					MemoryPeak:        430080,
+					Disk:              50,
+					DiskQuota:         10000000,
+				}))
+			})
+
+			It("keeps reporting the highest memory usage as peak", func() {
+				_, err := collector.Collect()
+				Expect(err).ToNot(HaveOccurred())
+
+				taskMetrics.Containers[0].Usage[v1.ResourceMemory] = resource.MustParse("100Ki")
+				podMetricsClient.ListReturns(&metricsv1beta1api.PodMetricsList{
+					Items: []metricsv1beta1api.PodMetrics{taskMetrics},
+				}, nil)
+
+				collected, err := collector.Collect()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(collected).To(HaveLen(1))
+				Expect(collected[0].Memory).To(Equal(float64(102400)))
+				Expect(collected[0].MemoryPeak).To(Equal(float64(430080)))
+			})
+
+			When("the task completes", func() {
+				BeforeEach(func() {
+					_, err := collector.Collect()
+					Expect(err).ToNot(HaveOccurred())
+
+					completedPod := createTaskPod(taskPodName)
+					completedPod.Status.Phase = v1.PodSucceeded
+					podsGetter.GetAllReturns([]v1.Pod{*completedPod}, nil)
+					podMetricsClient.ListReturns(&metricsv1beta1api.PodMetricsList{}, nil)
+				})
+
+				It("reports the peak memory once", func() {
+					collected, err := collector.Collect()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(collected).To(HaveLen(1))
+					Expect(collected[0].TaskCompleted).To(BeTrue())
+					Expect(collected[0].Memory).To(BeZero())
+					Expect(collected[0].MemoryPeak).To(Equal(float64(430080)))
+
+					collected, err = collector.Collect()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(collected).To(BeEmpty())
+				})
+			})
+
+			When("the task completed before it was ever collected", func() {
+				BeforeEach(func() {
+					completedPod := createTaskPod(taskPodName)
+					completedPod.Status.Phase = v1.PodFailed
+					podsGetter.GetAllReturns([]v1.Pod{*completedPod}, nil)
+				})
+
+				It("does not report it", func() {
+					collected, err := collector.Collect()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(collected).To(BeEmpty())
+				})
+			})
+		})
+
 		When("metrics client returns an error", func() {
 			BeforeEach(func() {
 				podList := []v1.Pod{*createPod(podName1)}
 				podsGetter.GetAllReturns(podList, nil)
 				podMetricsClient.ListReturns(&metricsv1beta1api.PodMetricsList{}, errors.New("oopsie"))
-				diskClient.GetPodMetricsReturns(map[string]float64{
-					podName1: 50,
+				diskClient.GetPodMetricsReturns(map[string]map[string]float64{
+					podName1: {"opi": 50},
 				}, nil)
 			})
 
@@ -252,6 +442,9 @@ var _ = Describe("Metrics", func() {
 					metrics.Message{
 						AppID:       podName1,
 						IndexID:     "9000",
+						AppGUID:     podName1 + "-app",
+						OrgGUID:     podName1 + "-org",
+						SpaceGUID:   podName1 + "-space",
 						CPU:         0,
 						Memory:      0,
 						MemoryQuota: 800000,
@@ -281,11 +474,26 @@ var _ = Describe("ForwardMetricsToEmitter", func() {
 	})
 })
 
+var _ = Describe("ForwardNodeScrapesToEmitter", func() {
+	It("should forward the scrape stats of every node", func() {
+		emitter := new(metricsfakes.FakeNodeScrapeEmitter)
+		source := new(k8sfakes.FakeNodeScrapeSource)
+		source.NodeScrapeStatsReturns([]metrics.NodeScrapeMessage{{Node: "node1"}, {Node: "node2"}})
+
+		k8s.ForwardNodeScrapesToEmitter(source, emitter)
+
+		Expect(emitter.EmitNodeScrapeCallCount()).To(Equal(2))
+		Expect(emitter.EmitNodeScrapeArgsForCall(0).Node).To(Equal("node1"))
+		Expect(emitter.EmitNodeScrapeArgsForCall(1).Node).To(Equal("node2"))
+	})
+})
+
 func createMetrics(podName string) metricsv1beta1api.PodMetrics {
 	return metricsv1beta1api.PodMetrics{
 		ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: "opi", ResourceVersion: "10", Labels: map[string]string{"key": "value"}},
 		Containers: []metricsv1beta1api.ContainerMetrics{
 			{
+				Name: "opi",
 				Usage: v1.ResourceList{
 					v1.ResourceCPU:    resource.MustParse("4205m"),
 					v1.ResourceMemory: resource.MustParse("420Ki"),
@@ -300,21 +508,46 @@ func createPod(podName string) *v1.Pod {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: podName,
 			Labels: map[string]string{
-				stset.LabelGUID: podName,
+				stset.LabelGUID:      podName,
+				stset.LabelAppGUID:   podName + "-app",
+				stset.LabelOrgGUID:   podName + "-org",
+				stset.LabelSpaceGUID: podName + "-space",
 			},
 			UID: types.UID(podName + "-uid"),
 		},
 		Spec: v1.PodSpec{
 			Containers: []v1.Container{
 				{
+					Name: "opi",
 					Resources: v1.ResourceRequirements{
 						Limits: v1.ResourceList{
 							v1.ResourceMemory:           *resource.NewScaledQuantity(800, resource.Kilo),
 							v1.ResourceEphemeralStorage: *resource.NewScaledQuantity(10, resource.Mega),
 						},
+						Requests: v1.ResourceList{
+							v1.ResourceCPU:    resource.MustParse("2"),
+							v1.ResourceMemory: resource.MustParse("840Ki"),
+						},
 					},
 				},
 			},
 		},
 	}
 }
+
+func createTaskPod(podName string) *v1.Pod {
+	pod := createPod(podName)
+	pod.Labels = map[string]string{
+		jobs.LabelGUID:       "task-guid",
+		jobs.LabelAppGUID:    "app-guid",
+		jobs.LabelSourceType: jobs.TaskSourceType,
+	}
+	pod.Annotations = map[string]string{
+		jobs.AnnotationOrgGUID:              "org-guid",
+		jobs.AnnotationSpaceGUID:            "space-guid",
+		jobs.AnnotationOpiTaskContainerName: "opi-task",
+	}
+	pod.Spec.Containers[0].Name = "opi-task"
+
+	return pod
+}
@@ -0,0 +1,41 @@
+package networkpolicy
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	PrometheusNamespace = "eirini"
+	PrometheusSubsystem = "policy_sync"
+)
+
+// Metrics describe the outcome of the last sync of a Syncer. A nil *Metrics
+// records nothing.
+type Metrics struct {
+	skippedPolicies prometheus.Gauge
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		skippedPolicies: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "skipped_policies",
+			Help:      "Number of policies the last sync could not turn into network policies",
+		}),
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.skippedPolicies.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.skippedPolicies.Collect(ch)
+}
+
+func (m *Metrics) recordSync(skipped int) {
+	if m == nil {
+		return
+	}
+
+	m.skippedPolicies.Set(float64(skipped))
+}
@@ -0,0 +1,107 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package networkpolicyfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/eirini/k8s/networkpolicy"
+)
+
+type FakePolicyGetter struct {
+	GetPoliciesStub        func() ([]networkpolicy.Policy, error)
+	getPoliciesMutex       sync.RWMutex
+	getPoliciesArgsForCall []struct {
+	}
+	getPoliciesReturns struct {
+		result1 []networkpolicy.Policy
+		result2 error
+	}
+	getPoliciesReturnsOnCall map[int]struct {
+		result1 []networkpolicy.Policy
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakePolicyGetter) GetPolicies() ([]networkpolicy.Policy, error) {
+	fake.getPoliciesMutex.Lock()
+	ret, specificReturn := fake.getPoliciesReturnsOnCall[len(fake.getPoliciesArgsForCall)]
+	fake.getPoliciesArgsForCall = append(fake.getPoliciesArgsForCall, struct {
+	}{})
+	stub := fake.GetPoliciesStub
+	fakeReturns := fake.getPoliciesReturns
+	fake.recordInvocation("GetPolicies", []interface{}{})
+	fake.getPoliciesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePolicyGetter) GetPoliciesCallCount() int {
+	fake.getPoliciesMutex.RLock()
+	defer fake.getPoliciesMutex.RUnlock()
+	return len(fake.getPoliciesArgsForCall)
+}
+
+func (fake *FakePolicyGetter) GetPoliciesCalls(stub func() ([]networkpolicy.Policy, error)) {
+	fake.getPoliciesMutex.Lock()
+	defer fake.getPoliciesMutex.Unlock()
+	fake.GetPoliciesStub = stub
+}
+
+func (fake *FakePolicyGetter) GetPoliciesReturns(result1 []networkpolicy.Policy, result2 error) {
+	fake.getPoliciesMutex.Lock()
+	defer fake.getPoliciesMutex.Unlock()
+	fake.GetPoliciesStub = nil
+	fake.getPoliciesReturns = struct {
+		result1 []networkpolicy.Policy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyGetter) GetPoliciesReturnsOnCall(i int, result1 []networkpolicy.Policy, result2 error) {
+	fake.getPoliciesMutex.Lock()
+	defer fake.getPoliciesMutex.Unlock()
+	fake.GetPoliciesStub = nil
+	if fake.getPoliciesReturnsOnCall == nil {
+		fake.getPoliciesReturnsOnCall = make(map[int]struct {
+			result1 []networkpolicy.Policy
+			result2 error
+		})
+	}
+	fake.getPoliciesReturnsOnCall[i] = struct {
+		result1 []networkpolicy.Policy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePolicyGetter) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.getPoliciesMutex.RLock()
+	defer fake.getPoliciesMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakePolicyGetter) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ networkpolicy.PolicyGetter = new(FakePolicyGetter)
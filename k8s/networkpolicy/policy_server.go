@@ -0,0 +1,71 @@
+package networkpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+const policiesPath = "/networking/v1/internal/policies"
+
+// Policy allows the instances of the source app to connect to a port range
+// of the instances of the destination app
+type Policy struct {
+	Source      PolicySource      `json:"source"`
+	Destination PolicyDestination `json:"destination"`
+}
+
+type PolicySource struct {
+	ID string `json:"id"`
+}
+
+type PolicyDestination struct {
+	ID       string    `json:"id"`
+	Protocol string    `json:"protocol"`
+	Ports    PortRange `json:"ports"`
+}
+
+type PortRange struct {
+	Start int32 `json:"start"`
+	End   int32 `json:"end"`
+}
+
+type policiesResponse struct {
+	TotalPolicies int      `json:"total_policies"`
+	Policies      []Policy `json:"policies"`
+}
+
+// PolicyServerClient reads the container to container policies from the
+// internal API of the CF network policy server
+type PolicyServerClient struct {
+	address    string
+	httpClient *http.Client
+}
+
+func NewPolicyServerClient(address string, httpClient *http.Client) *PolicyServerClient {
+	return &PolicyServerClient{
+		address:    address,
+		httpClient: httpClient,
+	}
+}
+
+func (c *PolicyServerClient) GetPolicies() ([]Policy, error) {
+	resp, err := c.httpClient.Get(c.address + policiesPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get policies")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("getting policies failed with status code %d", resp.StatusCode)
+	}
+
+	var policies policiesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&policies); err != nil {
+		return nil, errors.Wrap(err, "failed to decode policies")
+	}
+
+	return policies.Policies, nil
+}
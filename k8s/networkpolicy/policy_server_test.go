@@ -0,0 +1,89 @@
+package networkpolicy_test
+
+import (
+	"net/http"
+
+	. "code.cloudfoundry.org/eirini/k8s/networkpolicy"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("PolicyServerClient", func() {
+	var (
+		server   *ghttp.Server
+		client   *PolicyServerClient
+		policies []Policy
+		err      error
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		client = NewPolicyServerClient(server.URL(), http.DefaultClient)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		policies, err = client.GetPolicies()
+	})
+
+	When("the policy server returns policies", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/networking/v1/internal/policies"),
+				ghttp.RespondWith(http.StatusOK, `{
+					"total_policies": 1,
+					"policies": [{
+						"source": {"id": "frontend-guid", "tag": "0001"},
+						"destination": {"id": "backend-guid", "tag": "0002", "protocol": "tcp", "ports": {"start": 8080, "end": 8081}}
+					}]
+				}`),
+			))
+		})
+
+		It("returns them", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(ConsistOf(Policy{
+				Source: PolicySource{ID: "frontend-guid"},
+				Destination: PolicyDestination{
+					ID:       "backend-guid",
+					Protocol: "tcp",
+					Ports:    PortRange{Start: 8080, End: 8081},
+				},
+			}))
+		})
+	})
+
+	When("the policy server responds with an error status", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusInternalServerError, ""))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("status code 500")))
+		})
+	})
+
+	When("the policy server responds with invalid json", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusOK, "{"))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to decode policies")))
+		})
+	})
+
+	When("the policy server is not reachable", func() {
+		BeforeEach(func() {
+			server.Close()
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to get policies")))
+		})
+	})
+})
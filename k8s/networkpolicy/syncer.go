@@ -0,0 +1,258 @@
+package networkpolicy
+
+import (
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	LabelNetworkPolicy = "cloudfoundry.org/network_policy"
+	C2CNetworkPolicy   = "c2c"
+
+	DefaultDenyName = "c2c-default-deny"
+
+	// NetworkPolicies of the vendored API cannot express port ranges, so
+	// ranges are expanded into single ports up to this size
+	maxPortRangeSize = 100
+)
+
+//counterfeiter:generate . PolicyGetter
+//counterfeiter:generate . NetworkPolicyClient
+
+type PolicyGetter interface {
+	GetPolicies() ([]Policy, error)
+}
+
+type NetworkPolicyClient interface {
+	Apply(networkPolicy *networkingv1.NetworkPolicy) error
+	List(namespace, labelSelector string) ([]networkingv1.NetworkPolicy, error)
+	Delete(namespace, name string) error
+}
+
+// Syncer reconciles the container to container policies of the policy
+// server into a NetworkPolicy per destination app. Selecting the pods of a
+// destination isolates them, so every NetworkPolicy also lets in traffic from
+// pods which are not apps, such as routers. With default deny, apps which are
+// not the destination of any policy are isolated from other apps too
+type Syncer struct {
+	policies        PolicyGetter
+	networkPolicies NetworkPolicyClient
+	namespace       string
+	defaultDeny     bool
+	metrics         *Metrics
+	logger          lager.Logger
+}
+
+func NewSyncer(policies PolicyGetter, networkPolicies NetworkPolicyClient, namespace string, defaultDeny bool, metrics *Metrics, logger lager.Logger) Syncer {
+	return Syncer{
+		policies:        policies,
+		networkPolicies: networkPolicies,
+		namespace:       namespace,
+		defaultDeny:     defaultDeny,
+		metrics:         metrics,
+		logger:          logger,
+	}
+}
+
+func (s Syncer) Sync() error {
+	logger := s.logger.Session("sync")
+
+	policies, err := s.policies.GetPolicies()
+	if err != nil {
+		return errors.Wrap(err, "failed to get policies from policy server")
+	}
+
+	desired := map[string]bool{}
+
+	networkPolicies, skipped := s.toNetworkPolicies(logger, policies)
+	s.metrics.recordSync(skipped)
+
+	for _, networkPolicy := range networkPolicies {
+		if err := s.networkPolicies.Apply(networkPolicy); err != nil {
+			return errors.Wrapf(err, "failed to apply network policy %s", networkPolicy.Name)
+		}
+
+		desired[networkPolicy.Name] = true
+	}
+
+	existing, err := s.networkPolicies.List(s.namespace, LabelNetworkPolicy+"="+C2CNetworkPolicy)
+	if err != nil {
+		return errors.Wrap(err, "failed to list network policies")
+	}
+
+	for _, networkPolicy := range existing {
+		if desired[networkPolicy.Name] {
+			continue
+		}
+
+		if err := s.networkPolicies.Delete(networkPolicy.Namespace, networkPolicy.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete network policy %s", networkPolicy.Name)
+		}
+	}
+
+	logger.Debug("synced", lager.Data{"policies": len(policies), "skipped-policies": skipped, "network-policies": len(desired)})
+
+	return nil
+}
+
+// toNetworkPolicies returns the network policies for the policies, along with
+// the number of policies which cannot be expressed as network policies
+func (s Syncer) toNetworkPolicies(logger lager.Logger, policies []Policy) ([]*networkingv1.NetworkPolicy, int) {
+	rulesByDestination := map[string][]networkingv1.NetworkPolicyIngressRule{}
+	skipped := 0
+
+	for _, p := range sortPolicies(policies) {
+		ports, err := toNetworkPolicyPorts(p.Destination)
+		if err != nil {
+			logger.Error("skipping-policy", err, lager.Data{"source": p.Source.ID, "destination": p.Destination.ID})
+
+			skipped++
+
+			continue
+		}
+
+		rulesByDestination[p.Destination.ID] = append(rulesByDestination[p.Destination.ID], networkingv1.NetworkPolicyIngressRule{
+			From:  []networkingv1.NetworkPolicyPeer{appPeer(p.Source.ID)},
+			Ports: ports,
+		})
+	}
+
+	networkPolicies := []*networkingv1.NetworkPolicy{}
+
+	if s.defaultDeny {
+		networkPolicies = append(networkPolicies, s.networkPolicy(DefaultDenyName, &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: stset.LabelAppGUID, Operator: metav1.LabelSelectorOpExists},
+			},
+		}, nil))
+	}
+
+	for _, destination := range sortedKeys(rulesByDestination) {
+		networkPolicies = append(networkPolicies, s.networkPolicy(
+			"c2c-"+destination,
+			&metav1.LabelSelector{MatchLabels: map[string]string{stset.LabelAppGUID: destination}},
+			rulesByDestination[destination],
+		))
+	}
+
+	return networkPolicies, skipped
+}
+
+func (s Syncer) networkPolicy(name string, podSelector *metav1.LabelSelector, rules []networkingv1.NetworkPolicyIngressRule) *networkingv1.NetworkPolicy {
+	nonAppRule := networkingv1.NetworkPolicyIngressRule{
+		From: []networkingv1.NetworkPolicyPeer{nonAppPeer()},
+	}
+
+	return &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.namespace,
+			Labels:    map[string]string{LabelNetworkPolicy: C2CNetworkPolicy},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: *podSelector,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     append([]networkingv1.NetworkPolicyIngressRule{nonAppRule}, rules...),
+		},
+	}
+}
+
+func appPeer(appGUID string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{},
+		PodSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{stset.LabelAppGUID: appGUID},
+		},
+	}
+}
+
+func nonAppPeer() networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{},
+		PodSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: stset.LabelAppGUID, Operator: metav1.LabelSelectorOpDoesNotExist},
+			},
+		},
+	}
+}
+
+func toNetworkPolicyPorts(destination PolicyDestination) ([]networkingv1.NetworkPolicyPort, error) {
+	var protocol corev1.Protocol
+
+	switch strings.ToLower(destination.Protocol) {
+	case "tcp":
+		protocol = corev1.ProtocolTCP
+	case "udp":
+		protocol = corev1.ProtocolUDP
+	default:
+		return nil, errors.Errorf("unsupported protocol %q", destination.Protocol)
+	}
+
+	start, end := destination.Ports.Start, destination.Ports.End
+	if end == 0 {
+		end = start
+	}
+
+	if start <= 0 || end < start {
+		return nil, errors.Errorf("invalid port range %d-%d", start, end)
+	}
+
+	if end-start >= maxPortRangeSize {
+		return nil, errors.Errorf("port range %d-%d is larger than %d ports", start, end, maxPortRangeSize)
+	}
+
+	ports := []networkingv1.NetworkPolicyPort{}
+
+	for port := start; port <= end; port++ {
+		p := protocol
+		portValue := intstr.FromInt(int(port))
+		ports = append(ports, networkingv1.NetworkPolicyPort{Protocol: &p, Port: &portValue})
+	}
+
+	return ports, nil
+}
+
+func sortPolicies(policies []Policy) []Policy {
+	sorted := append([]Policy{}, policies...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Destination.ID != b.Destination.ID {
+			return a.Destination.ID < b.Destination.ID
+		}
+
+		if a.Source.ID != b.Source.ID {
+			return a.Source.ID < b.Source.ID
+		}
+
+		if a.Destination.Protocol != b.Destination.Protocol {
+			return a.Destination.Protocol < b.Destination.Protocol
+		}
+
+		return a.Destination.Ports.Start < b.Destination.Ports.Start
+	})
+
+	return sorted
+}
+
+func sortedKeys(rules map[string][]networkingv1.NetworkPolicyIngressRule) []string {
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,221 @@
+package networkpolicy_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s/networkpolicy"
+	"code.cloudfoundry.org/eirini/k8s/networkpolicy/networkpolicyfakes"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+var _ = Describe("Syncer", func() {
+	var (
+		policyGetter    *networkpolicyfakes.FakePolicyGetter
+		networkPolicies *networkpolicyfakes.FakeNetworkPolicyClient
+		defaultDeny     bool
+		metrics         *networkpolicy.Metrics
+		logger          *lagertest.TestLogger
+		syncErr         error
+	)
+
+	applied := func() []*networkingv1.NetworkPolicy {
+		result := []*networkingv1.NetworkPolicy{}
+		for i := 0; i < networkPolicies.ApplyCallCount(); i++ {
+			result = append(result, networkPolicies.ApplyArgsForCall(i))
+		}
+
+		return result
+	}
+
+	tcpPort := func(port int) networkingv1.NetworkPolicyPort {
+		protocol := corev1.ProtocolTCP
+		portValue := intstr.FromInt(port)
+
+		return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &portValue}
+	}
+
+	appPeer := func(guid string) networkingv1.NetworkPolicyPeer {
+		return networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{stset.LabelAppGUID: guid}},
+		}
+	}
+
+	BeforeEach(func() {
+		policyGetter = new(networkpolicyfakes.FakePolicyGetter)
+		networkPolicies = new(networkpolicyfakes.FakeNetworkPolicyClient)
+		defaultDeny = false
+		metrics = networkpolicy.NewMetrics()
+		logger = lagertest.NewTestLogger("syncer")
+
+		policyGetter.GetPoliciesReturns([]networkpolicy.Policy{
+			{
+				Source:      networkpolicy.PolicySource{ID: "frontend"},
+				Destination: networkpolicy.PolicyDestination{ID: "backend", Protocol: "tcp", Ports: networkpolicy.PortRange{Start: 8080, End: 8081}},
+			},
+			{
+				Source:      networkpolicy.PolicySource{ID: "admin"},
+				Destination: networkpolicy.PolicyDestination{ID: "backend", Protocol: "tcp", Ports: networkpolicy.PortRange{Start: 9000}},
+			},
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		syncer := networkpolicy.NewSyncer(policyGetter, networkPolicies, "workloads", defaultDeny, metrics, logger)
+		syncErr = syncer.Sync()
+	})
+
+	It("succeeds", func() {
+		Expect(syncErr).NotTo(HaveOccurred())
+	})
+
+	It("applies a network policy per destination app", func() {
+		Expect(applied()).To(HaveLen(1))
+
+		networkPolicy := applied()[0]
+		Expect(networkPolicy.Name).To(Equal("c2c-backend"))
+		Expect(networkPolicy.Namespace).To(Equal("workloads"))
+		Expect(networkPolicy.Labels).To(HaveKeyWithValue(networkpolicy.LabelNetworkPolicy, networkpolicy.C2CNetworkPolicy))
+		Expect(networkPolicy.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{stset.LabelAppGUID: "backend"}))
+		Expect(networkPolicy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+	})
+
+	It("allows the sources on the destination ports", func() {
+		rules := applied()[0].Spec.Ingress
+		Expect(rules).To(HaveLen(3))
+		Expect(rules[1]).To(Equal(networkingv1.NetworkPolicyIngressRule{
+			From:  []networkingv1.NetworkPolicyPeer{appPeer("admin")},
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(9000)},
+		}))
+		Expect(rules[2]).To(Equal(networkingv1.NetworkPolicyIngressRule{
+			From:  []networkingv1.NetworkPolicyPeer{appPeer("frontend")},
+			Ports: []networkingv1.NetworkPolicyPort{tcpPort(8080), tcpPort(8081)},
+		}))
+	})
+
+	It("keeps letting in traffic from pods which are not apps", func() {
+		rule := applied()[0].Spec.Ingress[0]
+		Expect(rule.Ports).To(BeEmpty())
+		Expect(rule.From).To(HaveLen(1))
+		Expect(rule.From[0].PodSelector.MatchExpressions).To(ConsistOf(metav1.LabelSelectorRequirement{
+			Key:      stset.LabelAppGUID,
+			Operator: metav1.LabelSelectorOpDoesNotExist,
+		}))
+	})
+
+	When("a policy has an unsupported protocol or port range", func() {
+		BeforeEach(func() {
+			policyGetter.GetPoliciesReturns([]networkpolicy.Policy{
+				{
+					Source:      networkpolicy.PolicySource{ID: "frontend"},
+					Destination: networkpolicy.PolicyDestination{ID: "backend", Protocol: "icmp", Ports: networkpolicy.PortRange{Start: 8080}},
+				},
+				{
+					Source:      networkpolicy.PolicySource{ID: "frontend"},
+					Destination: networkpolicy.PolicyDestination{ID: "backend", Protocol: "udp", Ports: networkpolicy.PortRange{Start: 1000, End: 2000}},
+				},
+				{
+					Source:      networkpolicy.PolicySource{ID: "frontend"},
+					Destination: networkpolicy.PolicyDestination{ID: "other", Protocol: "udp", Ports: networkpolicy.PortRange{Start: 53}},
+				},
+			}, nil)
+		})
+
+		It("skips it", func() {
+			Expect(applied()).To(HaveLen(1))
+			Expect(applied()[0].Name).To(Equal("c2c-other"))
+			Expect(applied()[0].Spec.Ingress).To(HaveLen(2))
+			Expect(*applied()[0].Spec.Ingress[1].Ports[0].Protocol).To(Equal(corev1.ProtocolUDP))
+		})
+
+		It("logs an error for every skipped policy", func() {
+			Expect(logger.Logs()).To(ContainElement(MatchFields(IgnoreExtras, Fields{
+				"Message":  Equal("syncer.sync.skipping-policy"),
+				"LogLevel": Equal(lager.ERROR),
+				"Data":     HaveKeyWithValue("error", "port range 1000-2000 is larger than 100 ports"),
+			})))
+		})
+
+		It("records the number of skipped policies", func() {
+			collected := make(chan prometheus.Metric, 1)
+			metrics.Collect(collected)
+
+			var skipped dto.Metric
+			Expect((<-collected).Write(&skipped)).To(Succeed())
+			Expect(skipped.GetGauge().GetValue()).To(Equal(2.0))
+		})
+	})
+
+	When("default deny is enabled", func() {
+		BeforeEach(func() {
+			defaultDeny = true
+		})
+
+		It("isolates all apps from each other", func() {
+			Expect(applied()).To(HaveLen(2))
+
+			networkPolicy := applied()[0]
+			Expect(networkPolicy.Name).To(Equal(networkpolicy.DefaultDenyName))
+			Expect(networkPolicy.Spec.PodSelector.MatchExpressions).To(ConsistOf(metav1.LabelSelectorRequirement{
+				Key:      stset.LabelAppGUID,
+				Operator: metav1.LabelSelectorOpExists,
+			}))
+			Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+			Expect(networkPolicy.Spec.Ingress[0].From[0].PodSelector.MatchExpressions[0].Operator).To(Equal(metav1.LabelSelectorOpDoesNotExist))
+		})
+	})
+
+	When("network policies exist for policies which were removed", func() {
+		BeforeEach(func() {
+			networkPolicies.ListReturns([]networkingv1.NetworkPolicy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "c2c-backend", Namespace: "workloads"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "c2c-gone", Namespace: "workloads"}},
+			}, nil)
+		})
+
+		It("deletes them", func() {
+			namespace, selector := networkPolicies.ListArgsForCall(0)
+			Expect(namespace).To(Equal("workloads"))
+			Expect(selector).To(Equal("cloudfoundry.org/network_policy=c2c"))
+
+			Expect(networkPolicies.DeleteCallCount()).To(Equal(1))
+			namespace, name := networkPolicies.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("workloads"))
+			Expect(name).To(Equal("c2c-gone"))
+		})
+	})
+
+	When("getting the policies fails", func() {
+		BeforeEach(func() {
+			policyGetter.GetPoliciesReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error without touching the network policies", func() {
+			Expect(syncErr).To(MatchError(ContainSubstring("boom")))
+			Expect(networkPolicies.ApplyCallCount()).To(BeZero())
+			Expect(networkPolicies.DeleteCallCount()).To(BeZero())
+		})
+	})
+
+	When("applying a network policy fails", func() {
+		BeforeEach(func() {
+			networkPolicies.ApplyReturns(errors.New("boom"))
+		})
+
+		It("returns an error without deleting anything", func() {
+			Expect(syncErr).To(MatchError(ContainSubstring("boom")))
+			Expect(networkPolicies.DeleteCallCount()).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,34 @@
+package reconciler
+
+import (
+	"code.cloudfoundry.org/eirini/bifrost"
+	"code.cloudfoundry.org/eirini/opi"
+)
+
+// applyImagePolicy checks the image of an LRP or task CR against the image
+// policy, as opi does for the requests of the Cloud Controller, and rewrites
+// it to the mirror of its registry. Mirrored images are pulled with the
+// credentials of the mirror, when it has any, and otherwise with those of the
+// CR. The error wraps eirini.ErrImageNotAllowed when the image is rejected
+func applyImagePolicy(policy bifrost.ImagePolicy, image string, registry *opi.PrivateRegistry) (string, *opi.PrivateRegistry, error) {
+	var username, password string
+	if registry != nil {
+		username, password = registry.Username, registry.Password
+	}
+
+	policyImage, err := policy.Apply(image, username, password)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if policyImage.Image == image {
+		return image, registry, nil
+	}
+
+	mirrorCredentials := policyImage.Username != username || policyImage.Password != password
+	if registry != nil && registry.SecretName != "" && !mirrorCredentials {
+		return policyImage.Image, registry, nil
+	}
+
+	return policyImage.Image, policyImage.PrivateRegistry(), nil
+}
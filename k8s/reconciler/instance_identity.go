@@ -0,0 +1,176 @@
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	eirinix "code.cloudfoundry.org/eirinix"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// OrphanedSecretGracePeriod is how long a secret the webhook creates for a
+// pod may exist without it. The webhook creates the secret before the pod is
+// stored, so a missing pod does not mean it is gone for good
+const OrphanedSecretGracePeriod = 5 * time.Minute
+
+//counterfeiter:generate . IdentityIssuer
+
+type IdentityIssuer interface {
+	Issue(id identity.Identity) ([]byte, []byte, error)
+}
+
+// InstanceIdentity renews the instance identity certificates before they
+// expire, or once the pod IP they should contain is known, and deletes the
+// secrets of pods which are gone. It is an eirinix reconciler, running next to
+// the webhook that creates the secrets
+type InstanceIdentity struct {
+	logger      lager.Logger
+	client      client.Client
+	issuer      IdentityIssuer
+	renewBefore time.Duration
+}
+
+func NewInstanceIdentity(logger lager.Logger, issuer IdentityIssuer, renewBefore time.Duration) *InstanceIdentity {
+	return &InstanceIdentity{
+		logger:      logger,
+		issuer:      issuer,
+		renewBefore: renewBefore,
+	}
+}
+
+// InjectClient is called by controller-runtime with the client of the manager
+// the reconciler is registered with
+func (r *InstanceIdentity) InjectClient(c client.Client) error {
+	r.client = c
+
+	return nil
+}
+
+func (r *InstanceIdentity) Register(m eirinix.Manager) error {
+	identitySecrets := predicate.NewPredicateFuncs(func(meta metav1.Object, _ runtime.Object) bool {
+		return meta.GetLabels()[identity.LabelPodName] != ""
+	})
+	appPods := predicate.NewPredicateFuncs(func(meta metav1.Object, _ runtime.Object) bool {
+		return meta.GetLabels()[stset.LabelSourceType] == stset.AppSourceType
+	})
+	podSecret := handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{
+			Namespace: o.Meta.GetNamespace(),
+			Name:      identity.SecretName(o.Meta.GetName()),
+		}}}
+	})
+
+	err := builder.
+		ControllerManagedBy(m.GetKubeManager()).
+		Named("instance-identity").
+		For(&corev1.Secret{}, builder.WithPredicates(identitySecrets)).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: podSecret}, builder.WithPredicates(appPods)).
+		Complete(r)
+
+	return exterrors.Wrap(err, "failed to build instance identity reconciler")
+}
+
+func (r *InstanceIdentity) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	logger := r.logger.Session("reconcile-instance-identity", lager.Data{"request": request})
+	ctx := context.Background()
+
+	secret := &corev1.Secret{}
+
+	err := r.client.Get(ctx, request.NamespacedName, secret)
+	if errors.IsNotFound(err) {
+		logger.Debug("no-such-secret")
+
+		return reconcile.Result{}, nil
+	}
+
+	if err != nil {
+		logger.Error("secret-get-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to get secret")
+	}
+
+	podName := secret.Labels[identity.LabelPodName]
+	if podName == "" {
+		return reconcile.Result{}, nil
+	}
+
+	pod := &corev1.Pod{}
+
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: secret.Namespace, Name: podName}, pod)
+	if errors.IsNotFound(err) {
+		return deleteOrphanedSecret(ctx, r.client, logger, secret)
+	}
+
+	if err != nil {
+		logger.Error("pod-get-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to get pod")
+	}
+
+	id := identity.FromPod(pod)
+
+	renew, renewAt, err := identity.NeedsRenewal(secret.Data[corev1.TLSCertKey], id, r.renewBefore)
+	if err != nil {
+		logger.Info("invalid-certificate", lager.Data{"error": err.Error()})
+	}
+
+	if !renew {
+		return reconcile.Result{RequeueAfter: time.Until(renewAt)}, nil
+	}
+
+	cert, key, err := r.issuer.Issue(id)
+	if err != nil {
+		logger.Error("issue-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to issue instance identity certificate")
+	}
+
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       cert,
+		corev1.TLSPrivateKeyKey: key,
+	}
+
+	if err = r.client.Update(ctx, secret); err != nil {
+		logger.Error("secret-update-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to update secret")
+	}
+
+	logger.Info("renewed-instance-identity")
+
+	return reconcile.Result{}, nil
+}
+
+// deleteOrphanedSecret deletes a secret of a pod which does not exist, once
+// it is older than OrphanedSecretGracePeriod
+func deleteOrphanedSecret(ctx context.Context, c client.Client, logger lager.Logger, secret *corev1.Secret) (reconcile.Result, error) {
+	age := time.Since(secret.CreationTimestamp.Time)
+	if age < OrphanedSecretGracePeriod {
+		return reconcile.Result{RequeueAfter: OrphanedSecretGracePeriod - age}, nil
+	}
+
+	err := c.Delete(ctx, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error("secret-delete-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to delete orphaned secret")
+	}
+
+	logger.Info("deleted-orphaned-secret")
+
+	return reconcile.Result{}, nil
+}
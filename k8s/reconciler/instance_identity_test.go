@@ -0,0 +1,245 @@
+package reconciler_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"code.cloudfoundry.org/eirini/k8s/reconciler"
+	"code.cloudfoundry.org/eirini/k8s/reconciler/reconcilerfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("reconciler.InstanceIdentity", func() {
+	var (
+		controllerClient *reconcilerfakes.FakeClient
+		issuer           *reconcilerfakes.FakeIdentityIssuer
+		identityRotator  *reconciler.InstanceIdentity
+		secret           *corev1.Secret
+		pod              *corev1.Pod
+		secretErr        error
+		podErr           error
+		result           reconcile.Result
+		resultErr        error
+	)
+
+	issueCert := func(id identity.Identity, validity time.Duration) []byte {
+		caCert, caKey := generateTestCA()
+		realIssuer, err := identity.NewIssuer(caCert, caKey, validity)
+		Expect(err).NotTo(HaveOccurred())
+		cert, _, err := realIssuer.Issue(id)
+		Expect(err).NotTo(HaveOccurred())
+
+		return cert
+	}
+
+	BeforeEach(func() {
+		controllerClient = new(reconcilerfakes.FakeClient)
+		issuer = new(reconcilerfakes.FakeIdentityIssuer)
+		issuer.IssueReturns([]byte("new-cert"), []byte("new-key"), nil)
+		identityRotator = reconciler.NewInstanceIdentity(lagertest.NewTestLogger("instance-identity"), issuer, 10*time.Minute)
+		Expect(identityRotator.InjectClient(controllerClient)).To(Succeed())
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-0", Namespace: "some-ns"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+		}
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "app-0-instance-identity",
+				Namespace:         "some-ns",
+				Labels:            map[string]string{identity.LabelPodName: "app-0"},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			},
+			Data: map[string][]byte{
+				"tls.crt": issueCert(identity.Identity{InstanceID: "app-0", IP: "10.0.0.1"}, time.Hour),
+				"tls.key": []byte("key"),
+			},
+		}
+		secretErr = nil
+		podErr = nil
+
+		controllerClient.GetStub = func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+			switch o := obj.(type) {
+			case *corev1.Secret:
+				secret.DeepCopyInto(o)
+
+				return secretErr
+			case *corev1.Pod:
+				Expect(key).To(Equal(types.NamespacedName{Namespace: "some-ns", Name: "app-0"}))
+				pod.DeepCopyInto(o)
+
+				return podErr
+			}
+
+			return nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, resultErr = identityRotator.Reconcile(reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "some-ns", Name: "app-0-instance-identity"},
+		})
+	})
+
+	It("requeues for when the certificate is due for renewal", func() {
+		Expect(resultErr).NotTo(HaveOccurred())
+		Expect(issuer.IssueCallCount()).To(Equal(0))
+		Expect(controllerClient.UpdateCallCount()).To(Equal(0))
+		Expect(result.RequeueAfter).To(BeNumerically("~", 50*time.Minute, time.Minute))
+	})
+
+	When("the certificate is about to expire", func() {
+		BeforeEach(func() {
+			secret.Data["tls.crt"] = issueCert(identity.Identity{InstanceID: "app-0", IP: "10.0.0.1"}, 5*time.Minute)
+		})
+
+		It("renews it", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(issuer.IssueCallCount()).To(Equal(1))
+			Expect(issuer.IssueArgsForCall(0)).To(Equal(identity.Identity{InstanceID: "app-0", IP: "10.0.0.1"}))
+
+			Expect(controllerClient.UpdateCallCount()).To(Equal(1))
+			_, obj, _ := controllerClient.UpdateArgsForCall(0)
+			updated := obj.(*corev1.Secret)
+			Expect(updated.Name).To(Equal("app-0-instance-identity"))
+			Expect(updated.Data).To(Equal(map[string][]byte{
+				"tls.crt": []byte("new-cert"),
+				"tls.key": []byte("new-key"),
+			}))
+		})
+
+		When("issuing fails", func() {
+			BeforeEach(func() {
+				issuer.IssueReturns(nil, nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(resultErr).To(MatchError(ContainSubstring("failed to issue instance identity certificate")))
+				Expect(controllerClient.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		When("updating the secret fails", func() {
+			BeforeEach(func() {
+				controllerClient.UpdateReturns(errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(resultErr).To(MatchError(ContainSubstring("failed to update secret")))
+			})
+		})
+	})
+
+	When("the certificate was issued before the pod got its IP", func() {
+		BeforeEach(func() {
+			secret.Data["tls.crt"] = issueCert(identity.Identity{InstanceID: "app-0"}, time.Hour)
+		})
+
+		It("renews it", func() {
+			Expect(issuer.IssueCallCount()).To(Equal(1))
+			Expect(controllerClient.UpdateCallCount()).To(Equal(1))
+		})
+	})
+
+	When("the secret does not exist", func() {
+		BeforeEach(func() {
+			secretErr = apierrors.NewNotFound(schema.GroupResource{}, "app-0-instance-identity")
+		})
+
+		It("does nothing", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(controllerClient.GetCallCount()).To(Equal(1))
+			Expect(controllerClient.UpdateCallCount()).To(Equal(0))
+			Expect(controllerClient.DeleteCallCount()).To(Equal(0))
+		})
+	})
+
+	When("getting the secret fails", func() {
+		BeforeEach(func() {
+			secretErr = errors.New("boom")
+		})
+
+		It("returns an error", func() {
+			Expect(resultErr).To(MatchError(ContainSubstring("failed to get secret")))
+		})
+	})
+
+	When("the pod does not exist", func() {
+		BeforeEach(func() {
+			podErr = apierrors.NewNotFound(schema.GroupResource{}, "app-0")
+		})
+
+		It("deletes the orphaned secret", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(controllerClient.DeleteCallCount()).To(Equal(1))
+			_, obj, _ := controllerClient.DeleteArgsForCall(0)
+			Expect(obj.(*corev1.Secret).Name).To(Equal("app-0-instance-identity"))
+		})
+
+		When("the secret has just been created", func() {
+			BeforeEach(func() {
+				secret.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+			})
+
+			It("gives the pod time to be created", func() {
+				Expect(resultErr).NotTo(HaveOccurred())
+				Expect(controllerClient.DeleteCallCount()).To(Equal(0))
+				Expect(result.RequeueAfter).To(BeNumerically("~", reconciler.OrphanedSecretGracePeriod-time.Minute, time.Second))
+			})
+		})
+	})
+
+	When("getting the pod fails", func() {
+		BeforeEach(func() {
+			podErr = errors.New("boom")
+		})
+
+		It("returns an error", func() {
+			Expect(resultErr).To(MatchError(ContainSubstring("failed to get pod")))
+			Expect(controllerClient.DeleteCallCount()).To(Equal(0))
+		})
+	})
+})
+
+func generateTestCA() ([]byte, []byte) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "instance-identity-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(2 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	caKeyDER, err := x509.MarshalECPrivateKey(caKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER})
+}
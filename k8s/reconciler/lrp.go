@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/k8s/utils"
 	"code.cloudfoundry.org/eirini/opi"
@@ -36,13 +37,14 @@ type StatefulSetGetter interface {
 	Get(namespace, name string) (*appsv1.StatefulSet, error)
 }
 
-func NewLRP(logger lager.Logger, lrps client.Client, desirer LRPDesirer, statefulsetGetter StatefulSetGetter, scheme *runtime.Scheme) *LRP {
+func NewLRP(logger lager.Logger, lrps client.Client, desirer LRPDesirer, statefulsetGetter StatefulSetGetter, scheme *runtime.Scheme, imagePolicy bifrost.ImagePolicy) *LRP {
 	return &LRP{
 		logger:            logger,
 		lrps:              lrps,
 		desirer:           desirer,
 		scheme:            scheme,
 		statefulsetGetter: statefulsetGetter,
+		imagePolicy:       imagePolicy,
 	}
 }
 
@@ -52,6 +54,7 @@ type LRP struct {
 	desirer           LRPDesirer
 	scheme            *runtime.Scheme
 	statefulsetGetter StatefulSetGetter
+	imagePolicy       bifrost.ImagePolicy
 }
 
 func (r *LRP) Reconcile(request reconcile.Request) (reconcile.Result, error) {
@@ -83,28 +86,34 @@ func (r *LRP) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 }
 
 func (r *LRP) do(lrp *eiriniv1.LRP) error {
-	_, err := r.desirer.Get(opi.LRPIdentifier{
+	appLRP, err := toOpiLrp(lrp)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse the crd spec to the lrp model")
+	}
+
+	appLRP.Image, appLRP.PrivateRegistry, err = applyImagePolicy(r.imagePolicy, appLRP.Image, appLRP.PrivateRegistry)
+	if err != nil {
+		r.logger.Info("image-not-allowed", lager.Data{"namespace": lrp.Namespace, "name": lrp.Name, "reason": err.Error()})
+
+		return r.reportImageRejection(lrp, err)
+	}
+
+	_, err = r.desirer.Get(opi.LRPIdentifier{
 		GUID:    lrp.Spec.GUID,
 		Version: lrp.Spec.Version,
 	})
 	if errors.Is(err, eirini.ErrNotFound) {
-		appLRP, parseErr := toOpiLrp(lrp)
-		if parseErr != nil {
-			return errors.Wrap(parseErr, "failed to parse the crd spec to the lrp model")
+		if err = r.desirer.Desire(lrp.Namespace, appLRP, r.setOwnerFn(lrp)); err != nil {
+			return errors.Wrap(err, "failed to desire lrp")
 		}
 
-		return errors.Wrap(r.desirer.Desire(lrp.Namespace, appLRP, r.setOwnerFn(lrp)), "failed to desire lrp")
+		return r.clearError(lrp)
 	}
 
 	if err != nil {
 		return errors.Wrap(err, "failed to get lrp")
 	}
 
-	appLRP, err := toOpiLrp(lrp)
-	if err != nil {
-		return errors.Wrap(err, "failed to parse the crd spec to the lrp model")
-	}
-
 	var errs *multierror.Error
 
 	err = r.updateStatus(lrp, appLRP)
@@ -116,6 +125,25 @@ func (r *LRP) do(lrp *eiriniv1.LRP) error {
 	return errs.ErrorOrNil()
 }
 
+// reportImageRejection records in the status of the LRP that its image was
+// rejected by the image policy. It is not retried, as only a change to the
+// LRP can fix it
+func (r *LRP) reportImageRejection(lrp *eiriniv1.LRP, rejection error) error {
+	lrp.Status.Error = rejection.Error()
+
+	return errors.Wrap(r.lrps.Status().Update(context.Background(), lrp), "failed to update lrp status")
+}
+
+func (r *LRP) clearError(lrp *eiriniv1.LRP) error {
+	if lrp.Status.Error == "" {
+		return nil
+	}
+
+	lrp.Status.Error = ""
+
+	return errors.Wrap(r.lrps.Status().Update(context.Background(), lrp), "failed to update lrp status")
+}
+
 func (r *LRP) updateStatus(lrp *eiriniv1.LRP, appLRP *opi.LRP) error {
 	statefulSetName, err := utils.GetStatefulsetName(appLRP)
 	if err != nil {
@@ -128,6 +156,7 @@ func (r *LRP) updateStatus(lrp *eiriniv1.LRP, appLRP *opi.LRP) error {
 	}
 
 	lrp.Status.Replicas = st.Status.ReadyReplicas
+	lrp.Status.Error = ""
 
 	return r.lrps.Status().Update(context.Background(), lrp)
 }
@@ -151,6 +180,11 @@ func toOpiLrp(lrp *eiriniv1.LRP) (*opi.LRP, error) {
 
 	opiLrp.TargetInstances = lrp.Spec.Instances
 
+	if lrp.Spec.RunsAsRoot && opiLrp.RunAsUser == nil {
+		root := int64(0)
+		opiLrp.RunAsUser = &root
+	}
+
 	if err := copier.Copy(&opiLrp.AppURIs, lrp.Spec.AppRoutes); err != nil {
 		return nil, errors.Wrap(err, "failed to copy app routes")
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/k8s/reconciler"
 	"code.cloudfoundry.org/eirini/k8s/reconciler/reconcilerfakes"
 	"code.cloudfoundry.org/eirini/opi"
@@ -12,6 +13,7 @@ import (
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -41,7 +43,7 @@ var _ = Describe("reconciler.LRP", func() {
 		statefulsetGetter = new(reconcilerfakes.FakeStatefulSetGetter)
 		logger = lagertest.NewTestLogger("lrp-reconciler")
 		scheme = eiriniv1scheme.Scheme
-		lrpreconciler = reconciler.NewLRP(logger, controllerClient, desirer, statefulsetGetter, scheme)
+		lrpreconciler = reconciler.NewLRP(logger, controllerClient, desirer, statefulsetGetter, scheme, bifrost.ImagePolicy{})
 
 		controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
 			lrp := o.(*eiriniv1.LRP)
@@ -89,6 +91,23 @@ var _ = Describe("reconciler.LRP", func() {
 		))
 	})
 
+	When("the CRD uses the deprecated runsAsRoot", func() {
+		BeforeEach(func() {
+			getLRP := controllerClient.GetStub
+			controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
+				err := getLRP(c, nn, o)
+				o.(*eiriniv1.LRP).Spec.RunsAsRoot = true
+
+				return err
+			}
+		})
+
+		It("runs the app as root", func() {
+			_, lrp, _ := desirer.DesireArgsForCall(0)
+			Expect(lrp.RunAsUser).To(PointTo(Equal(int64(0))))
+		})
+	})
+
 	It("sets an owner reference in the statefulset", func() {
 		Expect(resultErr).NotTo(HaveOccurred())
 
@@ -171,6 +190,94 @@ var _ = Describe("reconciler.LRP", func() {
 		})
 	})
 
+	When("the image policy rejects the image", func() {
+		BeforeEach(func() {
+			imagePolicy := bifrost.NewImagePolicy(eirini.ImagePolicyConfig{DeniedRegistries: []string{"denied.io"}})
+			lrpreconciler = reconciler.NewLRP(logger, controllerClient, desirer, statefulsetGetter, scheme, imagePolicy)
+
+			getLRP := controllerClient.GetStub
+			controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
+				err := getLRP(c, nn, o)
+				o.(*eiriniv1.LRP).Spec.Image = "denied.io/some/app"
+
+				return err
+			}
+		})
+
+		It("neither desires nor updates the app", func() {
+			Expect(desirer.DesireCallCount()).To(BeZero())
+			Expect(desirer.UpdateCallCount()).To(BeZero())
+		})
+
+		It("reports the rejection in the CRD status without retrying", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+
+			Expect(statusClient.UpdateCallCount()).To(Equal(1))
+			_, obj, _ := statusClient.UpdateArgsForCall(0)
+			Expect(obj.(*eiriniv1.LRP).Status.Error).To(ContainSubstring("registry denied.io is denied"))
+		})
+
+		When("updating the status fails", func() {
+			BeforeEach(func() {
+				statusClient.UpdateReturns(errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(resultErr).To(MatchError("failed to update lrp status: boom"))
+			})
+		})
+	})
+
+	When("the image policy mirrors the registry of the image", func() {
+		BeforeEach(func() {
+			imagePolicy := bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+				Mirrors: []eirini.ImageMirrorConfig{{Registry: "docker.io", Mirror: "mirror.internal", Username: "mirror-user", Password: "mirror-pass"}},
+			})
+			lrpreconciler = reconciler.NewLRP(logger, controllerClient, desirer, statefulsetGetter, scheme, imagePolicy)
+
+			getLRP := controllerClient.GetStub
+			controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
+				err := getLRP(c, nn, o)
+				o.(*eiriniv1.LRP).Spec.Image = "some/app"
+
+				return err
+			}
+		})
+
+		It("desires the app with the mirrored image and its credentials", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+
+			_, lrp, _ := desirer.DesireArgsForCall(0)
+			Expect(lrp.Image).To(Equal("mirror.internal/some/app"))
+			Expect(lrp.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+				Server:   "mirror.internal",
+				Username: "mirror-user",
+				Password: "mirror-pass",
+			}))
+		})
+	})
+
+	When("the CRD status has an error from an earlier rejection", func() {
+		BeforeEach(func() {
+			getLRP := controllerClient.GetStub
+			controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
+				err := getLRP(c, nn, o)
+				o.(*eiriniv1.LRP).Status.Error = "image not allowed"
+
+				return err
+			}
+		})
+
+		It("clears it once the app is desired", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(desirer.DesireCallCount()).To(Equal(1))
+
+			Expect(statusClient.UpdateCallCount()).To(Equal(1))
+			_, obj, _ := statusClient.UpdateArgsForCall(0)
+			Expect(obj.(*eiriniv1.LRP).Status.Error).To(BeEmpty())
+		})
+	})
+
 	When("the LRP doesn't exist", func() {
 		BeforeEach(func() {
 			controllerClient.GetStub = func(c context.Context, nn types.NamespacedName, o runtime.Object) error {
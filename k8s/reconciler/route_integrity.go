@@ -0,0 +1,146 @@
+package reconciler
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/route/integrity"
+	eirinix "code.cloudfoundry.org/eirinix"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+//counterfeiter:generate . RouteIntegrityIssuer
+
+type RouteIntegrityIssuer interface {
+	Issue(instanceID string) ([]byte, []byte, error)
+}
+
+// RouteIntegrityCerts renews the certificates the route integrity sidecars
+// serve before they expire, and deletes the secrets of pods which are gone.
+// The sidecar reloads the renewed certificate from the mounted secret
+type RouteIntegrityCerts struct {
+	logger      lager.Logger
+	client      client.Client
+	issuer      RouteIntegrityIssuer
+	renewBefore time.Duration
+}
+
+func NewRouteIntegrityCerts(logger lager.Logger, issuer RouteIntegrityIssuer, renewBefore time.Duration) *RouteIntegrityCerts {
+	return &RouteIntegrityCerts{
+		logger:      logger,
+		issuer:      issuer,
+		renewBefore: renewBefore,
+	}
+}
+
+// InjectClient is called by controller-runtime with the client of the manager
+// the reconciler is registered with
+func (r *RouteIntegrityCerts) InjectClient(c client.Client) error {
+	r.client = c
+
+	return nil
+}
+
+func (r *RouteIntegrityCerts) Register(m eirinix.Manager) error {
+	certSecrets := predicate.NewPredicateFuncs(func(meta metav1.Object, _ runtime.Object) bool {
+		return meta.GetLabels()[integrity.LabelPodName] != ""
+	})
+	appPods := predicate.NewPredicateFuncs(func(meta metav1.Object, _ runtime.Object) bool {
+		return meta.GetLabels()[stset.LabelSourceType] == stset.AppSourceType
+	})
+	podSecret := handler.ToRequestsFunc(func(o handler.MapObject) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{
+			Namespace: o.Meta.GetNamespace(),
+			Name:      integrity.SecretName(o.Meta.GetName()),
+		}}}
+	})
+
+	err := builder.
+		ControllerManagedBy(m.GetKubeManager()).
+		Named("route-integrity-certs").
+		For(&corev1.Secret{}, builder.WithPredicates(certSecrets)).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: podSecret}, builder.WithPredicates(appPods)).
+		Complete(r)
+
+	return exterrors.Wrap(err, "failed to build route integrity certs reconciler")
+}
+
+func (r *RouteIntegrityCerts) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	logger := r.logger.Session("reconcile-route-integrity-cert", lager.Data{"request": request})
+	ctx := context.Background()
+
+	secret := &corev1.Secret{}
+
+	err := r.client.Get(ctx, request.NamespacedName, secret)
+	if errors.IsNotFound(err) {
+		logger.Debug("no-such-secret")
+
+		return reconcile.Result{}, nil
+	}
+
+	if err != nil {
+		logger.Error("secret-get-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to get secret")
+	}
+
+	podName := secret.Labels[integrity.LabelPodName]
+	if podName == "" {
+		return reconcile.Result{}, nil
+	}
+
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: secret.Namespace, Name: podName}, &corev1.Pod{})
+	if errors.IsNotFound(err) {
+		return deleteOrphanedSecret(ctx, r.client, logger, secret)
+	}
+
+	if err != nil {
+		logger.Error("pod-get-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to get pod")
+	}
+
+	renew, renewAt, err := integrity.NeedsRenewal(secret.Data[corev1.TLSCertKey], podName, r.renewBefore)
+	if err != nil {
+		logger.Info("invalid-certificate", lager.Data{"error": err.Error()})
+	}
+
+	if !renew {
+		return reconcile.Result{RequeueAfter: time.Until(renewAt)}, nil
+	}
+
+	cert, key, err := r.issuer.Issue(podName)
+	if err != nil {
+		logger.Error("issue-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to issue route integrity certificate")
+	}
+
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       cert,
+		corev1.TLSPrivateKeyKey: key,
+	}
+
+	if err = r.client.Update(ctx, secret); err != nil {
+		logger.Error("secret-update-failed", err)
+
+		return reconcile.Result{}, exterrors.Wrap(err, "failed to update secret")
+	}
+
+	logger.Info("renewed-route-integrity-cert")
+
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,207 @@
+package reconciler_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/reconciler"
+	"code.cloudfoundry.org/eirini/k8s/reconciler/reconcilerfakes"
+	"code.cloudfoundry.org/eirini/route/integrity"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("reconciler.RouteIntegrityCerts", func() {
+	var (
+		controllerClient *reconcilerfakes.FakeClient
+		issuer           *reconcilerfakes.FakeRouteIntegrityIssuer
+		certRotator      *reconciler.RouteIntegrityCerts
+		secret           *corev1.Secret
+		secretErr        error
+		podErr           error
+		result           reconcile.Result
+		resultErr        error
+	)
+
+	issueCert := func(instanceID string, validity time.Duration) []byte {
+		caCert, caKey := generateTestCA()
+		cert, _, err := integrity.GenerateInstanceCert(caCert, caKey, instanceID, validity)
+		Expect(err).NotTo(HaveOccurred())
+
+		return cert
+	}
+
+	BeforeEach(func() {
+		controllerClient = new(reconcilerfakes.FakeClient)
+		issuer = new(reconcilerfakes.FakeRouteIntegrityIssuer)
+		issuer.IssueReturns([]byte("new-cert"), []byte("new-key"), nil)
+		certRotator = reconciler.NewRouteIntegrityCerts(lagertest.NewTestLogger("route-integrity"), issuer, 10*time.Minute)
+		Expect(certRotator.InjectClient(controllerClient)).To(Succeed())
+
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "app-0-route-integrity",
+				Namespace:         "some-ns",
+				Labels:            map[string]string{integrity.LabelPodName: "app-0"},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			},
+			Data: map[string][]byte{
+				"tls.crt": issueCert("app-0", time.Hour),
+				"tls.key": []byte("key"),
+			},
+		}
+		secretErr = nil
+		podErr = nil
+
+		controllerClient.GetStub = func(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+			switch o := obj.(type) {
+			case *corev1.Secret:
+				secret.DeepCopyInto(o)
+
+				return secretErr
+			case *corev1.Pod:
+				Expect(key).To(Equal(types.NamespacedName{Namespace: "some-ns", Name: "app-0"}))
+
+				return podErr
+			}
+
+			return nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, resultErr = certRotator.Reconcile(reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "some-ns", Name: "app-0-route-integrity"},
+		})
+	})
+
+	It("requeues for when the certificate is due for renewal", func() {
+		Expect(resultErr).NotTo(HaveOccurred())
+		Expect(issuer.IssueCallCount()).To(Equal(0))
+		Expect(controllerClient.UpdateCallCount()).To(Equal(0))
+		Expect(result.RequeueAfter).To(BeNumerically("~", 50*time.Minute, time.Minute))
+	})
+
+	When("the certificate is about to expire", func() {
+		BeforeEach(func() {
+			secret.Data["tls.crt"] = issueCert("app-0", 5*time.Minute)
+		})
+
+		It("renews it", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(issuer.IssueCallCount()).To(Equal(1))
+			Expect(issuer.IssueArgsForCall(0)).To(Equal("app-0"))
+
+			Expect(controllerClient.UpdateCallCount()).To(Equal(1))
+			_, obj, _ := controllerClient.UpdateArgsForCall(0)
+			updated := obj.(*corev1.Secret)
+			Expect(updated.Name).To(Equal("app-0-route-integrity"))
+			Expect(updated.Data).To(Equal(map[string][]byte{
+				"tls.crt": []byte("new-cert"),
+				"tls.key": []byte("new-key"),
+			}))
+		})
+
+		When("issuing fails", func() {
+			BeforeEach(func() {
+				issuer.IssueReturns(nil, nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(resultErr).To(MatchError(ContainSubstring("failed to issue route integrity certificate")))
+				Expect(controllerClient.UpdateCallCount()).To(Equal(0))
+			})
+		})
+
+		When("updating the secret fails", func() {
+			BeforeEach(func() {
+				controllerClient.UpdateReturns(errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(resultErr).To(MatchError(ContainSubstring("failed to update secret")))
+			})
+		})
+	})
+
+	When("the certificate is not valid", func() {
+		BeforeEach(func() {
+			secret.Data["tls.crt"] = []byte("garbage")
+		})
+
+		It("renews it", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(issuer.IssueCallCount()).To(Equal(1))
+			Expect(controllerClient.UpdateCallCount()).To(Equal(1))
+		})
+	})
+
+	When("the secret does not exist", func() {
+		BeforeEach(func() {
+			secretErr = apierrors.NewNotFound(schema.GroupResource{}, "app-0-route-integrity")
+		})
+
+		It("does nothing", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(controllerClient.GetCallCount()).To(Equal(1))
+			Expect(controllerClient.UpdateCallCount()).To(Equal(0))
+			Expect(controllerClient.DeleteCallCount()).To(Equal(0))
+		})
+	})
+
+	When("getting the secret fails", func() {
+		BeforeEach(func() {
+			secretErr = errors.New("boom")
+		})
+
+		It("returns an error", func() {
+			Expect(resultErr).To(MatchError(ContainSubstring("failed to get secret")))
+		})
+	})
+
+	When("the pod does not exist", func() {
+		BeforeEach(func() {
+			podErr = apierrors.NewNotFound(schema.GroupResource{}, "app-0")
+		})
+
+		It("deletes the orphaned secret", func() {
+			Expect(resultErr).NotTo(HaveOccurred())
+			Expect(controllerClient.DeleteCallCount()).To(Equal(1))
+			_, obj, _ := controllerClient.DeleteArgsForCall(0)
+			Expect(obj.(*corev1.Secret).Name).To(Equal("app-0-route-integrity"))
+		})
+
+		When("the secret has just been created", func() {
+			BeforeEach(func() {
+				secret.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Minute))
+			})
+
+			It("gives the pod time to be created", func() {
+				Expect(resultErr).NotTo(HaveOccurred())
+				Expect(controllerClient.DeleteCallCount()).To(Equal(0))
+				Expect(result.RequeueAfter).To(BeNumerically("~", reconciler.OrphanedSecretGracePeriod-time.Minute, time.Second))
+			})
+		})
+	})
+
+	When("getting the pod fails", func() {
+		BeforeEach(func() {
+			podErr = errors.New("boom")
+		})
+
+		It("returns an error", func() {
+			Expect(resultErr).To(MatchError(ContainSubstring("failed to get pod")))
+			Expect(controllerClient.DeleteCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/opi"
 	eiriniv1 "code.cloudfoundry.org/eirini/pkg/apis/eirini/v1"
@@ -24,14 +25,16 @@ type Task struct {
 	taskDesirer TaskDesirer
 	scheme      *runtime.Scheme
 	logger      lager.Logger
+	imagePolicy bifrost.ImagePolicy
 }
 
-func NewTask(logger lager.Logger, client client.Client, taskDesirer TaskDesirer, scheme *runtime.Scheme) *Task {
+func NewTask(logger lager.Logger, client client.Client, taskDesirer TaskDesirer, scheme *runtime.Scheme, imagePolicy bifrost.ImagePolicy) *Task {
 	return &Task{
 		client:      client,
 		taskDesirer: taskDesirer,
 		scheme:      scheme,
 		logger:      logger,
+		imagePolicy: imagePolicy,
 	}
 }
 
@@ -57,7 +60,16 @@ func (t *Task) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 		return reconcile.Result{}, fmt.Errorf("could not fetch task: %w", err)
 	}
 
-	err = t.taskDesirer.Desire(task.Namespace, toOpiTask(task), t.setOwnerFn(task))
+	opiTask := toOpiTask(task)
+
+	opiTask.Image, opiTask.PrivateRegistry, err = applyImagePolicy(t.imagePolicy, opiTask.Image, opiTask.PrivateRegistry)
+	if err != nil {
+		logger.Info("image-not-allowed", lager.Data{"reason": err.Error()})
+
+		return reconcile.Result{}, t.reportImageRejection(task, err)
+	}
+
+	err = t.taskDesirer.Desire(task.Namespace, opiTask, t.setOwnerFn(task))
 	if errors.IsAlreadyExists(err) {
 		logger.Info("task-already-exists")
 
@@ -75,6 +87,15 @@ func (t *Task) Reconcile(request reconcile.Request) (reconcile.Result, error) {
 	return reconcile.Result{}, nil
 }
 
+// reportImageRejection records in the status of the task that its image was
+// rejected by the image policy. It is not retried, as only a change to the
+// task can fix it
+func (t *Task) reportImageRejection(task *eiriniv1.Task, rejection error) error {
+	task.Status.Error = rejection.Error()
+
+	return exterrors.Wrap(t.client.Status().Update(context.Background(), task), "failed to update task status")
+}
+
 func (t *Task) setOwnerFn(task *eiriniv1.Task) func(interface{}) error {
 	return func(resource interface{}) error {
 		obj := resource.(metav1.Object)
@@ -107,9 +128,10 @@ func toOpiTask(task *eiriniv1.Task) *opi.Task {
 
 	if task.Spec.PrivateRegistry != nil {
 		opiTask.PrivateRegistry = &opi.PrivateRegistry{
-			Server:   task.Spec.PrivateRegistry.Server,
-			Username: task.Spec.PrivateRegistry.Username,
-			Password: task.Spec.PrivateRegistry.Password,
+			Server:     task.Spec.PrivateRegistry.Server,
+			Username:   task.Spec.PrivateRegistry.Username,
+			Password:   task.Spec.PrivateRegistry.Password,
+			SecretName: task.Spec.PrivateRegistry.SecretName,
 		}
 	}
 
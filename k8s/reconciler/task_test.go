@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/bifrost"
 	"code.cloudfoundry.org/eirini/k8s/reconciler"
 	"code.cloudfoundry.org/eirini/k8s/reconciler/reconcilerfakes"
 	"code.cloudfoundry.org/eirini/opi"
@@ -27,6 +29,8 @@ var _ = Describe("Task", func() {
 		reconcileResult  reconcile.Result
 		reconcileErr     error
 		controllerClient *reconcilerfakes.FakeClient
+		statusWriter     *reconcilerfakes.FakeStatusWriter
+		imagePolicy      bifrost.ImagePolicy
 		namespacedName   types.NamespacedName
 		taskDesirer      *reconcilerfakes.FakeTaskDesirer
 		scheme           *runtime.Scheme
@@ -34,6 +38,9 @@ var _ = Describe("Task", func() {
 
 	BeforeEach(func() {
 		controllerClient = new(reconcilerfakes.FakeClient)
+		statusWriter = new(reconcilerfakes.FakeStatusWriter)
+		controllerClient.StatusReturns(statusWriter)
+		imagePolicy = bifrost.ImagePolicy{}
 		namespacedName = types.NamespacedName{
 			Namespace: "my-namespace",
 			Name:      "my-name",
@@ -41,11 +48,11 @@ var _ = Describe("Task", func() {
 		taskDesirer = new(reconcilerfakes.FakeTaskDesirer)
 
 		scheme = eiriniv1scheme.Scheme
-		logger := lagertest.NewTestLogger("task-reconciler")
-		taskReconciler = reconciler.NewTask(logger, controllerClient, taskDesirer, scheme)
 	})
 
 	JustBeforeEach(func() {
+		logger := lagertest.NewTestLogger("task-reconciler")
+		taskReconciler = reconciler.NewTask(logger, controllerClient, taskDesirer, scheme, imagePolicy)
 		reconcileResult, reconcileErr = taskReconciler.Reconcile(reconcile.Request{NamespacedName: namespacedName})
 	})
 
@@ -62,9 +69,10 @@ var _ = Describe("Task", func() {
 				task.Spec.Image = "my-task-image"
 				task.Spec.CompletionCallback = "my-task-completion-callback"
 				task.Spec.PrivateRegistry = &eiriniv1.PrivateRegistry{
-					Server:   "pr-server",
-					Username: "pr-username",
-					Password: "pr-password",
+					Server:     "pr-server",
+					Username:   "pr-username",
+					Password:   "pr-password",
+					SecretName: "pr-secret",
 				}
 				task.Spec.Env = map[string]string{"foo": "2", "bar": "coffee"}
 				task.Spec.Command = []string{"beam", "me", "up"}
@@ -103,9 +111,10 @@ var _ = Describe("Task", func() {
 				Expect(opiTask.Image).To(Equal("my-task-image"))
 				Expect(opiTask.CompletionCallback).To(Equal("my-task-completion-callback"))
 				Expect(opiTask.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
-					Server:   "pr-server",
-					Username: "pr-username",
-					Password: "pr-password",
+					Server:     "pr-server",
+					Username:   "pr-username",
+					Password:   "pr-password",
+					SecretName: "pr-secret",
 				}))
 				Expect(opiTask.Env).To(Equal(map[string]string{"foo": "2", "bar": "coffee"}))
 				Expect(opiTask.Command).To(Equal([]string{"beam", "me", "up"}))
@@ -133,6 +142,56 @@ var _ = Describe("Task", func() {
 				Expect(job.ObjectMeta.OwnerReferences[0].Name).To(Equal("my-name"))
 			})
 		})
+
+		When("the image policy rejects the image", func() {
+			BeforeEach(func() {
+				imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{DeniedRepositories: []string{"docker.io/library/*"}})
+			})
+
+			It("does not desire the task", func() {
+				Expect(taskDesirer.DesireCallCount()).To(BeZero())
+			})
+
+			It("reports the rejection in the CR status without retrying", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+				Expect(reconcileResult.Requeue).To(BeFalse())
+
+				Expect(statusWriter.UpdateCallCount()).To(Equal(1))
+				_, obj, _ := statusWriter.UpdateArgsForCall(0)
+				Expect(obj.(*eiriniv1.Task).Status.Error).To(ContainSubstring("repository docker.io/library/my-task-image is denied"))
+			})
+
+			When("updating the status fails", func() {
+				BeforeEach(func() {
+					statusWriter.UpdateReturns(fmt.Errorf("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(reconcileErr).To(MatchError("failed to update task status: boom"))
+				})
+			})
+		})
+
+		When("the image policy mirrors the registry of the image", func() {
+			BeforeEach(func() {
+				imagePolicy = bifrost.NewImagePolicy(eirini.ImagePolicyConfig{
+					Mirrors: []eirini.ImageMirrorConfig{{Registry: "docker.io", Mirror: "mirror.internal"}},
+				})
+			})
+
+			It("desires the task with the mirrored image and the registry secret of the CR", func() {
+				Expect(reconcileErr).NotTo(HaveOccurred())
+
+				_, opiTask, _ := taskDesirer.DesireArgsForCall(0)
+				Expect(opiTask.Image).To(Equal("mirror.internal/library/my-task-image"))
+				Expect(opiTask.PrivateRegistry).To(Equal(&opi.PrivateRegistry{
+					Server:     "pr-server",
+					Username:   "pr-username",
+					Password:   "pr-password",
+					SecretName: "pr-secret",
+				}))
+			})
+		})
 	})
 
 	When("the task cannot be found", func() {
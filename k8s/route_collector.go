@@ -60,11 +60,15 @@ func (c RouteCollector) Collect() ([]route.Message, error) {
 				Name:       p.Labels[stset.LabelGUID],
 				Address:    p.Status.PodIP,
 				Port:       uint32(r.Port),
-				TLSPort:    0,
+				TLSPort:    stset.TLSPort(p.Annotations, uint32(r.Port)),
 				Routes: route.Routes{
 					RegisteredRoutes: []string{r.Hostname},
 				},
 			}
+			if routeMessage.TLSPort != 0 {
+				routeMessage.ServerCertDomainSAN = p.Name
+			}
+
 			routeMessages = append(routeMessages, routeMessage)
 		}
 	}
@@ -72,20 +76,102 @@ func (c RouteCollector) Collect() ([]route.Message, error) {
 	return routeMessages, nil
 }
 
-func (c RouteCollector) getRoutes(pod corev1.Pod, statefulsets map[string]appsv1.StatefulSet) ([]cf.Route, error) {
+// CollectTCP returns the TCP routes of all ready pods, grouped by the
+// container port they are mapped to
+func (c RouteCollector) CollectTCP() ([]route.TCPMessage, error) {
+	pods, err := c.podsGetter.GetAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pods")
+	}
+
+	statefulsets, err := c.getStatefulSets()
+	if err != nil {
+		return nil, err
+	}
+
+	tcpMessages := []route.TCPMessage{}
+
+	for _, p := range pods {
+		tcpRoutes, err := c.getTCPRoutes(p, statefulsets)
+		if err != nil {
+			c.logger.Debug("collect-tcp.failed-to-get-tcp-routes", lager.Data{"error": err.Error()})
+
+			continue
+		}
+
+		byPort := map[uint32][]route.TCPRoute{}
+		ports := []uint32{}
+
+		for _, r := range tcpRoutes {
+			if _, ok := byPort[r.ContainerPort]; !ok {
+				ports = append(ports, r.ContainerPort)
+			}
+
+			byPort[r.ContainerPort] = append(byPort[r.ContainerPort], route.TCPRoute{
+				RouterGroupGUID: r.RouterGroupGUID,
+				ExternalPort:    r.ExternalPort,
+			})
+		}
+
+		for _, port := range ports {
+			tcpMessages = append(tcpMessages, route.TCPMessage{
+				InstanceID: p.Name,
+				Name:       p.Labels[stset.LabelGUID],
+				Address:    p.Status.PodIP,
+				Port:       port,
+				TCPRoutes: route.TCPRoutes{
+					RegisteredRoutes: byPort[port],
+				},
+			})
+		}
+	}
+
+	return tcpMessages, nil
+}
+
+func (c RouteCollector) getTCPRoutes(pod corev1.Pod, statefulsets map[string]appsv1.StatefulSet) ([]cf.TCPRoute, error) {
+	s, err := c.getOwnerStatefulSet(pod, statefulsets)
+	if err != nil {
+		return nil, err
+	}
+
+	var tcpRoutes []cf.TCPRoute
+
+	routeJSON, ok := s.Annotations[stset.AnnotationRegisteredTCPRoutes]
+	if !ok {
+		return tcpRoutes, nil
+	}
+
+	if json.Unmarshal([]byte(routeJSON), &tcpRoutes) != nil {
+		return nil, fmt.Errorf("failed to unmarshal tcp routes for pod %s", pod.Name)
+	}
+
+	return tcpRoutes, nil
+}
+
+func (c RouteCollector) getOwnerStatefulSet(pod corev1.Pod, statefulsets map[string]appsv1.StatefulSet) (appsv1.StatefulSet, error) {
 	if !podReady(pod) {
-		return nil, fmt.Errorf("pod %s is not ready", pod.Name)
+		return appsv1.StatefulSet{}, fmt.Errorf("pod %s is not ready", pod.Name)
 	}
 
 	ssName, err := getStatefulSetName(pod)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get statefulset name for pod %s", pod.Name)
+		return appsv1.StatefulSet{}, fmt.Errorf("failed to get statefulset name for pod %s", pod.Name)
 	}
 
 	s, ok := statefulsets[ssName]
 
 	if !ok {
-		return nil, fmt.Errorf("statefulset for pod %s not found", pod.Name)
+		return appsv1.StatefulSet{}, fmt.Errorf("statefulset for pod %s not found", pod.Name)
+	}
+
+	return s, nil
+}
+
+func (c RouteCollector) getRoutes(pod corev1.Pod, statefulsets map[string]appsv1.StatefulSet) ([]cf.Route, error) {
+	s, err := c.getOwnerStatefulSet(pod, statefulsets)
+	if err != nil {
+		return nil, err
 	}
 
 	routeJSON, ok := s.Annotations[stset.AnnotationRegisteredRoutes]
@@ -292,6 +292,32 @@ var _ = Describe("RouteCollector", func() {
 			})
 		})
 
+		Context("and a pod has a route integrity proxy", func() {
+			BeforeEach(func() {
+				pod11.Annotations = map[string]string{
+					stset.AnnotationTLSPorts: `{"80":61001}`,
+				}
+				pods = []corev1.Pod{pod11}
+			})
+
+			It("should register the TLS port with the instance as the expected certificate SAN", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(routeMessages).To(Equal([]route.Message{
+					{
+						InstanceID:          "pod-11",
+						Name:                "pod-11-guid",
+						Address:             "10.0.0.1",
+						Port:                80,
+						TLSPort:             61001,
+						ServerCertDomainSAN: "pod-11",
+						Routes: route.Routes{
+							RegisteredRoutes: []string{"foo.example.com"},
+						},
+					},
+				}))
+			})
+		})
+
 		Context("and there is a pod with multiple owners as long as one is a StatefulSet", func() {
 			BeforeEach(func() {
 				pod11.OwnerReferences[0].Kind = "NotStatefulSet"
@@ -387,3 +413,111 @@ var _ = Describe("RouteCollector", func() {
 		})
 	})
 })
+
+var _ = Describe("RouteCollector CollectTCP", func() {
+	var (
+		podsGetter        *k8sfakes.FakePodsGetter
+		statefulSetGetter *k8sfakes.FakeStatefulSetGetter
+		pod               corev1.Pod
+		statefulset       appsv1.StatefulSet
+		tcpMessages       []route.TCPMessage
+		err               error
+	)
+
+	BeforeEach(func() {
+		tcpRoutes, marshalErr := json.Marshal([]cf.TCPRoute{
+			{RouterGroupGUID: "default-tcp", ExternalPort: 1234, ContainerPort: 8080},
+			{RouterGroupGUID: "default-tcp", ExternalPort: 1235, ContainerPort: 8080},
+			{RouterGroupGUID: "other-tcp", ExternalPort: 2222, ContainerPort: 9090},
+		})
+		Expect(marshalErr).ToNot(HaveOccurred())
+
+		statefulset = appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "ss-1",
+				Annotations: map[string]string{
+					stset.AnnotationRegisteredRoutes:    "[]",
+					stset.AnnotationRegisteredTCPRoutes: string(tcpRoutes),
+				},
+			},
+		}
+		pod = corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "pod-1",
+				Labels: map[string]string{stset.LabelGUID: "app-guid"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "StatefulSet",
+					Name:       "ss-1",
+				}},
+			},
+			Status: corev1.PodStatus{
+				PodIP: "10.0.0.1",
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+				},
+			},
+		}
+
+		podsGetter = new(k8sfakes.FakePodsGetter)
+		statefulSetGetter = new(k8sfakes.FakeStatefulSetGetter)
+	})
+
+	JustBeforeEach(func() {
+		podsGetter.GetAllReturns([]corev1.Pod{pod}, nil)
+		statefulSetGetter.GetBySourceTypeReturns([]appsv1.StatefulSet{statefulset}, nil)
+		collector := NewRouteCollector(podsGetter, statefulSetGetter, lagertest.NewTestLogger("collector-test"))
+		tcpMessages, err = collector.CollectTCP()
+	})
+
+	It("should return a tcp message per container port", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tcpMessages).To(Equal([]route.TCPMessage{
+			{
+				InstanceID: "pod-1",
+				Name:       "app-guid",
+				Address:    "10.0.0.1",
+				Port:       8080,
+				TCPRoutes: route.TCPRoutes{
+					RegisteredRoutes: []route.TCPRoute{
+						{RouterGroupGUID: "default-tcp", ExternalPort: 1234},
+						{RouterGroupGUID: "default-tcp", ExternalPort: 1235},
+					},
+				},
+			},
+			{
+				InstanceID: "pod-1",
+				Name:       "app-guid",
+				Address:    "10.0.0.1",
+				Port:       9090,
+				TCPRoutes: route.TCPRoutes{
+					RegisteredRoutes: []route.TCPRoute{
+						{RouterGroupGUID: "other-tcp", ExternalPort: 2222},
+					},
+				},
+			},
+		}))
+	})
+
+	When("the pod is not ready", func() {
+		BeforeEach(func() {
+			pod.Status.Conditions[0].Status = corev1.ConditionFalse
+		})
+
+		It("should not return tcp messages for it", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tcpMessages).To(BeEmpty())
+		})
+	})
+
+	When("the statefulset predates tcp routes", func() {
+		BeforeEach(func() {
+			delete(statefulset.Annotations, stset.AnnotationRegisteredTCPRoutes)
+		})
+
+		It("should not return tcp messages", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tcpMessages).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,113 @@
+package shared
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const envHashLength = 10
+
+// EnvSecretName is the name of the secret holding the environment of the
+// workload with the given name. It is derived from the environment, so that
+// changing the environment rolls out a new secret instead of changing the one
+// running instances refer to
+func EnvSecretName(workloadName string, env map[string]string) string {
+	hash := sha256.New()
+
+	for _, name := range sortedNames(env) {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		hash.Write([]byte(env[name]))
+		hash.Write([]byte{0})
+	}
+
+	return envSecretPrefix(workloadName) + hex.EncodeToString(hash.Sum(nil))[:envHashLength]
+}
+
+// CredentialsSecretName is the name of the secret holding the VCAP_SERVICES
+// of the env secret with the given name once its credential references are
+// resolved. It lives and dies with the env secret
+func CredentialsSecretName(envSecretName string) string {
+	return envSecretName + "-vcap-services"
+}
+
+// EnvSecretNames lists the environment secrets of the workload with the
+// given name that the container takes its environment from
+func EnvSecretNames(workloadName string, container corev1.Container) []string {
+	names := []string{}
+	seen := map[string]bool{}
+
+	for _, envVar := range container.Env {
+		if envVar.ValueFrom == nil || envVar.ValueFrom.SecretKeyRef == nil {
+			continue
+		}
+
+		name := envVar.ValueFrom.SecretKeyRef.Name
+		if !strings.HasPrefix(name, envSecretPrefix(workloadName)) || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// NewEnvSecret creates a secret holding the environment, keyed by variable
+// name
+func NewEnvSecret(name string, env map[string]string) *corev1.Secret {
+	data := map[string]string{}
+	for k, v := range env {
+		data[k] = v
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: data,
+	}
+}
+
+// MapToSecretEnvVar is like MapToEnvVar, but the EnvVars take their values
+// from the secret with the given name. They are sorted by name, so that the
+// resulting pod template does not change as long as the environment does not
+func MapToSecretEnvVar(secretName string, env map[string]string) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{}
+
+	for _, name := range sortedNames(env) {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  name,
+				},
+			},
+		})
+	}
+
+	return envVars
+}
+
+func envSecretPrefix(workloadName string) string {
+	return workloadName + "-env-"
+}
+
+func sortedNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,91 @@
+package shared_test
+
+import (
+	"code.cloudfoundry.org/eirini/k8s/shared"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("EnvSecret", func() {
+	var env map[string]string
+
+	BeforeEach(func() {
+		env = map[string]string{
+			"foo":  "bar",
+			"dora": "fedora",
+		}
+	})
+
+	Describe("EnvSecretName", func() {
+		It("is prefixed with the workload name", func() {
+			Expect(shared.EnvSecretName("my-app", env)).To(MatchRegexp("^my-app-env-[0-9a-f]{10}$"))
+		})
+
+		It("is the same for the same environment", func() {
+			Expect(shared.EnvSecretName("my-app", env)).To(Equal(shared.EnvSecretName("my-app", map[string]string{
+				"dora": "fedora",
+				"foo":  "bar",
+			})))
+		})
+
+		It("changes with the environment", func() {
+			Expect(shared.EnvSecretName("my-app", env)).NotTo(Equal(shared.EnvSecretName("my-app", map[string]string{
+				"foo":  "bar",
+				"dora": "explorer",
+			})))
+		})
+	})
+
+	Describe("CredentialsSecretName", func() {
+		It("is derived from the env secret name", func() {
+			Expect(shared.CredentialsSecretName("my-app-env-0123456789")).To(Equal("my-app-env-0123456789-vcap-services"))
+		})
+	})
+
+	Describe("NewEnvSecret", func() {
+		It("keys the environment by variable name", func() {
+			secret := shared.NewEnvSecret("my-secret", env)
+			Expect(secret.Name).To(Equal("my-secret"))
+			Expect(secret.Type).To(Equal(corev1.SecretTypeOpaque))
+			Expect(secret.StringData).To(Equal(env))
+		})
+	})
+
+	Describe("MapToSecretEnvVar", func() {
+		It("takes the values from the secret, sorted by name", func() {
+			Expect(shared.MapToSecretEnvVar("my-secret", env)).To(Equal([]corev1.EnvVar{
+				{Name: "dora", ValueFrom: secretKeyRef("my-secret", "dora")},
+				{Name: "foo", ValueFrom: secretKeyRef("my-secret", "foo")},
+			}))
+		})
+	})
+
+	Describe("EnvSecretNames", func() {
+		It("lists the env secrets of the workload the container refers to", func() {
+			container := corev1.Container{
+				Env: []corev1.EnvVar{
+					{Name: "dora", ValueFrom: secretKeyRef("my-app-env-0123456789", "dora")},
+					{Name: "foo", ValueFrom: secretKeyRef("my-app-env-0123456789", "foo")},
+					{Name: "old", ValueFrom: secretKeyRef("my-app-env-9876543210", "old")},
+					{Name: "other", ValueFrom: secretKeyRef("my-app-vcap-services", "other")},
+					{Name: "plain", Value: "value"},
+				},
+			}
+
+			Expect(shared.EnvSecretNames("my-app", container)).To(Equal([]string{
+				"my-app-env-0123456789",
+				"my-app-env-9876543210",
+			}))
+		})
+	})
+})
+
+func secretKeyRef(name, key string) *corev1.EnvVarSource {
+	return &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  key,
+		},
+	}
+}
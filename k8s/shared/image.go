@@ -0,0 +1,31 @@
+package shared
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ImagePullPolicy only skips pulling images which are pinned to a digest, as
+// a tag may have moved since the image was pulled on the node. Images of a
+// private registry are always pulled, so that the registry checks the
+// credentials of every pod; otherwise any pod on the node could run the
+// cached image by its digest
+func ImagePullPolicy(image string, privateRegistry bool) corev1.PullPolicy {
+	if !privateRegistry && strings.Contains(image, "@sha256:") {
+		return corev1.PullIfNotPresent
+	}
+
+	return corev1.PullAlways
+}
+
+// NodeSelector schedules the pods of images which are available for a single
+// architecture on nodes of that architecture. An empty architecture runs
+// anywhere
+func NodeSelector(architecture string) map[string]string {
+	if architecture == "" {
+		return nil
+	}
+
+	return map[string]string{corev1.LabelArchStable: architecture}
+}
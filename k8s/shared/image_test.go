@@ -0,0 +1,33 @@
+package shared_test
+
+import (
+	"code.cloudfoundry.org/eirini/k8s/shared"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("ImagePullPolicy", func() {
+	It("always pulls tagged images", func() {
+		Expect(shared.ImagePullPolicy("eirini/dorini:latest", false)).To(Equal(corev1.PullAlways))
+		Expect(shared.ImagePullPolicy("eirini/dorini", false)).To(Equal(corev1.PullAlways))
+	})
+
+	It("pulls images pinned to a digest only if they are not present", func() {
+		Expect(shared.ImagePullPolicy("eirini/dorini@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881", false)).To(Equal(corev1.PullIfNotPresent))
+	})
+
+	It("always pulls images of a private registry, even when they are pinned to a digest", func() {
+		Expect(shared.ImagePullPolicy("eirini/dorini@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881", true)).To(Equal(corev1.PullAlways))
+	})
+})
+
+var _ = Describe("NodeSelector", func() {
+	It("selects nodes of the architecture", func() {
+		Expect(shared.NodeSelector("arm64")).To(Equal(map[string]string{"kubernetes.io/arch": "arm64"}))
+	})
+
+	It("selects any node when there is no architecture", func() {
+		Expect(shared.NodeSelector("")).To(BeNil())
+	})
+})
@@ -15,7 +15,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-//counterfeiter:generate . SecretsCreator
+//counterfeiter:generate . SecretsClient
 //counterfeiter:generate . StatefulSetCreator
 //counterfeiter:generate . LRPToStatefulSetConverter
 
@@ -23,8 +23,10 @@ type LRPToStatefulSetConverter interface {
 	Convert(statefulSetName string, lrp *opi.LRP) (*appsv1.StatefulSet, error)
 }
 
-type SecretsCreator interface {
+type SecretsClient interface {
 	Create(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Update(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Delete(namespace string, name string) error
 }
 
 type StatefulSetCreator interface {
@@ -33,18 +35,20 @@ type StatefulSetCreator interface {
 
 type Desirer struct {
 	logger                    lager.Logger
-	secrets                   SecretsCreator
+	secrets                   SecretsClient
 	statefulSets              StatefulSetCreator
 	lrpToStatefulSetConverter LRPToStatefulSetConverter
 	createPodDisruptionBudget createPodDisruptionBudgetFunc
+	internalServices          internalServices
 }
 
 func NewDesirer(
 	logger lager.Logger,
-	secrets SecretsCreator,
+	secrets SecretsClient,
 	statefulSets StatefulSetCreator,
 	lrpToStatefulSetConverter LRPToStatefulSetConverter,
 	podDisruptionBudget PodDisruptionBudgetCreator,
+	services ServicesClient,
 ) Desirer {
 	return Desirer{
 		logger:                    logger,
@@ -52,6 +56,7 @@ func NewDesirer(
 		statefulSets:              statefulSets,
 		lrpToStatefulSetConverter: lrpToStatefulSetConverter,
 		createPodDisruptionBudget: newCreatePodDisruptionBudgetFunc(podDisruptionBudget),
+		internalServices:          internalServices{services: services},
 	}
 }
 
@@ -63,13 +68,27 @@ func (d *Desirer) Desire(namespace string, lrp *opi.LRP, opts ...shared.Option)
 		return err
 	}
 
-	if lrp.PrivateRegistry != nil {
+	if hasRegistryCredentials(lrp.PrivateRegistry) {
 		err = d.createRegistryCredsSecret(namespace, statefulSetName, lrp)
 		if err != nil {
 			return err
 		}
 	}
 
+	envSecret, err := d.createEnvSecret(namespace, statefulSetName, lrp)
+	if err != nil {
+		logger.Error("failed-to-create-env-secret", err)
+
+		return err
+	}
+
+	originalRequestSecret, err := d.createOriginalRequestSecret(namespace, statefulSetName, lrp)
+	if err != nil {
+		logger.Error("failed-to-create-original-request-secret", err)
+
+		return err
+	}
+
 	st, err := d.lrpToStatefulSetConverter.Convert(statefulSetName, lrp)
 	if err != nil {
 		return err
@@ -82,7 +101,8 @@ func (d *Desirer) Desire(namespace string, lrp *opi.LRP, opts ...shared.Option)
 		return err
 	}
 
-	if _, err := d.statefulSets.Create(namespace, st); err != nil {
+	createdStatefulSet, err := d.statefulSets.Create(namespace, st)
+	if err != nil {
 		var statusErr *k8serrors.StatusError
 		if errors.As(err, &statusErr) && statusErr.Status().Reason == metav1.StatusReasonAlreadyExists {
 			logger.Debug("statefulset-already-exists", lager.Data{"error": err.Error()})
@@ -93,12 +113,24 @@ func (d *Desirer) Desire(namespace string, lrp *opi.LRP, opts ...shared.Option)
 		return errors.Wrap(err, "failed to create statefulset")
 	}
 
+	if err := d.ownSecrets(namespace, createdStatefulSet, envSecret, originalRequestSecret); err != nil {
+		logger.Error("failed-to-own-secrets", err)
+
+		return err
+	}
+
 	if err := d.createPodDisruptionBudget(namespace, statefulSetName, lrp); err != nil {
 		logger.Error("failed-to-create-pod-disruption-budget", err)
 
 		return errors.Wrap(err, "failed to create pod disruption budget")
 	}
 
+	if err := d.internalServices.apply(logger, createdStatefulSet, lrp); err != nil {
+		logger.Error("failed-to-apply-internal-services", err)
+
+		return err
+	}
+
 	return nil
 }
 
@@ -113,6 +145,69 @@ func (d *Desirer) createRegistryCredsSecret(namespace, statefulSetName string, l
 	return errors.Wrap(err, "failed to create private registry secret for statefulset")
 }
 
+// createEnvSecret creates the secret the app container takes the environment
+// of the LRP from. It is created before the StatefulSet, so that it is there
+// for the pod webhooks by the time the first instance is scheduled
+func (d *Desirer) createEnvSecret(namespace, statefulSetName string, lrp *opi.LRP) (*corev1.Secret, error) {
+	_, env := splitEnv(lrp.Env)
+	if len(env) == 0 {
+		return nil, nil
+	}
+
+	secret, err := d.secrets.Create(namespace, shared.NewEnvSecret(shared.EnvSecretName(statefulSetName, env), env))
+	if k8serrors.IsAlreadyExists(err) {
+		return nil, nil
+	}
+
+	return secret, errors.Wrap(err, "failed to create env secret for statefulset")
+}
+
+// createOriginalRequestSecret keeps the redacted desire request of the LRP,
+// which is too large and too sensitive for an annotation
+func (d *Desirer) createOriginalRequestSecret(namespace, statefulSetName string, lrp *opi.LRP) (*corev1.Secret, error) {
+	if lrp.LRP == "" {
+		return nil, nil
+	}
+
+	secret, err := generateOriginalRequestSecret(statefulSetName, lrp.LRP)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err = d.secrets.Create(namespace, secret)
+	if k8serrors.IsAlreadyExists(err) {
+		return nil, nil
+	}
+
+	return secret, errors.Wrap(err, "failed to create original request secret for statefulset")
+}
+
+// ownSecrets makes the secrets created for the StatefulSet owned by it, so
+// that they go away with it
+func (d *Desirer) ownSecrets(namespace string, statefulSet *appsv1.StatefulSet, secrets ...*corev1.Secret) error {
+	for _, secret := range secrets {
+		if secret == nil {
+			continue
+		}
+
+		secret.OwnerReferences = append(secret.OwnerReferences, statefulSetOwnerReference(statefulSet))
+		if _, err := d.secrets.Update(namespace, secret); err != nil {
+			return errors.Wrapf(err, "failed to set owner of secret %s", secret.Name)
+		}
+	}
+
+	return nil
+}
+
+func statefulSetOwnerReference(statefulSet *appsv1.StatefulSet) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       "StatefulSet",
+		Name:       statefulSet.Name,
+		UID:        statefulSet.UID,
+	}
+}
+
 func generateRegistryCredsSecret(statefulSetName string, lrp *opi.LRP) (*corev1.Secret, error) {
 	dockerConfig := dockerutils.NewDockerConfig(
 		lrp.PrivateRegistry.Server,
@@ -139,3 +234,24 @@ func generateRegistryCredsSecret(statefulSetName string, lrp *opi.LRP) (*corev1.
 func privateRegistrySecretName(statefulSetName string) string {
 	return fmt.Sprintf("%s-registry-credentials", statefulSetName)
 }
+
+// hasRegistryCredentials tells whether the registry credentials come in
+// plaintext, to be put into a generated secret, rather than in a secret the
+// LRP refers to
+func hasRegistryCredentials(registry *opi.PrivateRegistry) bool {
+	return registry != nil && registry.SecretName == ""
+}
+
+// registryCredsSecretName is the name of the secret the LRP pulls its image
+// with, if it comes from a private registry
+func registryCredsSecretName(statefulSetName string, registry *opi.PrivateRegistry) string {
+	if registry == nil {
+		return ""
+	}
+
+	if registry.SecretName != "" {
+		return registry.SecretName
+	}
+
+	return privateRegistrySecretName(statefulSetName)
+}
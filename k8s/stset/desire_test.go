@@ -11,7 +11,6 @@ import (
 	"code.cloudfoundry.org/eirini/k8s/stset/stsetfakes"
 	"code.cloudfoundry.org/eirini/k8s/utils"
 	"code.cloudfoundry.org/eirini/opi"
-	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -27,11 +26,12 @@ import (
 
 var _ = Describe("Desirer", func() {
 	var (
-		logger                     lager.Logger
-		secrets                    *stsetfakes.FakeSecretsCreator
+		logger                     *lagertest.TestLogger
+		secrets                    *stsetfakes.FakeSecretsClient
 		statefulSets               *stsetfakes.FakeStatefulSetCreator
 		lrpToStatefulSetConverter  *stsetfakes.FakeLRPToStatefulSetConverter
 		podDisruptionBudget        *stsetfakes.FakePodDisruptionBudgetCreator
+		services                   *stsetfakes.FakeServicesClient
 		desireOptOne, desireOptTwo *sharedfakes.FakeOption
 
 		lrp       *opi.LRP
@@ -42,8 +42,14 @@ var _ = Describe("Desirer", func() {
 
 	BeforeEach(func() {
 		logger = lagertest.NewTestLogger("statefulset-desirer")
-		secrets = new(stsetfakes.FakeSecretsCreator)
+		secrets = new(stsetfakes.FakeSecretsClient)
 		statefulSets = new(stsetfakes.FakeStatefulSetCreator)
+		statefulSets.CreateStub = func(namespace string, statefulSet *v1.StatefulSet) (*v1.StatefulSet, error) {
+			created := statefulSet.DeepCopy()
+			created.UID = "the-uid"
+
+			return created, nil
+		}
 		lrpToStatefulSetConverter = new(stsetfakes.FakeLRPToStatefulSetConverter)
 		lrpToStatefulSetConverter.ConvertStub = func(statefulSetName string, lrp *opi.LRP) (*v1.StatefulSet, error) {
 			return &v1.StatefulSet{
@@ -54,11 +60,13 @@ var _ = Describe("Desirer", func() {
 		}
 
 		podDisruptionBudget = new(stsetfakes.FakePodDisruptionBudgetCreator)
+		services = new(stsetfakes.FakeServicesClient)
+		services.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "service"))
 		lrp = createLRP("Baldur", []opi.Route{{Hostname: "my.example.route", Port: 1000}})
 		desireOptOne = new(sharedfakes.FakeOption)
 		desireOptTwo = new(sharedfakes.FakeOption)
 
-		desirer = stset.NewDesirer(logger, secrets, statefulSets, lrpToStatefulSetConverter, podDisruptionBudget)
+		desirer = stset.NewDesirer(logger, secrets, statefulSets, lrpToStatefulSetConverter, podDisruptionBudget, services)
 	})
 
 	JustBeforeEach(func() {
@@ -96,6 +104,139 @@ var _ = Describe("Desirer", func() {
 		Expect(namespace).To(Equal("the-namespace"))
 	})
 
+	It("should create a headless service owned by the statefulset", func() {
+		Expect(services.ApplyCallCount()).To(Equal(1))
+
+		service := services.ApplyArgsForCall(0)
+		Expect(service.Name).To(Equal("internal-baldur-space-foo-34f869d015"))
+		Expect(service.Namespace).To(Equal("the-namespace"))
+		Expect(service.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+		Expect(service.Spec.Selector).To(Equal(map[string]string{
+			stset.LabelGUID:       "guid_1234",
+			stset.LabelVersion:    "version_1234",
+			stset.LabelSourceType: "APP",
+		}))
+		Expect(service.Labels).To(HaveKeyWithValue(stset.LabelServiceType, stset.InternalServiceType))
+		Expect(service.Spec.Ports).To(ConsistOf(
+			MatchFields(IgnoreExtras, Fields{"Name": Equal("port-8888"), "Port": Equal(int32(8888))}),
+			MatchFields(IgnoreExtras, Fields{"Name": Equal("port-9999"), "Port": Equal(int32(9999))}),
+		))
+		Expect(service.OwnerReferences).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+			"Kind": Equal("StatefulSet"),
+			"Name": Equal("baldur-space-foo-34f869d015"),
+			"UID":  BeEquivalentTo("the-uid"),
+		})))
+	})
+
+	When("the app has internal routes", func() {
+		BeforeEach(func() {
+			lrp.InternalRoutes = []opi.InternalRoute{
+				{Hostname: "backend.apps.internal"},
+				{Hostname: "Backend.other.internal"},
+				{Hostname: "9lives.apps.internal"},
+			}
+		})
+
+		It("should create a service per valid internal route hostname", func() {
+			Expect(services.ApplyCallCount()).To(Equal(3))
+
+			service := services.ApplyArgsForCall(1)
+			Expect(service.Name).To(Equal("backend-apps-internal"))
+			Expect(service.Annotations).To(HaveKeyWithValue(stset.AnnotationInternalRouteHostname, "backend.apps.internal"))
+			Expect(service.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+
+			service = services.ApplyArgsForCall(2)
+			Expect(service.Name).To(Equal("backend-other-internal"))
+			Expect(service.Annotations).To(HaveKeyWithValue(stset.AnnotationInternalRouteHostname, "backend.other.internal"))
+		})
+
+		When("a service of an internal route belongs to another lrp", func() {
+			BeforeEach(func() {
+				services.GetStub = func(namespace, name string) (*corev1.Service, error) {
+					if name == "backend-apps-internal" {
+						return &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+							Name:   name,
+							Labels: map[string]string{stset.LabelGUID: "other-guid"},
+						}}, nil
+					}
+
+					return nil, k8serrors.NewNotFound(schema.GroupResource{}, name)
+				}
+			})
+
+			It("should not take it over", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				Expect(services.ApplyCallCount()).To(Equal(2))
+				Expect(services.ApplyArgsForCall(1).Name).To(Equal("backend-other-internal"))
+				Expect(logger.LogMessages()).To(ContainElement(ContainSubstring("skipping-internal-service-of-other-lrp")))
+			})
+		})
+
+		When("a service of an internal route has no lrp", func() {
+			BeforeEach(func() {
+				services.GetReturns(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "unrelated"}}, nil)
+			})
+
+			It("should not take it over", func() {
+				Expect(services.ApplyCallCount()).To(BeZero())
+			})
+		})
+
+		When("the service of an internal route already belongs to the lrp", func() {
+			BeforeEach(func() {
+				services.GetReturns(&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{stset.LabelGUID: "guid_1234"},
+				}}, nil)
+			})
+
+			It("should apply it", func() {
+				Expect(services.ApplyCallCount()).To(Equal(3))
+			})
+		})
+
+		When("getting a service fails", func() {
+			BeforeEach(func() {
+				services.GetReturns(nil, errors.New("boom"))
+			})
+
+			It("should propagate the error", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("boom")))
+				Expect(services.ApplyCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	When("a previously created internal service is no longer desired", func() {
+		BeforeEach(func() {
+			services.ListReturns([]corev1.Service{
+				{ObjectMeta: metav1.ObjectMeta{Name: "internal-baldur-space-foo-34f869d015", Namespace: "the-namespace"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "the-namespace"}},
+			}, nil)
+		})
+
+		It("deletes it", func() {
+			Expect(services.ListCallCount()).To(Equal(1))
+			namespace, selector := services.ListArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(selector).To(Equal("cloudfoundry.org/guid=guid_1234,cloudfoundry.org/version=version_1234,cloudfoundry.org/service_type=internal"))
+
+			Expect(services.DeleteCallCount()).To(Equal(1))
+			namespace, name := services.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(name).To(Equal("stale"))
+		})
+	})
+
+	When("applying the internal services fails", func() {
+		BeforeEach(func() {
+			services.ApplyReturns(errors.New("boom"))
+		})
+
+		It("should propagate the error", func() {
+			Expect(desireErr).To(MatchError(ContainSubstring("boom")))
+		})
+	})
+
 	When("the app name contains unsupported characters", func() {
 		BeforeEach(func() {
 			lrp = createLRP("Балдър", []opi.Route{{Hostname: "my.example.route", Port: 10000}})
@@ -145,6 +286,10 @@ var _ = Describe("Desirer", func() {
 			It("does not fail", func() {
 				Expect(desireErr).NotTo(HaveOccurred())
 			})
+
+			It("does not touch the internal services", func() {
+				Expect(services.ApplyCallCount()).To(BeZero())
+			})
 		})
 
 		When("creating the statefulset fails", func() {
@@ -158,6 +303,133 @@ var _ = Describe("Desirer", func() {
 		})
 	})
 
+	It("should keep the redacted original request in a secret", func() {
+		Expect(secrets.CreateCallCount()).To(Equal(1))
+		secretNamespace, actualSecret := secrets.CreateArgsForCall(0)
+		Expect(secretNamespace).To(Equal("the-namespace"))
+		Expect(actualSecret.Name).To(Equal("baldur-space-foo-34f869d015-original-request"))
+		Expect(stset.ReadOriginalRequest(actualSecret)).To(Equal("[REDACTED]"))
+	})
+
+	When("the original request is a desire request", func() {
+		BeforeEach(func() {
+			lrp.LRP = `{
+				"guid": "guid_1234",
+				"environment": {"DATABASE_URL": "mysql://user:password@db", "FOO": "bar"},
+				"lifecycle": {"docker_lifecycle": {"image": "busybox", "registry_username": "user", "registry_password": "password"}},
+				"volume_mounts": [{"volume_id": "vol", "credentials": {"token": "abc"}}]
+			}`
+		})
+
+		It("should redact the environment and the credentials", func() {
+			_, actualSecret := secrets.CreateArgsForCall(0)
+			Expect(stset.ReadOriginalRequest(actualSecret)).To(MatchJSON(`{
+				"guid": "guid_1234",
+				"environment": {"DATABASE_URL": "[REDACTED]", "FOO": "[REDACTED]"},
+				"lifecycle": {"docker_lifecycle": {"image": "busybox", "registry_username": "user", "registry_password": "[REDACTED]"}},
+				"volume_mounts": [{"volume_id": "vol", "credentials": "[REDACTED]"}]
+			}`))
+		})
+	})
+
+	When("the original request is empty", func() {
+		BeforeEach(func() {
+			lrp.LRP = ""
+		})
+
+		It("should not create any secret", func() {
+			Expect(secrets.CreateCallCount()).To(BeZero())
+		})
+	})
+
+	When("the original request secret already exists", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "boom"))
+		})
+
+		It("should carry on", func() {
+			Expect(desireErr).NotTo(HaveOccurred())
+			Expect(statefulSets.CreateCallCount()).To(Equal(1))
+		})
+	})
+
+	When("creating the original request secret fails", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, errors.New("boom"))
+		})
+
+		It("should not create the statefulset", func() {
+			Expect(desireErr).To(MatchError(ContainSubstring("failed to create original request secret")))
+			Expect(statefulSets.CreateCallCount()).To(BeZero())
+		})
+	})
+
+	When("the app has an environment", func() {
+		BeforeEach(func() {
+			lrp.Env = map[string]string{
+				"VCAP_APPLICATION": `{"application_name":"baldur"}`,
+				"DATABASE_URL":     "mysql://user:password@db",
+			}
+			secrets.CreateStub = func(namespace string, secret *corev1.Secret) (*corev1.Secret, error) {
+				return secret.DeepCopy(), nil
+			}
+		})
+
+		It("should store it in a secret before creating the statefulset", func() {
+			Expect(secrets.CreateCallCount()).To(Equal(2))
+			secretNamespace, actualSecret := secrets.CreateArgsForCall(0)
+			Expect(secretNamespace).To(Equal("the-namespace"))
+			Expect(actualSecret.Name).To(HavePrefix("baldur-space-foo-34f869d015-env-"))
+			Expect(actualSecret.StringData).To(Equal(map[string]string{
+				"DATABASE_URL": "mysql://user:password@db",
+			}))
+		})
+
+		It("should make the statefulset own the secrets", func() {
+			Expect(secrets.UpdateCallCount()).To(Equal(2))
+			for i := 0; i < 2; i++ {
+				_, actualSecret := secrets.UpdateArgsForCall(i)
+				Expect(actualSecret.OwnerReferences).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Kind": Equal("StatefulSet"),
+					"Name": Equal("baldur-space-foo-34f869d015"),
+					"UID":  BeEquivalentTo("the-uid"),
+				})))
+			}
+		})
+
+		When("the secret already exists", func() {
+			BeforeEach(func() {
+				secrets.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "boom"))
+			})
+
+			It("should carry on", func() {
+				Expect(desireErr).NotTo(HaveOccurred())
+				Expect(statefulSets.CreateCallCount()).To(Equal(1))
+			})
+		})
+
+		When("creating the secret fails", func() {
+			BeforeEach(func() {
+				secrets.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("should not create the statefulset", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("failed to create env secret")))
+				Expect(statefulSets.CreateCallCount()).To(BeZero())
+			})
+		})
+
+		When("setting the owner of the secret fails", func() {
+			BeforeEach(func() {
+				secrets.UpdateReturns(nil, errors.New("boom"))
+			})
+
+			It("should propagate the error", func() {
+				Expect(desireErr).To(MatchError(ContainSubstring("failed to set owner of secret")))
+			})
+		})
+	})
+
 	When("the app references a private docker image", func() {
 		BeforeEach(func() {
 			lrp.PrivateRegistry = &opi.PrivateRegistry{
@@ -168,7 +440,7 @@ var _ = Describe("Desirer", func() {
 		})
 
 		It("should create a private repo secret containing the private repo credentials", func() {
-			Expect(secrets.CreateCallCount()).To(Equal(1))
+			Expect(secrets.CreateCallCount()).To(Equal(2))
 			secretNamespace, actualSecret := secrets.CreateArgsForCall(0)
 			Expect(secretNamespace).To(Equal("the-namespace"))
 			Expect(actualSecret.Name).To(Equal("baldur-space-foo-34f869d015-registry-credentials"))
@@ -183,6 +455,18 @@ var _ = Describe("Desirer", func() {
 				),
 			)
 		})
+
+		When("the app refers to a registry secret", func() {
+			BeforeEach(func() {
+				lrp.PrivateRegistry.SecretName = "my-registry-secret"
+			})
+
+			It("should not generate a private repo secret", func() {
+				Expect(secrets.CreateCallCount()).To(Equal(1))
+				_, actualSecret := secrets.CreateArgsForCall(0)
+				Expect(actualSecret.Type).NotTo(Equal(corev1.SecretTypeDockerConfigJson))
+			})
+		})
 	})
 })
 
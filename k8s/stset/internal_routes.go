@@ -0,0 +1,210 @@
+package stset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+//counterfeiter:generate . ServicesClient
+
+type ServicesClient interface {
+	Get(namespace, name string) (*corev1.Service, error)
+	Apply(service *corev1.Service) error
+	List(namespace, labelSelector string) ([]corev1.Service, error)
+	Delete(namespace, name string) error
+}
+
+// HeadlessServiceName is the name of the headless Service which gives the
+// instances of an LRP stable DNS names. It also serves as the governing
+// service of the StatefulSet
+func HeadlessServiceName(statefulSetName string) string {
+	return "internal-" + strings.ReplaceAll(statefulSetName, ".", "-")
+}
+
+// internalServices keeps a headless Service per LRP, plus one per internal
+// route hostname, in step with the StatefulSet owning them. The Service of
+// an internal route is named after its whole hostname, with the dots turned
+// into dashes, so that a DNS rewrite of <name>.apps.internal to
+// <name>-apps-internal.<namespace>.svc resolves it to the app instances.
+// Services which belong to another LRP, or to nobody, are never taken over
+type internalServices struct {
+	services ServicesClient
+}
+
+func (s internalServices) apply(logger lager.Logger, statefulSet *appsv1.StatefulSet, lrp *opi.LRP) error {
+	desired := map[string]bool{}
+
+	for _, service := range toInternalServices(logger, statefulSet, lrp) {
+		owned, err := s.ownedBy(service, lrp.GUID)
+		if err != nil {
+			return err
+		}
+
+		if !owned {
+			logger.Error("skipping-internal-service-of-other-lrp", errors.New("service exists and belongs to another lrp"), lager.Data{
+				"name":     service.Name,
+				"hostname": service.Annotations[AnnotationInternalRouteHostname],
+			})
+
+			continue
+		}
+
+		if err := s.services.Apply(service); err != nil {
+			return errors.Wrapf(err, "failed to apply internal service %s", service.Name)
+		}
+
+		desired[service.Name] = true
+	}
+
+	existing, err := s.services.List(statefulSet.Namespace, internalServicesSelector(lrp.LRPIdentifier))
+	if err != nil {
+		return errors.Wrap(err, "failed to list internal services")
+	}
+
+	for _, service := range existing {
+		if desired[service.Name] {
+			continue
+		}
+
+		if err := s.services.Delete(service.Namespace, service.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete internal service %s", service.Name)
+		}
+	}
+
+	return nil
+}
+
+// ownedBy tells whether the service does not exist yet or already belongs to
+// the LRP with the given GUID
+func (s internalServices) ownedBy(service *corev1.Service, guid string) (bool, error) {
+	existing, err := s.services.Get(service.Namespace, service.Name)
+	if k8serrors.IsNotFound(err) {
+		return true, nil
+	}
+
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get internal service %s", service.Name)
+	}
+
+	return existing.Labels[LabelGUID] == guid, nil
+}
+
+func (s internalServices) delete(namespace string, identifier opi.LRPIdentifier) error {
+	existing, err := s.services.List(namespace, internalServicesSelector(identifier))
+	if err != nil {
+		return errors.Wrap(err, "failed to list internal services")
+	}
+
+	for _, service := range existing {
+		if err := s.services.Delete(service.Namespace, service.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete internal service %s", service.Name)
+		}
+	}
+
+	return nil
+}
+
+func toInternalServices(logger lager.Logger, statefulSet *appsv1.StatefulSet, lrp *opi.LRP) []*corev1.Service {
+	services := []*corev1.Service{
+		headlessService(statefulSet, HeadlessServiceName(statefulSet.Name), lrp),
+	}
+
+	for _, hostname := range internalRouteHostnames(lrp.InternalRoutes) {
+		name := internalRouteServiceName(hostname)
+		if errs := validation.IsDNS1035Label(name); len(errs) != 0 {
+			logger.Info("skipping-invalid-internal-route", lager.Data{"hostname": hostname, "errors": errs})
+
+			continue
+		}
+
+		service := headlessService(statefulSet, name, lrp)
+		service.Annotations = map[string]string{AnnotationInternalRouteHostname: hostname}
+		services = append(services, service)
+	}
+
+	return services
+}
+
+func headlessService(statefulSet *appsv1.StatefulSet, name string, lrp *opi.LRP) *corev1.Service {
+	servicePorts := []corev1.ServicePort{}
+	for _, port := range lrp.Ports {
+		servicePorts = append(servicePorts, corev1.ServicePort{
+			Name:       fmt.Sprintf("port-%d", port),
+			Port:       port,
+			TargetPort: intstr.FromInt(int(port)),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: statefulSet.Namespace,
+			Labels: map[string]string{
+				LabelGUID:        lrp.GUID,
+				LabelVersion:     lrp.Version,
+				LabelServiceType: InternalServiceType,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(statefulSet, appsv1.SchemeGroupVersion.WithKind("StatefulSet")),
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  statefulSetLabelSelector(lrp).MatchLabels,
+			Ports:     servicePorts,
+		},
+	}
+}
+
+func internalRouteHostnames(routes []opi.InternalRoute) []string {
+	seen := map[string]bool{}
+	hostnames := []string{}
+
+	for _, r := range routes {
+		hostname := strings.ToLower(r.Hostname)
+		if !seen[hostname] {
+			seen[hostname] = true
+			hostnames = append(hostnames, hostname)
+		}
+	}
+
+	sort.Strings(hostnames)
+
+	return hostnames
+}
+
+func internalRouteServiceName(hostname string) string {
+	return strings.ReplaceAll(hostname, ".", "-")
+}
+
+func internalServicesSelector(identifier opi.LRPIdentifier) string {
+	return fmt.Sprintf("%s=%s,%s=%s,%s=%s",
+		LabelGUID, identifier.GUID,
+		LabelVersion, identifier.Version,
+		LabelServiceType, InternalServiceType,
+	)
+}
+
+func marshalInternalRoutes(routes []opi.InternalRoute) (string, error) {
+	if routes == nil {
+		routes = []opi.InternalRoute{}
+	}
+
+	internalRoutes, err := json.Marshal(routes)
+
+	return string(internalRoutes), errors.Wrap(err, "failed to marshal internal routes")
+}
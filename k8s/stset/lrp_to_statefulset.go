@@ -2,10 +2,12 @@ package stset
 
 import (
 	"encoding/json"
+	"path/filepath"
 
 	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/opi"
+	"code.cloudfoundry.org/eirini/route/integrity"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -15,7 +17,14 @@ import (
 
 //counterfeiter:generate . ProbeCreator
 
-const PodAffinityTermWeight = 100
+const (
+	PodAffinityTermWeight = 100
+
+	routeIntegrityCPURequest    = "10m"
+	routeIntegrityCPULimit      = "500m"
+	routeIntegrityMemoryRequest = "32Mi"
+	routeIntegrityMemoryLimit   = "128Mi"
+)
 
 type ProbeCreator func(lrp *opi.LRP) *corev1.Probe
 
@@ -25,6 +34,7 @@ type LRPToStatefulSet struct {
 	allowAutomountServiceAccountToken bool
 	livenessProbeCreator              ProbeCreator
 	readinessProbeCreator             ProbeCreator
+	routeIntegrity                    eirini.RouteIntegrityConfig
 }
 
 func NewLRPToStatefulSetConverter(
@@ -33,6 +43,7 @@ func NewLRPToStatefulSetConverter(
 	allowAutomountServiceAccountToken bool,
 	livenessProbeCreator ProbeCreator,
 	readinessProbeCreator ProbeCreator,
+	routeIntegrity eirini.RouteIntegrityConfig,
 ) *LRPToStatefulSet {
 	return &LRPToStatefulSet{
 		applicationServiceAccount:         applicationServiceAccount,
@@ -40,47 +51,12 @@ func NewLRPToStatefulSetConverter(
 		allowAutomountServiceAccountToken: allowAutomountServiceAccountToken,
 		livenessProbeCreator:              livenessProbeCreator,
 		readinessProbeCreator:             readinessProbeCreator,
+		routeIntegrity:                    routeIntegrity,
 	}
 }
 
 func (c *LRPToStatefulSet) Convert(statefulSetName string, lrp *opi.LRP) (*appsv1.StatefulSet, error) {
-	envs := shared.MapToEnvVar(lrp.Env)
-	fieldEnvs := []corev1.EnvVar{
-		{
-			Name: eirini.EnvPodName,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "metadata.name",
-				},
-			},
-		},
-		{
-			Name: eirini.EnvCFInstanceGUID,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "metadata.uid",
-				},
-			},
-		},
-		{
-			Name: eirini.EnvCFInstanceIP,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "status.hostIP",
-				},
-			},
-		},
-		{
-			Name: eirini.EnvCFInstanceInternalIP,
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{
-					FieldPath: "status.podIP",
-				},
-			},
-		},
-	}
-
-	envs = append(envs, fieldEnvs...)
+	envs := appEnv(statefulSetName, lrp.Env)
 	ports := []corev1.ContainerPort{}
 
 	for _, port := range lrp.Ports {
@@ -98,7 +74,7 @@ func (c *LRPToStatefulSet) Convert(statefulSetName string, lrp *opi.LRP) (*appsv
 		{
 			Name:            OPIContainerName,
 			Image:           lrp.Image,
-			ImagePullPolicy: corev1.PullAlways,
+			ImagePullPolicy: shared.ImagePullPolicy(lrp.Image, lrp.PrivateRegistry != nil),
 			Command:         lrp.Command,
 			Env:             envs,
 			Ports:           ports,
@@ -121,10 +97,12 @@ func (c *LRPToStatefulSet) Convert(statefulSetName string, lrp *opi.LRP) (*appsv
 		Spec: appsv1.StatefulSetSpec{
 			PodManagementPolicy: "Parallel",
 			Replicas:            int32ptr(lrp.TargetInstances),
+			ServiceName:         HeadlessServiceName(statefulSetName),
 			Template: corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
 					Containers:         containers,
 					ImagePullSecrets:   imagePullSecrets,
+					NodeSelector:       shared.NodeSelector(lrp.Architecture),
 					SecurityContext:    getGetSecurityContext(lrp),
 					ServiceAccountName: c.applicationServiceAccount,
 					Volumes:            volumes,
@@ -176,24 +154,49 @@ func (c *LRPToStatefulSet) Convert(statefulSetName string, lrp *opi.LRP) (*appsv
 		return nil, errors.Wrap(err, "failed to marshal app uris")
 	}
 
+	tcpRoutes, err := marshalTCPRoutes(lrp.TCPRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	internalRoutes, err := marshalInternalRoutes(lrp.InternalRoutes)
+	if err != nil {
+		return nil, err
+	}
+
 	annotations := map[string]string{
-		AnnotationSpaceName:        lrp.SpaceName,
-		AnnotationSpaceGUID:        lrp.SpaceGUID,
-		AnnotationOriginalRequest:  lrp.LRP,
-		AnnotationRegisteredRoutes: string(uris),
-		AnnotationAppID:            lrp.AppGUID,
-		AnnotationVersion:          lrp.Version,
-		AnnotationLastUpdated:      lrp.LastUpdated,
-		AnnotationProcessGUID:      lrp.ProcessGUID(),
-		AnnotationAppName:          lrp.AppName,
-		AnnotationOrgName:          lrp.OrgName,
-		AnnotationOrgGUID:          lrp.OrgGUID,
+		AnnotationSpaceName:           lrp.SpaceName,
+		AnnotationSpaceGUID:           lrp.SpaceGUID,
+		AnnotationRegisteredRoutes:    string(uris),
+		AnnotationRegisteredTCPRoutes: tcpRoutes,
+		AnnotationInternalRoutes:      internalRoutes,
+		AnnotationAppID:               lrp.AppGUID,
+		AnnotationVersion:             lrp.Version,
+		AnnotationLastUpdated:         lrp.LastUpdated,
+		AnnotationProcessGUID:         lrp.ProcessGUID(),
+		AnnotationAppName:             lrp.AppName,
+		AnnotationOrgName:             lrp.OrgName,
+		AnnotationOrgGUID:             lrp.OrgGUID,
+	}
+
+	if lrp.LRP != "" {
+		annotations[AnnotationOriginalRequestSecret] = originalRequestSecretName(statefulSetName)
+	}
+
+	if lrp.PrivateRegistry != nil {
+		annotations[AnnotationPrivateRegistrySecret] = registryCredsSecretName(statefulSetName, lrp.PrivateRegistry)
 	}
 
 	for k, v := range lrp.UserDefinedAnnotations {
 		annotations[k] = v
 	}
 
+	if c.routeIntegrity.Enabled {
+		if err := c.addRouteIntegrityProxy(&statefulSet.Spec.Template.Spec, lrp, annotations); err != nil {
+			return nil, err
+		}
+	}
+
 	statefulSet.Annotations = annotations
 	statefulSet.Spec.Template.Annotations = annotations
 	statefulSet.Spec.Template.Annotations[corev1.SeccompPodAnnotationKey] = corev1.SeccompProfileRuntimeDefault
@@ -201,6 +204,120 @@ func (c *LRPToStatefulSet) Convert(statefulSetName string, lrp *opi.LRP) (*appsv
 	return statefulSet, nil
 }
 
+// addRouteIntegrityProxy puts a TLS terminating proxy in front of the app
+// ports. An init container renders the proxy config into a volume shared with
+// the proxy. The instance certificate is issued by a pod webhook, which
+// replaces the placeholder volume with the secret it stores it in, so that the
+// CA never gets near app pods
+func (c *LRPToStatefulSet) addRouteIntegrityProxy(podSpec *corev1.PodSpec, lrp *opi.LRP, annotations map[string]string) error {
+	if len(lrp.Ports) == 0 {
+		return nil
+	}
+
+	tlsPorts := integrity.TLSPorts(lrp.Ports)
+
+	tlsPortsJSON, err := json.Marshal(tlsPorts)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal tls ports")
+	}
+
+	annotations[AnnotationTLSPorts] = string(tlsPortsJSON)
+
+	podSpec.Volumes = append(podSpec.Volumes,
+		corev1.Volume{
+			Name: integrity.ConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			},
+		},
+		corev1.Volume{
+			Name: integrity.InstanceCertVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			},
+		},
+	)
+
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:            integrity.InitContainerName,
+		Image:           c.routeIntegrity.InitImage,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Env: []corev1.EnvVar{
+			{Name: integrity.EnvTLSPorts, Value: string(tlsPortsJSON)},
+		},
+		Resources: routeIntegrityResources(),
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: integrity.ConfigVolumeName, MountPath: integrity.ConfigMountPath},
+		},
+	})
+
+	proxyPorts := make([]corev1.ContainerPort, 0, len(tlsPorts))
+	for _, appPort := range lrp.Ports {
+		proxyPorts = append(proxyPorts, corev1.ContainerPort{ContainerPort: tlsPorts[appPort]})
+	}
+
+	allowPrivilegeEscalation := false
+	podSpec.Containers = append(podSpec.Containers, corev1.Container{
+		Name:            integrity.ProxyContainerName,
+		Image:           c.routeIntegrity.ProxyImage,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Command:         []string{"envoy", "--config-path", filepath.Join(integrity.ConfigMountPath, integrity.EnvoyConfFileName)},
+		Ports:           proxyPorts,
+		Resources:       routeIntegrityResources(),
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: integrity.ConfigVolumeName, MountPath: integrity.ConfigMountPath, ReadOnly: true},
+			{Name: integrity.InstanceCertVolumeName, MountPath: integrity.InstanceCertMountPath, ReadOnly: true},
+		},
+	})
+
+	return nil
+}
+
+// routeIntegrityResources are the resources of the route integrity
+// containers, which only pass connections on to the app and so get the same
+// small share whatever the size of the app
+func routeIntegrityResources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(routeIntegrityCPULimit),
+			corev1.ResourceMemory: resource.MustParse(routeIntegrityMemoryLimit),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(routeIntegrityCPURequest),
+			corev1.ResourceMemory: resource.MustParse(routeIntegrityMemoryRequest),
+		},
+	}
+}
+
+// TLSPort returns the port the route integrity proxy of a pod terminates TLS
+// on for the given app port, or 0 when the pod has no proxy
+func TLSPort(annotations map[string]string, port uint32) uint32 {
+	tlsPortsJSON, ok := annotations[AnnotationTLSPorts]
+	if !ok {
+		return 0
+	}
+
+	var tlsPorts map[uint32]uint32
+	if err := json.Unmarshal([]byte(tlsPortsJSON), &tlsPorts); err != nil {
+		return 0
+	}
+
+	return tlsPorts[port]
+}
+
+func marshalTCPRoutes(routes []opi.TCPRoute) (string, error) {
+	if routes == nil {
+		routes = []opi.TCPRoute{}
+	}
+
+	tcpRoutes, err := json.Marshal(routes)
+
+	return string(tcpRoutes), errors.Wrap(err, "failed to marshal tcp routes")
+}
+
 func (c *LRPToStatefulSet) calculateImagePullSecrets(statefulSetName string, lrp *opi.LRP) []corev1.LocalObjectReference {
 	imagePullSecrets := []corev1.LocalObjectReference{
 		{Name: c.registrySecretName},
@@ -208,7 +325,7 @@ func (c *LRPToStatefulSet) calculateImagePullSecrets(statefulSetName string, lrp
 
 	if lrp.PrivateRegistry != nil {
 		imagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReference{
-			Name: privateRegistrySecretName(statefulSetName),
+			Name: registryCredsSecretName(statefulSetName, lrp.PrivateRegistry),
 		})
 	}
 
@@ -282,16 +399,20 @@ func int32ptr(i int) *int32 {
 	return &u
 }
 
+// getGetSecurityContext runs the app as the user and group the image asks
+// for. Unless that is root, the pod refuses to run as root
 func getGetSecurityContext(lrp *opi.LRP) *corev1.PodSecurityContext {
-	if lrp.RunsAsRoot {
-		return nil
+	securityContext := &corev1.PodSecurityContext{
+		RunAsUser:  lrp.RunAsUser,
+		RunAsGroup: lrp.RunAsGroup,
 	}
 
-	runAsNonRoot := true
-
-	return &corev1.PodSecurityContext{
-		RunAsNonRoot: &runAsNonRoot,
+	if lrp.RunAsUser == nil || *lrp.RunAsUser != 0 {
+		runAsNonRoot := true
+		securityContext.RunAsNonRoot = &runAsNonRoot
 	}
+
+	return securityContext
 }
 
 func toLabelSelectorRequirements(selector *metav1.LabelSelector) []metav1.LabelSelectorRequirement {
@@ -318,3 +439,79 @@ func statefulSetLabelSelector(lrp *opi.LRP) *metav1.LabelSelector {
 		},
 	}
 }
+
+// inlineEnv are the environment variables kept in the StatefulSet, as the pod
+// webhooks complete them for every instance
+var inlineEnv = map[string]bool{
+	eirini.EnvVCAPApplication: true,
+}
+
+// appEnv is the environment of the app container. The environment of the LRP
+// is taken from its env secret, except for inlineEnv
+func appEnv(statefulSetName string, env map[string]string) []corev1.EnvVar {
+	inline, secret := splitEnv(env)
+	envs := shared.MapToEnvVar(inline)
+
+	if len(secret) > 0 {
+		envs = append(envs, shared.MapToSecretEnvVar(shared.EnvSecretName(statefulSetName, secret), secret)...)
+	}
+
+	return append(envs, fieldEnvs()...)
+}
+
+func splitEnv(env map[string]string) (map[string]string, map[string]string) {
+	inline := map[string]string{}
+	secret := map[string]string{}
+
+	for k, v := range env {
+		if inlineEnv[k] {
+			inline[k] = v
+
+			continue
+		}
+
+		secret[k] = v
+	}
+
+	return inline, secret
+}
+
+func fieldEnvs() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name: eirini.EnvPodName,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		},
+		{
+			// the pod name, which is also the instance_id of VCAP_APPLICATION,
+			// as Diego keeps both equal
+			Name: eirini.EnvCFInstanceGUID,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		},
+		{
+			Name: eirini.EnvCFInstanceIP,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.hostIP",
+				},
+			},
+		},
+		{
+			Name: eirini.EnvCFInstanceInternalIP,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.podIP",
+				},
+			},
+		},
+	}
+
+}
@@ -2,6 +2,7 @@ package stset_test
 
 import (
 	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/k8s/stset"
 	"code.cloudfoundry.org/eirini/k8s/stset/stsetfakes"
 	"code.cloudfoundry.org/eirini/opi"
@@ -18,6 +19,7 @@ import (
 var _ = Describe("LRP to StatefulSet Converter", func() {
 	var (
 		allowAutomountServiceAccountToken bool
+		routeIntegrity                    eirini.RouteIntegrityConfig
 		livenessProbeCreator              *stsetfakes.FakeProbeCreator
 		readinessProbeCreator             *stsetfakes.FakeProbeCreator
 		lrp                               *opi.LRP
@@ -26,13 +28,14 @@ var _ = Describe("LRP to StatefulSet Converter", func() {
 
 	BeforeEach(func() {
 		allowAutomountServiceAccountToken = false
+		routeIntegrity = eirini.RouteIntegrityConfig{}
 		livenessProbeCreator = new(stsetfakes.FakeProbeCreator)
 		readinessProbeCreator = new(stsetfakes.FakeProbeCreator)
 		lrp = createLRP("Baldur", []opi.Route{{Hostname: "my.example.route", Port: 1000}})
 	})
 
 	JustBeforeEach(func() {
-		converter := stset.NewLRPToStatefulSetConverter("eirini", "secret-name", allowAutomountServiceAccountToken, livenessProbeCreator.Spy, readinessProbeCreator.Spy)
+		converter := stset.NewLRPToStatefulSetConverter("eirini", "secret-name", allowAutomountServiceAccountToken, livenessProbeCreator.Spy, readinessProbeCreator.Spy, routeIntegrity)
 
 		var err error
 		statefulSet, err = converter.Convert("Baldur", lrp)
@@ -55,8 +58,10 @@ var _ = Describe("LRP to StatefulSet Converter", func() {
 		Entry("AppName", stset.AnnotationAppName, "Baldur"),
 		Entry("AppID", stset.AnnotationAppID, "premium_app_guid_1234"),
 		Entry("Version", stset.AnnotationVersion, "version_1234"),
-		Entry("OriginalRequest", stset.AnnotationOriginalRequest, "original request"),
+		Entry("OriginalRequestSecret", stset.AnnotationOriginalRequestSecret, "Baldur-original-request"),
 		Entry("RegisteredRoutes", stset.AnnotationRegisteredRoutes, `[{"hostname":"my.example.route","port":1000}]`),
+		Entry("RegisteredTCPRoutes", stset.AnnotationRegisteredTCPRoutes, `[]`),
+		Entry("InternalRoutes", stset.AnnotationInternalRoutes, `[]`),
 		Entry("SpaceName", stset.AnnotationSpaceName, "space-foo"),
 		Entry("SpaceGUID", stset.AnnotationSpaceGUID, "space-guid"),
 		Entry("OrgName", stset.AnnotationOrgName, "org-foo"),
@@ -71,14 +76,21 @@ var _ = Describe("LRP to StatefulSet Converter", func() {
 		Entry("AppName", stset.AnnotationAppName, "Baldur"),
 		Entry("AppID", stset.AnnotationAppID, "premium_app_guid_1234"),
 		Entry("Version", stset.AnnotationVersion, "version_1234"),
-		Entry("OriginalRequest", stset.AnnotationOriginalRequest, "original request"),
+		Entry("OriginalRequestSecret", stset.AnnotationOriginalRequestSecret, "Baldur-original-request"),
 		Entry("RegisteredRoutes", stset.AnnotationRegisteredRoutes, `[{"hostname":"my.example.route","port":1000}]`),
+		Entry("RegisteredTCPRoutes", stset.AnnotationRegisteredTCPRoutes, `[]`),
+		Entry("InternalRoutes", stset.AnnotationInternalRoutes, `[]`),
 		Entry("SpaceName", stset.AnnotationSpaceName, "space-foo"),
 		Entry("SpaceGUID", stset.AnnotationSpaceGUID, "space-guid"),
 		Entry("OrgName", stset.AnnotationOrgName, "org-foo"),
 		Entry("OrgGUID", stset.AnnotationOrgGUID, "org-guid"),
 	)
 
+	It("should not put the original request in the annotations", func() {
+		Expect(statefulSet.Annotations).NotTo(HaveKey(stset.AnnotationOriginalRequest))
+		Expect(statefulSet.Spec.Template.Annotations).NotTo(HaveKey(stset.AnnotationOriginalRequest))
+	})
+
 	It("should provide last updated to the statefulset annotation", func() {
 		Expect(statefulSet.Annotations).To(HaveKeyWithValue(stset.AnnotationLastUpdated, lrp.LastUpdated))
 	})
@@ -91,6 +103,10 @@ var _ = Describe("LRP to StatefulSet Converter", func() {
 		Expect(string(statefulSet.Spec.PodManagementPolicy)).To(Equal("Parallel"))
 	})
 
+	It("should set the headless service as the governing service", func() {
+		Expect(statefulSet.Spec.ServiceName).To(Equal("internal-" + statefulSet.Name))
+	})
+
 	It("should set podImagePullSecret", func() {
 		Expect(statefulSet.Spec.Template.Spec.ImagePullSecrets).To(HaveLen(1))
 		secret := statefulSet.Spec.Template.Spec.ImagePullSecrets[0]
@@ -226,12 +242,38 @@ var _ = Describe("LRP to StatefulSet Converter", func() {
 		container := statefulSet.Spec.Template.Spec.Containers[0]
 		Expect(container.Env).To(ContainElements(
 			corev1.EnvVar{Name: eirini.EnvPodName, ValueFrom: expectedValFrom("metadata.name")},
-			corev1.EnvVar{Name: eirini.EnvCFInstanceGUID, ValueFrom: expectedValFrom("metadata.uid")},
+			corev1.EnvVar{Name: eirini.EnvCFInstanceGUID, ValueFrom: expectedValFrom("metadata.name")},
 			corev1.EnvVar{Name: eirini.EnvCFInstanceInternalIP, ValueFrom: expectedValFrom("status.podIP")},
 			corev1.EnvVar{Name: eirini.EnvCFInstanceIP, ValueFrom: expectedValFrom("status.hostIP")},
 		))
 	})
 
+	When("the app has an environment", func() {
+		BeforeEach(func() {
+			lrp.Env = map[string]string{
+				"VCAP_APPLICATION": `{"application_name":"baldur"}`,
+				"FOO":              "foo",
+				"BAR":              "bar",
+			}
+		})
+
+		It("should take it from the env secret", func() {
+			secretName := shared.EnvSecretName("Baldur", map[string]string{"FOO": "foo", "BAR": "bar"})
+			container := statefulSet.Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElements(
+				corev1.EnvVar{Name: "BAR", ValueFrom: secretValueFrom(secretName, "BAR")},
+				corev1.EnvVar{Name: "FOO", ValueFrom: secretValueFrom(secretName, "FOO")},
+			))
+		})
+
+		It("should keep VCAP_APPLICATION inline for the pod webhooks to complete", func() {
+			container := statefulSet.Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElement(
+				corev1.EnvVar{Name: "VCAP_APPLICATION", Value: `{"application_name":"baldur"}`},
+			))
+		})
+	})
+
 	When("the app has sidecars", func() {
 		BeforeEach(func() {
 			lrp.Sidecars = []opi.Sidecar{
@@ -304,13 +346,65 @@ var _ = Describe("LRP to StatefulSet Converter", func() {
 		})
 	})
 
+	When("the image is pinned to a digest", func() {
+		BeforeEach(func() {
+			lrp.Image = "eirini/dorini@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"
+		})
+
+		It("sets imagePullPolicy to IfNotPresent", func() {
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+		})
+
+		When("the image is in a private registry", func() {
+			BeforeEach(func() {
+				lrp.PrivateRegistry = &opi.PrivateRegistry{SecretName: "my-registry-secret"}
+			})
+
+			It("always pulls the image", func() {
+				Expect(statefulSet.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(Equal(corev1.PullAlways))
+			})
+		})
+	})
+
+	It("does not select nodes by architecture", func() {
+		Expect(statefulSet.Spec.Template.Spec.NodeSelector).To(BeEmpty())
+	})
+
+	When("the image is available for a single architecture", func() {
+		BeforeEach(func() {
+			lrp.Architecture = "arm64"
+		})
+
+		It("schedules the instances on nodes of that architecture", func() {
+			Expect(statefulSet.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"kubernetes.io/arch": "arm64"}))
+		})
+	})
+
 	When("application should run as root", func() {
 		BeforeEach(func() {
-			lrp.RunsAsRoot = true
+			root := int64(0)
+			lrp.RunAsUser = &root
+		})
+
+		It("runs the pod as root", func() {
+			securityContext := statefulSet.Spec.Template.Spec.SecurityContext
+			Expect(securityContext.RunAsNonRoot).To(BeNil())
+			Expect(securityContext.RunAsUser).To(PointTo(Equal(int64(0))))
+		})
+	})
+
+	When("the application runs as a given user and group", func() {
+		BeforeEach(func() {
+			uid, gid := int64(1000), int64(2000)
+			lrp.RunAsUser = &uid
+			lrp.RunAsGroup = &gid
 		})
 
-		It("does not set privileged context", func() {
-			Expect(statefulSet.Spec.Template.Spec.SecurityContext).To(BeNil())
+		It("runs the pod as that user and group, but never as root", func() {
+			securityContext := statefulSet.Spec.Template.Spec.SecurityContext
+			Expect(securityContext.RunAsNonRoot).To(PointTo(BeTrue()))
+			Expect(securityContext.RunAsUser).To(PointTo(Equal(int64(1000))))
+			Expect(securityContext.RunAsGroup).To(PointTo(Equal(int64(2000))))
 		})
 	})
 
@@ -328,5 +422,115 @@ var _ = Describe("LRP to StatefulSet Converter", func() {
 			secret := statefulSet.Spec.Template.Spec.ImagePullSecrets[1]
 			Expect(secret.Name).To(Equal("Baldur-registry-credentials"))
 		})
+
+		It("should record the secret in the annotations", func() {
+			Expect(statefulSet.Annotations).To(HaveKeyWithValue(stset.AnnotationPrivateRegistrySecret, "Baldur-registry-credentials"))
+		})
+
+		When("the app refers to a registry secret", func() {
+			BeforeEach(func() {
+				lrp.PrivateRegistry = &opi.PrivateRegistry{SecretName: "my-registry-secret"}
+			})
+
+			It("should pull the image with it", func() {
+				Expect(statefulSet.Spec.Template.Spec.ImagePullSecrets).To(HaveLen(2))
+				Expect(statefulSet.Spec.Template.Spec.ImagePullSecrets[1].Name).To(Equal("my-registry-secret"))
+				Expect(statefulSet.Annotations).To(HaveKeyWithValue(stset.AnnotationPrivateRegistrySecret, "my-registry-secret"))
+			})
+		})
+	})
+
+	It("should not add a route integrity proxy", func() {
+		Expect(statefulSet.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		Expect(statefulSet.Spec.Template.Spec.Containers).To(HaveLen(1))
+		Expect(statefulSet.Spec.Template.Annotations).NotTo(HaveKey(stset.AnnotationTLSPorts))
+	})
+
+	When("route integrity is enabled", func() {
+		BeforeEach(func() {
+			routeIntegrity = eirini.RouteIntegrityConfig{
+				Enabled:    true,
+				ProxyImage: "envoyproxy/envoy:v1.17",
+				InitImage:  "eirini/route-integrity-init",
+			}
+			lrp.Ports = []int32{8080, 9090}
+		})
+
+		It("should record the TLS port of every app port on the pods", func() {
+			Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue(stset.AnnotationTLSPorts, `{"8080":61001,"9090":61002}`))
+		})
+
+		It("should render the proxy config in an init container", func() {
+			initContainers := statefulSet.Spec.Template.Spec.InitContainers
+			Expect(initContainers).To(HaveLen(1))
+			Expect(initContainers[0].Name).To(Equal("route-integrity-init"))
+			Expect(initContainers[0].Image).To(Equal("eirini/route-integrity-init"))
+			Expect(initContainers[0].Env).To(ConsistOf(corev1.EnvVar{Name: "TLS_PORTS", Value: `{"8080":61001,"9090":61002}`}))
+			Expect(initContainers[0].VolumeMounts).To(ConsistOf(
+				corev1.VolumeMount{Name: "route-integrity-config", MountPath: "/etc/cf-route-integrity"},
+			))
+		})
+
+		It("should leave a placeholder for the instance certificate instead of mounting the CA", func() {
+			volumes := statefulSet.Spec.Template.Spec.Volumes
+			Expect(volumes).To(ContainElement(corev1.Volume{
+				Name: "route-integrity-cert",
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+				},
+			}))
+
+			for _, volume := range volumes {
+				Expect(volume.Secret).To(BeNil())
+			}
+		})
+
+		It("should limit the resources of the route integrity containers", func() {
+			proxy := statefulSet.Spec.Template.Spec.Containers[1]
+			Expect(proxy.Resources.Requests.Cpu().String()).To(Equal("10m"))
+			Expect(proxy.Resources.Requests.Memory().String()).To(Equal("32Mi"))
+			Expect(proxy.Resources.Limits.Cpu().String()).To(Equal("500m"))
+			Expect(proxy.Resources.Limits.Memory().String()).To(Equal("128Mi"))
+
+			Expect(statefulSet.Spec.Template.Spec.InitContainers[0].Resources).To(Equal(proxy.Resources))
+		})
+
+		It("should add the proxy sidecar listening on the TLS ports", func() {
+			containers := statefulSet.Spec.Template.Spec.Containers
+			Expect(containers).To(HaveLen(2))
+
+			proxy := containers[1]
+			Expect(proxy.Name).To(Equal("route-integrity-proxy"))
+			Expect(proxy.Image).To(Equal("envoyproxy/envoy:v1.17"))
+			Expect(proxy.Command).To(Equal([]string{"envoy", "--config-path", "/etc/cf-route-integrity/envoy.yaml"}))
+			Expect(proxy.Ports).To(Equal([]corev1.ContainerPort{{ContainerPort: 61001}, {ContainerPort: 61002}}))
+			Expect(proxy.VolumeMounts).To(ConsistOf(
+				corev1.VolumeMount{Name: "route-integrity-config", MountPath: "/etc/cf-route-integrity", ReadOnly: true},
+				corev1.VolumeMount{Name: "route-integrity-cert", MountPath: "/etc/cf-route-integrity-cert", ReadOnly: true},
+			))
+		})
+
+		When("the app has no ports", func() {
+			BeforeEach(func() {
+				lrp.Ports = nil
+			})
+
+			It("should not add a route integrity proxy", func() {
+				Expect(statefulSet.Spec.Template.Spec.InitContainers).To(BeEmpty())
+				Expect(statefulSet.Spec.Template.Spec.Containers).To(HaveLen(1))
+			})
+		})
+	})
+})
+
+var _ = Describe("TLSPort", func() {
+	It("should return the TLS port of an app port", func() {
+		annotations := map[string]string{stset.AnnotationTLSPorts: `{"8080":61001}`}
+		Expect(stset.TLSPort(annotations, 8080)).To(Equal(uint32(61001)))
+		Expect(stset.TLSPort(annotations, 9090)).To(BeZero())
+	})
+
+	It("should return 0 when the pod has no route integrity proxy", func() {
+		Expect(stset.TLSPort(map[string]string{}, 8080)).To(BeZero())
 	})
 })
@@ -0,0 +1,80 @@
+package stset
+
+import (
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+)
+
+// OriginalRequestMigrator moves the desire requests out of the annotations of
+// all StatefulSets created before they were kept in secrets. It only updates
+// the StatefulSets themselves, so their instances keep running; the pod
+// templates lose the annotation with their next change
+type OriginalRequestMigrator struct {
+	logger             lager.Logger
+	statefulSetGetter  StatefulSetsBySourceTypeGetter
+	statefulSetUpdater StatefulSetUpdater
+	secrets            SecretsClient
+}
+
+func NewOriginalRequestMigrator(
+	logger lager.Logger,
+	statefulSetGetter StatefulSetsBySourceTypeGetter,
+	statefulSetUpdater StatefulSetUpdater,
+	secrets SecretsClient,
+) OriginalRequestMigrator {
+	return OriginalRequestMigrator{
+		logger:             logger,
+		statefulSetGetter:  statefulSetGetter,
+		statefulSetUpdater: statefulSetUpdater,
+		secrets:            secrets,
+	}
+}
+
+// Migrate migrates every StatefulSet it can, and fails when any of them could
+// not be migrated. StatefulSets which fail are migrated with their next update
+// instead
+func (m OriginalRequestMigrator) Migrate() error {
+	logger := m.logger.Session("migrate-original-requests")
+
+	statefulSets, err := m.statefulSetGetter.GetBySourceType(AppSourceType)
+	if err != nil {
+		logger.Error("failed-to-list-statefulsets", err)
+
+		return errors.Wrap(err, "failed to list statefulsets")
+	}
+
+	failed := 0
+
+	for i := range statefulSets {
+		statefulSet := &statefulSets[i]
+		if _, ok := statefulSet.Annotations[AnnotationOriginalRequest]; !ok {
+			continue
+		}
+
+		data := lager.Data{"namespace": statefulSet.Namespace, "name": statefulSet.Name}
+
+		if err := migrateOriginalRequest(m.secrets, statefulSet); err != nil {
+			logger.Error("failed-to-migrate-original-request", err, data)
+
+			failed++
+
+			continue
+		}
+
+		if _, err := m.statefulSetUpdater.Update(statefulSet.Namespace, statefulSet); err != nil {
+			logger.Error("failed-to-update-statefulset", err, data)
+
+			failed++
+
+			continue
+		}
+
+		logger.Info("migrated-original-request", data)
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed to migrate the original request of %d statefulsets", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package stset_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/k8s/stset/stsetfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("OriginalRequestMigrator", func() {
+	var (
+		statefulSetGetter  *stsetfakes.FakeStatefulSetsBySourceTypeGetter
+		statefulSetUpdater *stsetfakes.FakeStatefulSetUpdater
+		secrets            *stsetfakes.FakeSecretsClient
+		request            string
+		err                error
+	)
+
+	BeforeEach(func() {
+		statefulSetGetter = new(stsetfakes.FakeStatefulSetsBySourceTypeGetter)
+		statefulSetUpdater = new(stsetfakes.FakeStatefulSetUpdater)
+		secrets = new(stsetfakes.FakeSecretsClient)
+		request = `{"guid":"guid_1234","environment":{"FOO":"foo"}}`
+
+		statefulSetGetter.GetBySourceTypeReturns([]appsv1.StatefulSet{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "baldur",
+					Namespace: "the-namespace",
+					Annotations: map[string]string{
+						stset.AnnotationOriginalRequest: request,
+					},
+				},
+				Spec: appsv1.StatefulSetSpec{
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								stset.AnnotationOriginalRequest: request,
+							},
+						},
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "thor",
+					Namespace: "the-namespace",
+					Annotations: map[string]string{
+						stset.AnnotationOriginalRequestSecret: "thor-original-request",
+					},
+				},
+			},
+		}, nil)
+	})
+
+	JustBeforeEach(func() {
+		migrator := stset.NewOriginalRequestMigrator(lagertest.NewTestLogger("migrate"), statefulSetGetter, statefulSetUpdater, secrets)
+		err = migrator.Migrate()
+	})
+
+	It("lists the app statefulsets", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statefulSetGetter.GetBySourceTypeCallCount()).To(Equal(1))
+		Expect(statefulSetGetter.GetBySourceTypeArgsForCall(0)).To(Equal(stset.AppSourceType))
+	})
+
+	It("moves the original request into a secret owned by the statefulset", func() {
+		Expect(secrets.CreateCallCount()).To(Equal(1))
+		namespace, secret := secrets.CreateArgsForCall(0)
+		Expect(namespace).To(Equal("the-namespace"))
+		Expect(secret.Name).To(Equal("baldur-original-request"))
+		Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+			APIVersion: "apps/v1",
+			Kind:       "StatefulSet",
+			Name:       "baldur",
+		}))
+		Expect(stset.ReadOriginalRequest(secret)).To(MatchJSON(`{"guid":"guid_1234","environment":{"FOO":"[REDACTED]"}}`))
+	})
+
+	It("only updates the annotations of the statefulset, leaving the pod template alone", func() {
+		Expect(statefulSetUpdater.UpdateCallCount()).To(Equal(1))
+		namespace, st := statefulSetUpdater.UpdateArgsForCall(0)
+		Expect(namespace).To(Equal("the-namespace"))
+		Expect(st.Name).To(Equal("baldur"))
+		Expect(st.Annotations).To(Equal(map[string]string{
+			stset.AnnotationOriginalRequestSecret: "baldur-original-request",
+		}))
+		Expect(st.Spec.Template.Annotations).To(Equal(map[string]string{
+			stset.AnnotationOriginalRequest: request,
+		}))
+	})
+
+	When("the secret already exists", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur-original-request"))
+		})
+
+		It("still migrates the statefulset", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(statefulSetUpdater.UpdateCallCount()).To(Equal(1))
+		})
+	})
+
+	When("listing the statefulsets fails", func() {
+		BeforeEach(func() {
+			statefulSetGetter.GetBySourceTypeReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to list statefulsets")))
+		})
+	})
+
+	When("creating the secret fails", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, errors.New("boom"))
+		})
+
+		It("does not update the statefulset", func() {
+			Expect(err).To(MatchError("failed to migrate the original request of 1 statefulsets"))
+			Expect(statefulSetUpdater.UpdateCallCount()).To(BeZero())
+		})
+	})
+
+	When("updating the statefulset fails", func() {
+		BeforeEach(func() {
+			statefulSetUpdater.UpdateReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError("failed to migrate the original request of 1 statefulsets"))
+		})
+	})
+})
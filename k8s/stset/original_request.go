@@ -0,0 +1,191 @@
+package stset
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// OriginalRequestKey is the key of the gzipped, redacted desire request
+	// in the original request secret
+	OriginalRequestKey = "original_request.json.gz"
+
+	redactedValue = "[REDACTED]"
+)
+
+// sensitiveFields are the fields of the desire request which are redacted
+// wherever they appear. Their names are matched case insensitively as
+// substrings, so that registry_password or client_secret are covered as well
+var sensitiveFields = []string{"password", "secret", "token", "credentials"}
+
+// ReadOriginalRequest returns the redacted desire request kept in an
+// original request secret
+func ReadOriginalRequest(secret *corev1.Secret) (string, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(secret.Data[OriginalRequestKey]))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read original request")
+	}
+	defer reader.Close()
+
+	request, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decompress original request")
+	}
+
+	return string(request), nil
+}
+
+// migrateOriginalRequest moves the desire request out of the annotations of
+// StatefulSets created before it was kept in a secret. The pod template is
+// left alone, so that the instances are not restarted for it
+func migrateOriginalRequest(secrets SecretsClient, statefulSet *appsv1.StatefulSet) error {
+	request, ok := statefulSet.Annotations[AnnotationOriginalRequest]
+	if !ok {
+		return nil
+	}
+
+	secret, err := generateOriginalRequestSecret(statefulSet.Name, request)
+	if err != nil {
+		return err
+	}
+
+	secret.OwnerReferences = []metav1.OwnerReference{statefulSetOwnerReference(statefulSet)}
+
+	_, err = secrets.Create(statefulSet.Namespace, secret)
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create original request secret")
+	}
+
+	delete(statefulSet.Annotations, AnnotationOriginalRequest)
+	statefulSet.Annotations[AnnotationOriginalRequestSecret] = secret.Name
+
+	return nil
+}
+
+// dropOriginalRequestFromTemplate replaces the desire request in the pod
+// template of a migrated StatefulSet with the reference to its secret. It is
+// only called along with other changes to the template, as it restarts the
+// instances
+func dropOriginalRequestFromTemplate(statefulSet *appsv1.StatefulSet) {
+	annotations := statefulSet.Spec.Template.Annotations
+	if _, ok := annotations[AnnotationOriginalRequest]; !ok {
+		return
+	}
+
+	delete(annotations, AnnotationOriginalRequest)
+	annotations[AnnotationOriginalRequestSecret] = originalRequestSecretName(statefulSet.Name)
+}
+
+func originalRequestSecretName(statefulSetName string) string {
+	return statefulSetName + "-original-request"
+}
+
+func generateOriginalRequestSecret(statefulSetName, request string) (*corev1.Secret, error) {
+	var compressed bytes.Buffer
+
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(redactOriginalRequest(request)); err != nil {
+		return nil, errors.Wrap(err, "failed to compress original request")
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to compress original request")
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: originalRequestSecretName(statefulSetName),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			OriginalRequestKey: compressed.Bytes(),
+		},
+	}, nil
+}
+
+// redactOriginalRequest blanks out the environment variable values and the
+// sensitive fields of a desire request. Anything which is not a JSON object is
+// redacted altogether, as there is no telling what it holds
+func redactOriginalRequest(request string) []byte {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(request), &fields); err != nil {
+		return []byte(redactedValue)
+	}
+
+	redacted, err := json.Marshal(redact(fields))
+	if err != nil {
+		return []byte(redactedValue)
+	}
+
+	return redacted
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			switch {
+			case key == "environment":
+				v[key] = redactEnvironment(field)
+			case isSensitive(key):
+				v[key] = redactedValue
+			default:
+				v[key] = redact(field)
+			}
+		}
+
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+
+		return v
+	default:
+		return v
+	}
+}
+
+// redactEnvironment keeps the names of the environment variables, which is
+// either a name to value object or a list of name/value pairs
+func redactEnvironment(environment interface{}) interface{} {
+	switch env := environment.(type) {
+	case map[string]interface{}:
+		for name := range env {
+			env[name] = redactedValue
+		}
+
+		return env
+	case []interface{}:
+		for _, item := range env {
+			if variable, ok := item.(map[string]interface{}); ok {
+				variable["value"] = redactedValue
+			}
+		}
+
+		return env
+	default:
+		return redactedValue
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, field := range sensitiveFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,19 +3,28 @@ package stset
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 
 const (
-	AnnotationAppName              = "cloudfoundry.org/application_name"
-	AnnotationVersion              = "cloudfoundry.org/version"
-	AnnotationAppID                = "cloudfoundry.org/application_id"
-	AnnotationSpaceName            = "cloudfoundry.org/space_name"
-	AnnotationOrgName              = "cloudfoundry.org/org_name"
-	AnnotationOrgGUID              = "cloudfoundry.org/org_guid"
-	AnnotationSpaceGUID            = "cloudfoundry.org/space_guid"
-	AnnotationLastUpdated          = "cloudfoundry.org/last_updated"
-	AnnotationProcessGUID          = "cloudfoundry.org/process_guid"
-	AnnotationRegisteredRoutes     = "cloudfoundry.org/routes"
-	AnnotationOriginalRequest      = "cloudfoundry.org/original_request"
-	AnnotationLastReportedAppCrash = "cloudfoundry.org/last_reported_app_crash"
-	AnnotationLastReportedLRPCrash = "cloudfoundry.org/last_reported_lrp_crash"
+	AnnotationAppName               = "cloudfoundry.org/application_name"
+	AnnotationVersion               = "cloudfoundry.org/version"
+	AnnotationAppID                 = "cloudfoundry.org/application_id"
+	AnnotationSpaceName             = "cloudfoundry.org/space_name"
+	AnnotationOrgName               = "cloudfoundry.org/org_name"
+	AnnotationOrgGUID               = "cloudfoundry.org/org_guid"
+	AnnotationSpaceGUID             = "cloudfoundry.org/space_guid"
+	AnnotationLastUpdated           = "cloudfoundry.org/last_updated"
+	AnnotationProcessGUID           = "cloudfoundry.org/process_guid"
+	AnnotationRegisteredRoutes      = "cloudfoundry.org/routes"
+	AnnotationRegisteredTCPRoutes   = "cloudfoundry.org/tcp_routes"
+	AnnotationInternalRoutes        = "cloudfoundry.org/internal_routes"
+	AnnotationInternalRouteHostname = "cloudfoundry.org/internal_route_hostname"
+	AnnotationOriginalRequestSecret = "cloudfoundry.org/original_request_secret"
+	// AnnotationOriginalRequest used to hold the desire request. It is only
+	// read to move it into the original request secret
+	AnnotationOriginalRequest       = "cloudfoundry.org/original_request"
+	AnnotationLastReportedAppCrash  = "cloudfoundry.org/last_reported_app_crash"
+	AnnotationLastReportedLRPCrash  = "cloudfoundry.org/last_reported_lrp_crash"
+	AnnotationTLSPorts              = "cloudfoundry.org/tls_ports"
+	AnnotationPrivateRegistrySecret = "cloudfoundry.org/private_registry_secret"
+	AnnotationPreviousEnvSecrets    = "cloudfoundry.org/previous_env_secrets"
 
 	AppSourceType = "APP"
 
@@ -28,6 +37,9 @@ const (
 	LabelAppGUID     = "cloudfoundry.org/app_guid"
 	LabelProcessType = "cloudfoundry.org/process_type"
 	LabelSourceType  = "cloudfoundry.org/source_type"
+	LabelServiceType = "cloudfoundry.org/service_type"
+
+	InternalServiceType = "internal"
 
 	OPIContainerName = "opi"
 
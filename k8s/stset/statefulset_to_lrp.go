@@ -29,6 +29,20 @@ func MapStatefulSetToLRP(s appsv1.StatefulSet) (*opi.LRP, error) {
 		return nil, errors.Wrap(err, "failed to unmarshal uris")
 	}
 
+	tcpRoutes := []opi.TCPRoute{}
+	if stTCPRoutes, ok := s.Annotations[AnnotationRegisteredTCPRoutes]; ok {
+		if err = json.Unmarshal([]byte(stTCPRoutes), &tcpRoutes); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal tcp routes")
+		}
+	}
+
+	internalRoutes := []opi.InternalRoute{}
+	if stInternalRoutes, ok := s.Annotations[AnnotationInternalRoutes]; ok {
+		if err = json.Unmarshal([]byte(stInternalRoutes), &internalRoutes); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal internal routes")
+		}
+	}
+
 	ports := []int32{}
 	container := s.Spec.Template.Spec.Containers[0]
 
@@ -61,6 +75,8 @@ func MapStatefulSetToLRP(s appsv1.StatefulSet) (*opi.LRP, error) {
 		Ports:            ports,
 		LastUpdated:      s.Annotations[AnnotationLastUpdated],
 		AppURIs:          uris,
+		TCPRoutes:        tcpRoutes,
+		InternalRoutes:   internalRoutes,
 		AppGUID:          s.Annotations[AnnotationAppID],
 		MemoryMB:         memory,
 		DiskMB:           disk,
@@ -23,13 +23,15 @@ var _ = Describe("Statefulset to LRP Converter", func() {
 					stset.LabelGUID: "Bald-guid",
 				},
 				Annotations: map[string]string{
-					stset.AnnotationProcessGUID:      "Baldur-guid",
-					stset.AnnotationLastUpdated:      "last-updated-some-time-ago",
-					stset.AnnotationRegisteredRoutes: `[{"hostname":"my.example.route","port":8080}]`,
-					stset.AnnotationAppID:            "guid_1234",
-					stset.AnnotationVersion:          "version_1234",
-					stset.AnnotationAppName:          "Baldur",
-					stset.AnnotationSpaceName:        "space-foo",
+					stset.AnnotationProcessGUID:         "Baldur-guid",
+					stset.AnnotationLastUpdated:         "last-updated-some-time-ago",
+					stset.AnnotationRegisteredRoutes:    `[{"hostname":"my.example.route","port":8080}]`,
+					stset.AnnotationRegisteredTCPRoutes: `[{"router_group_guid":"default-tcp","external_port":1234,"container_port":8080}]`,
+					stset.AnnotationInternalRoutes:      `[{"hostname":"baldur.apps.internal"}]`,
+					stset.AnnotationAppID:               "guid_1234",
+					stset.AnnotationVersion:             "version_1234",
+					stset.AnnotationAppName:             "Baldur",
+					stset.AnnotationSpaceName:           "space-foo",
 				},
 			},
 			Spec: appsv1.StatefulSetSpec{
@@ -120,6 +122,14 @@ var _ = Describe("Statefulset to LRP Converter", func() {
 		Expect(lrp.AppURIs).To(ConsistOf(opi.Route{Hostname: "my.example.route", Port: 8080}))
 	})
 
+	It("should set the correct LRP TCP routes", func() {
+		Expect(lrp.TCPRoutes).To(ConsistOf(opi.TCPRoute{RouterGroupGUID: "default-tcp", ExternalPort: 1234, ContainerPort: 8080}))
+	})
+
+	It("should set the correct LRP internal routes", func() {
+		Expect(lrp.InternalRoutes).To(ConsistOf(opi.InternalRoute{Hostname: "baldur.apps.internal"}))
+	})
+
 	It("should set the correct LRP AppGUID", func() {
 		Expect(lrp.AppGUID).To(Equal("guid_1234"))
 	})
@@ -151,4 +161,28 @@ var _ = Describe("Statefulset to LRP Converter", func() {
 			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal uris")))
 		})
 	})
+
+	When("the statefulset predates tcp routes", func() {
+		It("should have no tcp routes", func() {
+			statefulset := appsv1.StatefulSet{
+				ObjectMeta: meta.ObjectMeta{
+					Annotations: map[string]string{
+						stset.AnnotationRegisteredRoutes: `[]`,
+					},
+				},
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: int32ptr(1),
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: stset.OPIContainerName}},
+						},
+					},
+				},
+			}
+			lrp, err := stset.MapStatefulSetToLRP(statefulset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.TCPRoutes).To(BeEmpty())
+			Expect(lrp.InternalRoutes).To(BeEmpty())
+		})
+	})
 })
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/opi"
 	"code.cloudfoundry.org/lager"
 	"github.com/pkg/errors"
@@ -40,6 +41,7 @@ type Stopper struct {
 	podDisruptionBudget PodDisruptionBudgetDeleter
 	secretsDeleter      SecretsDeleter
 	getStatefulSet      getStatefulSetFunc
+	internalServices    internalServices
 }
 
 func NewStopper(
@@ -49,6 +51,7 @@ func NewStopper(
 	podDeleter PodDeleter,
 	podDisruptionBudget PodDisruptionBudgetDeleter,
 	secretsDeleter SecretsDeleter,
+	services ServicesClient,
 ) Stopper {
 	return Stopper{
 		logger:              logger,
@@ -57,34 +60,54 @@ func NewStopper(
 		podDisruptionBudget: podDisruptionBudget,
 		secretsDeleter:      secretsDeleter,
 		getStatefulSet:      newGetStatefulSetFunc(statefulSetGetter),
+		internalServices:    internalServices{services: services},
 	}
 }
 
-func (s *Stopper) Stop(identifier opi.LRPIdentifier) error {
+// Stop deletes the StatefulSet of the LRP along with everything it owns, and
+// returns the app and process type of the LRP it stopped, or nil when there
+// was nothing to stop
+func (s *Stopper) Stop(identifier opi.LRPIdentifier) (*opi.LRP, error) {
+	var stopped *opi.LRP
+
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		return s.stop(identifier)
+		var stopErr error
+		stopped, stopErr = s.stop(identifier)
+
+		return stopErr
 	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete statefulset")
+	}
 
-	return errors.Wrap(err, "failed to delete statefulset")
+	return stopped, nil
 }
 
-func (s *Stopper) stop(identifier opi.LRPIdentifier) error {
+func (s *Stopper) stop(identifier opi.LRPIdentifier) (*opi.LRP, error) {
 	logger := s.logger.Session("stop", lager.Data{"guid": identifier.GUID, "version": identifier.Version})
 	statefulSet, err := s.getStatefulSet(identifier)
 
 	if errors.Is(err, eirini.ErrNotFound) {
 		logger.Debug("statefulset-does-not-exist")
 
-		return nil
+		return nil, nil
 	}
 
 	if err != nil {
 		logger.Error("failed-to-get-statefulset", err)
 
-		return err
+		return nil, err
+	}
+
+	if err := s.deleteStatefulSet(logger, identifier, statefulSet); err != nil {
+		return nil, err
 	}
 
-	err = s.podDisruptionBudget.Delete(statefulSet.Namespace, statefulSet.Name)
+	return stoppedLRP(identifier, statefulSet), nil
+}
+
+func (s *Stopper) deleteStatefulSet(logger lager.Logger, identifier opi.LRPIdentifier, statefulSet *appsv1.StatefulSet) error {
+	err := s.podDisruptionBudget.Delete(statefulSet.Namespace, statefulSet.Name)
 	if err != nil && !k8serrors.IsNotFound(err) {
 		logger.Error("failed-to-delete-disruption-budget", err)
 
@@ -98,6 +121,27 @@ func (s *Stopper) stop(identifier opi.LRPIdentifier) error {
 		return err
 	}
 
+	err = s.deleteEnvSecrets(statefulSet)
+	if err != nil {
+		logger.Error("failed-to-delete-env-secrets", err)
+
+		return err
+	}
+
+	err = s.deleteOriginalRequestSecret(statefulSet)
+	if err != nil {
+		logger.Error("failed-to-delete-original-request-secret", err)
+
+		return err
+	}
+
+	err = s.internalServices.delete(statefulSet.Namespace, identifier)
+	if err != nil {
+		logger.Error("failed-to-delete-internal-services", err)
+
+		return err
+	}
+
 	if err := s.statefulSetDeleter.Delete(statefulSet.Namespace, statefulSet.Name); err != nil {
 		logger.Error("failed-to-delete-statefulset", err)
 
@@ -107,6 +151,15 @@ func (s *Stopper) stop(identifier opi.LRPIdentifier) error {
 	return nil
 }
 
+// stoppedLRP describes the stopped LRP well enough to tell its app about it
+func stoppedLRP(identifier opi.LRPIdentifier, statefulSet *appsv1.StatefulSet) *opi.LRP {
+	return &opi.LRP{
+		LRPIdentifier: identifier,
+		AppGUID:       statefulSet.Annotations[AnnotationAppID],
+		ProcessType:   statefulSet.Labels[LabelProcessType],
+	}
+}
+
 func (s *Stopper) deletePrivateRegistrySecret(statefulSet *appsv1.StatefulSet) error {
 	for _, secret := range statefulSet.Spec.Template.Spec.ImagePullSecrets {
 		if secret.Name == privateRegistrySecretName(statefulSet.Name) {
@@ -117,32 +170,76 @@ func (s *Stopper) deletePrivateRegistrySecret(statefulSet *appsv1.StatefulSet) e
 	return nil
 }
 
-func (s *Stopper) StopInstance(identifier opi.LRPIdentifier, index uint) error {
+func (s *Stopper) deleteEnvSecrets(statefulSet *appsv1.StatefulSet) error {
+	container := appContainer(statefulSet)
+	if container == nil {
+		return nil
+	}
+
+	return deleteEnvSecrets(s.secretsDeleter, statefulSet.Namespace, shared.EnvSecretNames(statefulSet.Name, *container))
+}
+
+// deleteEnvSecrets deletes the given env secrets along with the secrets
+// holding their interpolated VCAP_SERVICES
+func deleteEnvSecrets(secrets SecretsDeleter, namespace string, names []string) error {
+	for _, name := range names {
+		for _, secretName := range []string{name, shared.CredentialsSecretName(name)} {
+			err := secrets.Delete(namespace, secretName)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				return errors.Wrap(err, "failed to delete env secret")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Stopper) deleteOriginalRequestSecret(statefulSet *appsv1.StatefulSet) error {
+	name, ok := statefulSet.Annotations[AnnotationOriginalRequestSecret]
+	if !ok {
+		return nil
+	}
+
+	err := s.secretsDeleter.Delete(statefulSet.Namespace, name)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete original request secret")
+	}
+
+	return nil
+}
+
+// StopInstance deletes the pod of the instance, and returns the app and
+// process type of its LRP, or nil when there was nothing to stop
+func (s *Stopper) StopInstance(identifier opi.LRPIdentifier, index uint) (*opi.LRP, error) {
 	logger := s.logger.Session("stopInstance", lager.Data{"guid": identifier.GUID, "version": identifier.Version, "index": index})
 	statefulset, err := s.getStatefulSet(identifier)
 
 	if errors.Is(err, eirini.ErrNotFound) {
 		logger.Debug("statefulset-does-not-exist")
 
-		return nil
+		return nil, nil
 	}
 
 	if err != nil {
 		logger.Debug("failed-to-get-statefulset", lager.Data{"error": err.Error()})
 
-		return err
+		return nil, err
 	}
 
 	if int32(index) >= *statefulset.Spec.Replicas {
-		return eirini.ErrInvalidInstanceIndex
+		return nil, eirini.ErrInvalidInstanceIndex
 	}
 
 	err = s.podDeleter.Delete(statefulset.Namespace, fmt.Sprintf("%s-%d", statefulset.Name, index))
 	if k8serrors.IsNotFound(err) {
 		logger.Debug("pod-does-not-exist")
 
-		return nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to delete pod")
 	}
 
-	return errors.Wrap(err, "failed to delete pod")
+	return stoppedLRP(identifier, statefulset), nil
 }
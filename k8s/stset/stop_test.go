@@ -26,10 +26,23 @@ var _ = Describe("Stop", func() {
 		podDeleter         *stsetfakes.FakePodDeleter
 		pdbDeleter         *stsetfakes.FakePodDisruptionBudgetDeleter
 		secretsDeleter     *stsetfakes.FakeSecretsDeleter
+		services           *stsetfakes.FakeServicesClient
 
 		stopper stset.Stopper
 	)
 
+	stop := func(identifier opi.LRPIdentifier) error {
+		_, err := stopper.Stop(identifier)
+
+		return err
+	}
+
+	stopInstance := func(identifier opi.LRPIdentifier, index uint) error {
+		_, err := stopper.StopInstance(identifier, index)
+
+		return err
+	}
+
 	BeforeEach(func() {
 		logger = lagertest.NewTestLogger("test-stop-statefulset")
 		statefulSetGetter = new(stsetfakes.FakeStatefulSetByLRPIdentifierGetter)
@@ -37,8 +50,9 @@ var _ = Describe("Stop", func() {
 		podDeleter = new(stsetfakes.FakePodDeleter)
 		pdbDeleter = new(stsetfakes.FakePodDisruptionBudgetDeleter)
 		secretsDeleter = new(stsetfakes.FakeSecretsDeleter)
+		services = new(stsetfakes.FakeServicesClient)
 
-		stopper = stset.NewStopper(logger, statefulSetGetter, statefulSetDeleter, podDeleter, pdbDeleter, secretsDeleter)
+		stopper = stset.NewStopper(logger, statefulSetGetter, statefulSetDeleter, podDeleter, pdbDeleter, secretsDeleter, services)
 	})
 
 	Describe("Stop StatefulSet", func() {
@@ -62,7 +76,7 @@ var _ = Describe("Stop", func() {
 		})
 
 		It("deletes the statefulSet", func() {
-			Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
 			Expect(statefulSetDeleter.DeleteCallCount()).To(Equal(1))
 			namespace, name := statefulSetDeleter.DeleteArgsForCall(0)
 			Expect(namespace).To(Equal("the-namespace"))
@@ -70,13 +84,43 @@ var _ = Describe("Stop", func() {
 		})
 
 		It("should delete any corresponding pod disruption budgets", func() {
-			Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+			Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
 			Expect(pdbDeleter.DeleteCallCount()).To(Equal(1))
 			namespace, pdbName := pdbDeleter.DeleteArgsForCall(0)
 			Expect(namespace).To(Equal("the-namespace"))
 			Expect(pdbName).To(Equal("baldur"))
 		})
 
+		It("should delete the internal services of the lrp", func() {
+			services.ListReturns([]corev1.Service{
+				{ObjectMeta: metav1.ObjectMeta{Name: "internal-baldur", Namespace: "the-namespace"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "the-namespace"}},
+			}, nil)
+
+			Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+
+			namespace, selector := services.ListArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(selector).To(Equal("cloudfoundry.org/guid=guid_1234,cloudfoundry.org/version=version_1234,cloudfoundry.org/service_type=internal"))
+
+			Expect(services.DeleteCallCount()).To(Equal(2))
+			_, name := services.DeleteArgsForCall(0)
+			Expect(name).To(Equal("internal-baldur"))
+			_, name = services.DeleteArgsForCall(1)
+			Expect(name).To(Equal("backend"))
+		})
+
+		When("listing the internal services fails", func() {
+			BeforeEach(func() {
+				services.ListReturns(nil, errors.New("boom"))
+			})
+
+			It("returns the error and keeps the statefulset", func() {
+				Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("boom")))
+				Expect(statefulSetDeleter.DeleteCallCount()).To(BeZero())
+			})
+		})
+
 		When("the stateful set runs an image from a private registry", func() {
 			BeforeEach(func() {
 				statefulSets[0].Spec = appsv1.StatefulSetSpec{
@@ -91,7 +135,7 @@ var _ = Describe("Stop", func() {
 			})
 
 			It("deletes the secret holding the creds of the private registry", func() {
-				Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
 				Expect(secretsDeleter.DeleteCallCount()).To(Equal(1))
 				secretNs, secretName := secretsDeleter.DeleteArgsForCall(0)
 				Expect(secretName).To(Equal("baldur-registry-credentials"))
@@ -104,7 +148,7 @@ var _ = Describe("Stop", func() {
 				})
 
 				It("returns the error", func() {
-					Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("boom")))
+					Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("boom")))
 				})
 			})
 
@@ -117,18 +161,103 @@ var _ = Describe("Stop", func() {
 				})
 
 				It("succeeds", func() {
-					Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+					Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
 				})
 			})
 		})
 
+		When("the app takes its environment from a secret", func() {
+			BeforeEach(func() {
+				statefulSets[0].Spec.Template.Spec.Containers = []corev1.Container{
+					{
+						Name: stset.OPIContainerName,
+						Env: []corev1.EnvVar{
+							{Name: "FOO", ValueFrom: secretValueFrom("baldur-env-0123456789", "FOO")},
+							{Name: "BAR", ValueFrom: secretValueFrom("baldur-env-0123456789", "BAR")},
+							{Name: "VCAP_SERVICES", ValueFrom: secretValueFrom("baldur-vcap-services", "VCAP_SERVICES")},
+						},
+					},
+				}
+			})
+
+			It("deletes the env secret and its interpolated VCAP_SERVICES", func() {
+				Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(secretsDeleter.DeleteCallCount()).To(Equal(2))
+				secretNs, secretName := secretsDeleter.DeleteArgsForCall(0)
+				Expect(secretName).To(Equal("baldur-env-0123456789"))
+				Expect(secretNs).To(Equal("the-namespace"))
+				_, secretName = secretsDeleter.DeleteArgsForCall(1)
+				Expect(secretName).To(Equal("baldur-env-0123456789-vcap-services"))
+			})
+
+			When("deleting the env secret fails", func() {
+				BeforeEach(func() {
+					secretsDeleter.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns the error and keeps the statefulset", func() {
+					Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to delete env secret")))
+					Expect(statefulSetDeleter.DeleteCallCount()).To(BeZero())
+				})
+			})
+		})
+
+		When("the stateful set keeps its original request in a secret", func() {
+			BeforeEach(func() {
+				statefulSets[0].Annotations = map[string]string{
+					stset.AnnotationOriginalRequestSecret: "baldur-original-request",
+				}
+			})
+
+			It("deletes the secret", func() {
+				Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(secretsDeleter.DeleteCallCount()).To(Equal(1))
+				secretNs, secretName := secretsDeleter.DeleteArgsForCall(0)
+				Expect(secretName).To(Equal("baldur-original-request"))
+				Expect(secretNs).To(Equal("the-namespace"))
+			})
+
+			When("the secret does not exist", func() {
+				BeforeEach(func() {
+					secretsDeleter.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur-original-request"))
+				})
+
+				It("succeeds", func() {
+					Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				})
+			})
+
+			When("deleting the secret fails", func() {
+				BeforeEach(func() {
+					secretsDeleter.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns the error and keeps the statefulset", func() {
+					Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("failed to delete original request secret")))
+					Expect(statefulSetDeleter.DeleteCallCount()).To(BeZero())
+				})
+			})
+		})
+
+		It("returns the app and process type of the stopped LRP", func() {
+			statefulSets[0].Labels = map[string]string{stset.LabelProcessType: "worker"}
+			statefulSets[0].Annotations = map[string]string{stset.AnnotationAppID: "app-guid"}
+			statefulSetGetter.GetByLRPIdentifierReturns(statefulSets, nil)
+
+			lrp, err := stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.AppGUID).To(Equal("app-guid"))
+			Expect(lrp.ProcessType).To(Equal("worker"))
+			Expect(lrp.LRPIdentifier).To(Equal(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}))
+		})
+
 		When("deletion of stateful set fails", func() {
 			BeforeEach(func() {
 				statefulSetDeleter.DeleteReturns(errors.New("boom"))
 			})
 
 			It("should return a meaningful error", func() {
-				Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).
+				Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).
 					To(MatchError(ContainSubstring("failed to delete statefulset")))
 			})
 		})
@@ -138,7 +267,7 @@ var _ = Describe("Stop", func() {
 				statefulSetGetter.GetByLRPIdentifierReturns([]appsv1.StatefulSet{{}}, nil)
 				statefulSetDeleter.DeleteReturnsOnCall(0, k8serrors.NewConflict(schema.GroupResource{}, "foo", errors.New("boom")))
 				statefulSetDeleter.DeleteReturnsOnCall(1, nil)
-				Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
+				Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(Succeed())
 				Expect(statefulSetDeleter.DeleteCallCount()).To(Equal(2))
 			})
 		})
@@ -147,7 +276,7 @@ var _ = Describe("Stop", func() {
 			It("returns an error", func() {
 				pdbDeleter.DeleteReturns(errors.New("boom"))
 
-				Expect(stopper.Stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("boom")))
+				Expect(stop(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"})).To(MatchError(ContainSubstring("boom")))
 			})
 		})
 
@@ -157,7 +286,7 @@ var _ = Describe("Stop", func() {
 			})
 
 			It("should return a meaningful error", func() {
-				Expect(stopper.Stop(opi.LRPIdentifier{})).
+				Expect(stop(opi.LRPIdentifier{})).
 					To(MatchError(ContainSubstring("failed to list statefulsets")))
 			})
 		})
@@ -167,12 +296,14 @@ var _ = Describe("Stop", func() {
 				statefulSetGetter.GetByLRPIdentifierReturns([]appsv1.StatefulSet{}, nil)
 			})
 
-			It("succeeds", func() {
-				Expect(stopper.Stop(opi.LRPIdentifier{})).To(Succeed())
+			It("succeeds without a stopped LRP", func() {
+				lrp, err := stopper.Stop(opi.LRPIdentifier{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lrp).To(BeNil())
 			})
 
 			It("logs useful information", func() {
-				_ = stopper.Stop(opi.LRPIdentifier{GUID: "missing_guid", Version: "some_version"})
+				_ = stop(opi.LRPIdentifier{GUID: "missing_guid", Version: "some_version"})
 				Expect(logger).To(gbytes.Say("statefulset-does-not-exist.*missing_guid.*some_version"))
 			})
 		})
@@ -196,7 +327,7 @@ var _ = Describe("Stop", func() {
 		})
 
 		It("deletes a pod instance", func() {
-			Expect(stopper.StopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 0)).
+			Expect(stopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 0)).
 				To(Succeed())
 
 			Expect(podDeleter.DeleteCallCount()).To(Equal(1))
@@ -206,10 +337,16 @@ var _ = Describe("Stop", func() {
 			Expect(name).To(Equal("baldur-space-foo-34f869d015-0"))
 		})
 
+		It("returns the app and process type of the LRP", func() {
+			lrp, err := stopper.StopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lrp.LRPIdentifier).To(Equal(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}))
+		})
+
 		When("there's an internal K8s error", func() {
 			It("should return an error", func() {
 				statefulSetGetter.GetByLRPIdentifierReturns(nil, errors.New("boom"))
-				Expect(stopper.StopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 1)).
+				Expect(stopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 1)).
 					To(MatchError(ContainSubstring("failed to list statefulsets")))
 			})
 		})
@@ -217,14 +354,14 @@ var _ = Describe("Stop", func() {
 		When("the statefulset does not exist", func() {
 			It("succeeds", func() {
 				statefulSetGetter.GetByLRPIdentifierReturns([]appsv1.StatefulSet{}, nil)
-				Expect(stopper.StopInstance(opi.LRPIdentifier{GUID: "some", Version: "thing"}, 1)).To(Succeed())
+				Expect(stopInstance(opi.LRPIdentifier{GUID: "some", Version: "thing"}, 1)).To(Succeed())
 			})
 		})
 
 		When("the instance index is invalid", func() {
 			It("returns an error", func() {
 				podDeleter.DeleteReturns(errors.New("boom"))
-				Expect(stopper.StopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 42)).
+				Expect(stopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 42)).
 					To(MatchError(eirini.ErrInvalidInstanceIndex))
 			})
 		})
@@ -235,7 +372,7 @@ var _ = Describe("Stop", func() {
 			})
 
 			It("succeeds", func() {
-				Expect(stopper.StopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 1)).
+				Expect(stopInstance(opi.LRPIdentifier{GUID: "guid_1234", Version: "version_1234"}, 1)).
 					To(Succeed())
 			})
 		})
@@ -2,12 +2,17 @@ package stset
 
 import (
 	"encoding/json"
+	"strings"
 
+	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/opi"
 	"code.cloudfoundry.org/lager"
 	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 )
 
@@ -24,6 +29,8 @@ type Updater struct {
 	podDisruptionBudgetCreator PodDisruptionBudgetCreator
 	getStatefulSet             getStatefulSetFunc
 	createPodDisruptionBudget  createPodDisruptionBudgetFunc
+	internalServices           internalServices
+	secrets                    SecretsClient
 }
 
 func NewUpdater(
@@ -32,6 +39,8 @@ func NewUpdater(
 	statefulSetUpdater StatefulSetUpdater,
 	podDisruptionBudgetDeleter PodDisruptionBudgetDeleter,
 	podDisruptionBudgetCreator PodDisruptionBudgetCreator,
+	services ServicesClient,
+	secrets SecretsClient,
 ) Updater {
 	return Updater{
 		logger:                     logger,
@@ -40,6 +49,8 @@ func NewUpdater(
 		podDisruptionBudgetCreator: podDisruptionBudgetCreator,
 		getStatefulSet:             newGetStatefulSetFunc(statefulSetGetter),
 		createPodDisruptionBudget:  newCreatePodDisruptionBudgetFunc(podDisruptionBudgetCreator),
+		internalServices:           internalServices{services: services},
+		secrets:                    secrets,
 	}
 }
 
@@ -63,6 +74,8 @@ func (u *Updater) update(lrp *opi.LRP) error {
 
 	updatedStatefulSet, err := u.getUpdatedStatefulSetObj(statefulSet,
 		lrp.AppURIs,
+		lrp.TCPRoutes,
+		lrp.InternalRoutes,
 		lrp.TargetInstances,
 		lrp.LastUpdated,
 		lrp.Image,
@@ -73,6 +86,34 @@ func (u *Updater) update(lrp *opi.LRP) error {
 		return err
 	}
 
+	staleEnvSecrets, err := u.rotateEnvSecret(updatedStatefulSet, lrp.Env)
+	if err != nil {
+		logger.Error("failed-to-rotate-env-secret", err)
+
+		return err
+	}
+
+	if err = migrateOriginalRequest(u.secrets, updatedStatefulSet); err != nil {
+		logger.Error("failed-to-migrate-original-request", err)
+
+		return err
+	}
+
+	unusedRegistrySecret, err := u.updatePrivateRegistry(updatedStatefulSet, lrp)
+	if err != nil {
+		logger.Error("failed-to-update-private-registry", err)
+
+		return err
+	}
+
+	if lrp.Image != "" || lrp.PrivateRegistry != nil {
+		updateImagePullPolicy(updatedStatefulSet)
+	}
+
+	if !equality.Semantic.DeepEqual(statefulSet.Spec.Template, updatedStatefulSet.Spec.Template) {
+		dropOriginalRequestFromTemplate(updatedStatefulSet)
+	}
+
 	_, err = u.statefulSetUpdater.Update(updatedStatefulSet.Namespace, updatedStatefulSet)
 	if err != nil {
 		logger.Error("failed-to-update-statefulset", err, lager.Data{"namespace": statefulSet.Namespace})
@@ -80,14 +121,37 @@ func (u *Updater) update(lrp *opi.LRP) error {
 		return errors.Wrap(err, "failed to update statefulset")
 	}
 
-	return u.handlePodDisruptionBudget(logger,
+	if err := deleteEnvSecrets(u.secrets, updatedStatefulSet.Namespace, staleEnvSecrets); err != nil {
+		logger.Error("failed-to-delete-stale-env-secrets", err)
+
+		return err
+	}
+
+	if err := u.deleteUnusedRegistrySecret(updatedStatefulSet.Namespace, unusedRegistrySecret); err != nil {
+		logger.Error("failed-to-delete-unused-registry-secret", err)
+
+		return err
+	}
+
+	err = u.handlePodDisruptionBudget(logger,
 		statefulSet.Namespace,
 		statefulSet.Name,
 		lrp,
 	)
+	if err != nil {
+		return err
+	}
+
+	if err := u.internalServices.apply(logger, statefulSet, lrp); err != nil {
+		logger.Error("failed-to-apply-internal-services", err, lager.Data{"namespace": statefulSet.Namespace})
+
+		return err
+	}
+
+	return nil
 }
 
-func (u *Updater) getUpdatedStatefulSetObj(sts *appsv1.StatefulSet, routes []opi.Route, instances int, lastUpdated, image string) (*appsv1.StatefulSet, error) {
+func (u *Updater) getUpdatedStatefulSetObj(sts *appsv1.StatefulSet, routes []opi.Route, tcpRoutes []opi.TCPRoute, internalRoutes []opi.InternalRoute, instances int, lastUpdated, image string) (*appsv1.StatefulSet, error) {
 	updatedSts := sts.DeepCopy()
 
 	uris, err := json.Marshal(routes)
@@ -95,10 +159,22 @@ func (u *Updater) getUpdatedStatefulSetObj(sts *appsv1.StatefulSet, routes []opi
 		return nil, errors.Wrap(err, "failed to marshal routes")
 	}
 
+	tcpRoutesJSON, err := marshalTCPRoutes(tcpRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	internalRoutesJSON, err := marshalInternalRoutes(internalRoutes)
+	if err != nil {
+		return nil, err
+	}
+
 	count := int32(instances)
 	updatedSts.Spec.Replicas = &count
 	updatedSts.Annotations[AnnotationLastUpdated] = lastUpdated
 	updatedSts.Annotations[AnnotationRegisteredRoutes] = string(uris)
+	updatedSts.Annotations[AnnotationRegisteredTCPRoutes] = tcpRoutesJSON
+	updatedSts.Annotations[AnnotationInternalRoutes] = internalRoutesJSON
 
 	if image != "" {
 		for i, container := range updatedSts.Spec.Template.Spec.Containers {
@@ -111,6 +187,208 @@ func (u *Updater) getUpdatedStatefulSetObj(sts *appsv1.StatefulSet, routes []opi
 	return updatedSts, nil
 }
 
+// rotateEnvSecret points the app container of the StatefulSet at a new env
+// secret when the environment has changed. The secrets the StatefulSet was
+// using are kept, and listed in its previous env secrets annotation, so that
+// instances of the old revision can still start during the rollout and the
+// StatefulSet can be rolled back. Once a rollout has finished, the secrets
+// of the revisions before it are no longer used by any instance, and are
+// returned, to be deleted once the StatefulSet is updated. A nil environment
+// means that it is not being updated
+func (u *Updater) rotateEnvSecret(statefulSet *appsv1.StatefulSet, env map[string]string) ([]string, error) {
+	if env == nil {
+		return nil, nil
+	}
+
+	container := appContainer(statefulSet)
+	if container == nil {
+		return nil, nil
+	}
+
+	_, secretEnv := splitEnv(env)
+	secretName := shared.EnvSecretName(statefulSet.Name, secretEnv)
+
+	if len(secretEnv) > 0 {
+		secret := shared.NewEnvSecret(secretName, secretEnv)
+		secret.OwnerReferences = []metav1.OwnerReference{statefulSetOwnerReference(statefulSet)}
+
+		_, err := u.secrets.Create(statefulSet.Namespace, secret)
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return nil, errors.Wrap(err, "failed to create env secret")
+		}
+	}
+
+	inUse := map[string]bool{}
+	if len(secretEnv) > 0 {
+		inUse[secretName] = true
+	}
+
+	replaced := []string{}
+
+	for _, name := range shared.EnvSecretNames(statefulSet.Name, *container) {
+		if !inUse[name] {
+			inUse[name] = true
+
+			replaced = append(replaced, name)
+		}
+	}
+
+	container.Env = appEnv(statefulSet.Name, env)
+
+	if len(replaced) == 0 {
+		return nil, nil
+	}
+
+	previous := []string{}
+	stale := []string{}
+
+	for _, name := range previousEnvSecrets(statefulSet) {
+		switch {
+		case inUse[name]:
+		case rolloutFinished(statefulSet):
+			stale = append(stale, name)
+		default:
+			inUse[name] = true
+
+			previous = append(previous, name)
+		}
+	}
+
+	statefulSet.Annotations[AnnotationPreviousEnvSecrets] = strings.Join(append(previous, replaced...), ",")
+
+	return stale, nil
+}
+
+func previousEnvSecrets(statefulSet *appsv1.StatefulSet) []string {
+	annotation := statefulSet.Annotations[AnnotationPreviousEnvSecrets]
+	if annotation == "" {
+		return nil
+	}
+
+	return strings.Split(annotation, ",")
+}
+
+// rolloutFinished tells whether all instances of the StatefulSet run its
+// current revision
+func rolloutFinished(statefulSet *appsv1.StatefulSet) bool {
+	status := statefulSet.Status
+
+	return status.ObservedGeneration >= statefulSet.Generation && status.CurrentRevision == status.UpdateRevision
+}
+
+// updatePrivateRegistry brings the image pull secret of the StatefulSet in
+// line with the private registry of the LRP, which is nil when it is not being
+// updated. Plaintext credentials are written to the generated secret, which is
+// returned when the LRP refers to a secret of its own instead, to be deleted
+// once the StatefulSet no longer uses it
+func (u *Updater) updatePrivateRegistry(statefulSet *appsv1.StatefulSet, lrp *opi.LRP) (string, error) {
+	if lrp.PrivateRegistry == nil {
+		return "", nil
+	}
+
+	generated := privateRegistrySecretName(statefulSet.Name)
+	current := currentRegistryCredsSecretName(statefulSet)
+	desired := registryCredsSecretName(statefulSet.Name, lrp.PrivateRegistry)
+
+	if hasRegistryCredentials(lrp.PrivateRegistry) {
+		if err := u.storeRegistryCredsSecret(statefulSet, lrp); err != nil {
+			return "", err
+		}
+	}
+
+	if current == desired {
+		return "", nil
+	}
+
+	podSpec := &statefulSet.Spec.Template.Spec
+	imagePullSecrets := []corev1.LocalObjectReference{}
+
+	for _, secret := range podSpec.ImagePullSecrets {
+		if secret.Name != current && secret.Name != desired {
+			imagePullSecrets = append(imagePullSecrets, secret)
+		}
+	}
+
+	podSpec.ImagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReference{Name: desired})
+
+	for _, annotations := range []map[string]string{statefulSet.Annotations, statefulSet.Spec.Template.Annotations} {
+		if annotations != nil {
+			annotations[AnnotationPrivateRegistrySecret] = desired
+		}
+	}
+
+	if current == generated {
+		return generated, nil
+	}
+
+	return "", nil
+}
+
+func (u *Updater) storeRegistryCredsSecret(statefulSet *appsv1.StatefulSet, lrp *opi.LRP) error {
+	secret, err := generateRegistryCredsSecret(statefulSet.Name, lrp)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate private registry secret for statefulset")
+	}
+
+	_, err = u.secrets.Update(statefulSet.Namespace, secret)
+	if k8serrors.IsNotFound(err) {
+		_, err = u.secrets.Create(statefulSet.Namespace, secret)
+	}
+
+	return errors.Wrap(err, "failed to store private registry secret for statefulset")
+}
+
+// updateImagePullPolicy brings the pull policy of the app image in line with
+// its image and private registry, once both have been updated
+func updateImagePullPolicy(statefulSet *appsv1.StatefulSet) {
+	container := appContainer(statefulSet)
+	if container == nil {
+		return
+	}
+
+	container.ImagePullPolicy = shared.ImagePullPolicy(container.Image, currentRegistryCredsSecretName(statefulSet) != "")
+}
+
+// currentRegistryCredsSecretName is the image pull secret of the LRP the
+// StatefulSet was last given. StatefulSets which predate the annotation can
+// only have the generated one
+func currentRegistryCredsSecretName(statefulSet *appsv1.StatefulSet) string {
+	if name, ok := statefulSet.Annotations[AnnotationPrivateRegistrySecret]; ok {
+		return name
+	}
+
+	generated := privateRegistrySecretName(statefulSet.Name)
+	for _, secret := range statefulSet.Spec.Template.Spec.ImagePullSecrets {
+		if secret.Name == generated {
+			return generated
+		}
+	}
+
+	return ""
+}
+
+func (u *Updater) deleteUnusedRegistrySecret(namespace, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	if err := u.secrets.Delete(namespace, name); err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete private registry secret")
+	}
+
+	return nil
+}
+
+func appContainer(statefulSet *appsv1.StatefulSet) *corev1.Container {
+	for i, container := range statefulSet.Spec.Template.Spec.Containers {
+		if container.Name == OPIContainerName {
+			return &statefulSet.Spec.Template.Spec.Containers[i]
+		}
+	}
+
+	return nil
+}
+
 func (u *Updater) handlePodDisruptionBudget(logger lager.Logger, namespace, name string, lrp *opi.LRP) error {
 	if lrp.TargetInstances <= 1 {
 		err := u.podDisruptionBudgetDeleter.Delete(namespace, name)
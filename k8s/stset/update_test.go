@@ -1,6 +1,8 @@
 package stset_test
 
 import (
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s/shared"
 	"code.cloudfoundry.org/eirini/k8s/stset"
 	"code.cloudfoundry.org/eirini/k8s/stset/stsetfakes"
 	"code.cloudfoundry.org/eirini/opi"
@@ -23,6 +25,8 @@ var _ = Describe("Update", func() {
 		statefulSetUpdater *stsetfakes.FakeStatefulSetUpdater
 		pdbDeleter         *stsetfakes.FakePodDisruptionBudgetDeleter
 		pdbCreator         *stsetfakes.FakePodDisruptionBudgetCreator
+		services           *stsetfakes.FakeServicesClient
+		secrets            *stsetfakes.FakeSecretsClient
 
 		updatedLRP *opi.LRP
 		err        error
@@ -35,6 +39,9 @@ var _ = Describe("Update", func() {
 		statefulSetUpdater = new(stsetfakes.FakeStatefulSetUpdater)
 		pdbDeleter = new(stsetfakes.FakePodDisruptionBudgetDeleter)
 		pdbCreator = new(stsetfakes.FakePodDisruptionBudgetCreator)
+		services = new(stsetfakes.FakeServicesClient)
+		services.GetReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "service"))
+		secrets = new(stsetfakes.FakeSecretsClient)
 
 		updatedLRP = &opi.LRP{
 			LRPIdentifier: opi.LRPIdentifier{
@@ -46,6 +53,9 @@ var _ = Describe("Update", func() {
 			TargetInstances: 5,
 			LastUpdated:     "now",
 			AppURIs:         []opi.Route{{Hostname: "new-route.io", Port: 6666}},
+			TCPRoutes:       []opi.TCPRoute{{RouterGroupGUID: "default-tcp", ExternalPort: 1234, ContainerPort: 6666}},
+			InternalRoutes:  []opi.InternalRoute{{Hostname: "baldur.apps.internal"}},
+			Ports:           []int32{6666},
 			Image:           "new/image",
 		}
 
@@ -68,7 +78,13 @@ var _ = Describe("Update", func() {
 						Spec: corev1.PodSpec{
 							Containers: []corev1.Container{
 								{Name: "another-container", Image: "another/image"},
-								{Name: stset.OPIContainerName, Image: "old/image"},
+								{
+									Name:  stset.OPIContainerName,
+									Image: "old/image",
+									Env: []corev1.EnvVar{
+										{Name: "FOO", ValueFrom: secretValueFrom("baldur-env-0123456789", "FOO")},
+									},
+								},
 							},
 						},
 					},
@@ -80,7 +96,7 @@ var _ = Describe("Update", func() {
 	})
 
 	JustBeforeEach(func() {
-		updater := stset.NewUpdater(logger, statefulSetGetter, statefulSetUpdater, pdbDeleter, pdbCreator)
+		updater := stset.NewUpdater(logger, statefulSetGetter, statefulSetUpdater, pdbDeleter, pdbCreator, services, secrets)
 		err = updater.Update(updatedLRP)
 	})
 
@@ -95,12 +111,86 @@ var _ = Describe("Update", func() {
 		Expect(namespace).To(Equal("the-namespace"))
 		Expect(st.GetAnnotations()).To(HaveKeyWithValue(stset.AnnotationLastUpdated, "now"))
 		Expect(st.GetAnnotations()).To(HaveKeyWithValue(stset.AnnotationRegisteredRoutes, `[{"hostname":"new-route.io","port":6666}]`))
+		Expect(st.GetAnnotations()).To(HaveKeyWithValue(stset.AnnotationRegisteredTCPRoutes, `[{"router_group_guid":"default-tcp","external_port":1234,"container_port":6666}]`))
+		Expect(st.GetAnnotations()).To(HaveKeyWithValue(stset.AnnotationInternalRoutes, `[{"hostname":"baldur.apps.internal"}]`))
 		Expect(st.GetAnnotations()).NotTo(HaveKey("another"))
 		Expect(*st.Spec.Replicas).To(Equal(int32(5)))
 		Expect(st.Spec.Template.Spec.Containers[0].Image).To(Equal("another/image"))
 		Expect(st.Spec.Template.Spec.Containers[1].Image).To(Equal("new/image"))
 	})
 
+	It("applies the internal services", func() {
+		Expect(services.ApplyCallCount()).To(Equal(2))
+		Expect(services.ApplyArgsForCall(0).Name).To(Equal("internal-baldur"))
+		Expect(services.ApplyArgsForCall(1).Name).To(Equal("baldur-apps-internal"))
+		Expect(services.ApplyArgsForCall(1).Namespace).To(Equal("the-namespace"))
+		Expect(services.ApplyArgsForCall(1).Spec.Ports).To(HaveLen(1))
+	})
+
+	When("an internal route is removed", func() {
+		BeforeEach(func() {
+			updatedLRP.InternalRoutes = nil
+			services.ListReturns([]corev1.Service{
+				{ObjectMeta: metav1.ObjectMeta{Name: "internal-baldur", Namespace: "the-namespace"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "baldur", Namespace: "the-namespace"}},
+			}, nil)
+		})
+
+		It("deletes its service", func() {
+			Expect(services.DeleteCallCount()).To(Equal(1))
+			namespace, name := services.DeleteArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(name).To(Equal("baldur"))
+		})
+	})
+
+	When("applying the internal services fails", func() {
+		BeforeEach(func() {
+			services.ApplyReturns(errors.New("boom"))
+		})
+
+		It("returns the error", func() {
+			Expect(err).To(MatchError(ContainSubstring("boom")))
+		})
+	})
+
+	When("the new image is pinned to a digest", func() {
+		BeforeEach(func() {
+			updatedLRP.Image = "new/image@sha256:2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"
+		})
+
+		It("stops pulling the image when it is present", func() {
+			_, st := statefulSetUpdater.UpdateArgsForCall(0)
+			Expect(st.Spec.Template.Spec.Containers[1].ImagePullPolicy).To(Equal(corev1.PullIfNotPresent))
+			Expect(st.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(BeEmpty())
+		})
+
+		When("the image is in a private registry", func() {
+			BeforeEach(func() {
+				updatedLRP.PrivateRegistry = &opi.PrivateRegistry{SecretName: "my-registry-secret"}
+			})
+
+			It("keeps pulling the image", func() {
+				_, st := statefulSetUpdater.UpdateArgsForCall(0)
+				Expect(st.Spec.Template.Spec.Containers[1].ImagePullPolicy).To(Equal(corev1.PullAlways))
+			})
+		})
+
+		When("the statefulset already pulls the image with a private registry secret", func() {
+			BeforeEach(func() {
+				st, getErr := statefulSetGetter.GetByLRPIdentifier(opi.LRPIdentifier{})
+				Expect(getErr).NotTo(HaveOccurred())
+				st[0].Annotations[stset.AnnotationPrivateRegistrySecret] = "my-registry-secret"
+				statefulSetGetter.GetByLRPIdentifierReturns(st, nil)
+			})
+
+			It("keeps pulling the image", func() {
+				_, st := statefulSetUpdater.UpdateArgsForCall(0)
+				Expect(st.Spec.Template.Spec.Containers[1].ImagePullPolicy).To(Equal(corev1.PullAlways))
+			})
+		})
+	})
+
 	When("the image is missing", func() {
 		BeforeEach(func() {
 			updatedLRP.Image = ""
@@ -191,6 +281,339 @@ var _ = Describe("Update", func() {
 		})
 	})
 
+	It("leaves the environment alone when it is not being updated", func() {
+		Expect(secrets.CreateCallCount()).To(BeZero())
+		Expect(secrets.DeleteCallCount()).To(BeZero())
+
+		_, st := statefulSetUpdater.UpdateArgsForCall(0)
+		Expect(st.Spec.Template.Spec.Containers[1].Env).To(ConsistOf(
+			corev1.EnvVar{Name: "FOO", ValueFrom: secretValueFrom("baldur-env-0123456789", "FOO")},
+		))
+	})
+
+	When("the environment changes", func() {
+		BeforeEach(func() {
+			updatedLRP.Env = map[string]string{"FOO": "new-foo"}
+		})
+
+		It("stores it in a new secret owned by the statefulset", func() {
+			Expect(secrets.CreateCallCount()).To(Equal(1))
+			namespace, secret := secrets.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(secret.Name).To(HavePrefix("baldur-env-"))
+			Expect(secret.Name).NotTo(Equal("baldur-env-0123456789"))
+			Expect(secret.StringData).To(Equal(map[string]string{"FOO": "new-foo"}))
+			Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+				APIVersion: "apps/v1",
+				Kind:       "StatefulSet",
+				Name:       "baldur",
+			}))
+		})
+
+		It("points the app container at the new secret", func() {
+			_, secret := secrets.CreateArgsForCall(0)
+			_, st := statefulSetUpdater.UpdateArgsForCall(0)
+			Expect(st.Spec.Template.Spec.Containers[1].Env).To(ContainElement(
+				corev1.EnvVar{Name: "FOO", ValueFrom: secretValueFrom(secret.Name, "FOO")},
+			))
+			Expect(st.Spec.Template.Spec.Containers[1].Env).To(ContainElement(
+				corev1.EnvVar{Name: eirini.EnvPodName, ValueFrom: expectedValFrom("metadata.name")},
+			))
+		})
+
+		It("keeps the old secret for the instances of the old revision", func() {
+			Expect(secrets.DeleteCallCount()).To(BeZero())
+			_, st := statefulSetUpdater.UpdateArgsForCall(0)
+			Expect(st.Annotations).To(HaveKeyWithValue(stset.AnnotationPreviousEnvSecrets, "baldur-env-0123456789"))
+			Expect(st.Spec.Template.Annotations).NotTo(HaveKey(stset.AnnotationPreviousEnvSecrets))
+		})
+
+		When("creating the new secret fails", func() {
+			BeforeEach(func() {
+				secrets.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("does not update the statefulset", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to create env secret")))
+				Expect(statefulSetUpdater.UpdateCallCount()).To(BeZero())
+				Expect(secrets.DeleteCallCount()).To(BeZero())
+			})
+		})
+
+		When("there are secrets of earlier revisions", func() {
+			BeforeEach(func() {
+				st, getErr := statefulSetGetter.GetByLRPIdentifier(opi.LRPIdentifier{})
+				Expect(getErr).NotTo(HaveOccurred())
+				st[0].Annotations[stset.AnnotationPreviousEnvSecrets] = "baldur-env-aaaaaaaaaa"
+				st[0].Generation = 4
+				st[0].Status = appsv1.StatefulSetStatus{
+					ObservedGeneration: 4,
+					CurrentRevision:    "baldur-2",
+					UpdateRevision:     "baldur-2",
+				}
+				statefulSetGetter.GetByLRPIdentifierReturns(st, nil)
+			})
+
+			It("deletes them and their interpolated VCAP_SERVICES once the rollout has finished", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(secrets.DeleteCallCount()).To(Equal(2))
+				namespace, name := secrets.DeleteArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(name).To(Equal("baldur-env-aaaaaaaaaa"))
+				_, name = secrets.DeleteArgsForCall(1)
+				Expect(name).To(Equal("baldur-env-aaaaaaaaaa-vcap-services"))
+
+				_, st := statefulSetUpdater.UpdateArgsForCall(0)
+				Expect(st.Annotations).To(HaveKeyWithValue(stset.AnnotationPreviousEnvSecrets, "baldur-env-0123456789"))
+			})
+
+			When("the rollout is still in progress", func() {
+				BeforeEach(func() {
+					st, getErr := statefulSetGetter.GetByLRPIdentifier(opi.LRPIdentifier{})
+					Expect(getErr).NotTo(HaveOccurred())
+					st[0].Status.CurrentRevision = "baldur-1"
+					statefulSetGetter.GetByLRPIdentifierReturns(st, nil)
+				})
+
+				It("keeps them", func() {
+					Expect(secrets.DeleteCallCount()).To(BeZero())
+					_, st := statefulSetUpdater.UpdateArgsForCall(0)
+					Expect(st.Annotations).To(HaveKeyWithValue(stset.AnnotationPreviousEnvSecrets, "baldur-env-aaaaaaaaaa,baldur-env-0123456789"))
+				})
+			})
+
+			When("the statefulset has not observed its latest generation", func() {
+				BeforeEach(func() {
+					st, getErr := statefulSetGetter.GetByLRPIdentifier(opi.LRPIdentifier{})
+					Expect(getErr).NotTo(HaveOccurred())
+					st[0].Status.ObservedGeneration = 3
+					statefulSetGetter.GetByLRPIdentifierReturns(st, nil)
+				})
+
+				It("keeps them", func() {
+					Expect(secrets.DeleteCallCount()).To(BeZero())
+				})
+			})
+
+			When("updating the statefulset fails", func() {
+				BeforeEach(func() {
+					statefulSetUpdater.UpdateReturns(nil, errors.New("boom"))
+				})
+
+				It("keeps them", func() {
+					Expect(secrets.DeleteCallCount()).To(BeZero())
+				})
+			})
+
+			When("deleting them fails", func() {
+				BeforeEach(func() {
+					secrets.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(MatchError(ContainSubstring("failed to delete env secret")))
+				})
+			})
+
+			When("they are already gone", func() {
+				BeforeEach(func() {
+					secrets.DeleteReturns(k8serrors.NewNotFound(schema.GroupResource{}, "baldur-env-aaaaaaaaaa"))
+				})
+
+				It("succeeds", func() {
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+		})
+	})
+
+	It("does not create an original request secret", func() {
+		Expect(secrets.CreateCallCount()).To(BeZero())
+	})
+
+	When("the statefulset still has the original request annotation", func() {
+		BeforeEach(func() {
+			st, getErr := statefulSetGetter.GetByLRPIdentifier(opi.LRPIdentifier{})
+			Expect(getErr).NotTo(HaveOccurred())
+			st[0].Annotations[stset.AnnotationOriginalRequest] = `{"guid":"guid_1234","environment":{"FOO":"foo"}}`
+			st[0].Spec.Template.Annotations = map[string]string{
+				stset.AnnotationOriginalRequest: `{"guid":"guid_1234","environment":{"FOO":"foo"}}`,
+			}
+			statefulSetGetter.GetByLRPIdentifierReturns(st, nil)
+		})
+
+		It("moves it into a secret owned by the statefulset", func() {
+			Expect(secrets.CreateCallCount()).To(Equal(1))
+			namespace, secret := secrets.CreateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(secret.Name).To(Equal("baldur-original-request"))
+			Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+				APIVersion: "apps/v1",
+				Kind:       "StatefulSet",
+				Name:       "baldur",
+			}))
+			Expect(stset.ReadOriginalRequest(secret)).To(MatchJSON(`{"guid":"guid_1234","environment":{"FOO":"[REDACTED]"}}`))
+		})
+
+		It("replaces the annotation with a reference to the secret", func() {
+			_, st := statefulSetUpdater.UpdateArgsForCall(0)
+			for _, annotations := range []map[string]string{st.Annotations, st.Spec.Template.Annotations} {
+				Expect(annotations).NotTo(HaveKey(stset.AnnotationOriginalRequest))
+				Expect(annotations).To(HaveKeyWithValue(stset.AnnotationOriginalRequestSecret, "baldur-original-request"))
+			}
+		})
+
+		When("the pod template does not change otherwise", func() {
+			BeforeEach(func() {
+				updatedLRP.Image = ""
+			})
+
+			It("leaves the annotation of the pod template alone, so that the instances are not restarted", func() {
+				_, st := statefulSetUpdater.UpdateArgsForCall(0)
+				Expect(st.Annotations).NotTo(HaveKey(stset.AnnotationOriginalRequest))
+				Expect(st.Annotations).To(HaveKeyWithValue(stset.AnnotationOriginalRequestSecret, "baldur-original-request"))
+				Expect(st.Spec.Template.Annotations).To(Equal(map[string]string{
+					stset.AnnotationOriginalRequest: `{"guid":"guid_1234","environment":{"FOO":"foo"}}`,
+				}))
+			})
+		})
+
+		When("the secret already exists", func() {
+			BeforeEach(func() {
+				secrets.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "baldur-original-request"))
+			})
+
+			It("still replaces the annotation", func() {
+				Expect(err).NotTo(HaveOccurred())
+				_, st := statefulSetUpdater.UpdateArgsForCall(0)
+				Expect(st.Annotations).NotTo(HaveKey(stset.AnnotationOriginalRequest))
+			})
+		})
+
+		When("creating the secret fails", func() {
+			BeforeEach(func() {
+				secrets.CreateReturns(nil, errors.New("boom"))
+			})
+
+			It("keeps the annotation", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to create original request secret")))
+				Expect(statefulSetUpdater.UpdateCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	It("leaves the image pull secrets alone when the registry is not being updated", func() {
+		_, st := statefulSetUpdater.UpdateArgsForCall(0)
+		Expect(st.Spec.Template.Spec.ImagePullSecrets).To(BeEmpty())
+		Expect(st.Annotations).NotTo(HaveKey(stset.AnnotationPrivateRegistrySecret))
+	})
+
+	When("the registry credentials change", func() {
+		BeforeEach(func() {
+			updatedLRP.PrivateRegistry = &opi.PrivateRegistry{Server: "host", Username: "user", Password: "new-password"}
+
+			st, getErr := statefulSetGetter.GetByLRPIdentifier(opi.LRPIdentifier{})
+			Expect(getErr).NotTo(HaveOccurred())
+			st[0].Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{
+				{Name: "registry-secret"},
+				{Name: "baldur-registry-credentials"},
+			}
+			statefulSetGetter.GetByLRPIdentifierReturns(st, nil)
+		})
+
+		It("updates the generated secret", func() {
+			Expect(secrets.UpdateCallCount()).To(Equal(1))
+			namespace, secret := secrets.UpdateArgsForCall(0)
+			Expect(namespace).To(Equal("the-namespace"))
+			Expect(secret.Name).To(Equal("baldur-registry-credentials"))
+			Expect(secret.StringData[".dockerconfigjson"]).To(ContainSubstring(`"password":"new-password"`))
+		})
+
+		It("keeps pulling the image with it", func() {
+			_, st := statefulSetUpdater.UpdateArgsForCall(0)
+			Expect(st.Spec.Template.Spec.ImagePullSecrets).To(ConsistOf(
+				corev1.LocalObjectReference{Name: "registry-secret"},
+				corev1.LocalObjectReference{Name: "baldur-registry-credentials"},
+			))
+			Expect(secrets.DeleteCallCount()).To(BeZero())
+		})
+
+		When("the generated secret does not exist yet", func() {
+			BeforeEach(func() {
+				secrets.UpdateReturns(nil, k8serrors.NewNotFound(schema.GroupResource{}, "baldur-registry-credentials"))
+			})
+
+			It("creates it", func() {
+				Expect(secrets.CreateCallCount()).To(Equal(1))
+				_, secret := secrets.CreateArgsForCall(0)
+				Expect(secret.Name).To(Equal("baldur-registry-credentials"))
+			})
+		})
+
+		When("updating the generated secret fails", func() {
+			BeforeEach(func() {
+				secrets.UpdateReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to store private registry secret")))
+				Expect(statefulSetUpdater.UpdateCallCount()).To(BeZero())
+			})
+		})
+
+		When("the app switches to a registry secret of its own", func() {
+			BeforeEach(func() {
+				updatedLRP.PrivateRegistry = &opi.PrivateRegistry{SecretName: "my-registry-secret"}
+			})
+
+			It("pulls the image with it", func() {
+				Expect(secrets.UpdateCallCount()).To(BeZero())
+				_, st := statefulSetUpdater.UpdateArgsForCall(0)
+				Expect(st.Spec.Template.Spec.ImagePullSecrets).To(ConsistOf(
+					corev1.LocalObjectReference{Name: "registry-secret"},
+					corev1.LocalObjectReference{Name: "my-registry-secret"},
+				))
+				Expect(st.Annotations).To(HaveKeyWithValue(stset.AnnotationPrivateRegistrySecret, "my-registry-secret"))
+			})
+
+			It("deletes the generated secret", func() {
+				Expect(secrets.DeleteCallCount()).To(Equal(1))
+				namespace, name := secrets.DeleteArgsForCall(0)
+				Expect(namespace).To(Equal("the-namespace"))
+				Expect(name).To(Equal("baldur-registry-credentials"))
+			})
+
+			When("deleting the generated secret fails", func() {
+				BeforeEach(func() {
+					secrets.DeleteReturns(errors.New("boom"))
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(MatchError(ContainSubstring("failed to delete private registry secret")))
+				})
+			})
+		})
+	})
+
+	When("the environment is unchanged", func() {
+		BeforeEach(func() {
+			updatedLRP.Env = map[string]string{"FOO": "foo"}
+
+			st, getErr := statefulSetGetter.GetByLRPIdentifier(opi.LRPIdentifier{})
+			Expect(getErr).NotTo(HaveOccurred())
+			st[0].Spec.Template.Spec.Containers[1].Env = []corev1.EnvVar{
+				{Name: "FOO", ValueFrom: secretValueFrom(shared.EnvSecretName("baldur", updatedLRP.Env), "FOO")},
+			}
+			statefulSetGetter.GetByLRPIdentifierReturns(st, nil)
+			secrets.CreateReturns(nil, k8serrors.NewAlreadyExists(schema.GroupResource{}, "secret"))
+		})
+
+		It("keeps the secret", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secrets.DeleteCallCount()).To(BeZero())
+		})
+	})
+
 	When("update fails", func() {
 		BeforeEach(func() {
 			statefulSetUpdater.UpdateReturns(nil, errors.New("boom"))
@@ -226,3 +649,12 @@ var _ = Describe("Update", func() {
 		})
 	})
 })
+
+func secretValueFrom(name, key string) *corev1.EnvVarSource {
+	return &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  key,
+		},
+	}
+}
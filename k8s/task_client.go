@@ -19,6 +19,7 @@ type JobClient interface {
 
 type SecretClient interface {
 	Create(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Update(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
 	Delete(namespace, name string) error
 }
 
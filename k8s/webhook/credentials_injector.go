@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/credentials"
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/shared"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	eirinix "code.cloudfoundry.org/eirinix"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// CredentialsInjector resolves the credential references in the
+// VCAP_SERVICES of every new app instance and task, on behalf of the
+// instance, so that only the credentials of the services bound to its app
+// resolve. The interpolated VCAP_SERVICES is kept in a secret, named after
+// the env secret it was resolved from and refreshed whenever an instance
+// using it is created, so that the credentials are in neither the
+// StatefulSet nor the pod spec, and instances of different revisions keep
+// their own VCAP_SERVICES
+type CredentialsInjector struct {
+	logger             lager.Logger
+	resolvers          credentials.InstanceResolvers
+	secrets            SecretsClient
+	injectIntoSidecars bool
+}
+
+func NewCredentialsInjector(logger lager.Logger, resolvers credentials.InstanceResolvers, secrets SecretsClient, injectIntoSidecars bool) CredentialsInjector {
+	return CredentialsInjector{
+		logger:             logger,
+		resolvers:          resolvers,
+		secrets:            secrets,
+		injectIntoSidecars: injectIntoSidecars,
+	}
+}
+
+func (i CredentialsInjector) Handle(ctx context.Context, eiriniManager eirinix.Manager, pod *corev1.Pod, req admission.Request) admission.Response {
+	return mutatePodOnCreate(i.logger, eiriniManager, pod, req, []string{stset.AppSourceType, jobs.TaskSourceType}, func(logger lager.Logger, pod *corev1.Pod) error {
+		return i.inject(logger, podNamespace(pod, req), pod)
+	})
+}
+
+func (i CredentialsInjector) inject(logger lager.Logger, namespace string, pod *corev1.Pod) error {
+	app := appContainer(pod)
+	if app == nil {
+		logger.Info("no-opi-container-found")
+
+		return errors.New("no opi container found in pod")
+	}
+
+	vcapServices, envSecretName, err := i.envValue(namespace, app.Env, eirini.EnvVCAPServices)
+	if err != nil {
+		return exterrors.Wrap(err, "failed to read VCAP_SERVICES")
+	}
+
+	if vcapServices == "" {
+		return nil
+	}
+
+	resolver, err := i.resolvers.ForInstance(instanceIdentity(pod))
+	if err != nil {
+		return exterrors.Wrap(err, "failed to create credential resolver for instance")
+	}
+
+	interpolated, ok, err := credentials.Interpolate(vcapServices, resolver)
+	if err != nil {
+		return exterrors.Wrap(err, "failed to interpolate credentials")
+	}
+
+	if !ok {
+		logger.Debug("no-credential-references")
+
+		return nil
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      credentialsSecretName(pod, envSecretName),
+			Namespace: namespace,
+		},
+		StringData: map[string]string{
+			eirini.EnvVCAPServices: interpolated,
+		},
+	}
+
+	if err = storeSecret(i.secrets, pod, secret); err != nil {
+		return exterrors.Wrap(err, "failed to store VCAP_SERVICES secret")
+	}
+
+	envVar := corev1.EnvVar{
+		Name: eirini.EnvVCAPServices,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+				Key:                  eirini.EnvVCAPServices,
+			},
+		},
+	}
+
+	for _, container := range targetContainers(pod, app, i.injectIntoSidecars) {
+		logger.Debug("patching-vcap-services", lager.Data{"container": container.Name, "secret": secret.Name})
+		container.Env = setEnv(container.Env, []corev1.EnvVar{envVar})
+	}
+
+	return nil
+}
+
+// instanceIdentity is the identity of the pod, where task pods, which are
+// only named once they are created, are identified by the task GUID
+func instanceIdentity(pod *corev1.Pod) identity.Identity {
+	id := identity.FromPod(pod)
+	if id.InstanceID == "" {
+		id.InstanceID = pod.Annotations[jobs.AnnotationGUID]
+	}
+
+	return id
+}
+
+// credentialsSecretName is the name of the secret holding the interpolated
+// VCAP_SERVICES. When VCAP_SERVICES is set inline, as it is for tasks, it is
+// named after the owner of the pod
+func credentialsSecretName(pod *corev1.Pod, envSecretName string) string {
+	if envSecretName != "" {
+		return shared.CredentialsSecretName(envSecretName)
+	}
+
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		return owner.Name + "-vcap-services"
+	}
+
+	return pod.Name + "-vcap-services"
+}
+
+// envValue is the value of an environment variable, which is either set
+// inline or taken from a secret key, as the app environment is, along with
+// the name of that secret
+func (i CredentialsInjector) envValue(namespace string, env []corev1.EnvVar, name string) (string, string, error) {
+	for _, envVar := range env {
+		if envVar.Name != name {
+			continue
+		}
+
+		if envVar.ValueFrom == nil || envVar.ValueFrom.SecretKeyRef == nil {
+			return envVar.Value, "", nil
+		}
+
+		ref := envVar.ValueFrom.SecretKeyRef
+
+		secret, err := i.secrets.Get(namespace, ref.Name)
+		if err != nil {
+			return "", "", exterrors.Wrapf(err, "failed to get secret %s", ref.Name)
+		}
+
+		return string(secret.Data[ref.Key]), ref.Name, nil
+	}
+
+	return "", "", nil
+}
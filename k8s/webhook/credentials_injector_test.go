@@ -0,0 +1,309 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/credentials/credentialsfakes"
+	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/k8s/webhook/webhookfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("CredentialsInjector", func() {
+	var (
+		manager            *webhookfakes.FakeManager
+		resolvers          *credentialsfakes.FakeInstanceResolvers
+		resolver           *credentialsfakes.FakeResolver
+		secrets            *webhookfakes.FakeSecretsClient
+		injectIntoSidecars bool
+		pod                *corev1.Pod
+		actualResp         admission.Response
+		isController       bool
+		vcapServicesEnvVar corev1.EnvVar
+	)
+
+	BeforeEach(func() {
+		manager = new(webhookfakes.FakeManager)
+		manager.PatchFromPodReturns(admission.Allowed("patched"))
+		resolver = new(credentialsfakes.FakeResolver)
+		resolver.ResolveReturns(map[string]interface{}{"password": "secret"}, nil)
+		resolvers = new(credentialsfakes.FakeInstanceResolvers)
+		resolvers.ForInstanceReturns(resolver, nil)
+		secrets = new(webhookfakes.FakeSecretsClient)
+		injectIntoSidecars = false
+		isController = true
+
+		vcapServicesEnvVar = corev1.EnvVar{
+			Name: "VCAP_SERVICES",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "some-app-vcap-services"},
+					Key:                  "VCAP_SERVICES",
+				},
+			},
+		}
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-app-3",
+				Labels:    map[string]string{"cloudfoundry.org/source_type": "APP"},
+				Namespace: "some-ns",
+				Annotations: map[string]string{
+					stset.AnnotationAppID: "app-guid",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "StatefulSet", Name: "some-app", UID: "sts-uid", Controller: &isController},
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "opi",
+						Env: []corev1.EnvVar{
+							{Name: "VCAP_SERVICES", Value: `{"p-mysql": [{"name": "db", "credentials": {"credhub-ref": "/c/p-mysql/db"}}]}`},
+							{Name: "FOO", Value: "foo"},
+						},
+					},
+					{Name: "some-sidecar"},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		injector := webhook.NewCredentialsInjector(lagertest.NewTestLogger("credentials-injector"), resolvers, secrets, injectIntoSidecars)
+		req := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{Operation: v1beta1.Create}}
+		actualResp = injector.Handle(context.Background(), manager, pod, req)
+	})
+
+	It("stores the interpolated VCAP_SERVICES in a secret owned by the statefulset", func() {
+		Expect(resolver.ResolveCallCount()).To(Equal(1))
+		Expect(resolver.ResolveArgsForCall(0)).To(Equal("/c/p-mysql/db"))
+
+		Expect(secrets.CreateCallCount()).To(Equal(1))
+		namespace, secret := secrets.CreateArgsForCall(0)
+		Expect(namespace).To(Equal("some-ns"))
+		Expect(secret.Name).To(Equal("some-app-vcap-services"))
+		Expect(secret.StringData["VCAP_SERVICES"]).To(MatchJSON(`{"p-mysql": [{"name": "db", "credentials": {"password": "secret"}}]}`))
+		Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+			Kind: "StatefulSet", Name: "some-app", UID: "sts-uid",
+		}))
+	})
+
+	It("resolves the references on behalf of the instance", func() {
+		Expect(resolvers.ForInstanceCallCount()).To(Equal(1))
+		id := resolvers.ForInstanceArgsForCall(0)
+		Expect(id.InstanceID).To(Equal("some-app-3"))
+		Expect(id.AppGUID).To(Equal("app-guid"))
+	})
+
+	It("takes VCAP_SERVICES from the secret", func() {
+		Expect(actualResp.Allowed).To(BeTrue())
+		_, actualPod := manager.PatchFromPodArgsForCall(0)
+		Expect(actualPod.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+			{Name: "FOO", Value: "foo"},
+			vcapServicesEnvVar,
+		}))
+		Expect(actualPod.Spec.Containers[1].Env).To(BeEmpty())
+	})
+
+	When("injecting into sidecars is enabled", func() {
+		BeforeEach(func() {
+			injectIntoSidecars = true
+		})
+
+		It("gives the sidecars VCAP_SERVICES too", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers[1].Env).To(ConsistOf(vcapServicesEnvVar))
+		})
+	})
+
+	When("the secret already exists", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, apierrors.NewAlreadyExists(schema.GroupResource{}, "some-app-vcap-services"))
+		})
+
+		It("refreshes it", func() {
+			Expect(actualResp.Allowed).To(BeTrue())
+			Expect(secrets.UpdateCallCount()).To(Equal(1))
+		})
+	})
+
+	When("the pod runs a task", func() {
+		BeforeEach(func() {
+			pod.Name = ""
+			pod.GenerateName = "some-task-"
+			pod.Labels["cloudfoundry.org/source_type"] = "TASK"
+			pod.Annotations[jobs.AnnotationGUID] = "task-guid"
+			pod.Annotations[jobs.AnnotationOpiTaskContainerName] = "opi-task"
+			pod.OwnerReferences = []metav1.OwnerReference{
+				{Kind: "Job", Name: "some-task", UID: "job-uid", Controller: &isController},
+			}
+			pod.Spec.Containers[0].Name = "opi-task"
+		})
+
+		It("resolves the references on behalf of the task", func() {
+			Expect(resolvers.ForInstanceCallCount()).To(Equal(1))
+			id := resolvers.ForInstanceArgsForCall(0)
+			Expect(id.InstanceID).To(Equal("task-guid"))
+			Expect(id.AppGUID).To(Equal("app-guid"))
+		})
+
+		It("stores the interpolated VCAP_SERVICES in a secret owned by the job", func() {
+			Expect(secrets.CreateCallCount()).To(Equal(1))
+			_, secret := secrets.CreateArgsForCall(0)
+			Expect(secret.Name).To(Equal("some-task-vcap-services"))
+			Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+				Kind: "Job", Name: "some-task", UID: "job-uid",
+			}))
+		})
+
+		It("takes VCAP_SERVICES of the task container from the secret", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "VCAP_SERVICES",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "some-task-vcap-services"},
+						Key:                  "VCAP_SERVICES",
+					},
+				},
+			}))
+		})
+	})
+
+	When("the pod is neither an app instance nor a task", func() {
+		BeforeEach(func() {
+			pod.Labels["cloudfoundry.org/source_type"] = "STG"
+		})
+
+		It("leaves the pod alone", func() {
+			Expect(actualResp.Allowed).To(BeTrue())
+			Expect(resolvers.ForInstanceCallCount()).To(BeZero())
+			Expect(manager.PatchFromPodCallCount()).To(BeZero())
+		})
+	})
+
+	When("there are no credential references", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Env[0].Value = `{"p-mysql": [{"name": "db", "credentials": {"password": "plain"}}]}`
+		})
+
+		It("leaves the pod alone", func() {
+			Expect(secrets.CreateCallCount()).To(BeZero())
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod).To(Equal(pod))
+		})
+	})
+
+	When("VCAP_SERVICES is taken from the env secret", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Env[0] = corev1.EnvVar{
+				Name: "VCAP_SERVICES",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "some-app-env-0123456789"},
+						Key:                  "VCAP_SERVICES",
+					},
+				},
+			}
+			secrets.GetReturns(&corev1.Secret{
+				Data: map[string][]byte{
+					"VCAP_SERVICES": []byte(`{"p-mysql": [{"name": "db", "credentials": {"credhub-ref": "/c/p-mysql/db"}}]}`),
+				},
+			}, nil)
+		})
+
+		It("reads it from the secret", func() {
+			Expect(secrets.GetCallCount()).To(Equal(1))
+			namespace, name := secrets.GetArgsForCall(0)
+			Expect(namespace).To(Equal("some-ns"))
+			Expect(name).To(Equal("some-app-env-0123456789"))
+			Expect(resolver.ResolveArgsForCall(0)).To(Equal("/c/p-mysql/db"))
+		})
+
+		It("stores the interpolated VCAP_SERVICES in a secret named after the env secret", func() {
+			Expect(secrets.CreateCallCount()).To(Equal(1))
+			_, secret := secrets.CreateArgsForCall(0)
+			Expect(secret.Name).To(Equal("some-app-env-0123456789-vcap-services"))
+		})
+
+		It("takes VCAP_SERVICES from the interpolated secret instead", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{
+				Name: "VCAP_SERVICES",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "some-app-env-0123456789-vcap-services"},
+						Key:                  "VCAP_SERVICES",
+					},
+				},
+			}))
+		})
+
+		When("getting the secret fails", func() {
+			BeforeEach(func() {
+				secrets.GetReturns(nil, errors.New("boom"))
+			})
+
+			It("returns an error response", func() {
+				ExpectBadRequestErrorResponse(actualResp, "failed to read VCAP_SERVICES")
+			})
+		})
+	})
+
+	When("there is no VCAP_SERVICES", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Env = nil
+		})
+
+		It("leaves the pod alone", func() {
+			Expect(resolver.ResolveCallCount()).To(BeZero())
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod).To(Equal(pod))
+		})
+	})
+
+	When("there is no resolver for the instance", func() {
+		BeforeEach(func() {
+			resolvers.ForInstanceReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to create credential resolver for instance")
+			Expect(secrets.CreateCallCount()).To(BeZero())
+		})
+	})
+
+	When("a reference cannot be resolved", func() {
+		BeforeEach(func() {
+			resolver.ResolveReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to interpolate credentials")
+			Expect(secrets.CreateCallCount()).To(BeZero())
+		})
+	})
+
+	When("storing the secret fails", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to store VCAP_SERVICES secret")
+		})
+	})
+})
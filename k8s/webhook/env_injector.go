@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	eirinix "code.cloudfoundry.org/eirinix"
+	"code.cloudfoundry.org/lager"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//counterfeiter:generate -o webhookfakes/fake_manager.go code.cloudfoundry.org/eirinix.Manager
+
+// appSourceTypes are the pods most extensions mutate. The webhook receives
+// the pods of tasks as well when credential interpolation is enabled
+var appSourceTypes = []string{stset.AppSourceType}
+
+// EnvVarsFunc computes the environment variables to inject into a new app
+// instance. app is the container running the app within pod
+type EnvVarsFunc func(pod *corev1.Pod, app *corev1.Container) ([]corev1.EnvVar, error)
+
+// EnvInjector is an eirinix extension that sets the variables returned by
+// envVars in the app container of every new app instance, and optionally in
+// its sidecars too. Variables that are already set get replaced
+type EnvInjector struct {
+	logger             lager.Logger
+	envVars            EnvVarsFunc
+	injectIntoSidecars bool
+}
+
+func NewEnvInjector(logger lager.Logger, envVars EnvVarsFunc, injectIntoSidecars bool) EnvInjector {
+	return EnvInjector{
+		logger:             logger,
+		envVars:            envVars,
+		injectIntoSidecars: injectIntoSidecars,
+	}
+}
+
+func (i EnvInjector) Handle(ctx context.Context, eiriniManager eirinix.Manager, pod *corev1.Pod, req admission.Request) admission.Response {
+	return mutatePodOnCreate(i.logger, eiriniManager, pod, req, appSourceTypes, i.inject)
+}
+
+// mutatePodOnCreate applies mutate to a copy of a pod of one of the given
+// source types that is being created and responds with the resulting patch.
+// Any other pod is admitted as it is
+func mutatePodOnCreate(logger lager.Logger, eiriniManager eirinix.Manager, pod *corev1.Pod, req admission.Request, sourceTypes []string, mutate func(lager.Logger, *corev1.Pod) error) admission.Response {
+	logger = logger.Session("handle-webhook-request")
+
+	if req.Operation != v1beta1.Create {
+		return admission.Allowed("pod was already created")
+	}
+
+	if pod == nil {
+		err := errors.New("no pod could be decoded from the request")
+		logger.Error("no-pod-in-request", err)
+
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if !hasSourceType(pod, sourceTypes) {
+		return admission.Allowed("pod is not of a handled source type")
+	}
+
+	logger = logger.WithData(lager.Data{"pod-name": pod.Name, "pod-namespace": pod.Namespace})
+
+	podCopy := pod.DeepCopy()
+
+	err := mutate(logger, podCopy)
+	if err != nil {
+		logger.Error("failed-to-mutate-pod", err)
+
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	return eiriniManager.PatchFromPod(req, podCopy)
+}
+
+func (i EnvInjector) inject(logger lager.Logger, pod *corev1.Pod) error {
+	app := appContainer(pod)
+	if app == nil {
+		logger.Info("no-opi-container-found")
+
+		return errors.New("no opi container found in pod")
+	}
+
+	envVars, err := i.envVars(pod, app)
+	if err != nil {
+		return err
+	}
+
+	for _, container := range targetContainers(pod, app, i.injectIntoSidecars) {
+		logger.Debug("patching-env", lager.Data{"container": container.Name, "env-vars": envVars})
+		container.Env = setEnv(container.Env, envVars)
+	}
+
+	return nil
+}
+
+// targetContainers returns the app container, followed by all the other
+// containers in the pod if sidecars are included
+func targetContainers(pod *corev1.Pod, app *corev1.Container, includeSidecars bool) []*corev1.Container {
+	containers := []*corev1.Container{app}
+
+	if !includeSidecars {
+		return containers
+	}
+
+	for c := range pod.Spec.Containers {
+		if container := &pod.Spec.Containers[c]; container != app {
+			containers = append(containers, container)
+		}
+	}
+
+	return containers
+}
+
+func hasSourceType(pod *corev1.Pod, sourceTypes []string) bool {
+	for _, sourceType := range sourceTypes {
+		if pod.Labels[stset.LabelSourceType] == sourceType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appContainer returns the container running the app, or the task of task
+// pods
+func appContainer(pod *corev1.Pod) *corev1.Container {
+	name := stset.OPIContainerName
+	if taskContainerName, ok := pod.Annotations[jobs.AnnotationOpiTaskContainerName]; ok {
+		name = taskContainerName
+	}
+
+	for c := range pod.Spec.Containers {
+		if pod.Spec.Containers[c].Name == name {
+			return &pod.Spec.Containers[c]
+		}
+	}
+
+	return nil
+}
+
+// setEnv appends envVars to env, dropping any variables with the same name
+// first. Appending keeps the injected variables after the ones they might
+// refer to using $(VAR_NAME)
+func setEnv(env []corev1.EnvVar, envVars []corev1.EnvVar) []corev1.EnvVar {
+	injected := map[string]bool{}
+	for _, envVar := range envVars {
+		injected[envVar.Name] = true
+	}
+
+	result := []corev1.EnvVar{}
+
+	for _, envVar := range env {
+		if !injected[envVar.Name] {
+			result = append(result, envVar)
+		}
+	}
+
+	return append(result, envVars...)
+}
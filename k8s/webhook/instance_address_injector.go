@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type instancePort struct {
+	External int32 `json:"external"`
+	Internal int32 `json:"internal"`
+}
+
+// NewInstanceAddressEnvInjector points CF_INSTANCE_ADDR at the pod IP instead
+// of 0.0.0.0 and lists the ports of the app container in CF_INSTANCE_PORTS.
+// Pods are reachable on their container ports, so these are both the external
+// and the internal ports
+func NewInstanceAddressEnvInjector(logger lager.Logger, injectIntoSidecars bool) EnvInjector {
+	return NewEnvInjector(logger, instanceAddressEnvVars, injectIntoSidecars)
+}
+
+func instanceAddressEnvVars(_ *corev1.Pod, app *corev1.Container) ([]corev1.EnvVar, error) {
+	ports := []instancePort{}
+	for _, port := range app.Ports {
+		ports = append(ports, instancePort{External: port.ContainerPort, Internal: port.ContainerPort})
+	}
+
+	portsJSON, err := json.Marshal(ports)
+	if err != nil {
+		return nil, exterrors.Wrap(err, "failed to marshal instance ports")
+	}
+
+	envVars := []corev1.EnvVar{
+		{
+			Name: eirini.EnvCFInstanceInternalIP,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.podIP",
+				},
+			},
+		},
+		{Name: eirini.EnvCFInstancePorts, Value: string(portsJSON)},
+	}
+
+	if len(ports) == 0 {
+		return envVars, nil
+	}
+
+	port := ports[0].Internal
+
+	return append(envVars,
+		corev1.EnvVar{Name: eirini.EnvCFInstanceAddr, Value: fmt.Sprintf("$(%s):%d", eirini.EnvCFInstanceInternalIP, port)},
+		corev1.EnvVar{Name: eirini.EnvCFInstancePort, Value: strconv.Itoa(int(port))},
+	), nil
+}
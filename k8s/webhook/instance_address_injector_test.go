@@ -0,0 +1,112 @@
+package webhook_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/k8s/webhook/webhookfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("InstanceAddressEnvInjector", func() {
+	var (
+		manager            *webhookfakes.FakeManager
+		pod                *corev1.Pod
+		injectIntoSidecars bool
+		podIPEnvVar        corev1.EnvVar
+	)
+
+	BeforeEach(func() {
+		manager = new(webhookfakes.FakeManager)
+		injectIntoSidecars = false
+
+		podIPEnvVar = corev1.EnvVar{
+			Name: "CF_INSTANCE_INTERNAL_IP",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+			},
+		}
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "some-app-instance-0",
+				Labels: map[string]string{"cloudfoundry.org/source_type": "APP"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "opi",
+						Env: []corev1.EnvVar{
+							{Name: "CF_INSTANCE_ADDR", Value: "0.0.0.0:8080"},
+							{Name: "CF_INSTANCE_PORT", Value: "8080"},
+							{Name: "FOO", Value: "foo"},
+							podIPEnvVar,
+						},
+						Ports: []corev1.ContainerPort{
+							{ContainerPort: 8080},
+							{ContainerPort: 9090},
+						},
+					},
+					{Name: "some-sidecar"},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		injector := webhook.NewInstanceAddressEnvInjector(lagertest.NewTestLogger("instance-address-injector"), injectIntoSidecars)
+		req := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{Operation: v1beta1.Create}}
+		injector.Handle(context.Background(), manager, pod, req)
+	})
+
+	It("points the instance address at the pod IP", func() {
+		Expect(manager.PatchFromPodCallCount()).To(Equal(1))
+		_, actualPod := manager.PatchFromPodArgsForCall(0)
+
+		Expect(actualPod.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+			{Name: "FOO", Value: "foo"},
+			podIPEnvVar,
+			{Name: "CF_INSTANCE_PORTS", Value: `[{"external":8080,"internal":8080},{"external":9090,"internal":9090}]`},
+			{Name: "CF_INSTANCE_ADDR", Value: "$(CF_INSTANCE_INTERNAL_IP):8080"},
+			{Name: "CF_INSTANCE_PORT", Value: "8080"},
+		}))
+		Expect(actualPod.Spec.Containers[1].Env).To(BeEmpty())
+	})
+
+	When("the app has no ports", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Ports = nil
+			pod.Spec.Containers[0].Env = nil
+		})
+
+		It("only sets an empty port list", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers[0].Env).To(ConsistOf(
+				podIPEnvVar,
+				corev1.EnvVar{Name: "CF_INSTANCE_PORTS", Value: "[]"},
+			))
+		})
+	})
+
+	When("injecting into sidecars is enabled", func() {
+		BeforeEach(func() {
+			injectIntoSidecars = true
+		})
+
+		It("gives the sidecars the pod IP the address refers to", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers[1].Env).To(Equal([]corev1.EnvVar{
+				podIPEnvVar,
+				{Name: "CF_INSTANCE_PORTS", Value: `[{"external":8080,"internal":8080},{"external":9090,"internal":9090}]`},
+				{Name: "CF_INSTANCE_ADDR", Value: "$(CF_INSTANCE_INTERNAL_IP):8080"},
+				{Name: "CF_INSTANCE_PORT", Value: "8080"},
+			}))
+		})
+	})
+})
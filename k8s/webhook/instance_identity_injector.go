@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	eirinix "code.cloudfoundry.org/eirinix"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//counterfeiter:generate . IdentityIssuer
+//counterfeiter:generate . SecretsClient
+
+type IdentityIssuer interface {
+	Issue(id identity.Identity) ([]byte, []byte, error)
+}
+
+type SecretsClient interface {
+	Get(namespace, name string) (*corev1.Secret, error)
+	Create(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+	Update(namespace string, secret *corev1.Secret) (*corev1.Secret, error)
+}
+
+// InstanceIdentityInjector issues the identity credentials of every new app
+// instance into a secret of its own and mounts them into the instance. The
+// secret is owned by the StatefulSet of the instance, since the pod does not
+// exist yet
+type InstanceIdentityInjector struct {
+	logger             lager.Logger
+	issuer             IdentityIssuer
+	secrets            SecretsClient
+	injectIntoSidecars bool
+}
+
+func NewInstanceIdentityInjector(logger lager.Logger, issuer IdentityIssuer, secrets SecretsClient, injectIntoSidecars bool) InstanceIdentityInjector {
+	return InstanceIdentityInjector{
+		logger:             logger,
+		issuer:             issuer,
+		secrets:            secrets,
+		injectIntoSidecars: injectIntoSidecars,
+	}
+}
+
+func (i InstanceIdentityInjector) Handle(ctx context.Context, eiriniManager eirinix.Manager, pod *corev1.Pod, req admission.Request) admission.Response {
+	return mutatePodOnCreate(i.logger, eiriniManager, pod, req, appSourceTypes, func(logger lager.Logger, pod *corev1.Pod) error {
+		return i.inject(logger, podNamespace(pod, req), pod)
+	})
+}
+
+// podNamespace is the namespace of a pod being created, which is only set in
+// the request when the pod does not specify it
+func podNamespace(pod *corev1.Pod, req admission.Request) string {
+	if pod.Namespace != "" {
+		return pod.Namespace
+	}
+
+	return req.Namespace
+}
+
+func (i InstanceIdentityInjector) inject(logger lager.Logger, namespace string, pod *corev1.Pod) error {
+	if pod.Name == "" {
+		return errors.New("pod has no name")
+	}
+
+	app := appContainer(pod)
+	if app == nil {
+		logger.Info("no-opi-container-found")
+
+		return errors.New("no opi container found in pod")
+	}
+
+	if err := i.createSecret(namespace, pod); err != nil {
+		return err
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, identityVolume(pod.Name))
+
+	envVars := []corev1.EnvVar{
+		{Name: eirini.EnvCFInstanceCert, Value: filepath.Join(identity.MountPath, identity.CertFileName)},
+		{Name: eirini.EnvCFInstanceKey, Value: filepath.Join(identity.MountPath, identity.KeyFileName)},
+	}
+
+	for _, container := range targetContainers(pod, app, i.injectIntoSidecars) {
+		logger.Debug("mounting-instance-identity", lager.Data{"container": container.Name})
+
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      identity.VolumeName,
+			MountPath: identity.MountPath,
+			ReadOnly:  true,
+		})
+		container.Env = setEnv(container.Env, envVars)
+	}
+
+	return nil
+}
+
+func (i InstanceIdentityInjector) createSecret(namespace string, pod *corev1.Pod) error {
+	cert, key, err := i.issuer.Issue(identity.FromPod(pod))
+	if err != nil {
+		return exterrors.Wrap(err, "failed to issue instance identity certificate")
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      identity.SecretName(pod.Name),
+			Namespace: namespace,
+			Labels: map[string]string{
+				identity.LabelPodName: pod.Name,
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+		},
+	}
+
+	return exterrors.Wrap(storeSecret(i.secrets, pod, secret), "failed to store instance identity secret")
+}
+
+// storeSecret creates or replaces a secret created for a pod, making it owned
+// by the owner of the pod
+func storeSecret(secrets SecretsClient, pod *corev1.Pod, secret *corev1.Secret) error {
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		ownerRef := *owner
+		ownerRef.Controller = nil
+		secret.OwnerReferences = []metav1.OwnerReference{ownerRef}
+	}
+
+	_, err := secrets.Create(secret.Namespace, secret)
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(secret.Namespace, secret)
+	}
+
+	return err
+}
+
+func identityVolume(podName string) corev1.Volume {
+	return corev1.Volume{
+		Name: identity.VolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{
+					{
+						Secret: &corev1.SecretProjection{
+							LocalObjectReference: corev1.LocalObjectReference{Name: identity.SecretName(podName)},
+							Items: []corev1.KeyToPath{
+								{Key: corev1.TLSCertKey, Path: identity.CertFileName},
+								{Key: corev1.TLSPrivateKeyKey, Path: identity.KeyFileName},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,194 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s/identity"
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/k8s/webhook/webhookfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("InstanceIdentityInjector", func() {
+	var (
+		manager            *webhookfakes.FakeManager
+		issuer             *webhookfakes.FakeIdentityIssuer
+		secrets            *webhookfakes.FakeSecretsClient
+		injectIntoSidecars bool
+		pod                *corev1.Pod
+		req                admission.Request
+		actualResp         admission.Response
+		isController       bool
+	)
+
+	BeforeEach(func() {
+		manager = new(webhookfakes.FakeManager)
+		manager.PatchFromPodReturns(admission.Allowed("patched"))
+		issuer = new(webhookfakes.FakeIdentityIssuer)
+		issuer.IssueReturns([]byte("cert"), []byte("key"), nil)
+		secrets = new(webhookfakes.FakeSecretsClient)
+		injectIntoSidecars = false
+		isController = true
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "some-app-instance-3",
+				Labels: map[string]string{"cloudfoundry.org/source_type": "APP"},
+				Annotations: map[string]string{
+					"cloudfoundry.org/application_id": "app-guid",
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "StatefulSet", Name: "some-app-instance", UID: "sts-uid", Controller: &isController},
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "opi", Env: []corev1.EnvVar{{Name: "FOO", Value: "foo"}}},
+					{Name: "some-sidecar"},
+				},
+			},
+		}
+
+		req = admission.Request{
+			AdmissionRequest: v1beta1.AdmissionRequest{
+				Operation: v1beta1.Create,
+				Namespace: "some-ns",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		injector := webhook.NewInstanceIdentityInjector(lagertest.NewTestLogger("instance-identity-injector"), issuer, secrets, injectIntoSidecars)
+		actualResp = injector.Handle(context.Background(), manager, pod, req)
+	})
+
+	It("issues a certificate for the instance", func() {
+		Expect(issuer.IssueCallCount()).To(Equal(1))
+		Expect(issuer.IssueArgsForCall(0)).To(Equal(identity.Identity{
+			InstanceID: "some-app-instance-3",
+			AppGUID:    "app-guid",
+		}))
+	})
+
+	It("stores the credentials in a secret owned by the statefulset", func() {
+		Expect(secrets.CreateCallCount()).To(Equal(1))
+		namespace, secret := secrets.CreateArgsForCall(0)
+		Expect(namespace).To(Equal("some-ns"))
+		Expect(secret.Name).To(Equal("some-app-instance-3-instance-identity"))
+		Expect(secret.Labels).To(HaveKeyWithValue(identity.LabelPodName, "some-app-instance-3"))
+		Expect(secret.Data).To(Equal(map[string][]byte{
+			"tls.crt": []byte("cert"),
+			"tls.key": []byte("key"),
+		}))
+		Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+			Kind: "StatefulSet", Name: "some-app-instance", UID: "sts-uid",
+		}))
+	})
+
+	It("mounts the credentials into the app container", func() {
+		Expect(actualResp.Allowed).To(BeTrue())
+		Expect(manager.PatchFromPodCallCount()).To(Equal(1))
+		_, actualPod := manager.PatchFromPodArgsForCall(0)
+
+		Expect(actualPod.Spec.Volumes).To(HaveLen(1))
+		volume := actualPod.Spec.Volumes[0]
+		Expect(volume.Name).To(Equal(identity.VolumeName))
+		Expect(volume.Projected.Sources).To(HaveLen(1))
+		Expect(volume.Projected.Sources[0].Secret.Name).To(Equal("some-app-instance-3-instance-identity"))
+		Expect(volume.Projected.Sources[0].Secret.Items).To(ConsistOf(
+			corev1.KeyToPath{Key: "tls.crt", Path: "instance.crt"},
+			corev1.KeyToPath{Key: "tls.key", Path: "instance.key"},
+		))
+
+		app := actualPod.Spec.Containers[0]
+		Expect(app.VolumeMounts).To(ConsistOf(corev1.VolumeMount{
+			Name: identity.VolumeName, MountPath: "/etc/cf-instance-credentials", ReadOnly: true,
+		}))
+		Expect(app.Env).To(Equal([]corev1.EnvVar{
+			{Name: "FOO", Value: "foo"},
+			{Name: "CF_INSTANCE_CERT", Value: "/etc/cf-instance-credentials/instance.crt"},
+			{Name: "CF_INSTANCE_KEY", Value: "/etc/cf-instance-credentials/instance.key"},
+		}))
+
+		Expect(actualPod.Spec.Containers[1].VolumeMounts).To(BeEmpty())
+		Expect(actualPod.Spec.Containers[1].Env).To(BeEmpty())
+	})
+
+	When("injecting into sidecars is enabled", func() {
+		BeforeEach(func() {
+			injectIntoSidecars = true
+		})
+
+		It("mounts the credentials into the sidecars too", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			sidecar := actualPod.Spec.Containers[1]
+			Expect(sidecar.VolumeMounts).To(HaveLen(1))
+			Expect(sidecar.Env).To(HaveLen(2))
+		})
+	})
+
+	When("the secret already exists", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, apierrors.NewAlreadyExists(schema.GroupResource{}, "some-app-instance-3-instance-identity"))
+		})
+
+		It("replaces it", func() {
+			Expect(actualResp.Allowed).To(BeTrue())
+			Expect(secrets.UpdateCallCount()).To(Equal(1))
+			_, secret := secrets.UpdateArgsForCall(0)
+			Expect(secret.Data).To(HaveKeyWithValue("tls.crt", []byte("cert")))
+		})
+	})
+
+	When("issuing the certificate fails", func() {
+		BeforeEach(func() {
+			issuer.IssueReturns(nil, nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to issue instance identity certificate")
+			Expect(secrets.CreateCallCount()).To(Equal(0))
+		})
+	})
+
+	When("storing the secret fails", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to store instance identity secret")
+		})
+	})
+
+	When("the pod has no OPI container", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Name = "ipo"
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "no opi container found in pod")
+			Expect(secrets.CreateCallCount()).To(Equal(0))
+		})
+	})
+
+	When("the pod is being updated", func() {
+		BeforeEach(func() {
+			req.Operation = v1beta1.Update
+		})
+
+		It("does not issue a certificate", func() {
+			ExpectAllowResponse(actualResp)
+			Expect(issuer.IssueCallCount()).To(Equal(0))
+		})
+	})
+})
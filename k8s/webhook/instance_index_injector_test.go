@@ -23,6 +23,7 @@ var _ = Describe("InstanceIndexInjector", func() {
 		injector                 eirinix.Extension
 		manager                  *webhookfakes.FakeManager
 		logger                   lager.Logger
+		injectIntoSidecars       bool
 		pod                      *corev1.Pod
 		req                      admission.Request
 		actualResp, expectedResp admission.Response
@@ -31,11 +32,12 @@ var _ = Describe("InstanceIndexInjector", func() {
 	BeforeEach(func() {
 		manager = new(webhookfakes.FakeManager)
 		logger = lagertest.NewTestLogger("instance-index-injector")
-		injector = webhook.NewInstanceIndexEnvInjector(logger)
+		injectIntoSidecars = false
 
 		pod = &corev1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
-				Name: "some-app-instance-3",
+				Name:   "some-app-instance-3",
+				Labels: map[string]string{"cloudfoundry.org/source_type": "APP"},
 			},
 			Spec: corev1.PodSpec{
 				Containers: []corev1.Container{
@@ -65,6 +67,7 @@ var _ = Describe("InstanceIndexInjector", func() {
 	})
 
 	JustBeforeEach(func() {
+		injector = webhook.NewInstanceIndexEnvInjector(logger, injectIntoSidecars)
 		actualResp = injector.Handle(context.Background(), manager, pod, req)
 	})
 
@@ -86,6 +89,55 @@ var _ = Describe("InstanceIndexInjector", func() {
 		}))
 	})
 
+	When("the env var is already set", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Env = append(pod.Spec.Containers[0].Env, corev1.EnvVar{Name: "CF_INSTANCE_INDEX", Value: "42"})
+		})
+
+		It("replaces it", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+				{Name: "FOO", Value: "foo"},
+				{Name: "BAR", Value: "bar"},
+				{Name: "CF_INSTANCE_INDEX", Value: "3"},
+			}))
+		})
+	})
+
+	When("the pod has sidecars", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "some-sidecar"})
+		})
+
+		It("does not inject the env var into the sidecars", func() {
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Containers[1].Env).To(BeEmpty())
+		})
+
+		When("injecting into sidecars is enabled", func() {
+			BeforeEach(func() {
+				injectIntoSidecars = true
+			})
+
+			It("injects the env var into the sidecars too", func() {
+				_, actualPod := manager.PatchFromPodArgsForCall(0)
+				Expect(actualPod.Spec.Containers[0].Env).To(ContainElement(corev1.EnvVar{Name: "CF_INSTANCE_INDEX", Value: "3"}))
+				Expect(actualPod.Spec.Containers[1].Env).To(ConsistOf(corev1.EnvVar{Name: "CF_INSTANCE_INDEX", Value: "3"}))
+			})
+		})
+	})
+
+	When("the pod is not an app instance", func() {
+		BeforeEach(func() {
+			pod.Labels["cloudfoundry.org/source_type"] = "TASK"
+		})
+
+		It("allows the operation without interacting with the passed pod", func() {
+			Expect(manager.PatchFromPodCallCount()).To(Equal(0))
+			Expect(actualResp.Allowed).To(BeTrue())
+		})
+	})
+
 	Context("the passed pod has already been created", func() {
 		When("operation is Update", func() {
 			BeforeEach(func() {
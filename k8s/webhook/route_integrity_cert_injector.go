@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/route/integrity"
+	eirinix "code.cloudfoundry.org/eirinix"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//counterfeiter:generate . InstanceCertIssuer
+
+type InstanceCertIssuer interface {
+	Issue(instanceID string) ([]byte, []byte, error)
+}
+
+// RouteIntegrityCertInjector issues the certificate the route integrity proxy
+// of every new app instance serves into a secret of its own, and points the
+// placeholder certificate volume of the instance at it. Instances without a
+// proxy are left alone
+type RouteIntegrityCertInjector struct {
+	logger  lager.Logger
+	issuer  InstanceCertIssuer
+	secrets SecretsClient
+}
+
+func NewRouteIntegrityCertInjector(logger lager.Logger, issuer InstanceCertIssuer, secrets SecretsClient) RouteIntegrityCertInjector {
+	return RouteIntegrityCertInjector{
+		logger:  logger,
+		issuer:  issuer,
+		secrets: secrets,
+	}
+}
+
+func (i RouteIntegrityCertInjector) Handle(ctx context.Context, eiriniManager eirinix.Manager, pod *corev1.Pod, req admission.Request) admission.Response {
+	return mutatePodOnCreate(i.logger, eiriniManager, pod, req, appSourceTypes, func(logger lager.Logger, pod *corev1.Pod) error {
+		return i.inject(logger, podNamespace(pod, req), pod)
+	})
+}
+
+func (i RouteIntegrityCertInjector) inject(logger lager.Logger, namespace string, pod *corev1.Pod) error {
+	volume := certVolume(pod)
+	if volume == nil {
+		logger.Debug("no-route-integrity-proxy")
+
+		return nil
+	}
+
+	if pod.Name == "" {
+		return errors.New("pod has no name")
+	}
+
+	if err := i.createSecret(namespace, pod); err != nil {
+		return err
+	}
+
+	volume.VolumeSource = corev1.VolumeSource{
+		Secret: &corev1.SecretVolumeSource{SecretName: integrity.SecretName(pod.Name)},
+	}
+
+	return nil
+}
+
+func (i RouteIntegrityCertInjector) createSecret(namespace string, pod *corev1.Pod) error {
+	cert, key, err := i.issuer.Issue(pod.Name)
+	if err != nil {
+		return exterrors.Wrap(err, "failed to issue route integrity certificate")
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      integrity.SecretName(pod.Name),
+			Namespace: namespace,
+			Labels: map[string]string{
+				integrity.LabelPodName: pod.Name,
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+		},
+	}
+
+	return exterrors.Wrap(storeSecret(i.secrets, pod, secret), "failed to store route integrity secret")
+}
+
+func certVolume(pod *corev1.Pod) *corev1.Volume {
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == integrity.InstanceCertVolumeName {
+			return &pod.Spec.Volumes[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/k8s/webhook/webhookfakes"
+	"code.cloudfoundry.org/eirini/route/integrity"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("RouteIntegrityCertInjector", func() {
+	var (
+		manager      *webhookfakes.FakeManager
+		issuer       *webhookfakes.FakeInstanceCertIssuer
+		secrets      *webhookfakes.FakeSecretsClient
+		pod          *corev1.Pod
+		req          admission.Request
+		actualResp   admission.Response
+		isController bool
+	)
+
+	BeforeEach(func() {
+		manager = new(webhookfakes.FakeManager)
+		manager.PatchFromPodReturns(admission.Allowed("patched"))
+		issuer = new(webhookfakes.FakeInstanceCertIssuer)
+		issuer.IssueReturns([]byte("cert"), []byte("key"), nil)
+		secrets = new(webhookfakes.FakeSecretsClient)
+		isController = true
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "some-app-instance-3",
+				Labels: map[string]string{"cloudfoundry.org/source_type": "APP"},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "StatefulSet", Name: "some-app-instance", UID: "sts-uid", Controller: &isController},
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "opi"},
+					{Name: integrity.ProxyContainerName},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: integrity.ConfigVolumeName,
+						VolumeSource: corev1.VolumeSource{
+							EmptyDir: &corev1.EmptyDirVolumeSource{},
+						},
+					},
+					{
+						Name: integrity.InstanceCertVolumeName,
+						VolumeSource: corev1.VolumeSource{
+							EmptyDir: &corev1.EmptyDirVolumeSource{},
+						},
+					},
+				},
+			},
+		}
+
+		req = admission.Request{
+			AdmissionRequest: v1beta1.AdmissionRequest{
+				Operation: v1beta1.Create,
+				Namespace: "some-ns",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		injector := webhook.NewRouteIntegrityCertInjector(lagertest.NewTestLogger("route-integrity-cert-injector"), issuer, secrets)
+		actualResp = injector.Handle(context.Background(), manager, pod, req)
+	})
+
+	It("issues a certificate for the instance", func() {
+		Expect(issuer.IssueCallCount()).To(Equal(1))
+		Expect(issuer.IssueArgsForCall(0)).To(Equal("some-app-instance-3"))
+	})
+
+	It("stores the certificate in a secret owned by the statefulset", func() {
+		Expect(secrets.CreateCallCount()).To(Equal(1))
+		namespace, secret := secrets.CreateArgsForCall(0)
+		Expect(namespace).To(Equal("some-ns"))
+		Expect(secret.Name).To(Equal("some-app-instance-3-route-integrity"))
+		Expect(secret.Labels).To(HaveKeyWithValue(integrity.LabelPodName, "some-app-instance-3"))
+		Expect(secret.Type).To(Equal(corev1.SecretTypeTLS))
+		Expect(secret.Data).To(Equal(map[string][]byte{
+			"tls.crt": []byte("cert"),
+			"tls.key": []byte("key"),
+		}))
+		Expect(secret.OwnerReferences).To(ConsistOf(metav1.OwnerReference{
+			Kind: "StatefulSet", Name: "some-app-instance", UID: "sts-uid",
+		}))
+	})
+
+	It("points the certificate volume at the secret", func() {
+		Expect(actualResp.Allowed).To(BeTrue())
+		Expect(manager.PatchFromPodCallCount()).To(Equal(1))
+		_, actualPod := manager.PatchFromPodArgsForCall(0)
+
+		Expect(actualPod.Spec.Volumes).To(ConsistOf(
+			corev1.Volume{
+				Name: integrity.ConfigVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+			corev1.Volume{
+				Name: integrity.InstanceCertVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "some-app-instance-3-route-integrity"},
+				},
+			},
+		))
+	})
+
+	When("the secret already exists", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, apierrors.NewAlreadyExists(schema.GroupResource{}, "some-app-instance-3-route-integrity"))
+		})
+
+		It("replaces it", func() {
+			Expect(actualResp.Allowed).To(BeTrue())
+			Expect(secrets.UpdateCallCount()).To(Equal(1))
+			_, secret := secrets.UpdateArgsForCall(0)
+			Expect(secret.Data).To(HaveKeyWithValue("tls.crt", []byte("cert")))
+		})
+	})
+
+	When("the pod has no route integrity proxy", func() {
+		BeforeEach(func() {
+			pod.Spec.Volumes = nil
+		})
+
+		It("leaves the pod alone", func() {
+			Expect(actualResp.Allowed).To(BeTrue())
+			Expect(issuer.IssueCallCount()).To(Equal(0))
+			Expect(secrets.CreateCallCount()).To(Equal(0))
+			_, actualPod := manager.PatchFromPodArgsForCall(0)
+			Expect(actualPod.Spec.Volumes).To(BeEmpty())
+		})
+	})
+
+	When("issuing the certificate fails", func() {
+		BeforeEach(func() {
+			issuer.IssueReturns(nil, nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to issue route integrity certificate")
+			Expect(secrets.CreateCallCount()).To(Equal(0))
+		})
+	})
+
+	When("storing the secret fails", func() {
+		BeforeEach(func() {
+			secrets.CreateReturns(nil, errors.New("boom"))
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to store route integrity secret")
+		})
+	})
+
+	When("the pod is being updated", func() {
+		BeforeEach(func() {
+			req.Operation = v1beta1.Update
+		})
+
+		It("does not issue a certificate", func() {
+			ExpectAllowResponse(actualResp)
+			Expect(issuer.IssueCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/k8s/stset"
+	"code.cloudfoundry.org/eirini/models/cf"
+	"code.cloudfoundry.org/eirini/util"
+	"code.cloudfoundry.org/lager"
+	exterrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const startedAtLayout = "2006-01-02 15:04:05 -0700"
+
+// NewVCAPApplicationEnvInjector completes the VCAP_APPLICATION sent by Cloud
+// Controller with the details of the instance, as Diego does. Fields that
+// Cloud Controller did not set are filled in from the pod annotations
+func NewVCAPApplicationEnvInjector(logger lager.Logger, injectIntoSidecars bool) EnvInjector {
+	return NewEnvInjector(logger, vcapApplicationEnvVars, injectIntoSidecars)
+}
+
+func vcapApplicationEnvVars(pod *corev1.Pod, app *corev1.Container) ([]corev1.EnvVar, error) {
+	index, err := util.ParseAppIndex(pod.Name)
+	if err != nil {
+		return nil, exterrors.Wrap(err, "failed to parse app index")
+	}
+
+	vcapApplication, err := parseVCAPApplication(app)
+	if err != nil {
+		return nil, err
+	}
+
+	annotationFields := map[string]string{
+		"application_id":      stset.AnnotationAppID,
+		"application_name":    stset.AnnotationAppName,
+		"name":                stset.AnnotationAppName,
+		"application_version": stset.AnnotationVersion,
+		"space_id":            stset.AnnotationSpaceGUID,
+		"space_name":          stset.AnnotationSpaceName,
+		"organization_id":     stset.AnnotationOrgGUID,
+		"organization_name":   stset.AnnotationOrgName,
+	}
+
+	for field, annotation := range annotationFields {
+		if _, ok := vcapApplication[field]; !ok && pod.Annotations[annotation] != "" {
+			vcapApplication[field] = pod.Annotations[annotation]
+		}
+	}
+
+	if routes, ok := pod.Annotations[stset.AnnotationRegisteredRoutes]; ok {
+		uris, uriErr := appURIs(routes)
+		if uriErr != nil {
+			return nil, uriErr
+		}
+
+		vcapApplication["application_uris"] = uris
+		vcapApplication["uris"] = uris
+	}
+
+	startedAt := time.Now().UTC()
+	vcapApplication["instance_index"] = index
+	vcapApplication["instance_id"] = pod.Name
+	vcapApplication["host"] = "0.0.0.0"
+	vcapApplication["started_at"] = startedAt.Format(startedAtLayout)
+	vcapApplication["started_at_timestamp"] = startedAt.Unix()
+
+	vcapApplicationJSON, err := json.Marshal(vcapApplication)
+	if err != nil {
+		return nil, exterrors.Wrap(err, "failed to marshal VCAP_APPLICATION")
+	}
+
+	return []corev1.EnvVar{
+		{Name: eirini.EnvVCAPApplication, Value: string(vcapApplicationJSON)},
+	}, nil
+}
+
+func parseVCAPApplication(app *corev1.Container) (map[string]interface{}, error) {
+	vcapApplication := map[string]interface{}{}
+
+	for _, envVar := range app.Env {
+		if envVar.Name != eirini.EnvVCAPApplication || envVar.Value == "" {
+			continue
+		}
+
+		if err := json.Unmarshal([]byte(envVar.Value), &vcapApplication); err != nil {
+			return nil, exterrors.Wrap(err, "failed to parse VCAP_APPLICATION")
+		}
+	}
+
+	return vcapApplication, nil
+}
+
+func appURIs(routesJSON string) ([]string, error) {
+	routes := []cf.Route{}
+	if err := json.Unmarshal([]byte(routesJSON), &routes); err != nil {
+		return nil, exterrors.Wrap(err, "failed to unmarshal routes")
+	}
+
+	uris := []string{}
+	seen := map[string]bool{}
+
+	for _, route := range routes {
+		if !seen[route.Hostname] {
+			seen[route.Hostname] = true
+			uris = append(uris, route.Hostname)
+		}
+	}
+
+	return uris, nil
+}
@@ -0,0 +1,157 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/eirini/k8s/webhook"
+	"code.cloudfoundry.org/eirini/k8s/webhook/webhookfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("VCAPApplicationEnvInjector", func() {
+	var (
+		manager    *webhookfakes.FakeManager
+		pod        *corev1.Pod
+		actualResp admission.Response
+	)
+
+	BeforeEach(func() {
+		manager = new(webhookfakes.FakeManager)
+		manager.PatchFromPodReturns(admission.Allowed("patched"))
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "some-app-instance-3",
+				Labels: map[string]string{"cloudfoundry.org/source_type": "APP"},
+				Annotations: map[string]string{
+					"cloudfoundry.org/application_id":   "app-guid",
+					"cloudfoundry.org/application_name": "annotated-app-name",
+					"cloudfoundry.org/space_name":       "space-name",
+					"cloudfoundry.org/routes":           `[{"hostname":"foo.example.com","port":8080},{"hostname":"bar.example.com","port":9090},{"hostname":"foo.example.com","port":9090}]`,
+				},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "opi",
+						Env: []corev1.EnvVar{
+							{Name: "VCAP_APPLICATION", Value: `{"application_name":"app-name","limits":{"mem":256}}`},
+							{Name: "FOO", Value: "foo"},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		injector := webhook.NewVCAPApplicationEnvInjector(lagertest.NewTestLogger("vcap-application-injector"), false)
+		req := admission.Request{AdmissionRequest: v1beta1.AdmissionRequest{Operation: v1beta1.Create}}
+		actualResp = injector.Handle(context.Background(), manager, pod, req)
+	})
+
+	getVCAPApplication := func() map[string]interface{} {
+		Expect(manager.PatchFromPodCallCount()).To(Equal(1))
+		_, actualPod := manager.PatchFromPodArgsForCall(0)
+
+		env := actualPod.Spec.Containers[0].Env
+		Expect(env).To(HaveLen(2))
+		Expect(env[0]).To(Equal(corev1.EnvVar{Name: "FOO", Value: "foo"}))
+		Expect(env[1].Name).To(Equal("VCAP_APPLICATION"))
+
+		vcapApplication := map[string]interface{}{}
+		Expect(json.Unmarshal([]byte(env[1].Value), &vcapApplication)).To(Succeed())
+
+		return vcapApplication
+	}
+
+	It("adds the instance details to VCAP_APPLICATION", func() {
+		Expect(actualResp.Allowed).To(BeTrue())
+
+		vcapApplication := getVCAPApplication()
+		Expect(vcapApplication).To(HaveKeyWithValue("instance_index", BeNumerically("==", 3)))
+		Expect(vcapApplication).To(HaveKeyWithValue("instance_id", "some-app-instance-3"))
+		Expect(vcapApplication).To(HaveKeyWithValue("host", "0.0.0.0"))
+		Expect(vcapApplication).To(HaveKeyWithValue("application_uris", ConsistOf("foo.example.com", "bar.example.com")))
+		Expect(vcapApplication).To(HaveKeyWithValue("uris", ConsistOf("foo.example.com", "bar.example.com")))
+
+		startedAt, err := time.Parse("2006-01-02 15:04:05 -0700", vcapApplication["started_at"].(string))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(startedAt).To(BeTemporally("~", time.Now(), time.Minute))
+		Expect(vcapApplication).To(HaveKeyWithValue("started_at_timestamp", BeNumerically("==", startedAt.Unix())))
+	})
+
+	It("keeps the fields set by Cloud Controller", func() {
+		vcapApplication := getVCAPApplication()
+		Expect(vcapApplication).To(HaveKeyWithValue("application_name", "app-name"))
+		Expect(vcapApplication).To(HaveKeyWithValue("limits", HaveKeyWithValue("mem", BeNumerically("==", 256))))
+	})
+
+	It("fills in the missing fields from the pod annotations", func() {
+		vcapApplication := getVCAPApplication()
+		Expect(vcapApplication).To(HaveKeyWithValue("application_id", "app-guid"))
+		Expect(vcapApplication).To(HaveKeyWithValue("name", "annotated-app-name"))
+		Expect(vcapApplication).To(HaveKeyWithValue("space_name", "space-name"))
+		Expect(vcapApplication).NotTo(HaveKey("organization_name"))
+	})
+
+	When("the pod has no routes annotation", func() {
+		BeforeEach(func() {
+			delete(pod.Annotations, "cloudfoundry.org/routes")
+		})
+
+		It("does not set the app uris", func() {
+			Expect(getVCAPApplication()).NotTo(HaveKey("application_uris"))
+		})
+	})
+
+	When("VCAP_APPLICATION is not set", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "FOO", Value: "foo"}}
+		})
+
+		It("creates it", func() {
+			vcapApplication := getVCAPApplication()
+			Expect(vcapApplication).To(HaveKeyWithValue("application_name", "annotated-app-name"))
+			Expect(vcapApplication).To(HaveKeyWithValue("instance_id", "some-app-instance-3"))
+		})
+	})
+
+	When("VCAP_APPLICATION is not valid JSON", func() {
+		BeforeEach(func() {
+			pod.Spec.Containers[0].Env[0].Value = "{"
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to parse VCAP_APPLICATION")
+		})
+	})
+
+	When("the routes annotation is not valid JSON", func() {
+		BeforeEach(func() {
+			pod.Annotations["cloudfoundry.org/routes"] = "["
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "failed to unmarshal routes")
+		})
+	})
+
+	When("the pod name does not contain an index", func() {
+		BeforeEach(func() {
+			pod.Name = "my-instance-four"
+		})
+
+		It("returns an error response", func() {
+			ExpectBadRequestErrorResponse(actualResp, "pod my-instance-four name does not contain an index")
+		})
+	})
+})
@@ -0,0 +1,59 @@
+package logs
+
+import (
+	loggregator "code.cloudfoundry.org/go-loggregator"
+)
+
+const (
+	SourceTypeProc    = "APP/PROC"
+	SourceTypeTask    = "APP/TASK"
+	SourceTypeStaging = "STG"
+	SourceTypeCell    = "CELL"
+)
+
+//counterfeiter:generate . LoggregatorClient
+//counterfeiter:generate . Emitter
+
+type LoggregatorClient interface {
+	EmitLog(message string, opts ...loggregator.EmitLogOption)
+}
+
+type Emitter interface {
+	Emit(Line)
+}
+
+// Line is a single line in the logs of an app. SourceID is the app GUID,
+// SourceType tells apart the app output (e.g. APP/PROC/WEB) from the lines
+// Eirini itself adds (e.g. STG) and InstanceID is the instance index, 0 for
+// tasks and staging, or empty for lines about the app as a whole.
+type Line struct {
+	SourceID   string
+	SourceType string
+	InstanceID string
+	Message    string
+}
+
+type LoggregatorEmitter struct {
+	client LoggregatorClient
+}
+
+func NewLoggregatorEmitter(client LoggregatorClient) *LoggregatorEmitter {
+	return &LoggregatorEmitter{
+		client: client,
+	}
+}
+
+// Emit sends the line as stdout, because the Kubernetes log API does not tell
+// apart the stdout and stderr of a container.
+func (e *LoggregatorEmitter) Emit(l Line) {
+	e.client.EmitLog(
+		l.Message,
+		loggregator.WithSourceInfo(l.SourceID, l.SourceType, l.InstanceID),
+		loggregator.WithStdout(),
+	)
+}
+
+// DiscardEmitter drops all lines, for when no Loggregator is configured
+type DiscardEmitter struct{}
+
+func (DiscardEmitter) Emit(Line) {}
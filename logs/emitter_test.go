@@ -0,0 +1,40 @@
+package logs_test
+
+import (
+	"code.cloudfoundry.org/eirini/logs"
+	"code.cloudfoundry.org/eirini/logs/logsfakes"
+	"code.cloudfoundry.org/go-loggregator/rpc/loggregator_v2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoggregatorEmitter", func() {
+	It("should send the line with its source info as stdout", func() {
+		fakeClient := new(logsfakes.FakeLoggregatorClient)
+		emitter := logs.NewLoggregatorEmitter(fakeClient)
+
+		emitter.Emit(logs.Line{
+			SourceID:   "app-guid",
+			SourceType: "APP/PROC/WEB",
+			InstanceID: "2",
+			Message:    "hello there",
+		})
+		Expect(fakeClient.EmitLogCallCount()).To(Equal(1))
+
+		message, opts := fakeClient.EmitLogArgsForCall(0)
+		Expect(message).To(Equal("hello there"))
+
+		envelope := &loggregator_v2.Envelope{
+			Message: &loggregator_v2.Envelope_Log{Log: &loggregator_v2.Log{}},
+			Tags:    map[string]string{},
+		}
+		for _, o := range opts {
+			o(envelope)
+		}
+
+		Expect(envelope.SourceId).To(Equal("app-guid"))
+		Expect(envelope.InstanceId).To(Equal("2"))
+		Expect(envelope.Tags).To(HaveKeyWithValue("source_type", "APP/PROC/WEB"))
+		Expect(envelope.GetLog().Type).To(Equal(loggregator_v2.Log_OUT))
+	})
+})
@@ -0,0 +1,127 @@
+package logs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitNoticeInterval makes sure the app is told about dropped lines
+// without the notices themselves flooding its logs
+const rateLimitNoticeInterval = time.Second
+
+// RateLimitedEmitter drops the lines of an app once it has emitted more than
+// linesPerSecond lines per second on average, so that a single noisy app
+// cannot starve the others. Dropped lines are reported to the app with a
+// notice line. The limiters of apps which have not emitted anything for as
+// long as their bucket takes to refill are evicted, as a new one would behave
+// the same.
+type RateLimitedEmitter struct {
+	emitter        Emitter
+	linesPerSecond int
+	burst          int
+	idleTimeout    time.Duration
+	lock           sync.Mutex
+	limiters       map[string]*appLimiter
+	lastEviction   time.Time
+}
+
+type appLimiter struct {
+	limiter    *rate.Limiter
+	lastNotice time.Time
+	lastSeen   time.Time
+}
+
+func NewRateLimitedEmitter(emitter Emitter, linesPerSecond, burst int) *RateLimitedEmitter {
+	if burst < linesPerSecond {
+		burst = linesPerSecond
+	}
+
+	idleTimeout := rateLimitNoticeInterval
+	if linesPerSecond > 0 {
+		idleTimeout = time.Duration(burst) * time.Second / time.Duration(linesPerSecond)
+	}
+
+	return &RateLimitedEmitter{
+		emitter:        emitter,
+		linesPerSecond: linesPerSecond,
+		burst:          burst,
+		idleTimeout:    idleTimeout,
+		limiters:       map[string]*appLimiter{},
+		lastEviction:   time.Now(),
+	}
+}
+
+func (e *RateLimitedEmitter) Emit(l Line) {
+	allowed, notify := e.allow(l.SourceID)
+
+	if allowed {
+		e.emitter.Emit(l)
+
+		return
+	}
+
+	if notify {
+		e.emitter.Emit(Line{
+			SourceID:   l.SourceID,
+			SourceType: l.SourceType,
+			InstanceID: l.InstanceID,
+			Message:    fmt.Sprintf("app exceeded log rate limit (%d log-lines/sec across all of its instances) set by platform operator", e.linesPerSecond),
+		})
+	}
+}
+
+func (e *RateLimitedEmitter) allow(sourceID string) (bool, bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	now := time.Now()
+	e.evictIdle(now)
+
+	limiter, ok := e.limiters[sourceID]
+	if !ok {
+		limiter = &appLimiter{limiter: rate.NewLimiter(rate.Limit(e.linesPerSecond), e.burst)}
+		e.limiters[sourceID] = limiter
+	}
+
+	limiter.lastSeen = now
+
+	if limiter.limiter.AllowN(now, 1) {
+		return true, false
+	}
+
+	if now.Sub(limiter.lastNotice) < rateLimitNoticeInterval {
+		return false, false
+	}
+
+	limiter.lastNotice = now
+
+	return false, true
+}
+
+// Sources returns the number of apps a limiter is kept for
+func (e *RateLimitedEmitter) Sources() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return len(e.limiters)
+}
+
+// evictIdle drops the limiters of idle apps at most once per idle timeout,
+// so that the map does not grow with every app that has ever logged. It must
+// be called with the lock held
+func (e *RateLimitedEmitter) evictIdle(now time.Time) {
+	if now.Sub(e.lastEviction) < e.idleTimeout {
+		return
+	}
+
+	for sourceID, limiter := range e.limiters {
+		if now.Sub(limiter.lastSeen) >= e.idleTimeout {
+			delete(e.limiters, sourceID)
+		}
+	}
+
+	e.lastEviction = now
+}
@@ -0,0 +1,97 @@
+package logs_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/eirini/logs"
+	"code.cloudfoundry.org/eirini/logs/logsfakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimitedEmitter", func() {
+	var (
+		fakeEmitter *logsfakes.FakeEmitter
+		emitter     *logs.RateLimitedEmitter
+	)
+
+	line := func(sourceID string) logs.Line {
+		return logs.Line{SourceID: sourceID, SourceType: "APP/PROC/WEB", InstanceID: "0", Message: "line"}
+	}
+
+	BeforeEach(func() {
+		fakeEmitter = new(logsfakes.FakeEmitter)
+		emitter = logs.NewRateLimitedEmitter(fakeEmitter, 3, 0)
+	})
+
+	It("should forward lines within the limit", func() {
+		for i := 0; i < 3; i++ {
+			emitter.Emit(line("app"))
+		}
+
+		Expect(fakeEmitter.EmitCallCount()).To(Equal(3))
+	})
+
+	When("an app exceeds the limit", func() {
+		BeforeEach(func() {
+			for i := 0; i < 10; i++ {
+				emitter.Emit(line("app"))
+			}
+		})
+
+		It("should drop its lines and notify the app once", func() {
+			Expect(fakeEmitter.EmitCallCount()).To(Equal(4))
+
+			notice := fakeEmitter.EmitArgsForCall(3)
+			Expect(notice.SourceID).To(Equal("app"))
+			Expect(notice.SourceType).To(Equal("APP/PROC/WEB"))
+			Expect(notice.Message).To(HavePrefix("app exceeded log rate limit (3 log-lines/sec across all of its instances)"))
+		})
+
+		It("should not limit other apps", func() {
+			emitter.Emit(line("another-app"))
+
+			Expect(fakeEmitter.EmitCallCount()).To(Equal(5))
+			Expect(fakeEmitter.EmitArgsForCall(4)).To(Equal(line("another-app")))
+		})
+	})
+
+	When("a burst is configured", func() {
+		BeforeEach(func() {
+			emitter = logs.NewRateLimitedEmitter(fakeEmitter, 3, 5)
+		})
+
+		It("should allow the burst", func() {
+			for i := 0; i < 5; i++ {
+				emitter.Emit(line("app"))
+			}
+
+			Expect(fakeEmitter.EmitCallCount()).To(Equal(5))
+		})
+	})
+
+	When("an app has been idle for as long as its limiter takes to refill", func() {
+		BeforeEach(func() {
+			emitter = logs.NewRateLimitedEmitter(fakeEmitter, 10, 2)
+			for i := 0; i < 10; i++ {
+				emitter.Emit(line("app"))
+			}
+			Expect(emitter.Sources()).To(Equal(1))
+
+			time.Sleep(time.Second)
+			emitter.Emit(line("another-app"))
+		})
+
+		It("should evict its limiter", func() {
+			Expect(emitter.Sources()).To(Equal(1))
+		})
+
+		It("should let the app log again", func() {
+			calls := fakeEmitter.EmitCallCount()
+			emitter.Emit(line("app"))
+
+			Expect(fakeEmitter.EmitCallCount()).To(Equal(calls + 1))
+			Expect(fakeEmitter.EmitArgsForCall(calls)).To(Equal(line("app")))
+		})
+	})
+})
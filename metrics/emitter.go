@@ -5,29 +5,81 @@ import (
 )
 
 const (
-	CPUUnit    = "percentage"
-	MemoryUnit = "bytes"
-	DiskUnit   = "bytes"
+	CPUUnit         = "percentage"
+	MemoryUnit      = "bytes"
+	DiskUnit        = "bytes"
+	EntitlementUnit = "percentage"
+	StalenessUnit   = "seconds"
+	CountUnit       = "count"
+
+	SidecarTag  = "sidecar"
+	TaskGUIDTag = "task_guid"
+	NodeTag     = "node"
+
+	NodeScrapeSourceID = "metrics-collector"
 )
 
 //counterfeiter:generate . LoggregatorClient
+//counterfeiter:generate . Emitter
+//counterfeiter:generate . NodeScrapeEmitter
 
 type LoggregatorClient interface {
 	EmitGauge(...loggregator.EmitGaugeOption)
 }
 
+type Emitter interface {
+	Emit(Message)
+}
+
+// NodeScrapeEmitter reports how up to date the disk metrics of the nodes are
+type NodeScrapeEmitter interface {
+	EmitNodeScrape(NodeScrapeMessage)
+}
+
 type LoggregatorEmitter struct {
 	client LoggregatorClient
 }
 
+// Message holds the metrics of a single app instance or task. CPU, Memory and
+// Disk refer to the app container only, while the entitlements are the usage
+// of the whole pod as a percentage of the summed requests of all its
+// containers. For tasks, AppID is the app GUID, IndexID and TaskGUID are the
+// task GUID and MemoryPeak is the highest memory usage seen so far; once the
+// task has completed a final message with TaskCompleted set is emitted.
 type Message struct {
-	AppID       string
-	IndexID     string
+	AppID             string
+	IndexID           string
+	AppGUID           string
+	OrgGUID           string
+	SpaceGUID         string
+	CPU               float64
+	CPUEntitlement    float64
+	Memory            float64
+	MemoryEntitlement float64
+	MemoryQuota       float64
+	Disk              float64
+	DiskQuota         float64
+	TaskGUID          string
+	MemoryPeak        float64
+	TaskCompleted     bool
+	Sidecars          []SidecarMessage
+}
+
+type SidecarMessage struct {
+	Name        string
 	CPU         float64
 	Memory      float64
 	MemoryQuota float64
 	Disk        float64
-	DiskQuota   float64
+}
+
+// NodeScrapeMessage holds the seconds since the kubelet of a node was last
+// scraped successfully, or since it was first scraped when it never was, and
+// the number of failed scrapes
+type NodeScrapeMessage struct {
+	Node      string
+	Staleness float64
+	Failures  float64
 }
 
 func NewLoggregatorEmitter(client LoggregatorClient) *LoggregatorEmitter {
@@ -37,12 +89,73 @@ func NewLoggregatorEmitter(client LoggregatorClient) *LoggregatorEmitter {
 }
 
 func (e *LoggregatorEmitter) Emit(m Message) {
-	e.client.EmitGauge(
+	options := []loggregator.EmitGaugeOption{
 		loggregator.WithGaugeSourceInfo(m.AppID, m.IndexID),
 		loggregator.WithGaugeValue("cpu", m.CPU, CPUUnit),
+		loggregator.WithGaugeValue("cpu_entitlement", m.CPUEntitlement, EntitlementUnit),
 		loggregator.WithGaugeValue("memory", m.Memory, MemoryUnit),
+		loggregator.WithGaugeValue("memory_entitlement", m.MemoryEntitlement, EntitlementUnit),
 		loggregator.WithGaugeValue("memory_quota", m.MemoryQuota, MemoryUnit),
 		loggregator.WithGaugeValue("disk", m.Disk, DiskUnit),
 		loggregator.WithGaugeValue("disk_quota", m.DiskQuota, DiskUnit),
+	}
+
+	if m.TaskGUID != "" {
+		options = append(options,
+			loggregator.WithEnvelopeTag(TaskGUIDTag, m.TaskGUID),
+			loggregator.WithGaugeValue("memory_peak", m.MemoryPeak, MemoryUnit),
+		)
+	}
+
+	e.client.EmitGauge(options...)
+
+	// Log cache and cf app tell gauges apart by name only, so the sidecar
+	// gauges must not reuse the names of the app ones
+	for _, sidecar := range m.Sidecars {
+		e.client.EmitGauge(
+			loggregator.WithGaugeSourceInfo(m.AppID, m.IndexID),
+			loggregator.WithEnvelopeTag(SidecarTag, sidecar.Name),
+			loggregator.WithGaugeValue("sidecar_cpu", sidecar.CPU, CPUUnit),
+			loggregator.WithGaugeValue("sidecar_memory", sidecar.Memory, MemoryUnit),
+			loggregator.WithGaugeValue("sidecar_memory_quota", sidecar.MemoryQuota, MemoryUnit),
+			loggregator.WithGaugeValue("sidecar_disk", sidecar.Disk, DiskUnit),
+		)
+	}
+}
+
+// EmitNodeScrape reports the scrapes of a node with the metrics collector as
+// source and the node as instance
+func (e *LoggregatorEmitter) EmitNodeScrape(m NodeScrapeMessage) {
+	e.client.EmitGauge(
+		loggregator.WithGaugeSourceInfo(NodeScrapeSourceID, m.Node),
+		loggregator.WithEnvelopeTag(NodeTag, m.Node),
+		loggregator.WithGaugeValue("kubelet_scrape_staleness", m.Staleness, StalenessUnit),
+		loggregator.WithGaugeValue("kubelet_scrape_failures", m.Failures, CountUnit),
 	)
 }
+
+type MultiEmitter struct {
+	emitters []Emitter
+}
+
+func NewMultiEmitter(emitters ...Emitter) *MultiEmitter {
+	return &MultiEmitter{
+		emitters: emitters,
+	}
+}
+
+func (e *MultiEmitter) Emit(m Message) {
+	for _, emitter := range e.emitters {
+		emitter.Emit(m)
+	}
+}
+
+// EmitNodeScrape forwards the message to the emitters which report node
+// scrapes. Prometheus collects them from the disk metrics client instead
+func (e *MultiEmitter) EmitNodeScrape(m NodeScrapeMessage) {
+	for _, emitter := range e.emitters {
+		if scrapeEmitter, ok := emitter.(NodeScrapeEmitter); ok {
+			scrapeEmitter.EmitNodeScrape(m)
+		}
+	}
+}
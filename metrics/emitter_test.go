@@ -18,13 +18,15 @@ var _ = Describe("emitter", func() {
 		envelope := newEnvelope()
 
 		msg := metrics.Message{
-			AppID:       "amazing-app-id",
-			IndexID:     "best-index-id",
-			CPU:         100,
-			Memory:      320,
-			MemoryQuota: 500,
-			Disk:        645,
-			DiskQuota:   1001,
+			AppID:             "amazing-app-id",
+			IndexID:           "best-index-id",
+			CPU:               100,
+			CPUEntitlement:    50,
+			Memory:            320,
+			MemoryEntitlement: 25,
+			MemoryQuota:       500,
+			Disk:              645,
+			DiskQuota:         1001,
 		}
 		emitter.Emit(msg)
 		Expect(fakeClient.EmitGaugeCallCount()).To(Equal(1))
@@ -41,10 +43,18 @@ var _ = Describe("emitter", func() {
 				Unit:  metrics.CPUUnit,
 				Value: 100,
 			},
+			"cpu_entitlement": {
+				Unit:  metrics.EntitlementUnit,
+				Value: 50,
+			},
 			"memory": {
 				Unit:  metrics.MemoryUnit,
 				Value: 320,
 			},
+			"memory_entitlement": {
+				Unit:  metrics.EntitlementUnit,
+				Value: 25,
+			},
 			"memory_quota": {
 				Unit:  metrics.MemoryUnit,
 				Value: 500,
@@ -61,6 +71,84 @@ var _ = Describe("emitter", func() {
 
 		Expect(envelope.GetGauge().Metrics).To(Equal(expectedMetrics))
 	})
+
+	It("should emit the node scrapes with the node as instance", func() {
+		fakeClient := new(metricsfakes.FakeLoggregatorClient)
+		emitter := metrics.NewLoggregatorEmitter(fakeClient)
+
+		emitter.EmitNodeScrape(metrics.NodeScrapeMessage{Node: "node1", Staleness: 12, Failures: 3})
+		Expect(fakeClient.EmitGaugeCallCount()).To(Equal(1))
+
+		envelope := newEnvelope()
+		for _, g := range fakeClient.EmitGaugeArgsForCall(0) {
+			g(envelope)
+		}
+
+		Expect(envelope.SourceId).To(Equal(metrics.NodeScrapeSourceID))
+		Expect(envelope.InstanceId).To(Equal("node1"))
+		Expect(envelope.Tags).To(HaveKeyWithValue(metrics.NodeTag, "node1"))
+		Expect(envelope.GetGauge().Metrics).To(Equal(map[string]*loggregator_v2.GaugeValue{
+			"kubelet_scrape_staleness": {Unit: metrics.StalenessUnit, Value: 12},
+			"kubelet_scrape_failures":  {Unit: metrics.CountUnit, Value: 3},
+		}))
+	})
+
+	It("should emit a separate gauge for each sidecar", func() {
+		fakeClient := new(metricsfakes.FakeLoggregatorClient)
+		emitter := metrics.NewLoggregatorEmitter(fakeClient)
+
+		emitter.Emit(metrics.Message{
+			AppID:   "amazing-app-id",
+			IndexID: "best-index-id",
+			Sidecars: []metrics.SidecarMessage{{
+				Name:        "the-sidecar",
+				CPU:         10,
+				Memory:      20,
+				MemoryQuota: 30,
+				Disk:        40,
+			}},
+		})
+		Expect(fakeClient.EmitGaugeCallCount()).To(Equal(2))
+
+		envelope := newEnvelope()
+		for _, g := range fakeClient.EmitGaugeArgsForCall(1) {
+			g(envelope)
+		}
+
+		Expect(envelope.SourceId).To(Equal("amazing-app-id"))
+		Expect(envelope.InstanceId).To(Equal("best-index-id"))
+		Expect(envelope.Tags).To(HaveKeyWithValue(metrics.SidecarTag, "the-sidecar"))
+		Expect(envelope.GetGauge().Metrics).To(Equal(map[string]*loggregator_v2.GaugeValue{
+			"sidecar_cpu":          {Unit: metrics.CPUUnit, Value: 10},
+			"sidecar_memory":       {Unit: metrics.MemoryUnit, Value: 20},
+			"sidecar_memory_quota": {Unit: metrics.MemoryUnit, Value: 30},
+			"sidecar_disk":         {Unit: metrics.DiskUnit, Value: 40},
+		}))
+	})
+
+	It("should tag task metrics with the task GUID and report the peak memory", func() {
+		fakeClient := new(metricsfakes.FakeLoggregatorClient)
+		emitter := metrics.NewLoggregatorEmitter(fakeClient)
+
+		emitter.Emit(metrics.Message{
+			AppID:      "app-guid",
+			IndexID:    "task-guid",
+			TaskGUID:   "task-guid",
+			Memory:     20,
+			MemoryPeak: 50,
+		})
+		Expect(fakeClient.EmitGaugeCallCount()).To(Equal(1))
+
+		envelope := newEnvelope()
+		for _, g := range fakeClient.EmitGaugeArgsForCall(0) {
+			g(envelope)
+		}
+
+		Expect(envelope.SourceId).To(Equal("app-guid"))
+		Expect(envelope.InstanceId).To(Equal("task-guid"))
+		Expect(envelope.Tags).To(HaveKeyWithValue(metrics.TaskGUIDTag, "task-guid"))
+		Expect(envelope.GetGauge().Metrics).To(HaveKeyWithValue("memory_peak", &loggregator_v2.GaugeValue{Unit: metrics.MemoryUnit, Value: 50}))
+	})
 })
 
 func newEnvelope() *loggregator_v2.Envelope {
@@ -74,3 +162,34 @@ func newEnvelope() *loggregator_v2.Envelope {
 		Tags: make(map[string]string),
 	}
 }
+
+type fakeMetricsAndNodeScrapeEmitter struct {
+	*metricsfakes.FakeEmitter
+	*metricsfakes.FakeNodeScrapeEmitter
+}
+
+var _ = Describe("MultiEmitter", func() {
+	It("should forward node scrapes to the emitters which report them", func() {
+		metricsEmitter := new(metricsfakes.FakeEmitter)
+		scrapeEmitter := fakeMetricsAndNodeScrapeEmitter{new(metricsfakes.FakeEmitter), new(metricsfakes.FakeNodeScrapeEmitter)}
+		msg := metrics.NodeScrapeMessage{Node: "node1", Staleness: 12, Failures: 3}
+
+		metrics.NewMultiEmitter(metricsEmitter, scrapeEmitter).EmitNodeScrape(msg)
+
+		Expect(scrapeEmitter.EmitNodeScrapeCallCount()).To(Equal(1))
+		Expect(scrapeEmitter.EmitNodeScrapeArgsForCall(0)).To(Equal(msg))
+	})
+
+	It("should forward the message to every emitter", func() {
+		emitter1 := new(metricsfakes.FakeEmitter)
+		emitter2 := new(metricsfakes.FakeEmitter)
+		msg := metrics.Message{AppID: "app-id", IndexID: "1"}
+
+		metrics.NewMultiEmitter(emitter1, emitter2).Emit(msg)
+
+		Expect(emitter1.EmitCallCount()).To(Equal(1))
+		Expect(emitter1.EmitArgsForCall(0)).To(Equal(msg))
+		Expect(emitter2.EmitCallCount()).To(Equal(1))
+		Expect(emitter2.EmitArgsForCall(0)).To(Equal(msg))
+	})
+})
@@ -0,0 +1,76 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package metricsfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/eirini/metrics"
+)
+
+type FakeNodeScrapeEmitter struct {
+	EmitNodeScrapeStub        func(metrics.NodeScrapeMessage)
+	emitNodeScrapeMutex       sync.RWMutex
+	emitNodeScrapeArgsForCall []struct {
+		arg1 metrics.NodeScrapeMessage
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeNodeScrapeEmitter) EmitNodeScrape(arg1 metrics.NodeScrapeMessage) {
+	fake.emitNodeScrapeMutex.Lock()
+	fake.emitNodeScrapeArgsForCall = append(fake.emitNodeScrapeArgsForCall, struct {
+		arg1 metrics.NodeScrapeMessage
+	}{arg1})
+	stub := fake.EmitNodeScrapeStub
+	fake.recordInvocation("EmitNodeScrape", []interface{}{arg1})
+	fake.emitNodeScrapeMutex.Unlock()
+	if stub != nil {
+		fake.EmitNodeScrapeStub(arg1)
+	}
+}
+
+func (fake *FakeNodeScrapeEmitter) EmitNodeScrapeCallCount() int {
+	fake.emitNodeScrapeMutex.RLock()
+	defer fake.emitNodeScrapeMutex.RUnlock()
+	return len(fake.emitNodeScrapeArgsForCall)
+}
+
+func (fake *FakeNodeScrapeEmitter) EmitNodeScrapeCalls(stub func(metrics.NodeScrapeMessage)) {
+	fake.emitNodeScrapeMutex.Lock()
+	defer fake.emitNodeScrapeMutex.Unlock()
+	fake.EmitNodeScrapeStub = stub
+}
+
+func (fake *FakeNodeScrapeEmitter) EmitNodeScrapeArgsForCall(i int) metrics.NodeScrapeMessage {
+	fake.emitNodeScrapeMutex.RLock()
+	defer fake.emitNodeScrapeMutex.RUnlock()
+	argsForCall := fake.emitNodeScrapeArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeNodeScrapeEmitter) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.emitNodeScrapeMutex.RLock()
+	defer fake.emitNodeScrapeMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeNodeScrapeEmitter) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ metrics.NodeScrapeEmitter = new(FakeNodeScrapeEmitter)
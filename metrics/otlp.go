@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/eirini/otlp"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	otlpExportTimeout = 5 * time.Second
+
+	maxBufferedDataPoints = 50000
+)
+
+// OTLPEmitter buffers the gauges of the emitted messages, which Run exports
+// as a single batch per interval, so that an unreachable collector does not
+// hold up the other emitters. Gauges which do not fit into the buffer are
+// dropped
+type OTLPEmitter struct {
+	exporter  otlp.Exporter
+	resource  otlp.Resource
+	logger    lager.Logger
+	startTime time.Time
+
+	lock    sync.Mutex
+	points  []otlp.DataPoint
+	dropped int
+}
+
+// NewOTLPEmitter returns an emitter which exports metrics through exporter.
+// Export errors are written to logger, which must not export its logs
+// through the same exporter.
+func NewOTLPEmitter(exporter otlp.Exporter, resource otlp.Resource, logger lager.Logger) *OTLPEmitter {
+	return &OTLPEmitter{
+		exporter:  exporter,
+		resource:  resource,
+		logger:    logger,
+		startTime: time.Now(),
+	}
+}
+
+func (e *OTLPEmitter) Emit(m Message) {
+	now := time.Now()
+	attributes := map[string]string{
+		"source_id":      m.AppID,
+		"instance_index": m.IndexID,
+		"app_guid":       m.AppGUID,
+		"org_guid":       m.OrgGUID,
+		"space_guid":     m.SpaceGUID,
+	}
+
+	if m.TaskGUID != "" {
+		attributes[TaskGUIDTag] = m.TaskGUID
+	}
+
+	gauge := func(name string, value float64, unit string, attributes map[string]string) otlp.DataPoint {
+		return otlp.DataPoint{
+			Name:       name,
+			Unit:       unit,
+			Kind:       otlp.Gauge,
+			Value:      value,
+			Attributes: attributes,
+			Time:       now,
+		}
+	}
+
+	points := []otlp.DataPoint{
+		gauge("cpu", m.CPU, CPUUnit, attributes),
+		gauge("cpu_entitlement", m.CPUEntitlement, EntitlementUnit, attributes),
+		gauge("memory", m.Memory, MemoryUnit, attributes),
+		gauge("memory_entitlement", m.MemoryEntitlement, EntitlementUnit, attributes),
+		gauge("memory_quota", m.MemoryQuota, MemoryUnit, attributes),
+		gauge("disk", m.Disk, DiskUnit, attributes),
+		gauge("disk_quota", m.DiskQuota, DiskUnit, attributes),
+	}
+
+	if m.TaskGUID != "" {
+		points = append(points, gauge("memory_peak", m.MemoryPeak, MemoryUnit, attributes))
+	}
+
+	for _, sidecar := range m.Sidecars {
+		sidecarAttributes := map[string]string{SidecarTag: sidecar.Name}
+		for k, v := range attributes {
+			sidecarAttributes[k] = v
+		}
+
+		points = append(points,
+			gauge("sidecar_cpu", sidecar.CPU, CPUUnit, sidecarAttributes),
+			gauge("sidecar_memory", sidecar.Memory, MemoryUnit, sidecarAttributes),
+			gauge("sidecar_memory_quota", sidecar.MemoryQuota, MemoryUnit, sidecarAttributes),
+			gauge("sidecar_disk", sidecar.Disk, DiskUnit, sidecarAttributes),
+		)
+	}
+
+	e.buffer(points)
+}
+
+// EmitNodeScrape reports the scrapes of a node with the node as attribute
+func (e *OTLPEmitter) EmitNodeScrape(m NodeScrapeMessage) {
+	now := time.Now()
+	attributes := map[string]string{NodeTag: m.Node}
+
+	e.buffer([]otlp.DataPoint{
+		{
+			Name:       "kubelet_scrape_staleness",
+			Unit:       "s",
+			Kind:       otlp.Gauge,
+			Value:      m.Staleness,
+			Attributes: attributes,
+			Time:       now,
+		},
+		{
+			Name:       "kubelet_scrape_failures",
+			Unit:       "1",
+			Kind:       otlp.Counter,
+			Value:      m.Failures,
+			Attributes: attributes,
+			StartTime:  e.startTime,
+			Time:       now,
+		},
+	})
+}
+
+func (e *OTLPEmitter) buffer(points []otlp.DataPoint) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if len(e.points)+len(points) > maxBufferedDataPoints {
+		e.dropped += len(points)
+
+		return
+	}
+
+	e.points = append(e.points, points...)
+}
+
+// Flush exports the buffered gauges in one request
+func (e *OTLPEmitter) Flush(ctx context.Context) {
+	e.lock.Lock()
+	points := e.points
+	e.points = nil
+	dropped := e.dropped
+	e.dropped = 0
+	e.lock.Unlock()
+
+	if dropped > 0 {
+		e.logger.Info("dropped-data-points", lager.Data{"count": dropped})
+	}
+
+	if len(points) == 0 {
+		return
+	}
+
+	if err := e.exporter.ExportMetrics(ctx, otlp.EncodeMetrics(e.resource, points)); err != nil {
+		e.logger.Error("failed-to-export-metrics", err, lager.Data{"data-points": len(points)})
+	}
+}
+
+// Run flushes the emitter every interval until stop is closed
+func (e *OTLPEmitter) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+			e.Flush(ctx)
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/metrics"
+	"code.cloudfoundry.org/eirini/otlp"
+	"code.cloudfoundry.org/eirini/otlp/otlpfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OTLPEmitter", func() {
+	var (
+		exporter *otlpfakes.FakeExporter
+		logger   *lagertest.TestLogger
+		emitter  *metrics.OTLPEmitter
+		msg      metrics.Message
+	)
+
+	BeforeEach(func() {
+		exporter = new(otlpfakes.FakeExporter)
+		logger = lagertest.NewTestLogger("otlp-emitter")
+		emitter = metrics.NewOTLPEmitter(exporter, otlp.Resource{ServiceName: "metrics-collector"}, logger)
+		msg = metrics.Message{
+			AppID:       "process-guid",
+			IndexID:     "1",
+			AppGUID:     "app-guid",
+			CPU:         100,
+			Memory:      320,
+			MemoryQuota: 500,
+			Disk:        645,
+			DiskQuota:   1001,
+		}
+	})
+
+	It("does not export on emit", func() {
+		emitter.Emit(msg)
+
+		Expect(exporter.ExportMetricsCallCount()).To(BeZero())
+	})
+
+	It("exports the app instance gauges", func() {
+		emitter.Emit(msg)
+		emitter.Flush(context.Background())
+
+		Expect(exporter.ExportMetricsCallCount()).To(Equal(1))
+		_, request := exporter.ExportMetricsArgsForCall(0)
+		for _, expected := range []string{"cpu", "memory", "memory_quota", "disk", "disk_quota", "process-guid", "app-guid", "metrics-collector"} {
+			Expect(string(request)).To(ContainSubstring(expected))
+		}
+	})
+
+	It("exports the task GUID and peak memory for tasks", func() {
+		msg.TaskGUID = "task-guid"
+		msg.MemoryPeak = 640
+		emitter.Emit(msg)
+		emitter.Flush(context.Background())
+
+		_, request := exporter.ExportMetricsArgsForCall(0)
+		Expect(string(request)).To(ContainSubstring(metrics.TaskGUIDTag))
+		Expect(string(request)).To(ContainSubstring("task-guid"))
+		Expect(string(request)).To(ContainSubstring("memory_peak"))
+	})
+
+	It("exports all the messages emitted since the last flush in one request", func() {
+		emitter.Emit(msg)
+		msg.IndexID = "2"
+		msg.AppID = "other-process-guid"
+		emitter.Emit(msg)
+		emitter.Flush(context.Background())
+
+		Expect(exporter.ExportMetricsCallCount()).To(Equal(1))
+		_, request := exporter.ExportMetricsArgsForCall(0)
+		Expect(string(request)).To(ContainSubstring("process-guid"))
+		Expect(string(request)).To(ContainSubstring("other-process-guid"))
+	})
+
+	It("exports the node scrapes", func() {
+		emitter.EmitNodeScrape(metrics.NodeScrapeMessage{Node: "node1", Staleness: 12, Failures: 3})
+		emitter.Flush(context.Background())
+
+		_, request := exporter.ExportMetricsArgsForCall(0)
+		for _, expected := range []string{"kubelet_scrape_staleness", "kubelet_scrape_failures", metrics.NodeTag, "node1"} {
+			Expect(string(request)).To(ContainSubstring(expected))
+		}
+	})
+
+	It("does not export anything when nothing was emitted", func() {
+		emitter.Flush(context.Background())
+
+		Expect(exporter.ExportMetricsCallCount()).To(BeZero())
+	})
+
+	It("exports the gauges only once", func() {
+		emitter.Emit(msg)
+		emitter.Flush(context.Background())
+		emitter.Flush(context.Background())
+
+		Expect(exporter.ExportMetricsCallCount()).To(Equal(1))
+	})
+
+	When("the buffer is full", func() {
+		It("drops the gauges that do not fit and says so", func() {
+			for i := 0; i < 10000; i++ {
+				emitter.Emit(msg)
+			}
+			emitter.Flush(context.Background())
+
+			Expect(exporter.ExportMetricsCallCount()).To(Equal(1))
+			Expect(logger.LogMessages()).To(ContainElement("otlp-emitter.dropped-data-points"))
+		})
+	})
+
+	When("the export fails", func() {
+		BeforeEach(func() {
+			exporter.ExportMetricsReturns(errors.New("collector down"))
+		})
+
+		It("logs the error", func() {
+			emitter.Emit(msg)
+			emitter.Flush(context.Background())
+			Expect(logger.LogMessages()).To(ContainElement("otlp-emitter.failed-to-export-metrics"))
+		})
+	})
+})
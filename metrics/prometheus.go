@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	PrometheusNamespace        = "eirini"
+	PrometheusSubsystem        = "app"
+	PrometheusSidecarSubsystem = "sidecar"
+	PrometheusTaskSubsystem    = "task"
+)
+
+var (
+	prometheusLabels        = []string{"source_id", "app_guid", "org_guid", "space_guid", "instance_index"}
+	prometheusSidecarLabels = append(append([]string{}, prometheusLabels...), SidecarTag)
+	prometheusTaskLabels    = []string{"source_id", "app_guid", "org_guid", "space_guid", TaskGUIDTag}
+)
+
+type PrometheusEmitter struct {
+	maxAge            time.Duration
+	lock              sync.Mutex
+	messages          map[string]timestampedMessage
+	cpu               *prometheus.Desc
+	cpuEntitlement    *prometheus.Desc
+	memory            *prometheus.Desc
+	memoryEntitlement *prometheus.Desc
+	memoryQuota       *prometheus.Desc
+	disk              *prometheus.Desc
+	diskQuota         *prometheus.Desc
+	sidecarCPU        *prometheus.Desc
+	sidecarMemory     *prometheus.Desc
+	sidecarMemQuota   *prometheus.Desc
+	sidecarDisk       *prometheus.Desc
+	taskCPU           *prometheus.Desc
+	taskMemory        *prometheus.Desc
+	taskMemoryPeak    *prometheus.Desc
+	taskMemoryQuota   *prometheus.Desc
+	taskDisk          *prometheus.Desc
+	taskDiskQuota     *prometheus.Desc
+	descriptions      []*prometheus.Desc
+}
+
+type timestampedMessage struct {
+	message  Message
+	lastSeen time.Time
+}
+
+// NewPrometheusEmitter creates an emitter that keeps the latest message for
+// each app instance and exposes it as a set of gauges. Instances that have not
+// been emitted for longer than maxAge are dropped, so that stopped apps do not
+// linger on the /metrics endpoint.
+func NewPrometheusEmitter(maxAge time.Duration) *PrometheusEmitter {
+	e := &PrometheusEmitter{
+		maxAge:            maxAge,
+		messages:          map[string]timestampedMessage{},
+		cpu:               newAppGaugeDesc("cpu_percentage", "CPU usage of the app instance as a percentage of one core"),
+		cpuEntitlement:    newAppGaugeDesc("cpu_entitlement_percentage", "CPU usage of the instance pod as a percentage of its CPU requests"),
+		memory:            newAppGaugeDesc("memory_bytes", "Memory usage of the app instance in bytes"),
+		memoryEntitlement: newAppGaugeDesc("memory_entitlement_percentage", "Memory usage of the instance pod as a percentage of its memory requests"),
+		memoryQuota:       newAppGaugeDesc("memory_quota_bytes", "Memory limit of the app instance in bytes"),
+		disk:              newAppGaugeDesc("disk_bytes", "Disk usage of the app instance in bytes"),
+		diskQuota:         newAppGaugeDesc("disk_quota_bytes", "Disk limit of the app instance in bytes"),
+		sidecarCPU:        newSidecarGaugeDesc("cpu_percentage", "CPU usage of the sidecar as a percentage of one core"),
+		sidecarMemory:     newSidecarGaugeDesc("memory_bytes", "Memory usage of the sidecar in bytes"),
+		sidecarMemQuota:   newSidecarGaugeDesc("memory_quota_bytes", "Memory limit of the sidecar in bytes"),
+		sidecarDisk:       newSidecarGaugeDesc("disk_bytes", "Disk usage of the sidecar in bytes"),
+		taskCPU:           newTaskGaugeDesc("cpu_percentage", "CPU usage of the task as a percentage of one core"),
+		taskMemory:        newTaskGaugeDesc("memory_bytes", "Memory usage of the task in bytes"),
+		taskMemoryPeak:    newTaskGaugeDesc("memory_peak_bytes", "Highest memory usage of the task in bytes"),
+		taskMemoryQuota:   newTaskGaugeDesc("memory_quota_bytes", "Memory limit of the task in bytes"),
+		taskDisk:          newTaskGaugeDesc("disk_bytes", "Disk usage of the task in bytes"),
+		taskDiskQuota:     newTaskGaugeDesc("disk_quota_bytes", "Disk limit of the task in bytes"),
+	}
+	e.descriptions = []*prometheus.Desc{
+		e.cpu, e.cpuEntitlement, e.memory, e.memoryEntitlement, e.memoryQuota, e.disk, e.diskQuota,
+		e.sidecarCPU, e.sidecarMemory, e.sidecarMemQuota, e.sidecarDisk,
+		e.taskCPU, e.taskMemory, e.taskMemoryPeak, e.taskMemoryQuota, e.taskDisk, e.taskDiskQuota,
+	}
+
+	return e
+}
+
+func (e *PrometheusEmitter) Emit(m Message) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.messages[m.AppID+"/"+m.IndexID] = timestampedMessage{
+		message:  m,
+		lastSeen: time.Now(),
+	}
+}
+
+func (e *PrometheusEmitter) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range e.descriptions {
+		ch <- d
+	}
+}
+
+func (e *PrometheusEmitter) Collect(ch chan<- prometheus.Metric) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	now := time.Now()
+
+	for key, tm := range e.messages {
+		if now.Sub(tm.lastSeen) > e.maxAge {
+			delete(e.messages, key)
+
+			continue
+		}
+
+		m := tm.message
+
+		if m.TaskGUID != "" {
+			e.collectTask(ch, m)
+
+			continue
+		}
+
+		labels := []string{m.AppID, m.AppGUID, m.OrgGUID, m.SpaceGUID, m.IndexID}
+
+		ch <- prometheus.MustNewConstMetric(e.cpu, prometheus.GaugeValue, m.CPU, labels...)
+		ch <- prometheus.MustNewConstMetric(e.cpuEntitlement, prometheus.GaugeValue, m.CPUEntitlement, labels...)
+		ch <- prometheus.MustNewConstMetric(e.memory, prometheus.GaugeValue, m.Memory, labels...)
+		ch <- prometheus.MustNewConstMetric(e.memoryEntitlement, prometheus.GaugeValue, m.MemoryEntitlement, labels...)
+		ch <- prometheus.MustNewConstMetric(e.memoryQuota, prometheus.GaugeValue, m.MemoryQuota, labels...)
+		ch <- prometheus.MustNewConstMetric(e.disk, prometheus.GaugeValue, m.Disk, labels...)
+		ch <- prometheus.MustNewConstMetric(e.diskQuota, prometheus.GaugeValue, m.DiskQuota, labels...)
+
+		for _, sidecar := range m.Sidecars {
+			sidecarLabels := append(append([]string{}, labels...), sidecar.Name)
+
+			ch <- prometheus.MustNewConstMetric(e.sidecarCPU, prometheus.GaugeValue, sidecar.CPU, sidecarLabels...)
+			ch <- prometheus.MustNewConstMetric(e.sidecarMemory, prometheus.GaugeValue, sidecar.Memory, sidecarLabels...)
+			ch <- prometheus.MustNewConstMetric(e.sidecarMemQuota, prometheus.GaugeValue, sidecar.MemoryQuota, sidecarLabels...)
+			ch <- prometheus.MustNewConstMetric(e.sidecarDisk, prometheus.GaugeValue, sidecar.Disk, sidecarLabels...)
+		}
+	}
+}
+
+func (e *PrometheusEmitter) collectTask(ch chan<- prometheus.Metric, m Message) {
+	labels := []string{m.AppID, m.AppGUID, m.OrgGUID, m.SpaceGUID, m.TaskGUID}
+
+	ch <- prometheus.MustNewConstMetric(e.taskCPU, prometheus.GaugeValue, m.CPU, labels...)
+	ch <- prometheus.MustNewConstMetric(e.taskMemory, prometheus.GaugeValue, m.Memory, labels...)
+	ch <- prometheus.MustNewConstMetric(e.taskMemoryPeak, prometheus.GaugeValue, m.MemoryPeak, labels...)
+	ch <- prometheus.MustNewConstMetric(e.taskMemoryQuota, prometheus.GaugeValue, m.MemoryQuota, labels...)
+	ch <- prometheus.MustNewConstMetric(e.taskDisk, prometheus.GaugeValue, m.Disk, labels...)
+	ch <- prometheus.MustNewConstMetric(e.taskDiskQuota, prometheus.GaugeValue, m.DiskQuota, labels...)
+}
+
+func newAppGaugeDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(PrometheusNamespace, PrometheusSubsystem, name),
+		help,
+		prometheusLabels,
+		nil,
+	)
+}
+
+func newSidecarGaugeDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(PrometheusNamespace, PrometheusSidecarSubsystem, name),
+		help,
+		prometheusSidecarLabels,
+		nil,
+	)
+}
+
+func newTaskGaugeDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(PrometheusNamespace, PrometheusTaskSubsystem, name),
+		help,
+		prometheusTaskLabels,
+		nil,
+	)
+}
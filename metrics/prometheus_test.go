@@ -0,0 +1,119 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/eirini/metrics"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var _ = Describe("PrometheusEmitter", func() {
+	var (
+		emitter *metrics.PrometheusEmitter
+		server  *httptest.Server
+		maxAge  time.Duration
+	)
+
+	scrape := func() string {
+		resp, err := http.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		maxAge = time.Minute
+	})
+
+	JustBeforeEach(func() {
+		emitter = metrics.NewPrometheusEmitter(maxAge)
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(emitter)
+		server = httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+		emitter.Emit(metrics.Message{
+			AppID:       "process-guid",
+			IndexID:     "3",
+			AppGUID:     "app-guid",
+			OrgGUID:     "org-guid",
+			SpaceGUID:   "space-guid",
+			CPU:         12.5,
+			Memory:      320,
+			MemoryQuota: 500,
+			Disk:        645,
+			DiskQuota:   1001,
+			Sidecars: []metrics.SidecarMessage{{
+				Name:   "the-sidecar",
+				Memory: 42,
+			}},
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("exposes the app instance gauges", func() {
+		labels := `{app_guid="app-guid",instance_index="3",org_guid="org-guid",source_id="process-guid",space_guid="space-guid"}`
+
+		body := scrape()
+		Expect(body).To(ContainSubstring("eirini_app_cpu_percentage" + labels + " 12.5"))
+		Expect(body).To(ContainSubstring("eirini_app_memory_bytes" + labels + " 320"))
+		Expect(body).To(ContainSubstring("eirini_app_memory_quota_bytes" + labels + " 500"))
+		Expect(body).To(ContainSubstring("eirini_app_disk_bytes" + labels + " 645"))
+		Expect(body).To(ContainSubstring("eirini_app_disk_quota_bytes" + labels + " 1001"))
+	})
+
+	It("exposes the sidecar gauges", func() {
+		labels := `{app_guid="app-guid",instance_index="3",org_guid="org-guid",sidecar="the-sidecar",source_id="process-guid",space_guid="space-guid"}`
+
+		Expect(scrape()).To(ContainSubstring("eirini_sidecar_memory_bytes" + labels + " 42"))
+	})
+
+	It("exposes task gauges by task GUID", func() {
+		emitter.Emit(metrics.Message{
+			AppID:      "app-guid",
+			IndexID:    "task-guid",
+			TaskGUID:   "task-guid",
+			AppGUID:    "app-guid",
+			Memory:     100,
+			MemoryPeak: 300,
+		})
+		labels := `{app_guid="app-guid",org_guid="",source_id="app-guid",space_guid="",task_guid="task-guid"}`
+
+		body := scrape()
+		Expect(body).To(ContainSubstring("eirini_task_memory_bytes" + labels + " 100"))
+		Expect(body).To(ContainSubstring("eirini_task_memory_peak_bytes" + labels + " 300"))
+		Expect(body).NotTo(ContainSubstring(`instance_index="task-guid"`))
+	})
+
+	It("exposes only the latest message for an instance", func() {
+		emitter.Emit(metrics.Message{AppID: "process-guid", IndexID: "3", CPU: 42})
+
+		body := scrape()
+		Expect(body).To(ContainSubstring(`source_id="process-guid",space_guid=""} 42`))
+		Expect(body).NotTo(ContainSubstring("12.5"))
+	})
+
+	When("an instance has not been emitted for longer than the max age", func() {
+		BeforeEach(func() {
+			maxAge = 50 * time.Millisecond
+		})
+
+		It("stops exposing it", func() {
+			Eventually(scrape).ShouldNot(ContainSubstring("process-guid"))
+		})
+	})
+})
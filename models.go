@@ -19,11 +19,34 @@ const (
 	EnvCFInstanceAddr       = "CF_INSTANCE_ADDR"
 	EnvCFInstancePort       = "CF_INSTANCE_PORT"
 	EnvCFInstancePorts      = "CF_INSTANCE_PORTS"
+	EnvVCAPApplication      = "VCAP_APPLICATION"
+	EnvCFInstanceCert       = "CF_INSTANCE_CERT"
+	EnvCFInstanceKey        = "CF_INSTANCE_KEY"
+	EnvVCAPServices         = "VCAP_SERVICES"
 
 	AppMetricsEmissionIntervalInSecs = 15
+	PrometheusExporterPort           = 9090
+
+	AppLogRateLimitPerSecond     = 100
+	LogStreamRetryIntervalInSecs = 1
 
 	RegistrySecretName = "default-image-pull-secret"
 
+	OTLPProtocolGRPC         = "grpc"
+	OTLPProtocolHTTP         = "http"
+	OTLPExportIntervalInSecs = 10
+
+	RouteBackendNATS       = "nats"
+	RouteBackendIngress    = "ingress"
+	RouteBackendGatewayAPI = "gateway-api"
+
+	EnvMutatorInstanceIndex   = "instance_index"
+	EnvMutatorVCAPApplication = "vcap_application"
+	EnvMutatorInstanceAddress = "instance_address"
+
+	CredentialBackendCredHub    = "credhub"
+	CredentialBackendKubernetes = "kubernetes"
+
 	// Certs
 	TLSSecretKey  = "tls.key"
 	TLSSecretCert = "tls.crt"
@@ -47,6 +70,8 @@ var ErrNotFound = errors.New("not found")
 
 var ErrInvalidInstanceIndex = errors.New("invalid instance index")
 
+var ErrImageNotAllowed = errors.New("image not allowed by the image policy")
+
 type Config struct {
 	Properties              Properties `yaml:"opi"`
 	WorkloadsNamespace      string
@@ -54,6 +79,60 @@ type Config struct {
 	LeaderElectionNamespace string
 }
 
+type OTLPConfig struct {
+	Endpoint             string            `yaml:"endpoint"`
+	Protocol             string            `yaml:"protocol"`
+	Insecure             bool              `yaml:"insecure"`
+	CAPath               string            `yaml:"ca_path"`
+	Headers              map[string]string `yaml:"headers"`
+	ExportIntervalInSecs int               `yaml:"export_interval_in_secs"`
+}
+
+// RouteIntegrityConfig enables TLS between gorouter and app instances. Every
+// instance gets a proxy sidecar terminating TLS in front of the app ports,
+// with the certificate the instance-index-env-injector issues for it when its
+// route_integrity is enabled
+type RouteIntegrityConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	ProxyImage string `yaml:"proxy_image"`
+	InitImage  string `yaml:"init_image"`
+}
+
+// ImagePolicyConfig restricts the images apps, tasks and stagings may use.
+// Registries are host names, such as docker.io, and repositories are fully
+// qualified names, such as docker.io/library/*, where * matches within a path
+// segment. Denials win over allowances, and when nothing is allowed explicitly
+// everything that is not denied is. Mirrors rewrite images of a registry to
+// an internal mirror after the policy has been checked
+type ImagePolicyConfig struct {
+	AllowedRegistries   []string            `yaml:"allowed_registries"`
+	DeniedRegistries    []string            `yaml:"denied_registries"`
+	AllowedRepositories []string            `yaml:"allowed_repositories"`
+	DeniedRepositories  []string            `yaml:"denied_repositories"`
+	Mirrors             []ImageMirrorConfig `yaml:"mirrors"`
+}
+
+// ImageMirrorConfig maps a registry to a mirror, which may include a path,
+// such as mirror.internal/docker-hub. Images are pulled from the mirror with
+// its credentials when it has any, otherwise with the ones of the request
+type ImageMirrorConfig struct {
+	Registry string `yaml:"registry"`
+	Mirror   string `yaml:"mirror"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// ImagePlatformConfig is the platform the image metadata is fetched for when
+// an image is a manifest list. It defaults to linux and the architecture opi
+// runs on. SelectNodesByArchitecture pins apps and tasks of images available
+// for a single architecture to nodes of it, at the cost of fetching the image
+// metadata whenever they are desired
+type ImagePlatformConfig struct {
+	OS                        string `yaml:"os"`
+	Architecture              string `yaml:"architecture"`
+	SelectNodesByArchitecture bool   `yaml:"select_nodes_by_architecture"`
+}
+
 type KubeConfig struct {
 	ConfigPath string `yaml:"kube_config_path"`
 }
@@ -83,6 +162,18 @@ type Properties struct { //nolint:maligned
 	UnsafeAllowAutomountServiceAccountToken bool `yaml:"unsafe_allow_automount_service_account_token"`
 
 	ServePlaintext bool `yaml:"serve_plaintext"`
+
+	LoggregatorAddress  string `yaml:"loggregator_address"`
+	LoggregatorCertPath string
+	LoggregatorKeyPath  string
+	LoggregatorCAPath   string
+
+	RouteIntegrity RouteIntegrityConfig `yaml:"route_integrity"`
+
+	ImagePolicy   ImagePolicyConfig   `yaml:"image_policy"`
+	ImagePlatform ImagePlatformConfig `yaml:"image_platform"`
+
+	OTLP OTLPConfig `yaml:"otlp"`
 }
 
 type EventReporterConfig struct {
@@ -97,6 +188,8 @@ type EventReporterConfig struct {
 	LeaderElectionID        string
 	LeaderElectionNamespace string
 
+	OTLP OTLPConfig `yaml:"otlp"`
+
 	KubeConfig `yaml:",inline"`
 }
 
@@ -107,11 +200,77 @@ type RouteEmitterConfig struct {
 	EmitPeriodInSeconds uint   `yaml:"emit_period_in_seconds"`
 	WorkloadsNamespace  string
 
+	// NatsServers are the nats:// or tls:// URLs of the NATS cluster. When
+	// set they take precedence over NatsIP and NatsPort
+	NatsServers []string `yaml:"nats_servers"`
+	// NatsUser goes with NatsPassword and defaults to "nats"
+	NatsUser                       string        `yaml:"nats_user"`
+	NatsToken                      string        `yaml:"nats_token"`
+	NatsCredentialsFile            string        `yaml:"nats_credentials_file"`
+	NatsNKeySeedFile               string        `yaml:"nats_nkey_seed_file"`
+	NatsTLS                        NATSTLSConfig `yaml:"nats_tls"`
+	NatsReconnectBufferSizeInBytes int           `yaml:"nats_reconnect_buffer_size_in_bytes"`
+
+	// FullRefreshPeriodInSeconds is how often the route-collector registers
+	// all routes instead of only the changes. It has to stay below the time
+	// after which gorouter prunes routes that were not registered again
+	FullRefreshPeriodInSeconds uint `yaml:"full_refresh_period_in_seconds"`
+	PrometheusPort             int  `yaml:"prometheus_port"`
+
+	// The following only apply to route-emitter, which elects a leader so
+	// that only one replica publishes routes. Its informers resync every
+	// InformerResyncPeriodInSeconds, re-registering the routes of all pods
+	LeaderElectionID              string
+	LeaderElectionNamespace       string
+	HealthProbePort               int  `yaml:"health_probe_port"`
+	InformerResyncPeriodInSeconds uint `yaml:"informer_resync_period_in_seconds"`
+
+	RoutingAPI RoutingAPIConfig `yaml:"routing_api"`
+
+	// Backend is one of nats (default), ingress or gateway-api
+	Backend    string           `yaml:"backend"`
+	Ingress    IngressConfig    `yaml:"ingress"`
+	GatewayAPI GatewayAPIConfig `yaml:"gateway_api"`
+
 	KubeConfig `yaml:",inline"`
 }
 
+// NATSTLSConfig enables TLS to NATS, optionally authenticating with a client
+// certificate
+type NATSTLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CAPath   string `yaml:"ca_path"`
+	CertPath string `yaml:"cert_path"`
+	KeyPath  string `yaml:"key_path"`
+}
+
+type IngressConfig struct {
+	ClassName string `yaml:"class_name"`
+}
+
+// GatewayAPIConfig names the Gateway the app HTTPRoutes are attached to
+type GatewayAPIConfig struct {
+	GatewayName      string `yaml:"gateway_name"`
+	GatewayNamespace string `yaml:"gateway_namespace"`
+}
+
+// RoutingAPIConfig enables TCP routes, which are registered with the routing
+// API using a UAA client with the routing.routes.write scope
+type RoutingAPIConfig struct {
+	Enabled           bool   `yaml:"enabled"`
+	URL               string `yaml:"url"`
+	UAAURL            string `yaml:"uaa_url"`
+	ClientName        string `yaml:"client_name"`
+	ClientSecret      string `yaml:"client_secret"`
+	CAPath            string `yaml:"ca_path"`
+	TCPRouteTTLInSecs int    `yaml:"tcp_route_ttl_in_secs"`
+}
+
 type MetricsCollectorConfig struct {
-	LoggregatorAddress string `yaml:"loggregator_address"`
+	LoggregatorAddress  string `yaml:"loggregator_address"`
+	LoggregatorDisabled bool   `yaml:"loggregator_disabled"`
+
+	OTLP OTLPConfig `yaml:"otlp"`
 
 	WorkloadsNamespace  string
 	LoggregatorCertPath string
@@ -120,6 +279,26 @@ type MetricsCollectorConfig struct {
 
 	AppMetricsEmissionIntervalInSecs int `yaml:"app_metrics_emission_interval_in_secs"`
 
+	PrometheusEnabled bool `yaml:"prometheus_enabled"`
+	PrometheusPort    int  `yaml:"prometheus_port"`
+
+	KubeletScrapeConcurrency   int `yaml:"kubelet_scrape_concurrency"`
+	KubeletScrapeTimeoutInSecs int `yaml:"kubelet_scrape_timeout_in_secs"`
+
+	KubeConfig `yaml:",inline"`
+}
+
+type LogForwarderConfig struct {
+	LoggregatorAddress string `yaml:"loggregator_address"`
+
+	WorkloadsNamespace  string
+	LoggregatorCertPath string
+	LoggregatorKeyPath  string
+	LoggregatorCAPath   string
+
+	AppLogRateLimitPerSecond int `yaml:"app_log_rate_limit_per_second"`
+	AppLogRateLimitBurst     int `yaml:"app_log_rate_limit_burst"`
+
 	KubeConfig `yaml:",inline"`
 }
 
@@ -135,6 +314,28 @@ type TaskReporterConfig struct {
 
 	WorkloadsNamespace string
 
+	OTLP OTLPConfig `yaml:"otlp"`
+
+	KubeConfig `yaml:",inline"`
+}
+
+type PolicySyncConfig struct {
+	// PolicyServerAddress is the base URL of the internal API of the CF
+	// network policy server, e.g. https://policy-server.service.cf.internal:4003
+	PolicyServerAddress     string `yaml:"policy_server_address"`
+	PolicyServerTLSDisabled bool   `yaml:"policy_server_tls_disabled"`
+	PolicyServerCertPath    string `yaml:"policy_server_cert_path"`
+	PolicyServerKeyPath     string `yaml:"policy_server_key_path"`
+	PolicyServerCAPath      string `yaml:"policy_server_ca_path"`
+
+	SyncPeriodInSeconds uint `yaml:"sync_period_in_seconds"`
+	// DefaultDeny isolates apps from each other unless a policy allows it
+	DefaultDeny bool `yaml:"default_deny"`
+	// PrometheusPort serves the policy-sync metrics on /metrics when set
+	PrometheusPort int `yaml:"prometheus_port"`
+
+	WorkloadsNamespace string
+
 	KubeConfig `yaml:",inline"`
 }
 
@@ -144,7 +345,55 @@ type InstanceIndexEnvInjectorConfig struct {
 	ServicePort                int32  `yaml:"service_port"`
 	EiriniXOperatorFingerprint string
 
+	// EnvMutators lists the mutators to register, each as a webhook of its
+	// own: instance_index (the default), vcap_application and
+	// instance_address. They only patch the app container unless
+	// InjectIntoSidecars is set
+	EnvMutators        []string `yaml:"env_mutators"`
+	InjectIntoSidecars bool     `yaml:"inject_into_sidecars"`
+
+	InstanceIdentity        InstanceIdentityConfig        `yaml:"instance_identity"`
+	RouteIntegrity          RouteIntegrityCertsConfig     `yaml:"route_integrity"`
+	CredentialInterpolation CredentialInterpolationConfig `yaml:"credential_interpolation"`
+
 	WorkloadsNamespace string
 
 	KubeConfig `yaml:",inline"`
 }
+
+// InstanceIdentityConfig enables the CF_INSTANCE_CERT and CF_INSTANCE_KEY
+// credentials, signed by the CA at CACertPath and CAKeyPath. Certificates are
+// valid for a day and renewed when a quarter of that is left, unless
+// configured otherwise
+type InstanceIdentityConfig struct {
+	Enabled               bool   `yaml:"enabled"`
+	CACertPath            string `yaml:"ca_cert_path"`
+	CAKeyPath             string `yaml:"ca_key_path"`
+	CertValidityInMinutes int    `yaml:"cert_validity_in_minutes"`
+	RenewBeforeInMinutes  int    `yaml:"renew_before_in_minutes"`
+}
+
+// RouteIntegrityCertsConfig issues the certificates the route integrity
+// proxies of app instances serve, signed by the CA at CACertPath and
+// CAKeyPath, which gorouter has to trust. Like instance identities, they are
+// valid for a day and renewed when a quarter of that is left, unless
+// configured otherwise
+type RouteIntegrityCertsConfig struct {
+	Enabled               bool   `yaml:"enabled"`
+	CACertPath            string `yaml:"ca_cert_path"`
+	CAKeyPath             string `yaml:"ca_key_path"`
+	CertValidityInMinutes int    `yaml:"cert_validity_in_minutes"`
+	RenewBeforeInMinutes  int    `yaml:"renew_before_in_minutes"`
+}
+
+// CredentialInterpolationConfig resolves the credential references in
+// VCAP_SERVICES when app instances are created. Backend is either credhub,
+// authenticating as the instance with a certificate signed by the instance
+// identity CA, or kubernetes, which looks the credentials up in secrets in
+// SecretsNamespace instead
+type CredentialInterpolationConfig struct {
+	Backend          string `yaml:"backend"`
+	CredHubURL       string `yaml:"credhub_url"`
+	CredHubCAPath    string `yaml:"credhub_ca_path"`
+	SecretsNamespace string `yaml:"secrets_namespace"`
+}
@@ -179,6 +179,12 @@ type Route struct {
 	Port     int32  `json:"port"`
 }
 
+type TCPRoute struct {
+	RouterGroupGUID string `json:"router_group_guid"`
+	ExternalPort    uint32 `json:"external_port"`
+	ContainerPort   uint32 `json:"container_port"`
+}
+
 type AppCrashedRequest struct {
 	Instance        string `json:"instance"`
 	Index           int    `json:"index"`
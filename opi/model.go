@@ -44,11 +44,15 @@ type LRP struct {
 	RunningInstances       int
 	MemoryMB               int64
 	DiskMB                 int64
-	RunsAsRoot             bool
+	RunAsUser              *int64
+	RunAsGroup             *int64
+	Architecture           string
 	CPUWeight              uint8
 	VolumeMounts           []VolumeMount
 	LRP                    string
 	AppURIs                []Route
+	TCPRoutes              []TCPRoute
+	InternalRoutes         []InternalRoute
 	LastUpdated            string
 	UserDefinedAnnotations map[string]string
 }
@@ -65,10 +69,27 @@ type Route struct {
 	Port     int32  `json:"port"`
 }
 
+// TCPRoute maps a port of a router group of the TCP router to a port of the
+// app instances
+type TCPRoute struct {
+	RouterGroupGUID string `json:"router_group_guid"`
+	ExternalPort    uint32 `json:"external_port"`
+	ContainerPort   uint32 `json:"container_port"`
+}
+
+// InternalRoute is a hostname on an internal domain, such as apps.internal,
+// which other apps resolve to reach the app instances directly
+type InternalRoute struct {
+	Hostname string `json:"hostname"`
+}
+
 type PrivateRegistry struct {
 	Server   string
 	Username string
 	Password string
+	// SecretName refers to an existing dockerconfigjson secret holding the
+	// registry credentials, instead of Username and Password
+	SecretName string
 }
 
 type VolumeMount struct {
@@ -98,6 +119,7 @@ type Task struct {
 	Image              string
 	CompletionCallback string
 	PrivateRegistry    *PrivateRegistry
+	Architecture       string
 	Env                map[string]string
 	Command            []string
 	AppName            string
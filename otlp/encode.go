@@ -0,0 +1,211 @@
+package otlp
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	scopeName = "code.cloudfoundry.org/eirini"
+
+	aggregationTemporalityCumulative = 2
+)
+
+// Field numbers from the opentelemetry-proto definitions
+const (
+	exportRequestResourceField protowire.Number = 1
+
+	resourceAttributesField protowire.Number = 1
+
+	resourceScopeField protowire.Number = 1
+	resourceDataField  protowire.Number = 2
+
+	scopeField        protowire.Number = 1
+	scopeRecordsField protowire.Number = 2
+	scopeNameField    protowire.Number = 1
+
+	metricNameField  protowire.Number = 1
+	metricUnitField  protowire.Number = 3
+	metricGaugeField protowire.Number = 5
+	metricSumField   protowire.Number = 7
+
+	dataPointsField                protowire.Number = 1
+	sumAggregationTemporalityField protowire.Number = 2
+	sumIsMonotonicField            protowire.Number = 3
+
+	numberDataPointStartTimeField  protowire.Number = 2
+	numberDataPointTimeField       protowire.Number = 3
+	numberDataPointAsDoubleField   protowire.Number = 4
+	numberDataPointAttributesField protowire.Number = 7
+
+	logRecordTimeField           protowire.Number = 1
+	logRecordSeverityNumberField protowire.Number = 2
+	logRecordSeverityTextField   protowire.Number = 3
+	logRecordBodyField           protowire.Number = 5
+	logRecordAttributesField     protowire.Number = 6
+
+	keyValueKeyField   protowire.Number = 1
+	keyValueValueField protowire.Number = 2
+
+	anyValueStringField protowire.Number = 1
+)
+
+// EncodeMetrics encodes an ExportMetricsServiceRequest
+func EncodeMetrics(resource Resource, points []DataPoint) []byte {
+	var metrics [][]byte
+	for _, p := range points {
+		metrics = append(metrics, encodeMetric(p))
+	}
+
+	return encodeExportRequest(resource, metrics)
+}
+
+// EncodeLogs encodes an ExportLogsServiceRequest
+func EncodeLogs(resource Resource, records []LogRecord) []byte {
+	var logs [][]byte
+	for _, r := range records {
+		logs = append(logs, encodeLogRecord(r))
+	}
+
+	return encodeExportRequest(resource, logs)
+}
+
+func encodeExportRequest(resource Resource, records [][]byte) []byte {
+	var scope []byte
+	scope = appendMessage(scope, scopeField, appendString(nil, scopeNameField, scopeName))
+
+	for _, r := range records {
+		scope = appendMessage(scope, scopeRecordsField, r)
+	}
+
+	var res []byte
+	res = appendMessage(res, resourceScopeField, encodeResource(resource))
+	res = appendMessage(res, resourceDataField, scope)
+
+	return appendMessage(nil, exportRequestResourceField, res)
+}
+
+func encodeResource(resource Resource) []byte {
+	attributes := map[string]string{"service.name": resource.ServiceName}
+	for k, v := range resource.Attributes {
+		attributes[k] = v
+	}
+
+	return appendAttributes(nil, resourceAttributesField, attributes)
+}
+
+func encodeMetric(p DataPoint) []byte {
+	var point []byte
+	point = appendFixed64(point, numberDataPointStartTimeField, unixNano(p.StartTime))
+	point = appendFixed64(point, numberDataPointTimeField, unixNano(p.Time))
+	point = protowire.AppendTag(point, numberDataPointAsDoubleField, protowire.Fixed64Type)
+	point = protowire.AppendFixed64(point, math.Float64bits(p.Value))
+	point = appendAttributes(point, numberDataPointAttributesField, p.Attributes)
+
+	var data []byte
+	data = appendMessage(data, dataPointsField, point)
+
+	var metric []byte
+	metric = appendString(metric, metricNameField, p.Name)
+	metric = appendString(metric, metricUnitField, p.Unit)
+
+	if p.Kind == Counter {
+		data = appendVarint(data, sumAggregationTemporalityField, aggregationTemporalityCumulative)
+		data = appendVarint(data, sumIsMonotonicField, 1)
+
+		return appendMessage(metric, metricSumField, data)
+	}
+
+	return appendMessage(metric, metricGaugeField, data)
+}
+
+func encodeLogRecord(r LogRecord) []byte {
+	var record []byte
+	record = appendFixed64(record, logRecordTimeField, unixNano(r.Time))
+	record = appendVarint(record, logRecordSeverityNumberField, uint64(r.Severity))
+	record = appendString(record, logRecordSeverityTextField, severityText(r.Severity))
+	record = appendMessage(record, logRecordBodyField, encodeStringValue(r.Body))
+	record = appendAttributes(record, logRecordAttributesField, r.Attributes)
+
+	return record
+}
+
+func appendAttributes(b []byte, num protowire.Number, attributes map[string]string) []byte {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var kv []byte
+		kv = appendString(kv, keyValueKeyField, k)
+		kv = appendMessage(kv, keyValueValueField, encodeStringValue(attributes[k]))
+		b = appendMessage(b, num, kv)
+	}
+
+	return b
+}
+
+func encodeStringValue(s string) []byte {
+	return appendString(nil, anyValueStringField, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+
+	return protowire.AppendFixed64(b, v)
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return uint64(t.UnixNano())
+}
+
+func severityText(s Severity) string {
+	switch s {
+	case SeverityDebug:
+		return "DEBUG"
+	case SeverityInfo:
+		return "INFO"
+	case SeverityError:
+		return "ERROR"
+	case SeverityFatal:
+		return "FATAL"
+	}
+
+	return ""
+}
@@ -0,0 +1,94 @@
+package otlp_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/eirini/otlp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encode", func() {
+	var resource otlp.Resource
+
+	BeforeEach(func() {
+		resource = otlp.Resource{
+			ServiceName: "metrics-collector",
+			Attributes:  map[string]string{"k8s.namespace.name": "eirini"},
+		}
+	})
+
+	Describe("EncodeMetrics", func() {
+		var (
+			now    time.Time
+			points []otlp.DataPoint
+		)
+
+		BeforeEach(func() {
+			now = time.Unix(1000, 0)
+			points = []otlp.DataPoint{
+				{Name: "cpu", Unit: "percentage", Kind: otlp.Gauge, Value: 12.5, Attributes: map[string]string{"source_id": "my-app"}, Time: now},
+				{Name: "events", Unit: "1", Kind: otlp.Counter, Value: 3, StartTime: now, Time: now},
+			}
+		})
+
+		It("encodes the resource", func() {
+			request := otlp.EncodeMetrics(resource, points)
+
+			resources := protoMessages(request, 1, 1)
+			Expect(resources).To(HaveLen(1))
+			Expect(protoAttributes(resources[0], 1)).To(Equal(map[string]string{
+				"service.name":       "metrics-collector",
+				"k8s.namespace.name": "eirini",
+			}))
+		})
+
+		It("encodes the instrumentation scope", func() {
+			scopes := protoMessages(otlp.EncodeMetrics(resource, points), 1, 2, 1)
+			Expect(scopes).To(HaveLen(1))
+			Expect(protoString(scopes[0], 1)).To(Equal("code.cloudfoundry.org/eirini"))
+		})
+
+		It("encodes gauges", func() {
+			metrics := protoMessages(otlp.EncodeMetrics(resource, points), 1, 2, 2)
+			Expect(metrics).To(HaveLen(2))
+			Expect(protoString(metrics[0], 1)).To(Equal("cpu"))
+			Expect(protoString(metrics[0], 3)).To(Equal("percentage"))
+
+			dataPoints := protoMessages(metrics[0], 5, 1)
+			Expect(dataPoints).To(HaveLen(1))
+			Expect(protoDouble(dataPoints[0], 4)).To(Equal(12.5))
+			Expect(protoFields(dataPoints[0])[3]).To(ConsistOf(uint64(now.UnixNano())))
+			Expect(protoAttributes(dataPoints[0], 7)).To(Equal(map[string]string{"source_id": "my-app"}))
+		})
+
+		It("encodes counters as cumulative monotonic sums", func() {
+			metrics := protoMessages(otlp.EncodeMetrics(resource, points), 1, 2, 2)
+			Expect(protoFields(metrics[1])).NotTo(HaveKey(BeEquivalentTo(5)))
+
+			sums := protoMessages(metrics[1], 7)
+			Expect(sums).To(HaveLen(1))
+			Expect(protoFields(sums[0])[2]).To(ConsistOf(uint64(2)))
+			Expect(protoFields(sums[0])[3]).To(ConsistOf(uint64(1)))
+			Expect(protoDouble(protoMessages(sums[0], 1)[0], 4)).To(Equal(3.0))
+		})
+	})
+
+	Describe("EncodeLogs", func() {
+		It("encodes the log records", func() {
+			request := otlp.EncodeLogs(resource, []otlp.LogRecord{{
+				Time:       time.Unix(1000, 0),
+				Severity:   otlp.SeverityError,
+				Body:       "handler.desire-app.failed",
+				Attributes: map[string]string{"guid": "my-app"},
+			}})
+
+			records := protoMessages(request, 1, 2, 2)
+			Expect(records).To(HaveLen(1))
+			Expect(protoFields(records[0])[2]).To(ConsistOf(uint64(otlp.SeverityError)))
+			Expect(protoString(records[0], 3)).To(Equal("ERROR"))
+			Expect(protoString(protoMessages(records[0], 5)[0], 1)).To(Equal("handler.desire-app.failed"))
+			Expect(protoAttributes(records[0], 6)).To(Equal(map[string]string{"guid": "my-app"}))
+		})
+	})
+})
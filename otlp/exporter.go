@@ -0,0 +1,209 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	metricsExportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+	logsExportMethod    = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+	MetricsPath = "/v1/metrics"
+	LogsPath    = "/v1/logs"
+
+	protobufContentType = "application/x-protobuf"
+)
+
+//counterfeiter:generate . Exporter
+
+type Exporter interface {
+	ExportMetrics(ctx context.Context, request []byte) error
+	ExportLogs(ctx context.Context, request []byte) error
+}
+
+func NewExporter(cfg eirini.OTLPConfig) (Exporter, error) {
+	tlsConfig, err := createTLSConfig(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create otlp tls config")
+	}
+
+	switch cfg.Protocol {
+	case eirini.OTLPProtocolHTTP:
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+		return NewHTTPExporter(httpEndpoint(cfg), httpClient, cfg.Headers), nil
+	case eirini.OTLPProtocolGRPC, "":
+		transportOption := grpc.WithInsecure()
+		if tlsConfig != nil {
+			transportOption = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+		}
+
+		conn, err := grpc.Dial(cfg.Endpoint, transportOption)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to dial otlp collector")
+		}
+
+		return NewGRPCExporter(conn, cfg.Headers), nil
+	}
+
+	return nil, fmt.Errorf("unsupported otlp protocol %q", cfg.Protocol)
+}
+
+func createTLSConfig(cfg eirini.OTLPConfig) (*tls.Config, error) {
+	if cfg.Insecure {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAPath == "" {
+		return tlsConfig, nil
+	}
+
+	caBytes, err := ioutil.ReadFile(filepath.Clean(cfg.CAPath))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read ca file")
+	}
+
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("cannot parse ca cert")
+	}
+
+	return tlsConfig, nil
+}
+
+func httpEndpoint(cfg eirini.OTLPConfig) string {
+	if strings.Contains(cfg.Endpoint, "://") {
+		return strings.TrimSuffix(cfg.Endpoint, "/")
+	}
+
+	if cfg.Insecure {
+		return "http://" + cfg.Endpoint
+	}
+
+	return "https://" + cfg.Endpoint
+}
+
+type HTTPExporter struct {
+	endpoint string
+	client   *http.Client
+	headers  map[string]string
+}
+
+func NewHTTPExporter(endpoint string, client *http.Client, headers map[string]string) *HTTPExporter {
+	return &HTTPExporter{
+		endpoint: endpoint,
+		client:   client,
+		headers:  headers,
+	}
+}
+
+func (e *HTTPExporter) ExportMetrics(ctx context.Context, request []byte) error {
+	return e.post(ctx, MetricsPath, request)
+}
+
+func (e *HTTPExporter) ExportLogs(ctx context.Context, request []byte) error {
+	return e.post(ctx, LogsPath, request)
+}
+
+func (e *HTTPExporter) post(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	req.Header.Set("Content-Type", protobufContentType)
+
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("request failed with status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type GRPCExporter struct {
+	conn    *grpc.ClientConn
+	headers map[string]string
+}
+
+func NewGRPCExporter(conn *grpc.ClientConn, headers map[string]string) *GRPCExporter {
+	return &GRPCExporter{
+		conn:    conn,
+		headers: headers,
+	}
+}
+
+func (e *GRPCExporter) ExportMetrics(ctx context.Context, request []byte) error {
+	return e.invoke(ctx, metricsExportMethod, request)
+}
+
+func (e *GRPCExporter) ExportLogs(ctx context.Context, request []byte) error {
+	return e.invoke(ctx, logsExportMethod, request)
+}
+
+func (e *GRPCExporter) invoke(ctx context.Context, method string, request []byte) error {
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.headers))
+	}
+
+	in := RawMessage(request)
+	out := RawMessage{}
+
+	return errors.Wrap(e.conn.Invoke(ctx, method, &in, &out, grpc.ForceCodec(RawCodec{})), "export failed")
+}
+
+// RawMessage is a protobuf message that has already been encoded
+type RawMessage []byte
+
+// RawCodec passes RawMessages to and from the wire unchanged. It registers as
+// "proto" so that peers see a regular protobuf payload.
+type RawCodec struct{}
+
+func (RawCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(*RawMessage)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+
+	return *msg, nil
+}
+
+func (RawCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(*RawMessage)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+
+	*msg = append((*msg)[:0], data...)
+
+	return nil
+}
+
+func (RawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,220 @@
+package otlp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/eirini/otlp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type receivedRequest struct {
+	path    string
+	headers map[string]string
+	body    []byte
+}
+
+// serverCodec adapts RawCodec to the legacy codec interface grpc servers use
+type serverCodec struct {
+	otlp.RawCodec
+}
+
+func (serverCodec) String() string {
+	return "proto"
+}
+
+var _ = Describe("Exporter", func() {
+	var (
+		exporter otlp.Exporter
+		received chan receivedRequest
+		request  []byte
+	)
+
+	BeforeEach(func() {
+		received = make(chan receivedRequest, 10)
+		request = otlp.EncodeMetrics(otlp.Resource{ServiceName: "test"}, []otlp.DataPoint{{Name: "cpu", Value: 1}})
+	})
+
+	Describe("HTTPExporter", func() {
+		var (
+			server     *httptest.Server
+			statusCode int
+		)
+
+		BeforeEach(func() {
+			statusCode = http.StatusOK
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+
+				received <- receivedRequest{
+					path: r.URL.Path,
+					headers: map[string]string{
+						"Content-Type":  r.Header.Get("Content-Type"),
+						"Authorization": r.Header.Get("Authorization"),
+					},
+					body: body,
+				}
+				w.WriteHeader(statusCode)
+			}))
+
+			var err error
+			exporter, err = otlp.NewExporter(eirini.OTLPConfig{
+				Endpoint: server.URL,
+				Protocol: eirini.OTLPProtocolHTTP,
+				Insecure: true,
+				Headers:  map[string]string{"Authorization": "Bearer token"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("posts metrics to the metrics path", func() {
+			Expect(exporter.ExportMetrics(context.Background(), request)).To(Succeed())
+
+			var r receivedRequest
+			Eventually(received).Should(Receive(&r))
+			Expect(r.path).To(Equal(otlp.MetricsPath))
+			Expect(r.body).To(Equal(request))
+			Expect(r.headers).To(Equal(map[string]string{
+				"Content-Type":  "application/x-protobuf",
+				"Authorization": "Bearer token",
+			}))
+		})
+
+		It("posts logs to the logs path", func() {
+			Expect(exporter.ExportLogs(context.Background(), request)).To(Succeed())
+
+			var r receivedRequest
+			Eventually(received).Should(Receive(&r))
+			Expect(r.path).To(Equal(otlp.LogsPath))
+		})
+
+		When("the collector rejects the request", func() {
+			BeforeEach(func() {
+				statusCode = http.StatusBadRequest
+			})
+
+			It("returns an error", func() {
+				Expect(exporter.ExportMetrics(context.Background(), request)).To(MatchError(ContainSubstring("400")))
+			})
+		})
+	})
+
+	Describe("GRPCExporter", func() {
+		var (
+			server     *grpc.Server
+			listener   net.Listener
+			failExport bool
+		)
+
+		BeforeEach(func() {
+			failExport = false
+
+			var err error
+			listener, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+
+			server = grpc.NewServer(
+				grpc.CustomCodec(serverCodec{}),
+				grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+					method, _ := grpc.MethodFromServerStream(stream)
+					md, _ := metadata.FromIncomingContext(stream.Context())
+
+					msg := otlp.RawMessage{}
+					if err := stream.RecvMsg(&msg); err != nil {
+						return err
+					}
+
+					authorization := ""
+					if values := md.Get("authorization"); len(values) > 0 {
+						authorization = values[0]
+					}
+
+					received <- receivedRequest{
+						path:    method,
+						headers: map[string]string{"authorization": authorization},
+						body:    msg,
+					}
+
+					if failExport {
+						return status.Error(codes.Unavailable, "collector is down")
+					}
+
+					return stream.SendMsg(&otlp.RawMessage{})
+				}),
+			)
+
+			go func() {
+				defer GinkgoRecover()
+				Expect(server.Serve(listener)).To(Succeed())
+			}()
+
+			exporter, err = otlp.NewExporter(eirini.OTLPConfig{
+				Endpoint: listener.Addr().String(),
+				Protocol: eirini.OTLPProtocolGRPC,
+				Insecure: true,
+				Headers:  map[string]string{"authorization": "Bearer token"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			server.Stop()
+		})
+
+		It("calls the metrics service", func() {
+			Expect(exporter.ExportMetrics(context.Background(), request)).To(Succeed())
+
+			var r receivedRequest
+			Eventually(received).Should(Receive(&r))
+			Expect(r.path).To(Equal("/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"))
+			Expect(r.body).To(Equal(request))
+			Expect(r.headers).To(HaveKeyWithValue("authorization", "Bearer token"))
+		})
+
+		It("calls the logs service", func() {
+			Expect(exporter.ExportLogs(context.Background(), request)).To(Succeed())
+
+			var r receivedRequest
+			Eventually(received).Should(Receive(&r))
+			Expect(r.path).To(Equal("/opentelemetry.proto.collector.logs.v1.LogsService/Export"))
+		})
+
+		When("the collector fails the export", func() {
+			BeforeEach(func() {
+				failExport = true
+			})
+
+			It("returns an error", func() {
+				Expect(exporter.ExportMetrics(context.Background(), request)).To(MatchError(ContainSubstring("collector is down")))
+			})
+		})
+	})
+
+	When("the protocol is not supported", func() {
+		It("returns an error", func() {
+			_, err := otlp.NewExporter(eirini.OTLPConfig{Endpoint: "localhost:4317", Protocol: "carrier-pigeon"})
+			Expect(err).To(MatchError(ContainSubstring(`unsupported otlp protocol "carrier-pigeon"`)))
+		})
+	})
+
+	When("the CA file does not exist", func() {
+		It("returns an error", func() {
+			_, err := otlp.NewExporter(eirini.OTLPConfig{Endpoint: "localhost:4317", CAPath: "/does/not/exist"})
+			Expect(err).To(MatchError(ContainSubstring("failed to read ca file")))
+		})
+	})
+})
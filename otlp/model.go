@@ -0,0 +1,42 @@
+package otlp
+
+import "time"
+
+type MetricKind int
+
+const (
+	Gauge MetricKind = iota
+	Counter
+)
+
+type Severity int
+
+// Severity numbers as defined by the OpenTelemetry log data model
+const (
+	SeverityDebug Severity = 5
+	SeverityInfo  Severity = 9
+	SeverityError Severity = 17
+	SeverityFatal Severity = 21
+)
+
+type Resource struct {
+	ServiceName string
+	Attributes  map[string]string
+}
+
+type DataPoint struct {
+	Name       string
+	Unit       string
+	Kind       MetricKind
+	Value      float64
+	Attributes map[string]string
+	StartTime  time.Time
+	Time       time.Time
+}
+
+type LogRecord struct {
+	Time       time.Time
+	Severity   Severity
+	Body       string
+	Attributes map[string]string
+}
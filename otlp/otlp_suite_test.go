@@ -0,0 +1,88 @@
+package otlp_test
+
+import (
+	"math"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestOtlp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OTLP Suite")
+}
+
+// protoFields decodes one level of a protobuf message. Length-delimited
+// fields are returned as []byte, varints as uint64 and fixed64 as uint64.
+func protoFields(b []byte) map[protowire.Number][]interface{} {
+	fields := map[protowire.Number][]interface{}{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		Expect(n).To(BeNumerically(">", 0))
+		b = b[n:]
+
+		var value interface{}
+
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			value, n = protowire.ConsumeFixed64(b)
+		default:
+			Fail("unexpected wire type")
+		}
+
+		Expect(n).To(BeNumerically(">", 0))
+		b = b[n:]
+		fields[num] = append(fields[num], value)
+	}
+
+	return fields
+}
+
+func protoMessages(b []byte, path ...protowire.Number) [][]byte {
+	msgs := [][]byte{b}
+
+	for _, num := range path {
+		next := [][]byte{}
+
+		for _, msg := range msgs {
+			for _, v := range protoFields(msg)[num] {
+				next = append(next, v.([]byte))
+			}
+		}
+
+		msgs = next
+	}
+
+	return msgs
+}
+
+func protoString(b []byte, num protowire.Number) string {
+	values := protoFields(b)[num]
+	if len(values) == 0 {
+		return ""
+	}
+
+	return string(values[0].([]byte))
+}
+
+func protoDouble(b []byte, num protowire.Number) float64 {
+	return math.Float64frombits(protoFields(b)[num][0].(uint64))
+}
+
+func protoAttributes(b []byte, num protowire.Number) map[string]string {
+	attributes := map[string]string{}
+
+	for _, kv := range protoMessages(b, num) {
+		value := protoMessages(kv, 2)[0]
+		attributes[protoString(kv, 1)] = protoString(value, 1)
+	}
+
+	return attributes
+}
@@ -0,0 +1,200 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package otlpfakes
+
+import (
+	"context"
+	"sync"
+
+	"code.cloudfoundry.org/eirini/otlp"
+)
+
+type FakeExporter struct {
+	ExportLogsStub        func(context.Context, []byte) error
+	exportLogsMutex       sync.RWMutex
+	exportLogsArgsForCall []struct {
+		arg1 context.Context
+		arg2 []byte
+	}
+	exportLogsReturns struct {
+		result1 error
+	}
+	exportLogsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ExportMetricsStub        func(context.Context, []byte) error
+	exportMetricsMutex       sync.RWMutex
+	exportMetricsArgsForCall []struct {
+		arg1 context.Context
+		arg2 []byte
+	}
+	exportMetricsReturns struct {
+		result1 error
+	}
+	exportMetricsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeExporter) ExportLogs(arg1 context.Context, arg2 []byte) error {
+	var arg2Copy []byte
+	if arg2 != nil {
+		arg2Copy = make([]byte, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.exportLogsMutex.Lock()
+	ret, specificReturn := fake.exportLogsReturnsOnCall[len(fake.exportLogsArgsForCall)]
+	fake.exportLogsArgsForCall = append(fake.exportLogsArgsForCall, struct {
+		arg1 context.Context
+		arg2 []byte
+	}{arg1, arg2Copy})
+	stub := fake.ExportLogsStub
+	fakeReturns := fake.exportLogsReturns
+	fake.recordInvocation("ExportLogs", []interface{}{arg1, arg2Copy})
+	fake.exportLogsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeExporter) ExportLogsCallCount() int {
+	fake.exportLogsMutex.RLock()
+	defer fake.exportLogsMutex.RUnlock()
+	return len(fake.exportLogsArgsForCall)
+}
+
+func (fake *FakeExporter) ExportLogsCalls(stub func(context.Context, []byte) error) {
+	fake.exportLogsMutex.Lock()
+	defer fake.exportLogsMutex.Unlock()
+	fake.ExportLogsStub = stub
+}
+
+func (fake *FakeExporter) ExportLogsArgsForCall(i int) (context.Context, []byte) {
+	fake.exportLogsMutex.RLock()
+	defer fake.exportLogsMutex.RUnlock()
+	argsForCall := fake.exportLogsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeExporter) ExportLogsReturns(result1 error) {
+	fake.exportLogsMutex.Lock()
+	defer fake.exportLogsMutex.Unlock()
+	fake.ExportLogsStub = nil
+	fake.exportLogsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeExporter) ExportLogsReturnsOnCall(i int, result1 error) {
+	fake.exportLogsMutex.Lock()
+	defer fake.exportLogsMutex.Unlock()
+	fake.ExportLogsStub = nil
+	if fake.exportLogsReturnsOnCall == nil {
+		fake.exportLogsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.exportLogsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeExporter) ExportMetrics(arg1 context.Context, arg2 []byte) error {
+	var arg2Copy []byte
+	if arg2 != nil {
+		arg2Copy = make([]byte, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.exportMetricsMutex.Lock()
+	ret, specificReturn := fake.exportMetricsReturnsOnCall[len(fake.exportMetricsArgsForCall)]
+	fake.exportMetricsArgsForCall = append(fake.exportMetricsArgsForCall, struct {
+		arg1 context.Context
+		arg2 []byte
+	}{arg1, arg2Copy})
+	stub := fake.ExportMetricsStub
+	fakeReturns := fake.exportMetricsReturns
+	fake.recordInvocation("ExportMetrics", []interface{}{arg1, arg2Copy})
+	fake.exportMetricsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeExporter) ExportMetricsCallCount() int {
+	fake.exportMetricsMutex.RLock()
+	defer fake.exportMetricsMutex.RUnlock()
+	return len(fake.exportMetricsArgsForCall)
+}
+
+func (fake *FakeExporter) ExportMetricsCalls(stub func(context.Context, []byte) error) {
+	fake.exportMetricsMutex.Lock()
+	defer fake.exportMetricsMutex.Unlock()
+	fake.ExportMetricsStub = stub
+}
+
+func (fake *FakeExporter) ExportMetricsArgsForCall(i int) (context.Context, []byte) {
+	fake.exportMetricsMutex.RLock()
+	defer fake.exportMetricsMutex.RUnlock()
+	argsForCall := fake.exportMetricsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeExporter) ExportMetricsReturns(result1 error) {
+	fake.exportMetricsMutex.Lock()
+	defer fake.exportMetricsMutex.Unlock()
+	fake.ExportMetricsStub = nil
+	fake.exportMetricsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeExporter) ExportMetricsReturnsOnCall(i int, result1 error) {
+	fake.exportMetricsMutex.Lock()
+	defer fake.exportMetricsMutex.Unlock()
+	fake.ExportMetricsStub = nil
+	if fake.exportMetricsReturnsOnCall == nil {
+		fake.exportMetricsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.exportMetricsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeExporter) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.exportLogsMutex.RLock()
+	defer fake.exportLogsMutex.RUnlock()
+	fake.exportMetricsMutex.RLock()
+	defer fake.exportMetricsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeExporter) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ otlp.Exporter = new(FakeExporter)
@@ -0,0 +1,6 @@
+// Package otlp exports metrics and logs to an OpenTelemetry collector using
+// the OTLP protocol, over either gRPC or HTTP. Messages are protobuf-encoded by
+// hand so that we do not need to depend on the whole OpenTelemetry SDK.
+package otlp
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -0,0 +1,185 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pkg/errors"
+)
+
+const (
+	LogEventsCounterName = "eirini.log_events"
+
+	maxBufferedRecords = 1000
+	exportTimeout      = 10 * time.Second
+)
+
+type counterKey struct {
+	component string
+	event     string
+	level     string
+}
+
+// LogSink is a lager sink that exports the component logs to an OTLP
+// collector. It also counts log events by component, message and level, which
+// gives us counters for everything the handler, reconcilers and reporters log
+// (e.g. failed desires or reported crashes) without having to instrument them
+// one by one.
+type LogSink struct {
+	exporter  Exporter
+	resource  Resource
+	minLevel  lager.LogLevel
+	startTime time.Time
+
+	lock     sync.Mutex
+	records  []LogRecord
+	counters map[counterKey]float64
+	dropped  int
+}
+
+func NewLogSink(exporter Exporter, resource Resource, minLevel lager.LogLevel) *LogSink {
+	return &LogSink{
+		exporter:  exporter,
+		resource:  resource,
+		minLevel:  minLevel,
+		startTime: time.Now(),
+		counters:  map[counterKey]float64{},
+	}
+}
+
+func (s *LogSink) Log(log lager.LogFormat) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.counters[counterKey{component: log.Source, event: log.Message, level: levelName(log.LogLevel)}]++
+
+	if log.LogLevel < s.minLevel {
+		return
+	}
+
+	if len(s.records) >= maxBufferedRecords {
+		s.dropped++
+
+		return
+	}
+
+	s.records = append(s.records, LogRecord{
+		Time:       time.Now(),
+		Severity:   severity(log.LogLevel),
+		Body:       log.Message,
+		Attributes: logAttributes(log),
+	})
+}
+
+// Flush exports the buffered log records and the current value of the log
+// event counters
+func (s *LogSink) Flush(ctx context.Context) error {
+	s.lock.Lock()
+	records := s.records
+	s.records = nil
+	dropped := s.dropped
+	s.dropped = 0
+	points := s.counterDataPoints(time.Now())
+	s.lock.Unlock()
+
+	if dropped > 0 {
+		records = append(records, LogRecord{
+			Time:     time.Now(),
+			Severity: SeverityError,
+			Body:     fmt.Sprintf("dropped %d log records because the export buffer was full", dropped),
+		})
+	}
+
+	if len(records) > 0 {
+		if err := s.exporter.ExportLogs(ctx, EncodeLogs(s.resource, records)); err != nil {
+			return errors.Wrap(err, "failed to export logs")
+		}
+	}
+
+	if len(points) > 0 {
+		if err := s.exporter.ExportMetrics(ctx, EncodeMetrics(s.resource, points)); err != nil {
+			return errors.Wrap(err, "failed to export counters")
+		}
+	}
+
+	return nil
+}
+
+// Run flushes the sink every interval until stop is closed. Export errors are
+// written to errorLogger, which must not be a logger this sink is registered
+// with.
+func (s *LogSink) Run(interval time.Duration, stop <-chan struct{}, errorLogger lager.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+			if err := s.Flush(ctx); err != nil {
+				errorLogger.Error("failed-to-flush", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (s *LogSink) counterDataPoints(now time.Time) []DataPoint {
+	points := make([]DataPoint, 0, len(s.counters))
+
+	for key, value := range s.counters {
+		points = append(points, DataPoint{
+			Name:  LogEventsCounterName,
+			Unit:  "1",
+			Kind:  Counter,
+			Value: value,
+			Attributes: map[string]string{
+				"component": key.component,
+				"event":     key.event,
+				"level":     key.level,
+			},
+			StartTime: s.startTime,
+			Time:      now,
+		})
+	}
+
+	return points
+}
+
+func logAttributes(log lager.LogFormat) map[string]string {
+	attributes := map[string]string{"component": log.Source}
+
+	for k, v := range log.Data {
+		attributes[k] = fmt.Sprint(v)
+	}
+
+	if log.Error != nil {
+		attributes["error"] = log.Error.Error()
+	}
+
+	return attributes
+}
+
+func severity(level lager.LogLevel) Severity {
+	switch level {
+	case lager.DEBUG:
+		return SeverityDebug
+	case lager.INFO:
+		return SeverityInfo
+	case lager.ERROR:
+		return SeverityError
+	case lager.FATAL:
+		return SeverityFatal
+	}
+
+	return SeverityInfo
+}
+
+func levelName(level lager.LogLevel) string {
+	return severityText(severity(level))
+}
@@ -0,0 +1,86 @@
+package otlp_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/eirini/otlp"
+	"code.cloudfoundry.org/eirini/otlp/otlpfakes"
+	"code.cloudfoundry.org/lager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LogSink", func() {
+	var (
+		exporter *otlpfakes.FakeExporter
+		sink     *otlp.LogSink
+		logger   lager.Logger
+	)
+
+	BeforeEach(func() {
+		exporter = new(otlpfakes.FakeExporter)
+		sink = otlp.NewLogSink(exporter, otlp.Resource{ServiceName: "opi"}, lager.INFO)
+		logger = lager.NewLogger("handler")
+		logger.RegisterSink(sink)
+	})
+
+	It("exports the buffered log records on flush", func() {
+		logger.Info("desire-app", lager.Data{"guid": "my-app"})
+		logger.Error("desire-app-failed", errors.New("boom"))
+
+		Expect(sink.Flush(context.Background())).To(Succeed())
+		Expect(exporter.ExportLogsCallCount()).To(Equal(1))
+
+		_, request := exporter.ExportLogsArgsForCall(0)
+		records := protoMessages(request, 1, 2, 2)
+		Expect(records).To(HaveLen(2))
+		Expect(protoString(protoMessages(records[0], 5)[0], 1)).To(Equal("handler.desire-app"))
+		Expect(protoAttributes(records[0], 6)).To(Equal(map[string]string{"component": "handler", "guid": "my-app"}))
+		Expect(protoString(records[1], 3)).To(Equal("ERROR"))
+		Expect(protoAttributes(records[1], 6)).To(HaveKeyWithValue("error", "boom"))
+	})
+
+	It("does not export records below the minimum level", func() {
+		logger.Debug("noisy")
+
+		Expect(sink.Flush(context.Background())).To(Succeed())
+		Expect(exporter.ExportLogsCallCount()).To(BeZero())
+	})
+
+	It("exports cumulative log event counters", func() {
+		logger.Debug("noisy")
+		logger.Error("desire-app-failed", errors.New("boom"))
+		logger.Error("desire-app-failed", errors.New("boom"))
+		Expect(sink.Flush(context.Background())).To(Succeed())
+
+		logger.Error("desire-app-failed", errors.New("boom"))
+		Expect(sink.Flush(context.Background())).To(Succeed())
+
+		Expect(exporter.ExportMetricsCallCount()).To(Equal(2))
+		_, request := exporter.ExportMetricsArgsForCall(1)
+
+		values := map[string]float64{}
+		for _, metric := range protoMessages(request, 1, 2, 2) {
+			Expect(protoString(metric, 1)).To(Equal(otlp.LogEventsCounterName))
+			point := protoMessages(metric, 7, 1)[0]
+			values[protoAttributes(point, 7)["event"]] = protoDouble(point, 4)
+		}
+
+		Expect(values).To(Equal(map[string]float64{
+			"handler.noisy":             1,
+			"handler.desire-app-failed": 3,
+		}))
+	})
+
+	When("exporting fails", func() {
+		BeforeEach(func() {
+			exporter.ExportLogsReturns(errors.New("collector down"))
+		})
+
+		It("returns the error", func() {
+			logger.Info("desire-app")
+			Expect(sink.Flush(context.Background())).To(MatchError(ContainSubstring("collector down")))
+		})
+	})
+})
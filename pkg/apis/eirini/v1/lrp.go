@@ -37,16 +37,23 @@ type LRPSpec struct {
 	Instances              int               `json:"instances"`
 	MemoryMB               int64             `json:"memoryMB"`
 	DiskMB                 int64             `json:"diskMB"`
-	RunsAsRoot             bool              `json:"runsAsRoot"`
+	RunAsUser              *int64            `json:"runAsUser,omitempty"`
+	RunAsGroup             *int64            `json:"runAsGroup,omitempty"`
 	CPUWeight              uint8             `json:"cpuWeight"`
 	VolumeMounts           []VolumeMount     `json:"volumeMounts,omitempty"`
 	LastUpdated            string            `json:"lastUpdated"`
 	UserDefinedAnnotations map[string]string `json:"userDefinedAnnotations,omitempty"`
 	AppRoutes              []Route           `json:"appRoutes"`
+
+	// Deprecated: use RunAsUser, where 0 runs the app as root
+	RunsAsRoot bool `json:"runsAsRoot,omitempty"`
 }
 
 type LRPStatus struct {
 	Replicas int32 `json:"replicas"`
+	// Error is why the LRP could not be desired, such as its image being
+	// rejected by the image policy
+	Error string `json:"error,omitempty"`
 }
 
 type Route struct {
@@ -62,9 +69,15 @@ type Sidecar struct {
 }
 
 type PrivateRegistry struct {
-	Server   string `json:"server"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Server string `json:"server,omitempty"`
+	// Deprecated: use SecretName, which keeps the credentials out of the spec
+	Username string `json:"username,omitempty"`
+	// Deprecated: use SecretName, which keeps the credentials out of the spec
+	Password string `json:"password,omitempty"`
+	// SecretName is the name of a kubernetes.io/dockerconfigjson secret in
+	// the namespace of the resource holding the registry credentials. It
+	// takes precedence over Username and Password
+	SecretName string `json:"secretName,omitempty"`
 }
 
 type VolumeMount struct {
@@ -5,15 +5,16 @@ import (
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
 
 // Task describes a short-lived job running alongside an LRP
 type Task struct {
 	meta_v1.TypeMeta   `json:",inline"`
 	meta_v1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec TaskSpec `json:"spec"`
+	Spec   TaskSpec   `json:"spec"`
+	Status TaskStatus `json:"status"`
 }
 
 type TaskSpec struct {
@@ -35,6 +36,12 @@ type TaskSpec struct {
 	CPUWeight          uint8             `json:"cpuWeight"`
 }
 
+type TaskStatus struct {
+	// Error is why the task could not be desired, such as its image being
+	// rejected by the image policy
+	Error string `json:"error,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type TaskList struct {
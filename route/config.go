@@ -21,6 +21,16 @@ func ReadConfig(path string) (*eirini.RouteEmitterConfig, error) {
 		cfg.NatsPassword = envNATSPassword
 	}
 
+	envNATSToken := os.Getenv("NATS_TOKEN")
+	if envNATSToken != "" {
+		cfg.NatsToken = envNATSToken
+	}
+
+	envRoutingAPIClientSecret := os.Getenv("ROUTING_API_CLIENT_SECRET")
+	if envRoutingAPIClientSecret != "" {
+		cfg.RoutingAPI.ClientSecret = envRoutingAPIClientSecret
+	}
+
 	return cfg, nil
 }
 
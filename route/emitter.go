@@ -3,10 +3,11 @@ package route
 import (
 	"encoding/json"
 
-	"code.cloudfoundry.org/eirini/util"
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/lager"
 	nats "github.com/nats-io/nats.go"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -40,13 +41,24 @@ func NewMessageEmitter(publisher Publisher, logger lager.Logger) MessageEmitter
 	}
 }
 
-func NewEmitterFromConfig(natsIP string, natsPort int, natsPassword string, logger lager.Logger) (Emitter, error) {
-	nc, err := nats.Connect(util.GenerateNatsURL(natsPassword, natsIP, natsPort), nats.MaxReconnects(-1))
+// NewEmitterFromConfig connects to NATS and registers the metrics of the
+// connection with metricsRegisterer
+func NewEmitterFromConfig(cfg *eirini.RouteEmitterConfig, metricsRegisterer prometheus.Registerer, logger lager.Logger) (Emitter, error) {
+	emitterLogger := logger.Session("emitter")
+
+	options, err := NATSOptions(cfg, emitterLogger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure nats")
+	}
+
+	nc, err := nats.Connect(NATSServers(cfg), options...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to connect to nats")
 	}
 
-	emitterLogger := logger.Session("emitter")
+	if err := metricsRegisterer.Register(NewNATSMetrics(nc)); err != nil {
+		return nil, errors.Wrap(err, "failed to register nats metrics")
+	}
 
 	return NewMessageEmitter(&NATSPublisher{NatsClient: nc}, emitterLogger), nil
 }
@@ -86,12 +98,13 @@ func (e MessageEmitter) unregisterRoutes(route Message) {
 
 func (e MessageEmitter) publish(subject string, route Message) error {
 	message := RegistryMessage{
-		Host:              route.Address,
-		Port:              route.Port,
-		TLSPort:           route.TLSPort,
-		URIs:              route.RegisteredRoutes,
-		App:               route.Name,
-		PrivateInstanceID: route.InstanceID,
+		Host:                route.Address,
+		Port:                route.Port,
+		TLSPort:             route.TLSPort,
+		URIs:                route.RegisteredRoutes,
+		App:                 route.Name,
+		PrivateInstanceID:   route.InstanceID,
+		ServerCertDomainSAN: route.ServerCertDomainSAN,
 	}
 
 	if subject == unregisterSubject {
@@ -123,6 +123,30 @@ var _ = Describe("MessageEmitter", func() {
 		})
 	})
 
+	Context("When the instance expects TLS connections", func() {
+		BeforeEach(func() {
+			routes.UnregisteredRoutes = []string{}
+			routes.ServerCertDomainSAN = "instance-id"
+		})
+
+		It("should tell gorouter which SAN to expect in the instance certificate", func() {
+			messageEmitter.Emit(routes)
+
+			Expect(publisher.PublishCallCount()).To(Equal(1))
+			_, routeJSON := publisher.PublishArgsForCall(0)
+			Expect(routeJSON).To(MatchJSON(`
+			{
+				"host": "203.0.113.2",
+				"port": 8080,
+				"tls_port": 8443,
+				"uris": ["route1.my.app.com"],
+				"app": "app1",
+				"private_instance_id": "instance-id",
+				"server_cert_domain_san": "instance-id"
+			}`))
+		})
+	})
+
 	Context("When the route message is missing an address", func() {
 		BeforeEach(func() {
 			routes.Address = ""
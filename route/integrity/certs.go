@@ -0,0 +1,113 @@
+package integrity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const serialNumberBits = 128
+
+// Issuer issues instance certificates with a CA it holds on to, so that the
+// CA key stays with whoever issues the certificates
+type Issuer struct {
+	caCertPEM []byte
+	caKeyPEM  []byte
+	validity  time.Duration
+}
+
+func NewIssuer(caCertPEM, caKeyPEM []byte, validity time.Duration) (*Issuer, error) {
+	if _, err := tls.X509KeyPair(caCertPEM, caKeyPEM); err != nil {
+		return nil, errors.Wrap(err, "failed to load CA key pair")
+	}
+
+	return &Issuer{
+		caCertPEM: caCertPEM,
+		caKeyPEM:  caKeyPEM,
+		validity:  validity,
+	}, nil
+}
+
+func (i *Issuer) Issue(instanceID string) ([]byte, []byte, error) {
+	return GenerateInstanceCert(i.caCertPEM, i.caKeyPEM, instanceID, i.validity)
+}
+
+// GenerateInstanceCert issues a serving certificate for an app instance,
+// signed by the given CA, whose only SAN is the instance ID gorouter will
+// expect to find when it connects to the instance
+func GenerateInstanceCert(caCertPEM, caKeyPEM []byte, instanceID string, validity time.Duration) ([]byte, []byte, error) {
+	ca, err := tls.X509KeyPair(caCertPEM, caKeyPEM)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load CA key pair")
+	}
+
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse CA certificate")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberBits))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate serial number")
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: instanceID},
+		DNSNames:     []string{instanceID},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), ca.PrivateKey)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create certificate")
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to marshal key")
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
+
+// NeedsRenewal tells whether the certificate expires within renewBefore or is
+// not a certificate for the instance, and when it is due for renewal
+func NeedsRenewal(certPEM []byte, instanceID string, renewBefore time.Duration) (bool, time.Time, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return true, time.Time{}, errors.New("failed to decode certificate PEM")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true, time.Time{}, errors.Wrap(err, "failed to parse certificate")
+	}
+
+	renewAt := cert.NotAfter.Add(-renewBefore)
+	if !time.Now().Before(renewAt) {
+		return true, renewAt, nil
+	}
+
+	return cert.Subject.CommonName != instanceID, renewAt, nil
+}
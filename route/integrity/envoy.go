@@ -0,0 +1,120 @@
+package integrity
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+var envoyConfigTemplate = template.Must(template.New("envoy").Parse(`static_resources:
+  listeners:
+{{- range .Listeners }}
+  - name: listener-{{ .AppPort }}
+    address:
+      socket_address:
+        address: 0.0.0.0
+        port_value: {{ .TLSPort }}
+    filter_chains:
+    - filters:
+      - name: envoy.filters.network.tcp_proxy
+        typed_config:
+          "@type": type.googleapis.com/envoy.extensions.filters.network.tcp_proxy.v3.TcpProxy
+          stat_prefix: app-{{ .AppPort }}
+          cluster: app-{{ .AppPort }}
+      transport_socket:
+        name: envoy.transport_sockets.tls
+        typed_config:
+          "@type": type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.DownstreamTlsContext
+          common_tls_context:
+            tls_certificate_sds_secret_configs:
+            - name: {{ $.SecretName }}
+              sds_config:
+                resource_api_version: V3
+                path_config_source:
+                  path: {{ $.SDSConfigPath }}
+{{- end }}
+  clusters:
+{{- range .Listeners }}
+  - name: app-{{ .AppPort }}
+    connect_timeout: 0.25s
+    type: STATIC
+    load_assignment:
+      cluster_name: app-{{ .AppPort }}
+      endpoints:
+      - lb_endpoints:
+        - endpoint:
+            address:
+              socket_address:
+                address: 127.0.0.1
+                port_value: {{ .AppPort }}
+{{- end }}
+`))
+
+// sdsConfigTemplate serves the instance certificate from the files of the
+// secret volume it is mounted from, which are reloaded whenever kubelet swaps
+// the directory for a renewed certificate
+var sdsConfigTemplate = template.Must(template.New("sds").Parse(`resources:
+- "@type": type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret
+  name: {{ .SecretName }}
+  tls_certificate:
+    certificate_chain:
+      filename: {{ .CertPath }}
+    private_key:
+      filename: {{ .KeyPath }}
+    watched_directory:
+      path: {{ .CertsDir }}
+`))
+
+const sdsSecretName = "instance-cert"
+
+type listener struct {
+	AppPort int32
+	TLSPort int32
+}
+
+// EnvoyConfig renders a static envoy bootstrap config that terminates TLS on
+// every TLS port with the instance certificate, which it reads from the SDS
+// config at sdsConfigPath, and forwards the plain connection to the
+// corresponding app port
+func EnvoyConfig(tlsPorts map[int32]int32, sdsConfigPath string) ([]byte, error) {
+	listeners := make([]listener, 0, len(tlsPorts))
+	for appPort, tlsPort := range tlsPorts {
+		listeners = append(listeners, listener{AppPort: appPort, TLSPort: tlsPort})
+	}
+
+	sort.Slice(listeners, func(i, j int) bool { return listeners[i].AppPort < listeners[j].AppPort })
+
+	var config bytes.Buffer
+
+	err := envoyConfigTemplate.Execute(&config, map[string]interface{}{
+		"Listeners":     listeners,
+		"SecretName":    sdsSecretName,
+		"SDSConfigPath": sdsConfigPath,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render envoy config")
+	}
+
+	return config.Bytes(), nil
+}
+
+// SDSConfig renders the SDS config EnvoyConfig reads the instance
+// certificate in certsDir from
+func SDSConfig(certsDir string) ([]byte, error) {
+	var config bytes.Buffer
+
+	err := sdsConfigTemplate.Execute(&config, map[string]interface{}{
+		"SecretName": sdsSecretName,
+		"CertsDir":   certsDir,
+		"CertPath":   filepath.Join(certsDir, CertFileName),
+		"KeyPath":    filepath.Join(certsDir, KeyFileName),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render sds config")
+	}
+
+	return config.Bytes(), nil
+}
@@ -0,0 +1,55 @@
+package integrity
+
+import (
+	"sort"
+)
+
+const (
+	ProxyContainerName = "route-integrity-proxy"
+	InitContainerName  = "route-integrity-init"
+
+	ConfigVolumeName  = "route-integrity-config"
+	ConfigMountPath   = "/etc/cf-route-integrity"
+	EnvoyConfFileName = "envoy.yaml"
+	// SDSConfFileName is the file the proxy reads the instance certificate
+	// from through SDS, so that it picks up renewed certificates
+	SDSConfFileName = "instance-cert-sds.yaml"
+
+	// InstanceCertVolumeName is the volume the instance certificate is
+	// mounted from. Pods are created with an empty placeholder for it, which
+	// the webhook issuing the certificate points at the secret of the pod
+	InstanceCertVolumeName = "route-integrity-cert"
+	InstanceCertMountPath  = "/etc/cf-route-integrity-cert"
+	CertFileName           = "tls.crt"
+	KeyFileName            = "tls.key"
+
+	// LabelPodName marks the secrets holding instance certificates with the
+	// name of the pod they belong to
+	LabelPodName = "cloudfoundry.org/route_integrity_pod"
+
+	// TLSPortBase is the port the proxy listens on for the first app port,
+	// the same as the one Diego cells use
+	TLSPortBase = 61001
+
+	EnvTLSPorts = "TLS_PORTS"
+)
+
+// SecretName is the name of the secret holding the instance certificate of a
+// pod
+func SecretName(podName string) string {
+	return podName + "-route-integrity"
+}
+
+// TLSPorts maps every app port to the port the proxy terminates TLS on for it
+func TLSPorts(appPorts []int32) map[int32]int32 {
+	sorted := make([]int32, len(appPorts))
+	copy(sorted, appPorts)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	tlsPorts := map[int32]int32{}
+	for i, port := range sorted {
+		tlsPorts[port] = TLSPortBase + int32(i)
+	}
+
+	return tlsPorts
+}
@@ -0,0 +1,236 @@
+package integrity_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"code.cloudfoundry.org/eirini/route/integrity"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("TLSPorts", func() {
+	It("should allocate a TLS port per app port starting at the Diego TLS port", func() {
+		Expect(integrity.TLSPorts([]int32{9090, 8080})).To(Equal(map[int32]int32{
+			8080: 61001,
+			9090: 61002,
+		}))
+	})
+
+	It("should return no TLS ports when there are no app ports", func() {
+		Expect(integrity.TLSPorts(nil)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("GenerateInstanceCert", func() {
+	var (
+		caCert    *x509.Certificate
+		caCertPEM []byte
+		caKeyPEM  []byte
+	)
+
+	BeforeEach(func() {
+		caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "route-integrity-ca"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+		caDER, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+		Expect(err).NotTo(HaveOccurred())
+		caCert, err = x509.ParseCertificate(caDER)
+		Expect(err).NotTo(HaveOccurred())
+
+		caKeyDER, err := x509.MarshalECPrivateKey(caKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		caCertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+		caKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER})
+	})
+
+	It("should issue a certificate for the instance signed by the CA", func() {
+		certPEM, keyPEM, err := integrity.GenerateInstanceCert(caCertPEM, caKeyPEM, "dora-space-0", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyPEM).NotTo(BeEmpty())
+
+		block, _ := pem.Decode(certPEM)
+		Expect(block).NotTo(BeNil())
+		cert, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+
+		roots := x509.NewCertPool()
+		roots.AddCert(caCert)
+		_, err = cert.Verify(x509.VerifyOptions{
+			DNSName: "dora-space-0",
+			Roots:   roots,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cert.NotAfter).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+	})
+
+	When("the CA key pair is invalid", func() {
+		It("should return an error", func() {
+			_, _, err := integrity.GenerateInstanceCert(caCertPEM, []byte("nope"), "dora-space-0", time.Hour)
+			Expect(err).To(MatchError(ContainSubstring("failed to load CA key pair")))
+		})
+	})
+
+	Describe("Issuer", func() {
+		It("should issue certificates for instances with its CA", func() {
+			issuer, err := integrity.NewIssuer(caCertPEM, caKeyPEM, time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+
+			certPEM, _, err := issuer.Issue("dora-space-1")
+			Expect(err).NotTo(HaveOccurred())
+
+			block, _ := pem.Decode(certPEM)
+			Expect(block).NotTo(BeNil())
+			cert, err := x509.ParseCertificate(block.Bytes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cert.DNSNames).To(ConsistOf("dora-space-1"))
+			Expect(cert.CheckSignatureFrom(caCert)).To(Succeed())
+		})
+
+		It("should refuse an invalid CA key pair", func() {
+			_, err := integrity.NewIssuer(caCertPEM, []byte("nope"), time.Hour)
+			Expect(err).To(MatchError(ContainSubstring("failed to load CA key pair")))
+		})
+	})
+})
+
+var _ = Describe("EnvoyConfig", func() {
+	It("should proxy every TLS port to its app port using the instance certificate", func() {
+		configYAML, err := integrity.EnvoyConfig(map[int32]int32{8080: 61001, 9090: 61002}, "/config/sds.yaml")
+		Expect(err).NotTo(HaveOccurred())
+
+		var config struct {
+			StaticResources struct {
+				Listeners []struct {
+					Name    string `yaml:"name"`
+					Address struct {
+						SocketAddress struct {
+							PortValue int `yaml:"port_value"`
+						} `yaml:"socket_address"`
+					} `yaml:"address"`
+				} `yaml:"listeners"`
+				Clusters []struct {
+					Name string `yaml:"name"`
+				} `yaml:"clusters"`
+			} `yaml:"static_resources"`
+		}
+		Expect(yaml.Unmarshal(configYAML, &config)).To(Succeed())
+
+		listeners := config.StaticResources.Listeners
+		Expect(listeners).To(HaveLen(2))
+		Expect(listeners[0].Name).To(Equal("listener-8080"))
+		Expect(listeners[0].Address.SocketAddress.PortValue).To(Equal(61001))
+		Expect(listeners[1].Name).To(Equal("listener-9090"))
+		Expect(listeners[1].Address.SocketAddress.PortValue).To(Equal(61002))
+
+		Expect(config.StaticResources.Clusters).To(HaveLen(2))
+		Expect(string(configYAML)).To(ContainSubstring("path: /config/sds.yaml"))
+		Expect(string(configYAML)).To(ContainSubstring("port_value: 9090"))
+	})
+})
+
+var _ = Describe("SDSConfig", func() {
+	It("should serve the instance certificate and reload it when its directory changes", func() {
+		configYAML, err := integrity.SDSConfig("/certs")
+		Expect(err).NotTo(HaveOccurred())
+
+		var config struct {
+			Resources []struct {
+				Name           string `yaml:"name"`
+				TLSCertificate struct {
+					CertificateChain struct {
+						Filename string `yaml:"filename"`
+					} `yaml:"certificate_chain"`
+					PrivateKey struct {
+						Filename string `yaml:"filename"`
+					} `yaml:"private_key"`
+					WatchedDirectory struct {
+						Path string `yaml:"path"`
+					} `yaml:"watched_directory"`
+				} `yaml:"tls_certificate"`
+			} `yaml:"resources"`
+		}
+		Expect(yaml.Unmarshal(configYAML, &config)).To(Succeed())
+
+		Expect(config.Resources).To(HaveLen(1))
+		cert := config.Resources[0].TLSCertificate
+		Expect(cert.CertificateChain.Filename).To(Equal("/certs/tls.crt"))
+		Expect(cert.PrivateKey.Filename).To(Equal("/certs/tls.key"))
+		Expect(cert.WatchedDirectory.Path).To(Equal("/certs"))
+	})
+})
+
+var _ = Describe("NeedsRenewal", func() {
+	var caCertPEM, caKeyPEM []byte
+
+	BeforeEach(func() {
+		caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "route-integrity-ca"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+		caDER, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+		Expect(err).NotTo(HaveOccurred())
+		caKeyDER, err := x509.MarshalECPrivateKey(caKey)
+		Expect(err).NotTo(HaveOccurred())
+
+		caCertPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+		caKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER})
+	})
+
+	issueCert := func(instanceID string, validity time.Duration) []byte {
+		certPEM, _, err := integrity.GenerateInstanceCert(caCertPEM, caKeyPEM, instanceID, validity)
+		Expect(err).NotTo(HaveOccurred())
+
+		return certPEM
+	}
+
+	It("should not renew a certificate before renewBefore its expiry", func() {
+		renew, renewAt, err := integrity.NeedsRenewal(issueCert("dora-space-0", time.Hour), "dora-space-0", 10*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renew).To(BeFalse())
+		Expect(renewAt).To(BeTemporally("~", time.Now().Add(50*time.Minute), time.Minute))
+	})
+
+	It("should renew a certificate about to expire", func() {
+		renew, _, err := integrity.NeedsRenewal(issueCert("dora-space-0", 5*time.Minute), "dora-space-0", 10*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renew).To(BeTrue())
+	})
+
+	It("should renew a certificate of another instance", func() {
+		renew, _, err := integrity.NeedsRenewal(issueCert("dora-space-1", time.Hour), "dora-space-0", 10*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(renew).To(BeTrue())
+	})
+
+	It("should renew an invalid certificate", func() {
+		renew, _, err := integrity.NeedsRenewal([]byte("nope"), "dora-space-0", 10*time.Minute)
+		Expect(err).To(HaveOccurred())
+		Expect(renew).To(BeTrue())
+	})
+})
@@ -0,0 +1,66 @@
+package route
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	PrometheusNamespace = "eirini"
+	PrometheusSubsystem = "route_emitter"
+)
+
+// Metrics describe the route table of a CollectorScheduler and how long it
+// takes to emit its changes. A nil *Metrics records nothing.
+type Metrics struct {
+	tableSize       prometheus.Gauge
+	emitDuration    prometheus.Histogram
+	emittedMessages prometheus.Counter
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		tableSize: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "route_table_size",
+			Help:      "Number of app instance ports in the route table",
+		}),
+		emitDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "emit_duration_seconds",
+			Help:      "Time taken to emit the route changes of a sync",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		emittedMessages: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: PrometheusNamespace,
+			Subsystem: PrometheusSubsystem,
+			Name:      "emitted_messages_total",
+			Help:      "Number of route messages emitted",
+		}),
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.tableSize.Describe(ch)
+	m.emitDuration.Describe(ch)
+	m.emittedMessages.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.tableSize.Collect(ch)
+	m.emitDuration.Collect(ch)
+	m.emittedMessages.Collect(ch)
+}
+
+func (m *Metrics) recordSync(tableSize, emitted int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.tableSize.Set(float64(tableSize))
+	m.emitDuration.Observe(duration.Seconds())
+	m.emittedMessages.Add(float64(emitted))
+}
@@ -1,6 +1,7 @@
 package route
 
 //counterfeiter:generate . Collector
+//counterfeiter:generate . TCPCollector
 
 type Routes struct {
 	RegisteredRoutes   []string
@@ -14,6 +15,28 @@ type Message struct {
 	TLSPort    uint32
 	InstanceID string
 	Name       string
+	// ServerCertDomainSAN is the SAN gorouter expects in the certificate of
+	// the instance when it connects to TLSPort
+	ServerCertDomainSAN string
+}
+
+type TCPRoute struct {
+	RouterGroupGUID string
+	ExternalPort    uint32
+}
+
+type TCPRoutes struct {
+	RegisteredRoutes   []TCPRoute
+	UnregisteredRoutes []TCPRoute
+}
+
+// TCPMessage maps ports of the TCP router to a port of an app instance
+type TCPMessage struct {
+	TCPRoutes
+	Address    string
+	Port       uint32
+	InstanceID string
+	Name       string
 }
 
 type Informer interface {
@@ -23,3 +46,7 @@ type Informer interface {
 type Collector interface {
 	Collect() ([]Message, error)
 }
+
+type TCPCollector interface {
+	CollectTCP() ([]TCPMessage, error)
+}
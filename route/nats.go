@@ -0,0 +1,144 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/lager"
+	nats "github.com/nats-io/nats.go"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultNATSUser = "nats"
+
+// NATSServers returns the comma separated server URLs to connect to
+func NATSServers(cfg *eirini.RouteEmitterConfig) string {
+	if len(cfg.NatsServers) != 0 {
+		return strings.Join(cfg.NatsServers, ",")
+	}
+
+	return fmt.Sprintf("nats://%s:%d", cfg.NatsIP, cfg.NatsPort)
+}
+
+// NATSOptions translates the config into connection options. Credentials
+// files, NKeys, tokens and passwords are mutually exclusive and are picked in
+// that order. Publishing never fails while reconnecting, as long as the
+// messages fit into the reconnect buffer.
+func NATSOptions(cfg *eirini.RouteEmitterConfig, logger lager.Logger) ([]nats.Option, error) {
+	options := []nats.Option{
+		nats.Name("eirini-route-emitter"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Info("nats-disconnected", lager.Data{"reason": fmt.Sprintf("%v", err)})
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logger.Info("nats-reconnected", lager.Data{"url": nc.ConnectedUrl()})
+		}),
+		nats.ClosedHandler(func(_ *nats.Conn) {
+			logger.Info("nats-connection-closed")
+		}),
+	}
+
+	if cfg.NatsReconnectBufferSizeInBytes != 0 {
+		options = append(options, nats.ReconnectBufSize(cfg.NatsReconnectBufferSizeInBytes))
+	}
+
+	authOption, err := natsAuthOption(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if authOption != nil {
+		options = append(options, authOption)
+	}
+
+	return append(options, natsTLSOptions(cfg.NatsTLS)...), nil
+}
+
+func natsAuthOption(cfg *eirini.RouteEmitterConfig) (nats.Option, error) {
+	switch {
+	case cfg.NatsCredentialsFile != "":
+		return nats.UserCredentials(cfg.NatsCredentialsFile), nil
+	case cfg.NatsNKeySeedFile != "":
+		option, err := nats.NkeyOptionFromSeed(cfg.NatsNKeySeedFile)
+
+		return option, errors.Wrap(err, "failed to load nkey seed")
+	case cfg.NatsToken != "":
+		return nats.Token(cfg.NatsToken), nil
+	case cfg.NatsPassword != "":
+		user := cfg.NatsUser
+		if user == "" {
+			user = defaultNATSUser
+		}
+
+		return nats.UserInfo(user, cfg.NatsPassword), nil
+	default:
+		return nil, nil
+	}
+}
+
+func natsTLSOptions(cfg eirini.NATSTLSConfig) []nats.Option {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	options := []nats.Option{nats.Secure()}
+
+	if cfg.CAPath != "" {
+		options = append(options, nats.RootCAs(cfg.CAPath))
+	}
+
+	if cfg.CertPath != "" && cfg.KeyPath != "" {
+		options = append(options, nats.ClientCert(cfg.CertPath, cfg.KeyPath))
+	}
+
+	return options
+}
+
+// NATSMetrics exposes the state of a NATS connection
+type NATSMetrics struct {
+	conn        *nats.Conn
+	connected   *prometheus.Desc
+	reconnects  *prometheus.Desc
+	buffered    *prometheus.Desc
+	outMessages *prometheus.Desc
+}
+
+func NewNATSMetrics(conn *nats.Conn) *NATSMetrics {
+	newDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(PrometheusNamespace, PrometheusSubsystem, name), help, nil, nil)
+	}
+
+	return &NATSMetrics{
+		conn:        conn,
+		connected:   newDesc("nats_connected", "Whether the connection to NATS is established"),
+		reconnects:  newDesc("nats_reconnects_total", "Number of times the connection to NATS was re-established"),
+		buffered:    newDesc("nats_buffered_bytes", "Bytes waiting in the reconnect buffer to be published"),
+		outMessages: newDesc("nats_published_messages_total", "Number of messages published to NATS"),
+	}
+}
+
+func (m *NATSMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.connected
+	ch <- m.reconnects
+	ch <- m.buffered
+	ch <- m.outMessages
+}
+
+func (m *NATSMetrics) Collect(ch chan<- prometheus.Metric) {
+	connected := 0.0
+	if m.conn.IsConnected() {
+		connected = 1
+	}
+
+	// Buffered only fails once the connection is closed, when nothing is buffered
+	buffered, _ := m.conn.Buffered()
+	stats := m.conn.Stats()
+
+	ch <- prometheus.MustNewConstMetric(m.connected, prometheus.GaugeValue, connected)
+	ch <- prometheus.MustNewConstMetric(m.reconnects, prometheus.CounterValue, float64(stats.Reconnects))
+	ch <- prometheus.MustNewConstMetric(m.buffered, prometheus.GaugeValue, float64(buffered))
+	ch <- prometheus.MustNewConstMetric(m.outMessages, prometheus.CounterValue, float64(stats.OutMsgs))
+}
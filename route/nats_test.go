@@ -0,0 +1,210 @@
+package route_test
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/eirini"
+	. "code.cloudfoundry.org/eirini/route"
+	"code.cloudfoundry.org/lager/lagertest"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	nats "github.com/nats-io/nats.go"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ = Describe("NATS", func() {
+	var (
+		server     *natsserver.Server
+		subscriber *nats.Conn
+		serverOpts natsserver.Options
+		cfg        *eirini.RouteEmitterConfig
+		logger     *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		serverOpts = natstest.DefaultTestOptions
+		serverOpts.Port = natsserver.RANDOM_PORT
+		logger = lagertest.NewTestLogger("nats")
+	})
+
+	JustBeforeEach(func() {
+		server = natstest.RunServer(&serverOpts)
+
+		host, port, err := net.SplitHostPort(server.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		cfg.NatsIP = host
+		cfg.NatsPort, err = strconv.Atoi(port)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if subscriber != nil {
+			subscriber.Close()
+			subscriber = nil
+		}
+		server.Shutdown()
+	})
+
+	subscribe := func() chan *nats.Msg {
+		var err error
+		subscriber, err = nats.Connect(NATSServers(cfg), mustNATSOptions(cfg)...)
+		Expect(err).NotTo(HaveOccurred())
+
+		messages := make(chan *nats.Msg, 1)
+		_, err = subscriber.ChanSubscribe("router.register", messages)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subscriber.Flush()).To(Succeed())
+
+		return messages
+	}
+
+	message := Message{
+		Name:       "app",
+		InstanceID: "app-0",
+		Address:    "10.0.0.1",
+		Port:       8080,
+		Routes:     Routes{RegisteredRoutes: []string{"app.example.com"}},
+	}
+
+	emitRoute := func(registry *prometheus.Registry) Emitter {
+		emitter, err := NewEmitterFromConfig(cfg, registry, logger)
+		Expect(err).NotTo(HaveOccurred())
+
+		emitter.Emit(message)
+
+		return emitter
+	}
+
+	When("authenticating with a password", func() {
+		BeforeEach(func() {
+			serverOpts.Username = "nats"
+			serverOpts.Password = "secret"
+			cfg = &eirini.RouteEmitterConfig{NatsPassword: "secret"}
+		})
+
+		It("publishes routes", func() {
+			messages := subscribe()
+			emitRoute(prometheus.NewRegistry())
+
+			Eventually(messages, 5*time.Second).Should(Receive())
+		})
+
+		It("fails to connect with the wrong password", func() {
+			cfg.NatsPassword = "wrong"
+
+			_, err := NewEmitterFromConfig(cfg, prometheus.NewRegistry(), logger)
+			Expect(err).To(MatchError(ContainSubstring("failed to connect to nats")))
+		})
+	})
+
+	When("authenticating with a token", func() {
+		BeforeEach(func() {
+			serverOpts.Authorization = "the-token"
+			cfg = &eirini.RouteEmitterConfig{NatsToken: "the-token"}
+		})
+
+		It("publishes routes", func() {
+			messages := subscribe()
+			emitRoute(prometheus.NewRegistry())
+
+			Eventually(messages, 5*time.Second).Should(Receive())
+		})
+	})
+
+	When("a list of servers is configured", func() {
+		BeforeEach(func() {
+			cfg = &eirini.RouteEmitterConfig{}
+		})
+
+		It("connects to any of them", func() {
+			cfg.NatsServers = []string{"nats://127.0.0.1:1", "nats://" + server.Addr().String()}
+			Expect(NATSServers(cfg)).To(Equal("nats://127.0.0.1:1,nats://" + server.Addr().String()))
+
+			messages := subscribe()
+			emitRoute(prometheus.NewRegistry())
+
+			Eventually(messages, 5*time.Second).Should(Receive())
+		})
+	})
+
+	When("the nkey seed file does not exist", func() {
+		BeforeEach(func() {
+			cfg = &eirini.RouteEmitterConfig{NatsNKeySeedFile: "/does/not/exist"}
+		})
+
+		It("fails", func() {
+			_, err := NewEmitterFromConfig(cfg, prometheus.NewRegistry(), logger)
+			Expect(err).To(MatchError(ContainSubstring("failed to load nkey seed")))
+		})
+	})
+
+	When("the nats CA cannot be read", func() {
+		BeforeEach(func() {
+			cfg = &eirini.RouteEmitterConfig{NatsTLS: eirini.NATSTLSConfig{Enabled: true, CAPath: "/does/not/exist"}}
+		})
+
+		It("fails", func() {
+			_, err := NewEmitterFromConfig(cfg, prometheus.NewRegistry(), logger)
+			Expect(err).To(MatchError(ContainSubstring("rootCA")))
+		})
+	})
+
+	Describe("metrics", func() {
+		var registry *prometheus.Registry
+
+		BeforeEach(func() {
+			cfg = &eirini.RouteEmitterConfig{}
+			registry = prometheus.NewRegistry()
+		})
+
+		gauge := func(name string) float64 {
+			families, err := registry.Gather()
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, family := range families {
+				if family.GetName() == name {
+					metric := family.GetMetric()[0]
+					if metric.GetGauge() != nil {
+						return metric.GetGauge().GetValue()
+					}
+
+					return metric.GetCounter().GetValue()
+				}
+			}
+
+			Fail("metric not found: " + name)
+
+			return 0
+		}
+
+		It("reports the state of the connection", func() {
+			emitRoute(registry)
+
+			Expect(gauge("eirini_route_emitter_nats_connected")).To(Equal(1.0))
+			Expect(gauge("eirini_route_emitter_nats_published_messages_total")).To(Equal(1.0))
+			Expect(gauge("eirini_route_emitter_nats_reconnects_total")).To(Equal(0.0))
+		})
+
+		It("buffers messages while disconnected", func() {
+			emitter := emitRoute(registry)
+			server.Shutdown()
+			Eventually(func() float64 { return gauge("eirini_route_emitter_nats_connected") }).Should(Equal(0.0))
+
+			emitter.Emit(message)
+
+			Expect(gauge("eirini_route_emitter_nats_buffered_bytes")).To(BeNumerically(">", 0))
+			Expect(logger.LogMessages()).NotTo(ContainElement(ContainSubstring("failed-to-publish")))
+		})
+	})
+})
+
+func mustNATSOptions(cfg *eirini.RouteEmitterConfig) []nats.Option {
+	options, err := NATSOptions(cfg, lagertest.NewTestLogger("subscriber"))
+	Expect(err).NotTo(HaveOccurred())
+
+	return options
+}
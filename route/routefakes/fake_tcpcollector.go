@@ -0,0 +1,107 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package routefakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/eirini/route"
+)
+
+type FakeTCPCollector struct {
+	CollectTCPStub        func() ([]route.TCPMessage, error)
+	collectTCPMutex       sync.RWMutex
+	collectTCPArgsForCall []struct {
+	}
+	collectTCPReturns struct {
+		result1 []route.TCPMessage
+		result2 error
+	}
+	collectTCPReturnsOnCall map[int]struct {
+		result1 []route.TCPMessage
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeTCPCollector) CollectTCP() ([]route.TCPMessage, error) {
+	fake.collectTCPMutex.Lock()
+	ret, specificReturn := fake.collectTCPReturnsOnCall[len(fake.collectTCPArgsForCall)]
+	fake.collectTCPArgsForCall = append(fake.collectTCPArgsForCall, struct {
+	}{})
+	stub := fake.CollectTCPStub
+	fakeReturns := fake.collectTCPReturns
+	fake.recordInvocation("CollectTCP", []interface{}{})
+	fake.collectTCPMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeTCPCollector) CollectTCPCallCount() int {
+	fake.collectTCPMutex.RLock()
+	defer fake.collectTCPMutex.RUnlock()
+	return len(fake.collectTCPArgsForCall)
+}
+
+func (fake *FakeTCPCollector) CollectTCPCalls(stub func() ([]route.TCPMessage, error)) {
+	fake.collectTCPMutex.Lock()
+	defer fake.collectTCPMutex.Unlock()
+	fake.CollectTCPStub = stub
+}
+
+func (fake *FakeTCPCollector) CollectTCPReturns(result1 []route.TCPMessage, result2 error) {
+	fake.collectTCPMutex.Lock()
+	defer fake.collectTCPMutex.Unlock()
+	fake.CollectTCPStub = nil
+	fake.collectTCPReturns = struct {
+		result1 []route.TCPMessage
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeTCPCollector) CollectTCPReturnsOnCall(i int, result1 []route.TCPMessage, result2 error) {
+	fake.collectTCPMutex.Lock()
+	defer fake.collectTCPMutex.Unlock()
+	fake.CollectTCPStub = nil
+	if fake.collectTCPReturnsOnCall == nil {
+		fake.collectTCPReturnsOnCall = make(map[int]struct {
+			result1 []route.TCPMessage
+			result2 error
+		})
+	}
+	fake.collectTCPReturnsOnCall[i] = struct {
+		result1 []route.TCPMessage
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeTCPCollector) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.collectTCPMutex.RLock()
+	defer fake.collectTCPMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeTCPCollector) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ route.TCPCollector = new(FakeTCPCollector)
@@ -0,0 +1,153 @@
+package route
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/eirini"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/tlsconfig"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	DefaultTCPRouteTTLInSecs = 120
+
+	tcpRoutesCreatePath = "/routing/v1/tcp_routes/create"
+	tcpRoutesDeletePath = "/routing/v1/tcp_routes/delete"
+	uaaTokenPath        = "/oauth/token"
+)
+
+//counterfeiter:generate . TCPEmitter
+
+type TCPEmitter interface {
+	Emit(TCPMessage)
+}
+
+// TCPRouteMapping is how the routing API represents a TCP route to a single
+// backend
+type TCPRouteMapping struct {
+	RouterGroupGUID string `json:"router_group_guid"`
+	Port            uint32 `json:"port"`
+	BackendIP       string `json:"backend_ip"`
+	BackendPort     uint32 `json:"backend_port"`
+	InstanceID      string `json:"instance_id,omitempty"`
+	TTL             *int   `json:"ttl,omitempty"`
+}
+
+// RoutingAPIEmitter registers TCP routes with the routing API. Registrations
+// expire after the TTL, so they have to be emitted again periodically
+type RoutingAPIEmitter struct {
+	client    *http.Client
+	apiURL    string
+	ttlInSecs int
+	logger    lager.Logger
+}
+
+func NewRoutingAPIEmitter(client *http.Client, apiURL string, ttlInSecs int, logger lager.Logger) RoutingAPIEmitter {
+	return RoutingAPIEmitter{
+		client:    client,
+		apiURL:    strings.TrimSuffix(apiURL, "/"),
+		ttlInSecs: ttlInSecs,
+		logger:    logger,
+	}
+}
+
+// NewTCPEmitterFromConfig returns an emitter that drops all TCP routes when
+// the routing API is not enabled
+func NewTCPEmitterFromConfig(cfg eirini.RoutingAPIConfig, logger lager.Logger) (TCPEmitter, error) {
+	if !cfg.Enabled {
+		return DiscardTCPEmitter{}, nil
+	}
+
+	httpClient := &http.Client{}
+
+	if cfg.CAPath != "" {
+		tlsConfig, err := tlsconfig.Build(tlsconfig.WithInternalServiceDefaults()).Client(tlsconfig.WithAuthorityFromFile(cfg.CAPath))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to build routing api tls config")
+		}
+
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	oauthConfig := clientcredentials.Config{
+		ClientID:     cfg.ClientName,
+		ClientSecret: cfg.ClientSecret,
+		TokenURL:     strings.TrimSuffix(cfg.UAAURL, "/") + uaaTokenPath,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+
+	ttl := cfg.TCPRouteTTLInSecs
+	if ttl == 0 {
+		ttl = DefaultTCPRouteTTLInSecs
+	}
+
+	return NewRoutingAPIEmitter(oauthConfig.Client(ctx), cfg.URL, ttl, logger.Session("routing-api-emitter")), nil
+}
+
+func (e RoutingAPIEmitter) Emit(route TCPMessage) {
+	if len(route.Address) == 0 {
+		e.logger.Debug("route-address-missing", lager.Data{"app-name": route.Name, "instance-id": route.InstanceID})
+
+		return
+	}
+
+	if len(route.RegisteredRoutes) != 0 {
+		ttl := e.ttlInSecs
+		if err := e.post(tcpRoutesCreatePath, toMappings(route, route.RegisteredRoutes, &ttl)); err != nil {
+			e.logger.Error("failed-to-register-tcp-routes", err, lager.Data{"routes": route.RegisteredRoutes})
+		}
+	}
+
+	if len(route.UnregisteredRoutes) != 0 {
+		if err := e.post(tcpRoutesDeletePath, toMappings(route, route.UnregisteredRoutes, nil)); err != nil {
+			e.logger.Error("failed-to-unregister-tcp-routes", err, lager.Data{"routes": route.UnregisteredRoutes})
+		}
+	}
+}
+
+func (e RoutingAPIEmitter) post(path string, mappings []TCPRouteMapping) error {
+	body, err := json.Marshal(mappings)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal tcp route mappings")
+	}
+
+	resp, err := e.client.Post(e.apiURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to send tcp route mappings")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("routing api responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func toMappings(route TCPMessage, tcpRoutes []TCPRoute, ttl *int) []TCPRouteMapping {
+	mappings := make([]TCPRouteMapping, 0, len(tcpRoutes))
+	for _, r := range tcpRoutes {
+		mappings = append(mappings, TCPRouteMapping{
+			RouterGroupGUID: r.RouterGroupGUID,
+			Port:            r.ExternalPort,
+			BackendIP:       route.Address,
+			BackendPort:     route.Port,
+			InstanceID:      route.InstanceID,
+			TTL:             ttl,
+		})
+	}
+
+	return mappings
+}
+
+type DiscardTCPEmitter struct{}
+
+func (DiscardTCPEmitter) Emit(TCPMessage) {}
@@ -0,0 +1,155 @@
+package route_test
+
+import (
+	"net/http"
+
+	"code.cloudfoundry.org/eirini"
+	. "code.cloudfoundry.org/eirini/route"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("RoutingAPIEmitter", func() {
+	var (
+		server  *ghttp.Server
+		logger  *lagertest.TestLogger
+		emitter RoutingAPIEmitter
+		message TCPMessage
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+		logger = lagertest.NewTestLogger("routing-api")
+		emitter = NewRoutingAPIEmitter(&http.Client{}, server.URL()+"/", 120, logger)
+
+		message = TCPMessage{
+			TCPRoutes: TCPRoutes{
+				RegisteredRoutes:   []TCPRoute{{RouterGroupGUID: "default-tcp", ExternalPort: 1234}},
+				UnregisteredRoutes: []TCPRoute{{RouterGroupGUID: "default-tcp", ExternalPort: 4321}},
+			},
+			Address:    "10.0.0.1",
+			Port:       8080,
+			InstanceID: "dora-0",
+			Name:       "dora-guid",
+		}
+
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/routing/v1/tcp_routes/create"),
+				ghttp.VerifyJSON(`[{
+					"router_group_guid": "default-tcp",
+					"port": 1234,
+					"backend_ip": "10.0.0.1",
+					"backend_port": 8080,
+					"instance_id": "dora-0",
+					"ttl": 120
+				}]`),
+				ghttp.RespondWith(http.StatusCreated, nil),
+			),
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/routing/v1/tcp_routes/delete"),
+				ghttp.VerifyJSON(`[{
+					"router_group_guid": "default-tcp",
+					"port": 4321,
+					"backend_ip": "10.0.0.1",
+					"backend_port": 8080,
+					"instance_id": "dora-0"
+				}]`),
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			),
+		)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should register and unregister the tcp route mappings", func() {
+		emitter.Emit(message)
+		Expect(server.ReceivedRequests()).To(HaveLen(2))
+		Expect(logger.Logs()).To(BeEmpty())
+	})
+
+	When("there are no unregistered routes", func() {
+		BeforeEach(func() {
+			message.UnregisteredRoutes = nil
+		})
+
+		It("should only register routes", func() {
+			emitter.Emit(message)
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		})
+	})
+
+	When("the message is missing an address", func() {
+		BeforeEach(func() {
+			message.Address = ""
+		})
+
+		It("should not call the routing api", func() {
+			emitter.Emit(message)
+			Expect(server.ReceivedRequests()).To(BeEmpty())
+		})
+	})
+
+	When("the routing api rejects the routes", func() {
+		BeforeEach(func() {
+			server.SetHandler(0, ghttp.RespondWith(http.StatusUnauthorized, nil))
+		})
+
+		It("should log the failure", func() {
+			emitter.Emit(message)
+			Expect(logger.Logs()).To(HaveLen(1))
+			Expect(logger.Logs()[0].Message).To(Equal("routing-api.failed-to-register-tcp-routes"))
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue("error", "routing api responded with status 401"))
+		})
+	})
+})
+
+var _ = Describe("NewTCPEmitterFromConfig", func() {
+	It("should discard tcp routes when the routing api is not enabled", func() {
+		emitter, err := NewTCPEmitterFromConfig(eirini.RoutingAPIConfig{}, lagertest.NewTestLogger("routing-api"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(emitter).To(Equal(DiscardTCPEmitter{}))
+	})
+
+	It("should authenticate with UAA before calling the routing api", func() {
+		uaa := ghttp.NewServer()
+		defer uaa.Close()
+		routingAPI := ghttp.NewServer()
+		defer routingAPI.Close()
+
+		uaa.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/oauth/token"),
+			ghttp.VerifyBasicAuth("eirini", "secret"),
+			ghttp.RespondWithJSONEncoded(http.StatusOK, map[string]interface{}{
+				"access_token": "the-token",
+				"token_type":   "bearer",
+				"expires_in":   3600,
+			}),
+		))
+		routingAPI.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/routing/v1/tcp_routes/create"),
+			ghttp.VerifyHeaderKV("Authorization", "Bearer the-token"),
+			ghttp.RespondWith(http.StatusCreated, nil),
+		))
+
+		emitter, err := NewTCPEmitterFromConfig(eirini.RoutingAPIConfig{
+			Enabled:      true,
+			URL:          routingAPI.URL(),
+			UAAURL:       uaa.URL(),
+			ClientName:   "eirini",
+			ClientSecret: "secret",
+		}, lagertest.NewTestLogger("routing-api"))
+		Expect(err).NotTo(HaveOccurred())
+
+		emitter.Emit(TCPMessage{
+			TCPRoutes: TCPRoutes{RegisteredRoutes: []TCPRoute{{RouterGroupGUID: "default-tcp", ExternalPort: 1234}}},
+			Address:   "10.0.0.1",
+			Port:      8080,
+		})
+		Expect(routingAPI.ReceivedRequests()).To(HaveLen(1))
+	})
+})
@@ -1,6 +1,8 @@
 package route
 
 import (
+	"time"
+
 	"code.cloudfoundry.org/eirini/util"
 	"github.com/pkg/errors"
 )
@@ -11,22 +13,72 @@ type Emitter interface {
 	Emit(Message)
 }
 
+// CollectorScheduler periodically emits all routes. When RouteTable is set
+// only the changes since the previous tick are emitted, and every route is
+// registered again on each FullRefreshEvery-th tick. TCP routes are only
+// collected when both TCPCollector and TCPEmitter are set
 type CollectorScheduler struct {
-	Collector Collector
-	Scheduler util.TaskScheduler
-	Emitter   Emitter
+	Collector        Collector
+	Scheduler        util.TaskScheduler
+	Emitter          Emitter
+	RouteTable       *RouteTable
+	FullRefreshEvery uint
+	Metrics          *Metrics
+	TCPCollector     TCPCollector
+	TCPEmitter       TCPEmitter
 }
 
 func (c CollectorScheduler) Start() {
+	var ticks uint
+
 	c.Scheduler.Schedule(func() error {
 		routes, err := c.Collector.Collect()
 		if err != nil {
 			return errors.Wrap(err, "failed to collect routes")
 		}
+
+		if c.RouteTable != nil {
+			routes = c.RouteTable.Sync(routes, c.FullRefreshEvery == 0 || ticks%c.FullRefreshEvery == 0)
+			ticks++
+		}
+
+		start := time.Now()
 		for _, r := range routes {
 			c.Emitter.Emit(r)
 		}
+		c.Metrics.recordSync(c.tableSize(len(routes)), len(routes), time.Since(start))
+
+		if c.TCPCollector == nil || c.TCPEmitter == nil {
+			return nil
+		}
+
+		tcpRoutes, err := c.TCPCollector.CollectTCP()
+		if err != nil {
+			return errors.Wrap(err, "failed to collect tcp routes")
+		}
+		for _, r := range tcpRoutes {
+			c.TCPEmitter.Emit(r)
+		}
 
 		return nil
 	})
 }
+
+func (c CollectorScheduler) tableSize(emitted int) int {
+	if c.RouteTable == nil {
+		return emitted
+	}
+
+	return c.RouteTable.Size()
+}
+
+// FullRefreshTicks converts the full refresh period into a number of ticks of
+// the emit period, for FullRefreshEvery, rounding down so that routes are
+// never refreshed less often than configured
+func FullRefreshTicks(fullRefreshPeriod, emitPeriod uint) uint {
+	if fullRefreshPeriod <= emitPeriod {
+		return 1
+	}
+
+	return fullRefreshPeriod / emitPeriod
+}
@@ -8,6 +8,8 @@ import (
 	"code.cloudfoundry.org/eirini/util/utilfakes"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 var _ = Describe("Scheduler", func() {
@@ -47,6 +49,85 @@ var _ = Describe("Scheduler", func() {
 		Expect(emitter.EmitArgsForCall(1)).To(Equal(Message{Name: "zashto"}))
 	})
 
+	When("a route table is used", func() {
+		var task func() error
+
+		BeforeEach(func() {
+			collectorScheduler.RouteTable = NewRouteTable()
+			collectorScheduler.FullRefreshEvery = 3
+			collectorScheduler.Metrics = NewMetrics()
+			collector.CollectReturns([]Message{
+				{Name: "ama", InstanceID: "ama-0", Address: "10.0.0.1", Port: 8080, Routes: Routes{RegisteredRoutes: []string{"ama.example.com"}}},
+			}, nil)
+
+			collectorScheduler.Start()
+			task = scheduler.ScheduleArgsForCall(0)
+		})
+
+		It("should only emit routes which changed since the last tick", func() {
+			Expect(task()).To(Succeed())
+			Expect(emitter.EmitCallCount()).To(Equal(1))
+
+			Expect(task()).To(Succeed())
+			Expect(task()).To(Succeed())
+			Expect(emitter.EmitCallCount()).To(Equal(1))
+		})
+
+		It("should emit all routes on every full refresh", func() {
+			for i := 0; i < 4; i++ {
+				Expect(task()).To(Succeed())
+			}
+
+			Expect(emitter.EmitCallCount()).To(Equal(2))
+			Expect(emitter.EmitArgsForCall(1).RegisteredRoutes).To(ConsistOf("ama.example.com"))
+		})
+
+		It("should record the size of the route table", func() {
+			Expect(task()).To(Succeed())
+
+			metrics := make(chan prometheus.Metric, 3)
+			collectorScheduler.Metrics.Collect(metrics)
+
+			var tableSize dto.Metric
+			Expect((<-metrics).Write(&tableSize)).To(Succeed())
+			Expect(tableSize.GetGauge().GetValue()).To(Equal(1.0))
+		})
+	})
+
+	When("tcp routes are collected", func() {
+		var (
+			tcpCollector *routefakes.FakeTCPCollector
+			tcpEmitter   *routefakes.FakeTCPEmitter
+		)
+
+		BeforeEach(func() {
+			tcpCollector = new(routefakes.FakeTCPCollector)
+			tcpEmitter = new(routefakes.FakeTCPEmitter)
+			collectorScheduler.TCPCollector = tcpCollector
+			collectorScheduler.TCPEmitter = tcpEmitter
+		})
+
+		It("should emit the collected tcp routes", func() {
+			tcpCollector.CollectTCPReturns([]TCPMessage{{Name: "ama"}}, nil)
+
+			collectorScheduler.Start()
+			task := scheduler.ScheduleArgsForCall(0)
+
+			Expect(task()).To(Succeed())
+			Expect(tcpEmitter.EmitCallCount()).To(Equal(1))
+			Expect(tcpEmitter.EmitArgsForCall(0)).To(Equal(TCPMessage{Name: "ama"}))
+		})
+
+		It("should propagate tcp collection errors to the Scheduler", func() {
+			tcpCollector.CollectTCPReturns(nil, errors.New("collector failure"))
+
+			collectorScheduler.Start()
+			task := scheduler.ScheduleArgsForCall(0)
+
+			Expect(task()).To(MatchError(Equal("failed to collect tcp routes: collector failure")))
+		})
+	})
+
 	It("should propagate errors to the Scheduler", func() {
 		collector.CollectReturns(nil, errors.New("collector failure"))
 
@@ -57,3 +138,14 @@ var _ = Describe("Scheduler", func() {
 		Expect(task()).To(MatchError(Equal("failed to collect routes: collector failure")))
 	})
 })
+
+var _ = Describe("FullRefreshTicks", func() {
+	It("rounds the full refresh period down to whole emit periods", func() {
+		Expect(FullRefreshTicks(30, 4)).To(Equal(uint(7)))
+	})
+
+	It("refreshes on every tick when the period is not longer than the emit period", func() {
+		Expect(FullRefreshTicks(5, 5)).To(Equal(uint(1)))
+		Expect(FullRefreshTicks(0, 5)).To(Equal(uint(1)))
+	})
+})
@@ -0,0 +1,172 @@
+package route
+
+import "sort"
+
+type tableKey struct {
+	name       string
+	instanceID string
+	port       uint32
+}
+
+type tableEntry struct {
+	address             string
+	tlsPort             uint32
+	serverCertDomainSAN string
+	hostnames           []string
+}
+
+func (e tableEntry) sameBackend(other tableEntry) bool {
+	return e.address == other.address &&
+		e.tlsPort == other.tlsPort &&
+		e.serverCertDomainSAN == other.serverCertDomainSAN
+}
+
+// RouteTable remembers the routes of every app instance port as of the last
+// sync, so that only the differences need to be sent to the routers
+type RouteTable struct {
+	entries map[tableKey]tableEntry
+}
+
+func NewRouteTable() *RouteTable {
+	return &RouteTable{entries: map[tableKey]tableEntry{}}
+}
+
+func (t *RouteTable) Size() int {
+	return len(t.entries)
+}
+
+// Sync replaces the content of the table with the collected routes and
+// returns the messages which bring the routers from the previous state to the
+// new one. All hostnames of an instance port are batched into a single
+// message. On a full refresh every route in the table is registered again,
+// which keeps the routers from pruning them.
+func (t *RouteTable) Sync(collected []Message, fullRefresh bool) []Message {
+	entries := toTableEntries(collected)
+	messages := []Message{}
+
+	for _, key := range sortedKeys(t.entries, entries) {
+		oldEntry, existed := t.entries[key]
+		newEntry, exists := entries[key]
+
+		switch {
+		case !exists:
+			messages = append(messages, toMessage(key, oldEntry, nil, oldEntry.hostnames))
+		case existed && !oldEntry.sameBackend(newEntry):
+			messages = append(messages,
+				toMessage(key, oldEntry, nil, oldEntry.hostnames),
+				toMessage(key, newEntry, newEntry.hostnames, nil),
+			)
+		case !existed || fullRefresh:
+			messages = append(messages, toMessage(key, newEntry, newEntry.hostnames, difference(oldEntry.hostnames, newEntry.hostnames)))
+		default:
+			added := difference(newEntry.hostnames, oldEntry.hostnames)
+			removed := difference(oldEntry.hostnames, newEntry.hostnames)
+
+			if len(added) != 0 || len(removed) != 0 {
+				messages = append(messages, toMessage(key, newEntry, added, removed))
+			}
+		}
+	}
+
+	t.entries = entries
+
+	return messages
+}
+
+func toTableEntries(messages []Message) map[tableKey]tableEntry {
+	entries := map[tableKey]tableEntry{}
+
+	for _, m := range messages {
+		key := tableKey{name: m.Name, instanceID: m.InstanceID, port: m.Port}
+		entry := entries[key]
+		entry.address = m.Address
+		entry.tlsPort = m.TLSPort
+		entry.serverCertDomainSAN = m.ServerCertDomainSAN
+
+		for _, hostname := range m.RegisteredRoutes {
+			if !contains(entry.hostnames, hostname) {
+				entry.hostnames = append(entry.hostnames, hostname)
+			}
+		}
+
+		entries[key] = entry
+	}
+
+	for key, entry := range entries {
+		sort.Strings(entry.hostnames)
+		entries[key] = entry
+	}
+
+	return entries
+}
+
+func toMessage(key tableKey, entry tableEntry, registered, unregistered []string) Message {
+	message := Message{
+		Name:                key.name,
+		InstanceID:          key.instanceID,
+		Port:                key.port,
+		Address:             entry.address,
+		TLSPort:             entry.tlsPort,
+		ServerCertDomainSAN: entry.serverCertDomainSAN,
+	}
+
+	if len(registered) != 0 {
+		message.RegisteredRoutes = registered
+	}
+
+	if len(unregistered) != 0 {
+		message.UnregisteredRoutes = unregistered
+	}
+
+	return message
+}
+
+func difference(hostnames, other []string) []string {
+	result := []string{}
+
+	for _, h := range hostnames {
+		if !contains(other, h) {
+			result = append(result, h)
+		}
+	}
+
+	return result
+}
+
+func sortedKeys(tables ...map[tableKey]tableEntry) []tableKey {
+	seen := map[tableKey]bool{}
+	keys := []tableKey{}
+
+	for _, table := range tables {
+		for key := range table {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+
+		if keys[i].instanceID != keys[j].instanceID {
+			return keys[i].instanceID < keys[j].instanceID
+		}
+
+		return keys[i].port < keys[j].port
+	})
+
+	return keys
+}
+
+func contains(hostnames []string, hostname string) bool {
+	for _, h := range hostnames {
+		if h == hostname {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,119 @@
+package route_test
+
+import (
+	. "code.cloudfoundry.org/eirini/route"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteTable", func() {
+	var (
+		table     *RouteTable
+		collected []Message
+	)
+
+	message := func(instanceID, address string, port uint32, hostname string) Message {
+		return Message{
+			Name:       "app-guid",
+			InstanceID: instanceID,
+			Address:    address,
+			Port:       port,
+			Routes:     Routes{RegisteredRoutes: []string{hostname}},
+		}
+	}
+
+	BeforeEach(func() {
+		table = NewRouteTable()
+		collected = []Message{
+			message("app-0", "10.0.0.1", 8080, "foo.example.com"),
+			message("app-0", "10.0.0.1", 8080, "bar.example.com"),
+			message("app-0", "10.0.0.1", 9090, "admin.example.com"),
+			message("app-1", "10.0.0.2", 8080, "foo.example.com"),
+		}
+	})
+
+	It("batches the hostnames of an instance port into a single message", func() {
+		Expect(table.Sync(collected, false)).To(Equal([]Message{
+			{
+				Name: "app-guid", InstanceID: "app-0", Address: "10.0.0.1", Port: 8080,
+				Routes: Routes{RegisteredRoutes: []string{"bar.example.com", "foo.example.com"}},
+			},
+			{
+				Name: "app-guid", InstanceID: "app-0", Address: "10.0.0.1", Port: 9090,
+				Routes: Routes{RegisteredRoutes: []string{"admin.example.com"}},
+			},
+			{
+				Name: "app-guid", InstanceID: "app-1", Address: "10.0.0.2", Port: 8080,
+				Routes: Routes{RegisteredRoutes: []string{"foo.example.com"}},
+			},
+		}))
+		Expect(table.Size()).To(Equal(3))
+	})
+
+	When("the routes were already synced", func() {
+		BeforeEach(func() {
+			table.Sync(collected, false)
+		})
+
+		It("emits nothing when nothing changed", func() {
+			Expect(table.Sync(collected, false)).To(BeEmpty())
+		})
+
+		It("registers everything again on a full refresh", func() {
+			Expect(table.Sync(collected, true)).To(HaveLen(3))
+		})
+
+		It("only emits the hostnames that changed", func() {
+			collected[1] = message("app-0", "10.0.0.1", 8080, "baz.example.com")
+
+			Expect(table.Sync(collected, false)).To(Equal([]Message{
+				{
+					Name: "app-guid", InstanceID: "app-0", Address: "10.0.0.1", Port: 8080,
+					Routes: Routes{
+						RegisteredRoutes:   []string{"baz.example.com"},
+						UnregisteredRoutes: []string{"bar.example.com"},
+					},
+				},
+			}))
+		})
+
+		It("unregisters removed hostnames on a full refresh", func() {
+			collected = collected[1:]
+
+			Expect(table.Sync(collected, true)).To(ContainElement(Message{
+				Name: "app-guid", InstanceID: "app-0", Address: "10.0.0.1", Port: 8080,
+				Routes: Routes{
+					RegisteredRoutes:   []string{"bar.example.com"},
+					UnregisteredRoutes: []string{"foo.example.com"},
+				},
+			}))
+		})
+
+		It("unregisters the routes of instances that are gone", func() {
+			collected = collected[:3]
+
+			Expect(table.Sync(collected, false)).To(Equal([]Message{
+				{
+					Name: "app-guid", InstanceID: "app-1", Address: "10.0.0.2", Port: 8080,
+					Routes: Routes{UnregisteredRoutes: []string{"foo.example.com"}},
+				},
+			}))
+			Expect(table.Size()).To(Equal(2))
+		})
+
+		It("moves the routes of an instance whose address changed", func() {
+			collected[3] = message("app-1", "10.0.0.3", 8080, "foo.example.com")
+
+			Expect(table.Sync(collected, false)).To(Equal([]Message{
+				{
+					Name: "app-guid", InstanceID: "app-1", Address: "10.0.0.2", Port: 8080,
+					Routes: Routes{UnregisteredRoutes: []string{"foo.example.com"}},
+				},
+				{
+					Name: "app-guid", InstanceID: "app-1", Address: "10.0.0.3", Port: 8080,
+					Routes: Routes{RegisteredRoutes: []string{"foo.example.com"}},
+				},
+			}))
+		})
+	})
+})
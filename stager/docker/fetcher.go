@@ -2,15 +2,51 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
+	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/containers/image/docker"
 	"github.com/containers/image/image"
+	"github.com/containers/image/manifest"
 	"github.com/containers/image/types"
+	digest "github.com/opencontainers/go-digest"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
 
-func Fetch(dockerRef string, sysCtx types.SystemContext) (*v1.ImageConfig, error) {
+const defaultOS = "linux"
+
+// ImageMetadata is what Fetch finds out about an image
+type ImageMetadata struct {
+	Config v1.ImageConfig
+	// Digest is the digest of the manifest the reference resolves to, which
+	// is the one of the manifest list for multi-arch images
+	Digest string
+	// Architectures are the architectures the image is available for on the
+	// target OS, when it says so
+	Architectures []string
+}
+
+type manifestList struct {
+	Manifests []manifestListEntry `json:"manifests"`
+}
+
+type manifestListEntry struct {
+	Digest   string `json:"digest"`
+	Platform struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+		Variant      string `json:"variant"`
+	} `json:"platform"`
+}
+
+// Fetch returns the metadata of the image. For manifest lists it picks the
+// image of the OSChoice and ArchitectureChoice of the system context, which
+// default to linux and the architecture opi runs on, or the only image of
+// the target OS when there is just one architecture
+func Fetch(dockerRef string, sysCtx types.SystemContext) (*ImageMetadata, error) {
 	ref, err := docker.ParseReference(dockerRef)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse docker reference")
@@ -24,7 +60,33 @@ func Fetch(dockerRef string, sysCtx types.SystemContext) (*v1.ImageConfig, error
 	}
 	defer imgSrc.Close()
 
-	img, err := image.FromUnparsedImage(ctx, &sysCtx, image.UnparsedInstance(imgSrc, nil))
+	rawManifest, mimeType, err := imgSrc.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get manifest")
+	}
+
+	manifestDigest, err := manifest.Digest(rawManifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute manifest digest")
+	}
+
+	var (
+		instanceDigest *digest.Digest
+		architectures  []string
+	)
+
+	if isManifestList(rawManifest, mimeType) {
+		var instance digest.Digest
+
+		instance, architectures, err = chooseInstance(rawManifest, targetOS(sysCtx), targetArchitecture(sysCtx))
+		if err != nil {
+			return nil, err
+		}
+
+		instanceDigest = &instance
+	}
+
+	img, err := image.FromUnparsedImage(ctx, &sysCtx, image.UnparsedInstance(imgSrc, instanceDigest))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get image")
 	}
@@ -34,5 +96,115 @@ func Fetch(dockerRef string, sysCtx types.SystemContext) (*v1.ImageConfig, error
 		return nil, errors.Wrap(err, "failed to get oci config")
 	}
 
-	return &imgV1.Config, nil
+	if instanceDigest == nil && imgV1.Architecture != "" {
+		architectures = []string{imgV1.Architecture}
+	}
+
+	return &ImageMetadata{
+		Config:        imgV1.Config,
+		Digest:        manifestDigest.String(),
+		Architectures: architectures,
+	}, nil
+}
+
+func isManifestList(rawManifest []byte, mimeType string) bool {
+	if mimeType == "" {
+		mimeType = manifest.GuessMIMEType(rawManifest)
+	}
+
+	return mimeType == manifest.DockerV2ListMediaType || mimeType == v1.MediaTypeImageIndex
+}
+
+// chooseInstance returns the digest of the image of the manifest list for the
+// platform, along with the architectures available for its OS
+func chooseInstance(rawManifest []byte, os, architecture string) (digest.Digest, []string, error) {
+	var list manifestList
+	if err := json.Unmarshal(rawManifest, &list); err != nil {
+		return "", nil, errors.Wrap(err, "failed to parse manifest list")
+	}
+
+	var candidates []manifestListEntry
+
+	for _, entry := range list.Manifests {
+		if entry.Platform.OS == os {
+			candidates = append(candidates, entry)
+		}
+	}
+
+	architectures := distinctArchitectures(candidates)
+
+	for _, entry := range candidates {
+		if entry.Platform.Architecture == architecture {
+			return digest.Digest(entry.Digest), architectures, nil
+		}
+	}
+
+	if len(architectures) == 1 {
+		return digest.Digest(candidates[0].Digest), architectures, nil
+	}
+
+	return "", nil, errors.Errorf("image is not available for %s/%s, available platforms: %s",
+		os, architecture, strings.Join(platformsOf(list.Manifests), ", "))
+}
+
+func distinctArchitectures(entries []manifestListEntry) []string {
+	seen := map[string]bool{}
+	architectures := []string{}
+
+	for _, entry := range entries {
+		arch := entry.Platform.Architecture
+		if arch == "" || arch == "unknown" || seen[arch] {
+			continue
+		}
+
+		seen[arch] = true
+
+		architectures = append(architectures, arch)
+	}
+
+	sort.Strings(architectures)
+
+	return architectures
+}
+
+// platformsOf lists the platforms of a manifest list as os/arch[/variant],
+// leaving out entries which are not images, such as attestations
+func platformsOf(entries []manifestListEntry) []string {
+	platforms := []string{}
+
+	for _, entry := range entries {
+		p := entry.Platform
+		if p.OS == "" || p.OS == "unknown" {
+			continue
+		}
+
+		platform := p.OS + "/" + p.Architecture
+		if p.Variant != "" {
+			platform += "/" + p.Variant
+		}
+
+		platforms = append(platforms, platform)
+	}
+
+	if len(platforms) == 0 {
+		return []string{"none"}
+	}
+
+	return platforms
+}
+
+func targetOS(sysCtx types.SystemContext) string {
+	if sysCtx.OSChoice != "" {
+		return sysCtx.OSChoice
+	}
+
+	return defaultOS
+}
+
+func targetArchitecture(sysCtx types.SystemContext) string {
+	if sysCtx.ArchitectureChoice != "" {
+		return sysCtx.ArchitectureChoice
+	}
+
+	return runtime.GOARCH
 }
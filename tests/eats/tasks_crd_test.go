@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"code.cloudfoundry.org/eirini/k8s/jobs"
+	"code.cloudfoundry.org/eirini/k8s/shared"
+	"code.cloudfoundry.org/eirini/k8s/utils/dockerutils"
 	eiriniv1 "code.cloudfoundry.org/eirini/pkg/apis/eirini/v1"
 	"code.cloudfoundry.org/eirini/tests"
 	. "github.com/onsi/ginkgo"
@@ -132,7 +134,15 @@ var _ = Describe("Tasks CRD [needs-logs-for: eirini-api, eirini-controller]", fu
 
 			taskContainer := job.Spec.Template.Spec.Containers[0]
 			Expect(taskContainer.Image).To(Equal("eirini/busybox"))
-			Expect(taskContainer.Env).To(ContainElement(corev1.EnvVar{Name: "FOO", Value: "BAR"}))
+			Expect(taskContainer.Env).To(ContainElement(corev1.EnvVar{
+				Name: "FOO",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: shared.EnvSecretName(job.Name, map[string]string{"FOO": "BAR"})},
+						Key:                  "FOO",
+					},
+				},
+			}))
 			Expect(taskContainer.Command).To(Equal([]string{"sh", "-c", "sleep 1"}))
 
 			Eventually(getJobConditions).Should(ConsistOf(MatchFields(IgnoreExtras, Fields{
@@ -174,6 +184,37 @@ var _ = Describe("Tasks CRD [needs-logs-for: eirini-api, eirini-controller]", fu
 				Expect(secret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
 				Expect(secret.Data).To(HaveKey(".dockerconfigjson"))
 			})
+
+			When("the task refers to a registry secret instead", func() {
+				BeforeEach(func() {
+					dockerConfigJSON, err := dockerutils.NewDockerConfig(
+						"index.docker.io/v1/",
+						"eiriniuser",
+						tests.GetEiriniDockerHubPassword(),
+					).JSON()
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = fixture.Clientset.CoreV1().Secrets(fixture.Namespace).Create(context.Background(), &corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: "my-registry-secret"},
+						Type:       corev1.SecretTypeDockerConfigJson,
+						StringData: map[string]string{dockerutils.DockerConfigKey: dockerConfigJSON},
+					}, metav1.CreateOptions{})
+					Expect(err).NotTo(HaveOccurred())
+
+					task.Spec.PrivateRegistry = &eiriniv1.PrivateRegistry{SecretName: "my-registry-secret"}
+				})
+
+				It("runs the job with the referenced secret", func() {
+					Eventually(listTaskJobs).Should(HaveLen(1))
+					Expect(listTaskJobs()[0].Spec.Template.Spec.ImagePullSecrets).To(ContainElement(
+						corev1.LocalObjectReference{Name: "my-registry-secret"},
+					))
+					Eventually(getJobConditions).Should(ConsistOf(MatchFields(IgnoreExtras, Fields{
+						"Type":   Equal(batchv1.JobComplete),
+						"Status": Equal(corev1.ConditionTrue),
+					})))
+				})
+			})
 		})
 	})
 
@@ -21,6 +21,7 @@ import (
 type EiriniBinaries struct {
 	OPI                      Binary `json:"opi"`
 	RouteCollector           Binary `json:"route_collector"`
+	RouteEmitter             Binary `json:"route_emitter"`
 	MetricsCollector         Binary `json:"metrics_collector"`
 	RouteStatefulsetInformer Binary `json:"route_stateful_set_informer"`
 	RoutePodInformer         Binary `json:"route_pod_informer"`
@@ -28,6 +29,8 @@ type EiriniBinaries struct {
 	TaskReporter             Binary `json:"task_reporter"`
 	EiriniController         Binary `json:"eirini_controller"`
 	InstanceIndexEnvInjector Binary `json:"instance_index_env_injector"`
+	LogForwarder             Binary `json:"log_forwarder"`
+	PolicySync               Binary `json:"policy_sync"`
 	ExternalBinsPath         bool
 	BinsPath                 string
 }
@@ -37,6 +40,7 @@ func NewEiriniBinaries() EiriniBinaries {
 	bins.setBinsPath()
 	bins.OPI = NewBinary("code.cloudfoundry.org/eirini/cmd/opi", bins.BinsPath, []string{"connect"})
 	bins.RouteCollector = NewBinary("code.cloudfoundry.org/eirini/cmd/route-collector", bins.BinsPath, []string{})
+	bins.RouteEmitter = NewBinary("code.cloudfoundry.org/eirini/cmd/route-emitter", bins.BinsPath, []string{})
 	bins.MetricsCollector = NewBinary("code.cloudfoundry.org/eirini/cmd/metrics-collector", bins.BinsPath, []string{})
 	bins.RouteStatefulsetInformer = NewBinary("code.cloudfoundry.org/eirini/cmd/route-statefulset-informer", bins.BinsPath, []string{})
 	bins.RoutePodInformer = NewBinary("code.cloudfoundry.org/eirini/cmd/route-pod-informer", bins.BinsPath, []string{})
@@ -44,6 +48,8 @@ func NewEiriniBinaries() EiriniBinaries {
 	bins.TaskReporter = NewBinary("code.cloudfoundry.org/eirini/cmd/task-reporter", bins.BinsPath, []string{})
 	bins.EiriniController = NewBinary("code.cloudfoundry.org/eirini/cmd/eirini-controller", bins.BinsPath, []string{})
 	bins.InstanceIndexEnvInjector = NewBinary("code.cloudfoundry.org/eirini/cmd/instance-index-env-injector", bins.BinsPath, []string{})
+	bins.LogForwarder = NewBinary("code.cloudfoundry.org/eirini/cmd/log-forwarder", bins.BinsPath, []string{})
+	bins.PolicySync = NewBinary("code.cloudfoundry.org/eirini/cmd/policy-sync", bins.BinsPath, []string{})
 
 	return bins
 }
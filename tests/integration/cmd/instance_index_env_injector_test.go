@@ -100,6 +100,57 @@ var _ = Describe("InstanceIndexEnvInjector", func() {
 			Consistently(session).ShouldNot(gexec.Exit())
 		})
 
+		When("all env mutators are enabled", func() {
+			BeforeEach(func() {
+				config.EnvMutators = []string{"instance_index", "vcap_application", "instance_address"}
+			})
+
+			It("registers a webhook per mutator", func() {
+				Eventually(getHook, "20s").Should(Succeed())
+				Expect(hook.Webhooks).To(HaveLen(3))
+			})
+		})
+
+		When("an env mutator is unknown", func() {
+			BeforeEach(func() {
+				config.EnvMutators = []string{"instance_index", "foo"}
+			})
+
+			It("fails", func() {
+				Eventually(session, "10s").Should(gexec.Exit())
+				Expect(session.ExitCode()).NotTo(BeZero())
+				Expect(session.Err).To(gbytes.Say(`failed to create env mutator: unknown env mutator "foo"`))
+			})
+		})
+
+		When("instance identity is enabled without a CA", func() {
+			BeforeEach(func() {
+				config.InstanceIdentity = eirini.InstanceIdentityConfig{
+					Enabled:    true,
+					CACertPath: "/does/not/exist",
+					CAKeyPath:  "/does/not/exist",
+				}
+			})
+
+			It("fails", func() {
+				Eventually(session, "10s").Should(gexec.Exit())
+				Expect(session.ExitCode()).NotTo(BeZero())
+				Expect(session.Err).To(gbytes.Say("failed to read instance identity CA certificate"))
+			})
+		})
+
+		When("the credential interpolation backend is unknown", func() {
+			BeforeEach(func() {
+				config.CredentialInterpolation.Backend = "vault"
+			})
+
+			It("fails", func() {
+				Eventually(session, "10s").Should(gexec.Exit())
+				Expect(session.ExitCode()).NotTo(BeZero())
+				Expect(session.Err).To(gbytes.Say(`failed to create credential resolver: unknown credential backend "vault"`))
+			})
+		})
+
 		When("the config file doesn't exist", func() {
 			It("exits reporting missing config file", func() {
 				session = eiriniBins.InstanceIndexEnvInjector.Restart("/does/not/exist", session)
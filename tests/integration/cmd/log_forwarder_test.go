@@ -0,0 +1,68 @@
+package cmd_test
+
+import (
+	"os"
+
+	"code.cloudfoundry.org/eirini"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("LogForwarder", func() {
+	var (
+		config         *eirini.LogForwarderConfig
+		configFilePath string
+		session        *gexec.Session
+	)
+
+	BeforeEach(func() {
+		config = &eirini.LogForwarderConfig{
+			KubeConfig: eirini.KubeConfig{
+				ConfigPath: pathToTestFixture("kube.conf"),
+			},
+			WorkloadsNamespace:  fixture.Namespace,
+			LoggregatorCAPath:   pathToTestFixture("cert"),
+			LoggregatorCertPath: pathToTestFixture("cert"),
+			LoggregatorKeyPath:  pathToTestFixture("key"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		session, configFilePath = eiriniBins.LogForwarder.Run(config)
+	})
+
+	AfterEach(func() {
+		if configFilePath != "" {
+			Expect(os.Remove(configFilePath)).To(Succeed())
+		}
+		if session != nil {
+			Eventually(session.Kill()).Should(gexec.Exit())
+		}
+	})
+
+	It("should be able to start properly", func() {
+		Consistently(session, "5s").ShouldNot(gexec.Exit())
+	})
+
+	When("the config file doesn't exist", func() {
+		It("exits reporting missing config file", func() {
+			session = eiriniBins.LogForwarder.Restart("/does/not/exist", session)
+			Eventually(session).Should(gexec.Exit())
+			Expect(session.ExitCode).ToNot(BeZero())
+			Expect(session.Err).To(gbytes.Say("Failed to read config file: failed to read file"))
+		})
+	})
+
+	When("the loggregator CA file is missing", func() {
+		BeforeEach(func() {
+			config.LoggregatorCAPath = "/somewhere/over/the/rainbow"
+		})
+
+		It("should exit with a useful error message", func() {
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).Should(gbytes.Say(`"Loggregator CA" file at "/somewhere/over/the/rainbow" does not exist`))
+		})
+	})
+})
@@ -1,6 +1,8 @@
 package cmd_test
 
 import (
+	"fmt"
+	"net/http"
 	"os"
 
 	"code.cloudfoundry.org/eirini"
@@ -105,4 +107,36 @@ var _ = Describe("MetricsCollector", func() {
 			Expect(session.Err).Should(gbytes.Say(`Failed to create loggregator tls config: cannot parse ca cert`))
 		})
 	})
+
+	When("only the prometheus exporter is enabled", func() {
+		BeforeEach(func() {
+			config.LoggregatorDisabled = true
+			config.LoggregatorCAPath = "/somewhere/over/the/rainbow"
+			config.PrometheusEnabled = true
+			config.PrometheusPort = fixture.NextAvailablePort()
+		})
+
+		It("serves the metrics endpoint", func() {
+			Eventually(func() (int, error) {
+				resp, err := http.Get(fmt.Sprintf("http://localhost:%d/metrics", config.PrometheusPort))
+				if err != nil {
+					return 0, err
+				}
+				defer resp.Body.Close()
+
+				return resp.StatusCode, nil
+			}).Should(Equal(http.StatusOK))
+		})
+	})
+
+	When("all emitters are disabled", func() {
+		BeforeEach(func() {
+			config.LoggregatorDisabled = true
+		})
+
+		It("should exit with a useful error message", func() {
+			Eventually(session).Should(gexec.Exit(1))
+			Expect(session.Err).Should(gbytes.Say("No metrics emitters configured"))
+		})
+	})
 })
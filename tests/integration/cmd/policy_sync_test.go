@@ -0,0 +1,95 @@
+package cmd_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"code.cloudfoundry.org/eirini"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+	"github.com/onsi/gomega/ghttp"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("PolicySync", func() {
+	var (
+		config         *eirini.PolicySyncConfig
+		configFilePath string
+		session        *gexec.Session
+		policyServer   *ghttp.Server
+	)
+
+	BeforeEach(func() {
+		policyServer = ghttp.NewServer()
+		policyServer.RouteToHandler("GET", "/networking/v1/internal/policies", ghttp.RespondWith(http.StatusOK, `{
+			"total_policies": 1,
+			"policies": [{
+				"source": {"id": "frontend-guid"},
+				"destination": {"id": "backend-guid", "protocol": "tcp", "ports": {"start": 8080, "end": 8080}}
+			}]
+		}`))
+
+		config = &eirini.PolicySyncConfig{
+			KubeConfig: eirini.KubeConfig{
+				ConfigPath: fixture.KubeConfigPath,
+			},
+			PolicyServerAddress:     policyServer.URL(),
+			PolicyServerTLSDisabled: true,
+			SyncPeriodInSeconds:     1,
+			WorkloadsNamespace:      fixture.Namespace,
+		}
+	})
+
+	JustBeforeEach(func() {
+		session, configFilePath = eiriniBins.PolicySync.Run(config)
+	})
+
+	AfterEach(func() {
+		policyServer.Close()
+
+		if configFilePath != "" {
+			Expect(os.Remove(configFilePath)).To(Succeed())
+		}
+		if session != nil {
+			Eventually(session.Kill()).Should(gexec.Exit())
+		}
+	})
+
+	It("creates network policies for the policies of the policy server", func() {
+		Eventually(func() ([]networkingv1.NetworkPolicy, error) {
+			list, err := fixture.Clientset.NetworkingV1().NetworkPolicies(fixture.Namespace).List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+
+			return list.Items, nil
+		}).Should(ContainElement(WithTransform(func(p networkingv1.NetworkPolicy) string {
+			return p.Name
+		}, Equal("c2c-backend-guid"))))
+	})
+
+	When("the config file doesn't exist", func() {
+		It("exits reporting missing config file", func() {
+			session = eiriniBins.PolicySync.Restart("/does/not/exist", session)
+			Eventually(session).Should(gexec.Exit())
+			Expect(session.ExitCode).ToNot(BeZero())
+			Expect(session.Err).To(gbytes.Say("Failed to read config file: failed to read file"))
+		})
+	})
+
+	When("the policy server address is missing", func() {
+		BeforeEach(func() {
+			config.PolicyServerAddress = ""
+		})
+
+		It("fails", func() {
+			Eventually(session).Should(gexec.Exit())
+			Expect(session.ExitCode()).NotTo(BeZero())
+			Expect(session.Err).To(gbytes.Say("policy_server_address is required"))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package cmd_test
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"code.cloudfoundry.org/eirini"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/gexec"
+)
+
+var _ = Describe("RouteEmitter", func() {
+	var (
+		config         *eirini.RouteEmitterConfig
+		configFilePath string
+		session        *gexec.Session
+
+		natsServerOpts natsserver.Options
+		natsServer     *natsserver.Server
+	)
+
+	BeforeEach(func() {
+		natsServerOpts = natstest.DefaultTestOptions
+		natsServerOpts.Username = "nats"
+		natsServerOpts.Password = "password"
+		natsServerOpts.Port = fixture.NextAvailablePort()
+		natsServer = natstest.RunServer(&natsServerOpts)
+
+		config = defaultRouteEmitterConfig(natsServerOpts)
+		config.ConfigPath = fixture.KubeConfigPath
+		config.WorkloadsNamespace = fixture.Namespace
+		config.LeaderElectionID = fmt.Sprintf("test-route-emitter-%d", GinkgoParallelNode())
+		config.LeaderElectionNamespace = fixture.Namespace
+		config.HealthProbePort = fixture.NextAvailablePort()
+	})
+
+	JustBeforeEach(func() {
+		session, configFilePath = eiriniBins.RouteEmitter.Run(config)
+	})
+
+	AfterEach(func() {
+		natsServer.Shutdown()
+
+		if configFilePath != "" {
+			Expect(os.Remove(configFilePath)).To(Succeed())
+		}
+		if session != nil {
+			Eventually(session.Kill()).Should(gexec.Exit())
+		}
+	})
+
+	When("route emitter is executed with valid config", func() {
+		It("should be able to start properly", func() {
+			Consistently(session, "5s").ShouldNot(gexec.Exit())
+		})
+
+		It("serves the health endpoints", func() {
+			for _, endpoint := range []string{"healthz", "readyz"} {
+				url := fmt.Sprintf("http://localhost:%d/%s", config.HealthProbePort, endpoint)
+				Eventually(func() (int, error) {
+					resp, err := http.Get(url) //#nosec G107
+					if err != nil {
+						return 0, err
+					}
+					defer resp.Body.Close()
+
+					return resp.StatusCode, nil
+				}).Should(Equal(http.StatusOK))
+			}
+		})
+	})
+
+	When("the config file doesn't exist", func() {
+		It("exits reporting missing config file", func() {
+			session = eiriniBins.RouteEmitter.Restart("/does/not/exist", session)
+			Eventually(session).Should(gexec.Exit())
+			Expect(session.ExitCode).ToNot(BeZero())
+			Expect(session.Err).To(gbytes.Say("failed to read config from /does/not/exist: failed to read file"))
+		})
+	})
+
+	When("nonexsistent kubeconfig path is provided", func() {
+		BeforeEach(func() {
+			config.ConfigPath = "foo"
+		})
+
+		It("fails", func() {
+			Eventually(session).Should(gexec.Exit())
+			Expect(session.ExitCode()).NotTo(BeZero())
+			Expect(session.Err).To(gbytes.Say("foo: no such file or directory"))
+		})
+	})
+})
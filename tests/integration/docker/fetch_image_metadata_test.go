@@ -11,26 +11,76 @@ import (
 var _ = Describe("Fetch Docker Image Metadata", func() {
 	Context("public image from DockerHub", func() {
 		It("should return the correct exposed ports", func() {
-			imgConfig, err := docker.Fetch("//docker.io/eirini/custom-port:latest", types.SystemContext{})
+			imgMetadata, err := docker.Fetch("//docker.io/eirini/custom-port:latest", types.SystemContext{})
 
 			Expect(err).To(BeNil())
-			Expect(imgConfig).ToNot(BeNil())
-			Expect(imgConfig.ExposedPorts).To(HaveLen(1))
-			Expect(imgConfig.ExposedPorts).To(HaveKey("8888/tcp"))
+			Expect(imgMetadata).ToNot(BeNil())
+			Expect(imgMetadata.Config.ExposedPorts).To(HaveLen(1))
+			Expect(imgMetadata.Config.ExposedPorts).To(HaveKey("8888/tcp"))
+		})
+
+		It("should return the architecture of the image", func() {
+			imgMetadata, err := docker.Fetch("//docker.io/eirini/custom-port:latest", types.SystemContext{})
+
+			Expect(err).To(BeNil())
+			Expect(imgMetadata.Architectures).To(Equal([]string{"amd64"}))
+		})
+
+		It("should return the digest of the image manifest", func() {
+			imgMetadata, err := docker.Fetch("//docker.io/eirini/custom-port:latest", types.SystemContext{})
+
+			Expect(err).To(BeNil())
+			Expect(imgMetadata.Digest).To(MatchRegexp("^sha256:[0-9a-f]{64}$"))
+		})
+
+		Context("when the image is a manifest list", func() {
+			It("should return all the architectures of the image", func() {
+				imgMetadata, err := docker.Fetch("//docker.io/library/busybox:latest", types.SystemContext{
+					ArchitectureChoice: "arm64",
+				})
+
+				Expect(err).To(BeNil())
+				Expect(imgMetadata.Architectures).To(ContainElements("amd64", "arm64"))
+			})
+
+			It("should return the digest of the manifest list", func() {
+				amd64Metadata, err := docker.Fetch("//docker.io/library/busybox:latest", types.SystemContext{
+					ArchitectureChoice: "amd64",
+				})
+				Expect(err).To(BeNil())
+
+				arm64Metadata, err := docker.Fetch("//docker.io/library/busybox:latest", types.SystemContext{
+					ArchitectureChoice: "arm64",
+				})
+				Expect(err).To(BeNil())
+
+				Expect(amd64Metadata.Digest).To(Equal(arm64Metadata.Digest))
+			})
+
+			Context("when the image is not available for the platform", func() {
+				It("should return an error listing the available platforms", func() {
+					_, err := docker.Fetch("//docker.io/library/busybox:latest", types.SystemContext{
+						ArchitectureChoice: "sparc",
+					})
+
+					Expect(err).To(MatchError(ContainSubstring("image is not available for linux/sparc")))
+					Expect(err).To(MatchError(ContainSubstring("linux/arm64")))
+				})
+			})
 		})
 
 		Context("when repo is invalid", func() {
 			It("should return an error", func() {
-				imgConfig, err := docker.Fetch("//docker.io/eirini/no_such_image:latest", types.SystemContext{})
+				imgMetadata, err := docker.Fetch("//docker.io/eirini/no_such_image:latest", types.SystemContext{})
 
 				Expect(err).To(MatchError(ContainSubstring("failed to get image source")))
-				Expect(imgConfig).To(BeNil())
+				Expect(imgMetadata).To(BeNil())
 			})
 		})
 
 		Context("private image from DockerHub", func() {
 			It("should return the correct exposed ports", func() {
-				imgConfig, err := docker.Fetch("//docker.io/eiriniuser/notdora:custom-port", types.SystemContext{
+				imgMetadata, err := docker.Fetch("//docker.io/eiriniuser/notdora:custom-port", types.SystemContext{
 					DockerAuthConfig: &types.DockerAuthConfig{
 						Username: "eiriniuser",
 						Password: tests.GetEiriniDockerHubPassword(),
@@ -38,9 +88,9 @@ var _ = Describe("Fetch Docker Image Metadata", func() {
 				})
 
 				Expect(err).To(BeNil())
-				Expect(imgConfig).ToNot(BeNil())
-				Expect(imgConfig.ExposedPorts).To(HaveLen(1))
-				Expect(imgConfig.ExposedPorts).To(HaveKey("8888/tcp"))
+				Expect(imgMetadata).ToNot(BeNil())
+				Expect(imgMetadata.Config.ExposedPorts).To(HaveLen(1))
+				Expect(imgMetadata.Config.ExposedPorts).To(HaveKey("8888/tcp"))
 			})
 		})
 	})
@@ -92,6 +92,7 @@ var _ = Describe("Events", func() {
 				false,
 				k8s.CreateLivenessProbe,
 				k8s.CreateReadinessProbe,
+				eirini.RouteIntegrityConfig{},
 			)
 			lrpClient = k8s.NewLRPClient(
 				logger,
@@ -100,6 +101,7 @@ var _ = Describe("Events", func() {
 				client.NewPod(fixture.Clientset, fixture.Namespace),
 				client.NewPodDisruptionBudget(fixture.Clientset),
 				client.NewEvent(fixture.Clientset),
+				client.NewService(fixture.Clientset),
 				lrpToStatefulSetConverter,
 				stset.NewStatefulSetToLRPConverter(),
 			)
@@ -22,7 +22,7 @@ var _ = Describe("Kubelet Client", func() {
 		Expect(nodes.Items).ToNot(BeEmpty())
 
 		name := nodes.Items[0].Name
-		stats, err := client.StatsSummary(name)
+		stats, err := client.StatsSummary(context.Background(), name)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(stats.Pods).ToNot(BeEmpty())
 		Expect(stats.Pods[0].PodRef.Name).ToNot(BeEmpty())
@@ -33,7 +33,7 @@ var _ = Describe("Kubelet Client", func() {
 	When("the node name is not correct", func() {
 		It("should retrun an error", func() {
 			name := "does-not-exist"
-			_, err := client.StatsSummary(name)
+			_, err := client.StatsSummary(context.Background(), name)
 			Expect(err).To(HaveOccurred())
 		})
 	})
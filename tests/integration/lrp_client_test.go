@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/k8s/client"
 	"code.cloudfoundry.org/eirini/k8s/stset"
@@ -13,6 +14,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,6 +49,7 @@ var _ = Describe("LRPClient", func() {
 			false,
 			k8s.CreateLivenessProbe,
 			k8s.CreateReadinessProbe,
+			eirini.RouteIntegrityConfig{},
 		)
 		lrpClient = k8s.NewLRPClient(
 			logger,
@@ -55,6 +58,7 @@ var _ = Describe("LRPClient", func() {
 			client.NewPod(fixture.Clientset, fixture.Namespace),
 			client.NewPodDisruptionBudget(fixture.Clientset),
 			client.NewEvent(fixture.Clientset),
+			client.NewService(fixture.Clientset),
 			lrpToStatefulSetConverter,
 			stset.NewStatefulSetToLRPConverter(),
 		)
@@ -76,7 +80,21 @@ var _ = Describe("LRPClient", func() {
 			Expect(statefulset.Spec.Template.Spec.Containers[0].Command).To(Equal(odinLRP.Command))
 			Expect(statefulset.Spec.Template.Spec.Containers[0].Image).To(Equal(odinLRP.Image))
 			Expect(statefulset.Spec.Replicas).To(Equal(int32ptr(odinLRP.TargetInstances)))
-			Expect(statefulset.Annotations[stset.AnnotationOriginalRequest]).To(Equal(odinLRP.LRP))
+			Expect(statefulset.Annotations).NotTo(HaveKey(stset.AnnotationOriginalRequest))
+		})
+
+		It("should keep the redacted original request in a secret owned by the StatefulSet", func() {
+			statefulset := getStatefulSetForLRP(odinLRP)
+			secretName := statefulset.Annotations[stset.AnnotationOriginalRequestSecret]
+			Expect(secretName).NotTo(BeEmpty())
+
+			secret, err := fixture.Clientset.CoreV1().Secrets(fixture.Namespace).Get(context.Background(), secretName, v1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secret.OwnerReferences).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Kind": Equal("StatefulSet"),
+				"Name": Equal(statefulset.Name),
+			})))
+			Expect(stset.ReadOriginalRequest(secret)).To(Equal("[REDACTED]"))
 		})
 
 		It("should create all associated pods", func() {
@@ -215,7 +233,8 @@ var _ = Describe("LRPClient", func() {
 			BeforeEach(func() {
 				odinLRP.Image = "eirini/nginx-integration"
 				odinLRP.Command = nil
-				odinLRP.RunsAsRoot = true
+				root := int64(0)
+				odinLRP.RunAsUser = &root
 				odinLRP.Health.Type = "http"
 				odinLRP.Health.Port = 8080
 			})
@@ -270,7 +289,7 @@ var _ = Describe("LRPClient", func() {
 
 			statefulsetName = getStatefulSetForLRP(odinLRP).Name
 
-			err = lrpClient.Stop(odinLRP.LRPIdentifier)
+			_, err = lrpClient.Stop(odinLRP.LRPIdentifier)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
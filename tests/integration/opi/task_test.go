@@ -80,11 +80,24 @@ var _ = Describe("Tasks", func() {
 				By("specifying the right containers", func() {
 					jobContainers := jobsList.Items[0].Spec.Template.Spec.Containers
 					Expect(jobContainers).To(HaveLen(1))
-					Expect(jobContainers[0].Env).To(ContainElement(corev1.EnvVar{Name: "my-env", Value: "my-value"}))
 					Expect(jobContainers[0].Image).To(Equal("eirini/busybox"))
 					Expect(jobContainers[0].Command).To(ConsistOf("/bin/echo", "hello"))
 				})
 
+				By("taking the environment from the env secret of the job", func() {
+					envSecretName := ""
+					for _, envVar := range jobsList.Items[0].Spec.Template.Spec.Containers[0].Env {
+						if envVar.Name == "my-env" && envVar.ValueFrom != nil && envVar.ValueFrom.SecretKeyRef != nil {
+							envSecretName = envVar.ValueFrom.SecretKeyRef.Name
+						}
+					}
+					Expect(envSecretName).To(HavePrefix(jobsList.Items[0].Name + "-env-"))
+
+					envSecret, err := fixture.Clientset.CoreV1().Secrets(fixture.Namespace).Get(context.Background(), envSecretName, metav1.GetOptions{})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(envSecret.Data).To(HaveKeyWithValue("my-env", []byte("my-value")))
+				})
+
 				By("not mounting the service account token", func() {
 					Eventually(func() ([]corev1.Pod, error) {
 						pods, err := fixture.Clientset.CoreV1().Pods(fixture.Namespace).List(context.Background(), metav1.ListOptions{})
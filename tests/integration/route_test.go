@@ -3,6 +3,7 @@ package integration_test
 import (
 	"sync"
 
+	"code.cloudfoundry.org/eirini"
 	"code.cloudfoundry.org/eirini/k8s"
 	"code.cloudfoundry.org/eirini/k8s/client"
 	informerroute "code.cloudfoundry.org/eirini/k8s/informers/route"
@@ -41,6 +42,7 @@ var _ = Describe("Routes", func() {
 			false,
 			k8s.CreateLivenessProbe,
 			k8s.CreateReadinessProbe,
+			eirini.RouteIntegrityConfig{},
 		)
 		lrpClient = k8s.NewLRPClient(
 			logger,
@@ -49,6 +51,7 @@ var _ = Describe("Routes", func() {
 			client.NewPod(fixture.Clientset, fixture.Namespace),
 			client.NewPodDisruptionBudget(fixture.Clientset),
 			client.NewEvent(fixture.Clientset),
+			client.NewService(fixture.Clientset),
 			lrpToStatefulSetConverter,
 			stset.NewStatefulSetToLRPConverter(),
 		)
@@ -197,7 +200,8 @@ fi;`,
 
 		When("an app is stopped", func() {
 			It("sends unregister routes message", func() {
-				Expect(lrpClient.Stop(odinLRP.LRPIdentifier)).To(Succeed())
+				_, err := lrpClient.Stop(odinLRP.LRPIdentifier)
+				Expect(err).NotTo(HaveOccurred())
 				pods := listPods(odinLRP.LRPIdentifier)
 
 				Eventually(fakeRouteEmitter.EmitCallCount).Should(Equal(2))
@@ -329,7 +333,8 @@ fi;`,
 		When("an app instance is stopped", func() {
 			It("sends unregister routes message", func() {
 				pods := listPods(odinLRP.LRPIdentifier)
-				Expect(lrpClient.StopInstance(odinLRP.LRPIdentifier, 0)).To(Succeed())
+				_, err := lrpClient.StopInstance(odinLRP.LRPIdentifier, 0)
+				Expect(err).NotTo(HaveOccurred())
 
 				Eventually(fakeRouteEmitter.EmitCallCount).Should(Equal(1))
 				Expect(fakeRouteEmitter.EmitArgsForCall(0)).To(Equal(route.Message{